@@ -3,6 +3,7 @@ package adapter
 import (
 	"context"
 	"net/netip"
+	"time"
 
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
@@ -19,14 +20,24 @@ type DNSRouter interface {
 	Exchange(ctx context.Context, message *dns.Msg, options DNSQueryOptions) (*dns.Msg, error)
 	Lookup(ctx context.Context, domain string, options DNSQueryOptions) ([]netip.Addr, error)
 	ClearCache()
+	Rules() []DNSRule
 	LookupReverseMapping(ip netip.Addr) (string, bool)
+	// LookupNAT64 returns the IPv4 address embedded in an address synthesized by DNS64.
+	LookupNAT64(ip netip.Addr) (netip.Addr, bool)
+	TestDNS(ctx context.Context, metadata InboundContext) *DNSTestResult
+	// QueryLog returns nil if the query log is disabled.
+	QueryLog() DNSQueryLog
 	ResetNetwork()
 }
 
 type DNSClient interface {
 	Start()
+	Close() error
 	Exchange(ctx context.Context, transport DNSTransport, message *dns.Msg, options DNSQueryOptions, responseChecker func(responseAddrs []netip.Addr) bool) (*dns.Msg, error)
 	Lookup(ctx context.Context, transport DNSTransport, domain string, options DNSQueryOptions, responseChecker func(responseAddrs []netip.Addr) bool) ([]netip.Addr, error)
+	LookupNAT64(ip netip.Addr) (netip.Addr, bool)
+	// RegisterNAT64Prefix makes LookupNAT64 map addresses in prefix.
+	RegisterNAT64Prefix(prefix netip.Prefix)
 	ClearCache()
 }
 
@@ -37,6 +48,9 @@ type DNSQueryOptions struct {
 	DisableCache   bool
 	RewriteTTL     *uint32
 	ClientSubnet   netip.Prefix
+	RequireDNSSEC  bool
+	// DNS64Prefix synthesizes AAAA records from A records if set.
+	DNS64Prefix netip.Prefix
 }
 
 func DNSQueryOptionsFrom(ctx context.Context, options *option.DomainResolveOptions) (*DNSQueryOptions, error) {
@@ -63,6 +77,22 @@ type RDRCStore interface {
 	SaveRDRCAsync(transportName string, qName string, qType uint16, logger logger.Logger)
 }
 
+type DNSCacheStore interface {
+	LoadDNSCache() ([]DNSCacheEntry, error)
+	// SaveDNSCache replaces all saved entries.
+	SaveDNSCache(entries []DNSCacheEntry) error
+	ClearDNSCache() error
+}
+
+// DNSCacheEntry is a cached response with its original expiry time.
+// Transport is empty unless the cache is independent, and ExpireAt is zero
+// if the response never expires.
+type DNSCacheEntry struct {
+	Transport string
+	ExpireAt  time.Time
+	Message   *dns.Msg
+}
+
 type DNSTransport interface {
 	Lifecycle
 	Type() string
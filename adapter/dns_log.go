@@ -0,0 +1,48 @@
+package adapter
+
+import (
+	"time"
+)
+
+type DNSQueryLog interface {
+	// Entries returns logged queries matching the filter, newest first.
+	Entries(filter DNSQueryLogFilter) []DNSQueryLogEntry
+	Stats(top int) DNSQueryStats
+	Reset()
+}
+
+type DNSQueryLogFilter struct {
+	// Domain matches entries whose domain contains it.
+	Domain string
+	Client string
+	Limit  int
+}
+
+type DNSQueryLogEntry struct {
+	Time      time.Time     `json:"time"`
+	Client    string        `json:"client,omitempty"`
+	Inbound   string        `json:"inbound,omitempty"`
+	Domain    string        `json:"domain"`
+	QueryType string        `json:"type"`
+	Rule      string        `json:"rule,omitempty"`
+	Server    string        `json:"server,omitempty"`
+	Rcode     string        `json:"rcode"`
+	Answers   []string      `json:"answers,omitempty"`
+	Latency   time.Duration `json:"latency"`
+	Cached    bool          `json:"cached,omitempty"`
+	Blocked   bool          `json:"blocked,omitempty"`
+}
+
+type DNSQueryStats struct {
+	Queries    uint64          `json:"queries"`
+	Cached     uint64          `json:"cached"`
+	Blocked    uint64          `json:"blocked"`
+	TopDomains []DNSQueryCount `json:"top_domains"`
+	TopBlocked []DNSQueryCount `json:"top_blocked"`
+	Clients    []DNSQueryCount `json:"clients"`
+}
+
+type DNSQueryCount struct {
+	Name  string `json:"name"`
+	Count uint64 `json:"count"`
+}
@@ -23,6 +23,8 @@ type EndpointManager interface {
 	Lifecycle
 	Endpoints() []Endpoint
 	Get(tag string) (Endpoint, bool)
+	// Revision changes whenever endpoints are created or removed.
+	Revision() uint64
 	Remove(tag string) error
 	Create(ctx context.Context, router Router, logger log.ContextLogger, tag string, endpointType string, options any) error
 }
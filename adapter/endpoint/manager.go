@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"sync"
+	"sync/atomic"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/taskmonitor"
@@ -23,6 +24,7 @@ type Manager struct {
 	stage         adapter.StartStage
 	endpoints     []adapter.Endpoint
 	endpointByTag map[string]adapter.Endpoint
+	revision      atomic.Uint64
 }
 
 func NewManager(logger log.ContextLogger, registry adapter.EndpointRegistry) *Manager {
@@ -94,6 +96,10 @@ func (m *Manager) Get(tag string) (adapter.Endpoint, bool) {
 	return endpoint, found
 }
 
+func (m *Manager) Revision() uint64 {
+	return m.revision.Load()
+}
+
 func (m *Manager) Remove(tag string) error {
 	m.access.Lock()
 	endpoint, found := m.endpointByTag[tag]
@@ -109,6 +115,7 @@ func (m *Manager) Remove(tag string) error {
 		panic("invalid endpoint index")
 	}
 	m.endpoints = append(m.endpoints[:index], m.endpoints[index+1:]...)
+	m.revision.Add(1)
 	started := m.started
 	m.access.Unlock()
 	if started {
@@ -152,5 +159,6 @@ func (m *Manager) Create(ctx context.Context, router adapter.Router, logger log.
 	}
 	m.endpoints = append(m.endpoints, endpoint)
 	m.endpointByTag[tag] = endpoint
+	m.revision.Add(1)
 	return nil
 }
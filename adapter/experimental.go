@@ -7,6 +7,7 @@ import (
 	"io"
 	"time"
 
+	"github.com/sagernet/sing-box/option"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/observable"
 	"github.com/sagernet/sing/common/varbin"
@@ -56,6 +57,51 @@ type CacheFile interface {
 	StoreGroupExpand(group string, expand bool) error
 	LoadRuleSet(tag string) *SavedBinary
 	SaveRuleSet(tag string, set *SavedBinary) error
+	LoadHosts(key string) *SavedBinary
+	SaveHosts(key string, hosts *SavedBinary) error
+	LoadUserQuota(service string) []*SavedUserQuota
+	SaveUserQuota(service string, users []*SavedUserQuota) error
+
+	StoreConnectionHistory() bool
+	ConnectionHistoryStore
+
+	StoreDNS() bool
+	DNSCacheStore
+}
+
+type ConnectionHistoryStore interface {
+	SaveConnectionHistoryAsync(record *ConnectionRecord)
+	// LoadConnectionHistory returns records closed in [from, to) that are
+	// accepted by filter, newest first, at most limit records if limit > 0.
+	LoadConnectionHistory(from time.Time, to time.Time, filter func(record *ConnectionRecord) bool, limit int) ([]*ConnectionRecord, error)
+}
+
+// ConnectionRecord describes a closed connection.
+type ConnectionRecord struct {
+	ID          string    `json:"id"`
+	Network     string    `json:"network"`
+	Inbound     string    `json:"inbound"`
+	User        string    `json:"user,omitempty"`
+	Source      string    `json:"source"`
+	Destination string    `json:"destination"`
+	Host        string    `json:"host,omitempty"`
+	Process     string    `json:"process,omitempty"`
+	Rule        string    `json:"rule"`
+	Outbound    string    `json:"outbound"`
+	Chain       []string  `json:"chain"`
+	Upload      int64     `json:"upload"`
+	Download    int64     `json:"download"`
+	CreatedAt   time.Time `json:"start"`
+	ClosedAt    time.Time `json:"end"`
+}
+
+type SavedUserQuota struct {
+	Name      string    `json:"name"`
+	Uplink    int64     `json:"uplink"`
+	Downlink  int64     `json:"downlink"`
+	LastReset time.Time `json:"last_reset"`
+	// Options is only saved for users added at runtime.
+	Options *option.UserQuotaUser `json:"options,omitempty"`
 }
 
 type SavedBinary struct {
@@ -5,10 +5,13 @@ import (
 	"net/netip"
 	"time"
 
+	"github.com/sagernet/sing-box/common/ratelimit"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/miekg/dns"
 )
 
 type Inbound interface {
@@ -52,12 +55,22 @@ type InboundContext struct {
 
 	// sniffer
 
-	Protocol     string
-	Domain       string
-	Client       string
-	SniffContext any
-	SnifferNames []string
-	SniffError   error
+	Protocol string
+	Domain   string
+	Client   string
+	// ClientHello is the sniffed TLS or QUIC client hello, kept only if
+	// client fingerprints are matched, see sniff.ClientFingerprint.
+	ClientHello     []byte
+	ClientHelloQUIC bool
+	JA3             string
+	JA4             string
+	HTTPMethod      string
+	HTTPPath        string
+	HTTPHost        string
+	HTTPUserAgent   string
+	SniffContext    any
+	SnifferNames    []string
+	SniffError      error
 
 	// cache
 
@@ -72,6 +85,7 @@ type InboundContext struct {
 	TLSFragment               bool
 	TLSFragmentFallbackDelay  time.Duration
 	TLSRecordFragment         bool
+	RateLimits                []ratelimit.Limit
 
 	NetworkStrategy     *C.NetworkStrategy
 	NetworkType         []C.InterfaceType
@@ -83,6 +97,7 @@ type InboundContext struct {
 	GeoIPCode            string
 	ProcessInfo          *ConnectionOwner
 	QueryType            uint16
+	DNSResponse          *dns.Msg
 	FakeIP               bool
 
 	// rule cache
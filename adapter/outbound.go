@@ -42,6 +42,8 @@ type OutboundManager interface {
 	Outbounds() []Outbound
 	Outbound(tag string) (Outbound, bool)
 	Default() Outbound
+	// Revision changes whenever outbounds or endpoints are created or removed.
+	Revision() uint64
 	Remove(tag string) error
 	Create(ctx context.Context, router Router, logger log.ContextLogger, tag string, outboundType string, options any) error
 	// DupOverrideDetour duplicates the outbound with the specified tag and sets the override and detour for the duplicated outbound.
@@ -0,0 +1,9 @@
+package adapter
+
+import "github.com/sagernet/sing-box/option"
+
+// ConfigReloader applies a new configuration to a running instance,
+// recreating only the components whose options changed.
+type ConfigReloader interface {
+	Reload(options option.Options) error
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"net"
 	"net/http"
+	"net/netip"
 	"sync"
 	"time"
 
@@ -25,8 +26,13 @@ type Router interface {
 	ConnectionRouterEx
 	RuleSet(tag string) (RuleSet, bool)
 	Rules() []Rule
+	TestRoute(ctx context.Context, metadata InboundContext) (*RouteTestResult, error)
 	NeedFindProcess() bool
+	LookupASN(addr netip.Addr) (uint32, bool)
 	AppendTracker(tracker ConnectionTracker)
+	RemoveTracker(tracker ConnectionTracker)
+	AppendAuthorizer(authorizer UserAuthorizer)
+	RemoveAuthorizer(authorizer UserAuthorizer)
 	ResetNetwork()
 }
 
@@ -35,6 +41,13 @@ type ConnectionTracker interface {
 	RoutedPacketConnection(ctx context.Context, conn N.PacketConn, metadata InboundContext, matchedRule Rule, matchOutbound Outbound) N.PacketConn
 }
 
+// UserAuthorizer rejects connections of authenticated users before routing.
+// The returned release function is called once routing finishes, successfully or not,
+// after trackers have been called for routed connections.
+type UserAuthorizer interface {
+	AuthorizeUser(ctx context.Context, metadata InboundContext) (release func(), err error)
+}
+
 // Deprecated: Use ConnectionRouterEx instead.
 type ConnectionRouter interface {
 	RouteConnection(ctx context.Context, conn net.Conn, metadata InboundContext) error
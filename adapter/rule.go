@@ -1,6 +1,9 @@
 package adapter
 
 import (
+	"sync/atomic"
+	"time"
+
 	C "github.com/sagernet/sing-box/constant"
 )
 
@@ -14,12 +17,43 @@ type Rule interface {
 	SimpleLifecycle
 	Type() string
 	Action() RuleAction
+	Statistics() *RuleStatistics
 }
 
 type DNSRule interface {
 	Rule
 	WithAddressLimit() bool
 	MatchAddressLimit(metadata *InboundContext) bool
+	// MatchResponse reports whether the rule is evaluated against responses.
+	MatchResponse() bool
+}
+
+// RuleStatistics counts matches of a rule and the traffic routed by it.
+type RuleStatistics struct {
+	Hits      atomic.Int64
+	Upload    atomic.Int64
+	Download  atomic.Int64
+	lastMatch atomic.Int64
+}
+
+func (s *RuleStatistics) Hit() {
+	s.Hits.Add(1)
+	s.lastMatch.Store(time.Now().UnixNano())
+}
+
+func (s *RuleStatistics) LastMatch() time.Time {
+	lastMatch := s.lastMatch.Load()
+	if lastMatch == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, lastMatch)
+}
+
+func (s *RuleStatistics) Reset() {
+	s.Hits.Store(0)
+	s.Upload.Store(0)
+	s.Download.Store(0)
+	s.lastMatch.Store(0)
 }
 
 type RuleAction interface {
@@ -29,7 +63,7 @@ type RuleAction interface {
 
 func IsFinalAction(action RuleAction) bool {
 	switch action.Type() {
-	case C.RuleActionTypeSniff, C.RuleActionTypeResolve:
+	case C.RuleActionTypeSniff, C.RuleActionTypeResolve, C.RuleActionTypeLimit:
 		return false
 	default:
 		return true
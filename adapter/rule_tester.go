@@ -0,0 +1,166 @@
+package adapter
+
+import (
+	"net/netip"
+	"path/filepath"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/miekg/dns"
+)
+
+// RuleTestRequest describes a hypothetical connection to be matched
+// against route and DNS rules.
+type RuleTestRequest struct {
+	Inbound     string `json:"inbound,omitempty"`
+	InboundType string `json:"inbound_type,omitempty"`
+	Network     string `json:"network,omitempty"`
+	Source      string `json:"source,omitempty"`
+	SourcePort  uint16 `json:"source_port,omitempty"`
+	Domain      string `json:"domain,omitempty"`
+	IP          string `json:"ip,omitempty"`
+	Port        uint16 `json:"port,omitempty"`
+	Protocol    string `json:"protocol,omitempty"`
+	Client      string `json:"client,omitempty"`
+	QueryType   string `json:"query_type,omitempty"`
+	User        string `json:"user,omitempty"`
+	ProcessName string `json:"process_name,omitempty"`
+	ProcessPath string `json:"process_path,omitempty"`
+	PackageName string `json:"package_name,omitempty"`
+	ProcessUser string `json:"process_user,omitempty"`
+}
+
+// ruleTestProcessDirectory is the directory of the process path simulated
+// if only the process name is given, so that process_path rules do not
+// match as no real path is in it.
+const ruleTestProcessDirectory = "<unknown>"
+
+func (r RuleTestRequest) Metadata() (InboundContext, error) {
+	var metadata InboundContext
+	metadata.Inbound = r.Inbound
+	metadata.InboundType = r.InboundType
+	switch r.Network {
+	case "":
+		metadata.Network = N.NetworkTCP
+	case N.NetworkTCP, N.NetworkUDP, N.NetworkICMP:
+		metadata.Network = r.Network
+	default:
+		return InboundContext{}, E.New("unknown network: ", r.Network)
+	}
+	if r.Source != "" {
+		sourceAddr, err := netip.ParseAddr(r.Source)
+		if err != nil {
+			return InboundContext{}, E.Cause(err, "parse source")
+		}
+		metadata.Source = M.SocksaddrFrom(sourceAddr, r.SourcePort)
+	}
+	if r.IP != "" {
+		destinationAddr, err := netip.ParseAddr(r.IP)
+		if err != nil {
+			return InboundContext{}, E.Cause(err, "parse ip")
+		}
+		metadata.Destination = M.SocksaddrFrom(destinationAddr, r.Port)
+		metadata.Domain = r.Domain
+	} else if r.Domain != "" {
+		metadata.Destination = M.Socksaddr{Fqdn: r.Domain, Port: r.Port}
+	} else {
+		return InboundContext{}, E.New("missing domain or ip")
+	}
+	metadata.Protocol = r.Protocol
+	metadata.Client = r.Client
+	if r.QueryType != "" {
+		queryType, loaded := dns.StringToType[r.QueryType]
+		if !loaded {
+			return InboundContext{}, E.New("unknown query type: ", r.QueryType)
+		}
+		metadata.QueryType = queryType
+	}
+	metadata.User = r.User
+	if r.ProcessName != "" || r.ProcessPath != "" || r.PackageName != "" || r.ProcessUser != "" {
+		processInfo := &ConnectionOwner{
+			UserName:    r.ProcessUser,
+			ProcessPath: r.ProcessPath,
+		}
+		if r.ProcessName != "" {
+			// process_name rules match the base name of the path
+			if processInfo.ProcessPath == "" {
+				processInfo.ProcessPath = filepath.Join(ruleTestProcessDirectory, r.ProcessName)
+			} else if filepath.Base(processInfo.ProcessPath) != r.ProcessName {
+				return InboundContext{}, E.New("process name ", r.ProcessName, " does not match process path ", r.ProcessPath)
+			}
+		}
+		if r.PackageName != "" {
+			processInfo.AndroidPackageNames = []string{r.PackageName}
+		}
+		metadata.ProcessInfo = processInfo
+	}
+	return metadata, nil
+}
+
+// RuleTrace records the evaluation of one rule.
+type RuleTrace struct {
+	Index   int    `json:"index"`
+	Rule    string `json:"rule"`
+	Action  string `json:"action"`
+	Matched bool   `json:"matched"`
+	Note    string `json:"note,omitempty"`
+}
+
+type RouteTestResult struct {
+	RuleIndex   int         `json:"rule_index"`
+	Rule        string      `json:"rule,omitempty"`
+	Action      string      `json:"action"`
+	Outbound    string      `json:"outbound,omitempty"`
+	Chain       []string    `json:"chain,omitempty"`
+	Destination string      `json:"destination"`
+	Trace       []RuleTrace `json:"trace"`
+}
+
+type DNSTestResult struct {
+	RuleIndex     int         `json:"rule_index"`
+	Rule          string      `json:"rule,omitempty"`
+	Action        string      `json:"action"`
+	Server        string      `json:"server,omitempty"`
+	Strategy      string      `json:"strategy,omitempty"`
+	DisableCache  bool        `json:"disable_cache,omitempty"`
+	RequireDNSSEC bool        `json:"require_dnssec,omitempty"`
+	DNS64Prefix   string      `json:"dns64_prefix,omitempty"`
+	Trace         []RuleTrace `json:"trace"`
+}
+
+// OutboundChain returns tags of outbound and the outbounds selected by it
+// if it is a group.
+func OutboundChain(outbound Outbound) []string {
+	var chain []string
+	walkOutboundChain(outbound, func(it Outbound) {
+		chain = append(chain, it.Tag())
+	})
+	return chain
+}
+
+// FinalOutbound returns the outbound finally selected by outbound if it is
+// a group, or outbound itself.
+func FinalOutbound(outbound Outbound) Outbound {
+	walkOutboundChain(outbound, func(it Outbound) {
+		outbound = it
+	})
+	return outbound
+}
+
+func walkOutboundChain(outbound Outbound, yield func(it Outbound)) {
+	yield(outbound)
+	for depth := 0; depth < 100; depth++ {
+		group, isGroup := outbound.(OutboundGroup)
+		if !isGroup {
+			return
+		}
+		var loaded bool
+		outbound, loaded = group.Outbound(group.Now())
+		if !loaded {
+			return
+		}
+		yield(outbound)
+	}
+}
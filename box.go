@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
@@ -38,6 +39,8 @@ import (
 var _ adapter.SimpleLifecycle = (*Box)(nil)
 
 type Box struct {
+	ctx             context.Context
+	options         option.Options
 	createdAt       time.Time
 	logFactory      log.Factory
 	logger          log.ContextLogger
@@ -52,6 +55,8 @@ type Box struct {
 	connection      *route.ConnectionManager
 	router          *route.Router
 	internalService []adapter.LifecycleService
+	reloadAccess    sync.Mutex
+	started         bool
 	done            chan struct{}
 }
 
@@ -391,7 +396,7 @@ func New(options Options) (*Box, error) {
 		internalServices = append(internalServices, clashServer)
 	}
 	if needV2RayAPI {
-		v2rayServer, err := experimental.NewV2RayServer(logFactory.NewLogger("v2ray-api"), common.PtrValueOrDefault(experimentalOptions.V2RayAPI))
+		v2rayServer, err := experimental.NewV2RayServer(ctx, logFactory.NewLogger("v2ray-api"), common.PtrValueOrDefault(experimentalOptions.V2RayAPI))
 		if err != nil {
 			return nil, E.Cause(err, "create v2ray-server")
 		}
@@ -417,7 +422,9 @@ func New(options Options) (*Box, error) {
 		timeService.TimeService = ntpService
 		internalServices = append(internalServices, adapter.NewLifecycleService(ntpService, "ntp service"))
 	}
-	return &Box{
+	instance := &Box{
+		ctx:             ctx,
+		options:         options.Options,
 		network:         networkManager,
 		endpoint:        endpointManager,
 		inbound:         inboundManager,
@@ -433,7 +440,9 @@ func New(options Options) (*Box, error) {
 		logger:          logFactory.Logger(),
 		internalService: internalServices,
 		done:            make(chan struct{}),
-	}, nil
+	}
+	service.MustRegister[adapter.ConfigReloader](ctx, instance)
+	return instance, nil
 }
 
 func (s *Box) PreStart() error {
@@ -527,6 +536,9 @@ func (s *Box) start() error {
 	if err != nil {
 		return err
 	}
+	s.reloadAccess.Lock()
+	s.started = true
+	s.reloadAccess.Unlock()
 	return nil
 }
 
@@ -582,6 +594,10 @@ func (s *Box) Router() adapter.Router {
 	return s.router
 }
 
+func (s *Box) DNSRouter() adapter.DNSRouter {
+	return s.dnsRouter
+}
+
 func (s *Box) Inbound() adapter.InboundManager {
 	return s.inbound
 }
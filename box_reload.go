@@ -0,0 +1,577 @@
+package box
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/experimental"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/json"
+)
+
+var _ adapter.ConfigReloader = (*Box)(nil)
+
+// ErrRestartRequired is returned by Reload if the new configuration changes
+// options that can only be applied by restarting the instance.
+var ErrRestartRequired = E.New("configuration change requires a restart")
+
+// Reload applies options to the started instance.
+//
+// Endpoints, inbounds, outbounds, providers, services and DNS servers are
+// recreated only if their options changed, so connections on unchanged
+// outbounds survive. Route rules, rule-sets and DNS rules are replaced as a
+// whole. Other changes are rejected with ErrRestartRequired before anything
+// is touched. If applying fails half way, the previous options are restored.
+func (s *Box) Reload(options option.Options) error {
+	s.reloadAccess.Lock()
+	defer s.reloadAccess.Unlock()
+	select {
+	case <-s.done:
+		return os.ErrClosed
+	default:
+	}
+	if !s.started {
+		return E.New("service is not started")
+	}
+	reloadAt := time.Now()
+	plan, err := s.planReload(options)
+	if err != nil {
+		return err
+	}
+	err = plan.apply()
+	if err != nil {
+		s.logger.Error("reload failed, rolling back: ", err)
+		rollbackErr := plan.rollback().apply()
+		if rollbackErr != nil {
+			s.logger.Error("rollback failed, a restart is recommended to recover: ", rollbackErr)
+		}
+		return E.Cause(err, "reload")
+	}
+	s.options = options
+	s.logger.Info("sing-box reloaded (", F.Seconds(time.Since(reloadAt).Seconds()), "s)")
+	return nil
+}
+
+type optionsDiff struct {
+	removed []string
+	updated []string
+}
+
+func (d optionsDiff) changed(tag string) bool {
+	return common.Contains(d.removed, tag) || common.Contains(d.updated, tag)
+}
+
+// reverse returns the diff undoing d, where oldTags are tags before d:
+// changed components are recreated with old options, and added ones are removed.
+func (d optionsDiff) reverse(oldTags []string) optionsDiff {
+	var reversed optionsDiff
+	for _, tag := range slices.Concat(d.removed, d.updated) {
+		if common.Contains(oldTags, tag) {
+			reversed.updated = append(reversed.updated, tag)
+		} else {
+			reversed.removed = append(reversed.removed, tag)
+		}
+	}
+	return reversed
+}
+
+func diffOptions[T any](ctx context.Context, oldList []T, newList []T, tagOf func(it *T) string) (optionsDiff, error) {
+	var diff optionsDiff
+	oldContent := make(map[string][]byte, len(oldList))
+	oldTags := make([]string, 0, len(oldList))
+	for i := range oldList {
+		tag := tagOrIndex(tagOf(&oldList[i]), i)
+		content, err := json.MarshalContext(ctx, &oldList[i])
+		if err != nil {
+			return optionsDiff{}, err
+		}
+		oldContent[tag] = content
+		oldTags = append(oldTags, tag)
+	}
+	newTags := make(map[string]bool, len(newList))
+	for i := range newList {
+		tag := tagOrIndex(tagOf(&newList[i]), i)
+		content, err := json.MarshalContext(ctx, &newList[i])
+		if err != nil {
+			return optionsDiff{}, err
+		}
+		if previous, loaded := oldContent[tag]; !loaded || !bytes.Equal(previous, content) {
+			diff.updated = append(diff.updated, tag)
+		}
+		newTags[tag] = true
+	}
+	for _, tag := range oldTags {
+		if !newTags[tag] {
+			diff.removed = append(diff.removed, tag)
+		}
+	}
+	return diff, nil
+}
+
+func optionTags[T any](list []T, tagOf func(it *T) string) []string {
+	tags := make([]string, 0, len(list))
+	for i := range list {
+		tags = append(tags, tagOrIndex(tagOf(&list[i]), i))
+	}
+	return tags
+}
+
+func indexOptions[T any](list []T, tagOf func(it *T) string) map[string]*T {
+	optionsByTag := make(map[string]*T, len(list))
+	for i := range list {
+		optionsByTag[tagOrIndex(tagOf(&list[i]), i)] = &list[i]
+	}
+	return optionsByTag
+}
+
+func tagOrIndex(tag string, index int) string {
+	if tag != "" {
+		return tag
+	}
+	return F.ToString(index)
+}
+
+func optionsEqual(ctx context.Context, oldValue any, newValue any) (bool, error) {
+	oldContent, err := json.MarshalContext(ctx, oldValue)
+	if err != nil {
+		return false, err
+	}
+	newContent, err := json.MarshalContext(ctx, newValue)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(oldContent, newContent), nil
+}
+
+// reloadInvariant strips all options that can be applied by Reload.
+func reloadInvariant(options option.Options) option.Options {
+	options.RawMessage = nil
+	options.Endpoints = nil
+	options.Inbounds = nil
+	options.Outbounds = nil
+	options.Providers = nil
+	options.Services = nil
+	if options.Route != nil {
+		routeOptions := *options.Route
+		routeOptions.Rules = nil
+		routeOptions.RuleSet = nil
+		options.Route = &routeOptions
+	}
+	if options.DNS != nil {
+		dnsOptions := *options.DNS
+		dnsOptions.Servers = nil
+		dnsOptions.Rules = nil
+		options.DNS = &dnsOptions
+	}
+	return options
+}
+
+type reloadPlan struct {
+	box        *Box
+	from       option.Options
+	options    option.Options
+	endpoints  optionsDiff
+	inbounds   optionsDiff
+	outbounds  optionsDiff
+	providers  optionsDiff
+	services   optionsDiff
+	dnsServers optionsDiff
+	rules      bool
+}
+
+func (s *Box) planReload(options option.Options) (*reloadPlan, error) {
+	ctx := s.ctx
+	equal, err := optionsEqual(ctx, reloadInvariant(s.options), reloadInvariant(options))
+	if err != nil {
+		return nil, err
+	}
+	if !equal {
+		return nil, ErrRestartRequired
+	}
+	if !slices.Equal(experimental.CalculateClashModeList(s.options), experimental.CalculateClashModeList(options)) {
+		return nil, E.Cause(ErrRestartRequired, "clash mode list changed")
+	}
+	plan := &reloadPlan{
+		box:     s,
+		from:    s.options,
+		options: options,
+	}
+	plan.endpoints, err = diffOptions(ctx, s.options.Endpoints, options.Endpoints, func(it *option.Endpoint) string { return it.Tag })
+	if err != nil {
+		return nil, E.Cause(err, "compare endpoints")
+	}
+	plan.inbounds, err = diffOptions(ctx, s.options.Inbounds, options.Inbounds, func(it *option.Inbound) string { return it.Tag })
+	if err != nil {
+		return nil, E.Cause(err, "compare inbounds")
+	}
+	plan.outbounds, err = diffOptions(ctx, s.options.Outbounds, options.Outbounds, func(it *option.Outbound) string { return it.Tag })
+	if err != nil {
+		return nil, E.Cause(err, "compare outbounds")
+	}
+	plan.providers, err = diffOptions(ctx, s.options.Providers, options.Providers, func(it *option.Provider) string { return it.Tag })
+	if err != nil {
+		return nil, E.Cause(err, "compare providers")
+	}
+	plan.services, err = diffOptions(ctx, s.options.Services, options.Services, func(it *option.Service) string { return it.Tag })
+	if err != nil {
+		return nil, E.Cause(err, "compare services")
+	}
+	oldDNSOptions := common.PtrValueOrDefault(s.options.DNS)
+	newDNSOptions := common.PtrValueOrDefault(options.DNS)
+	plan.dnsServers, err = diffOptions(ctx, oldDNSOptions.Servers, newDNSOptions.Servers, func(it *option.DNSServerOptions) string { return it.Tag })
+	if err != nil {
+		return nil, E.Cause(err, "compare DNS servers")
+	}
+	for _, servers := range [][]option.DNSServerOptions{oldDNSOptions.Servers, newDNSOptions.Servers} {
+		for i, server := range servers {
+			if server.Type == C.DNSTypeFakeIP && plan.dnsServers.changed(tagOrIndex(server.Tag, i)) {
+				return nil, E.Cause(ErrRestartRequired, "fakeip server changed")
+			}
+		}
+	}
+	oldRouteOptions := common.PtrValueOrDefault(s.options.Route)
+	newRouteOptions := common.PtrValueOrDefault(options.Route)
+	for _, values := range [][2]any{
+		{oldRouteOptions.Rules, newRouteOptions.Rules},
+		{oldRouteOptions.RuleSet, newRouteOptions.RuleSet},
+		{oldDNSOptions.Rules, newDNSOptions.Rules},
+	} {
+		equal, err = optionsEqual(ctx, values[0], values[1])
+		if err != nil {
+			return nil, E.Cause(err, "compare rules")
+		}
+		if !equal {
+			plan.rules = true
+			break
+		}
+	}
+	return plan, nil
+}
+
+// rollback returns the plan restoring options the plan is applied from.
+func (p *reloadPlan) rollback() *reloadPlan {
+	return &reloadPlan{
+		box:        p.box,
+		from:       p.options,
+		options:    p.from,
+		endpoints:  p.endpoints.reverse(optionTags(p.from.Endpoints, func(it *option.Endpoint) string { return it.Tag })),
+		inbounds:   p.inbounds.reverse(optionTags(p.from.Inbounds, func(it *option.Inbound) string { return it.Tag })),
+		outbounds:  p.outbounds.reverse(optionTags(p.from.Outbounds, func(it *option.Outbound) string { return it.Tag })),
+		providers:  p.providers.reverse(optionTags(p.from.Providers, func(it *option.Provider) string { return it.Tag })),
+		services:   p.services.reverse(optionTags(p.from.Services, func(it *option.Service) string { return it.Tag })),
+		dnsServers: p.dnsServers.reverse(optionTags(common.PtrValueOrDefault(p.from.DNS).Servers, func(it *option.DNSServerOptions) string { return it.Tag })),
+		rules:      p.rules,
+	}
+}
+
+// apply removes and creates components in the plan. Missing components are
+// skipped on removal, so that a failed plan can be rolled back.
+func (p *reloadPlan) apply() error {
+	s := p.box
+	ctx := s.ctx
+	oldServices := indexOptions(p.from.Services, func(it *option.Service) string { return it.Tag })
+	newServices := indexOptions(p.options.Services, func(it *option.Service) string { return it.Tag })
+	newEndpoints := indexOptions(p.options.Endpoints, func(it *option.Endpoint) string { return it.Tag })
+	newInbounds := indexOptions(p.options.Inbounds, func(it *option.Inbound) string { return it.Tag })
+	newOutbounds := indexOptions(p.options.Outbounds, func(it *option.Outbound) string { return it.Tag })
+	newProviders := indexOptions(p.options.Providers, func(it *option.Provider) string { return it.Tag })
+	newDNSOptions := common.PtrValueOrDefault(p.options.DNS)
+	newDNSServers := indexOptions(newDNSOptions.Servers, func(it *option.DNSServerOptions) string { return it.Tag })
+
+	// Listeners go first, so that recreated ones can bind the same addresses.
+	for _, tag := range slices.Concat(p.services.removed, p.services.updated) {
+		serviceOptions, loaded := oldServices[tag]
+		if !loaded {
+			continue
+		}
+		if _, loaded = s.service.Get(serviceOptions.Type, tag); loaded {
+			s.logger.Debug("reload: remove service[", tag, "]")
+			err := s.service.Remove(serviceOptions.Type, tag)
+			if err != nil {
+				return E.Cause(err, "remove service[", tag, "]")
+			}
+		}
+	}
+	for _, tag := range slices.Concat(p.inbounds.removed, p.inbounds.updated) {
+		if _, loaded := s.inbound.Get(tag); loaded {
+			s.logger.Debug("reload: remove inbound[", tag, "]")
+			err := s.inbound.Remove(tag)
+			if err != nil {
+				return E.Cause(err, "remove inbound[", tag, "]")
+			}
+		}
+	}
+	for _, tag := range slices.Concat(p.endpoints.removed, p.endpoints.updated) {
+		if _, loaded := s.endpoint.Get(tag); loaded {
+			s.logger.Debug("reload: remove endpoint[", tag, "]")
+			err := s.endpoint.Remove(tag)
+			if err != nil {
+				return E.Cause(err, "remove endpoint[", tag, "]")
+			}
+		}
+	}
+	// Providers are removed before being recreated, since closing a provider
+	// removes outbounds under the same tags the new one creates.
+	for _, tag := range slices.Concat(p.providers.removed, p.providers.updated) {
+		if _, loaded := s.provider.Provider(tag); loaded {
+			s.logger.Debug("reload: remove provider[", tag, "]")
+			err := s.provider.Remove(tag)
+			if err != nil {
+				return E.Cause(err, "remove provider[", tag, "]")
+			}
+		}
+	}
+
+	sequence := newReloadSequence()
+	for _, tag := range p.endpoints.updated {
+		endpointOptions := newEndpoints[tag]
+		s.logger.Debug("reload: create endpoint[", tag, "]")
+		err := s.endpoint.Create(
+			adapter.WithContext(ctx, &adapter.InboundContext{Outbound: tag}),
+			s.router,
+			s.logFactory.NewLogger(F.ToString("endpoint/", endpointOptions.Type, "[", tag, "]")),
+			tag,
+			endpointOptions.Type,
+			endpointOptions.Options,
+		)
+		if err != nil {
+			return E.Cause(err, "create endpoint[", tag, "]")
+		}
+		sequence.created(tag)
+	}
+	for _, tag := range p.providers.updated {
+		providerOptions := newProviders[tag]
+		s.logger.Debug("reload: create provider[", tag, "]")
+		err := s.provider.Create(ctx, s.router, s.logFactory, tag, providerOptions.Type, providerOptions.Options)
+		if err != nil {
+			return E.Cause(err, "create provider[", tag, "]")
+		}
+	}
+
+	pendingOutbounds := p.outbounds.updated
+	if len(p.providers.removed)+len(p.providers.updated) > 0 {
+		// groups hold the providers they are initialized with
+		for _, outbound := range s.outbound.Outbounds() {
+			_, isConfigured := newOutbounds[outbound.Tag()]
+			_, isProviderGroup := outbound.(interface{ Providers() []adapter.Provider })
+			if isConfigured && isProviderGroup && !common.Contains(pendingOutbounds, outbound.Tag()) {
+				pendingOutbounds = append(pendingOutbounds, outbound.Tag())
+			}
+		}
+	}
+	err := sequence.createAll(pendingOutbounds, func(tag string) error {
+		outboundOptions := newOutbounds[tag]
+		s.logger.Debug("reload: create outbound[", tag, "]")
+		return s.outbound.Create(
+			adapter.WithContext(ctx, &adapter.InboundContext{Outbound: tag}),
+			s.router,
+			s.logFactory.NewLogger(F.ToString("outbound/", outboundOptions.Type, "[", tag, "]")),
+			tag,
+			outboundOptions.Type,
+			outboundOptions.Options,
+		)
+	}, func() []*reloadNode {
+		var nodes []*reloadNode
+		for _, it := range s.outbound.Outbounds() {
+			if _, isConfigured := newOutbounds[it.Tag()]; isConfigured {
+				nodes = append(nodes, &reloadNode{tag: it.Tag(), dependencies: it.Dependencies()})
+			}
+		}
+		return nodes
+	})
+	if err != nil {
+		return E.Cause(err, "create outbounds")
+	}
+
+	err = sequence.createAll(p.dnsServers.updated, func(tag string) error {
+		serverOptions := newDNSServers[tag]
+		s.logger.Debug("reload: create DNS server[", tag, "]")
+		return s.dnsTransport.Create(
+			ctx,
+			s.logFactory.NewLogger(F.ToString("dns/", serverOptions.Type, "[", tag, "]")),
+			tag,
+			serverOptions.Type,
+			serverOptions.Options,
+		)
+	}, func() []*reloadNode {
+		var nodes []*reloadNode
+		for _, it := range s.dnsTransport.Transports() {
+			if _, isConfigured := newDNSServers[it.Tag()]; isConfigured {
+				nodes = append(nodes, &reloadNode{tag: it.Tag(), dependencies: it.Dependencies()})
+			}
+		}
+		return nodes
+	})
+	if err != nil {
+		return E.Cause(err, "create DNS servers")
+	}
+
+	if p.rules {
+		s.logger.Debug("reload: replace rules")
+		ruleReload, err := s.router.PrepareReload(common.PtrValueOrDefault(p.options.Route), newDNSOptions)
+		if err != nil {
+			return E.Cause(err, "initialize router")
+		}
+		commitDNSRules, err := s.dnsRouter.PrepareReload(ruleReload.Context(), newDNSOptions.Rules)
+		if err != nil {
+			ruleReload.Abort()
+			return E.Cause(err, "initialize dns router")
+		}
+		ruleReload.Commit()
+		commitDNSRules()
+	}
+
+	err = removeAll(p.outbounds.removed, func(tag string) error {
+		if _, loaded := s.outbound.Outbound(tag); !loaded {
+			return nil
+		}
+		s.logger.Debug("reload: remove outbound[", tag, "]")
+		return s.outbound.Remove(tag)
+	})
+	if err != nil {
+		return E.Cause(err, "remove outbounds")
+	}
+	err = removeAll(p.dnsServers.removed, func(tag string) error {
+		if _, loaded := s.dnsTransport.Transport(tag); !loaded {
+			return nil
+		}
+		s.logger.Debug("reload: remove DNS server[", tag, "]")
+		return s.dnsTransport.Remove(tag)
+	})
+	if err != nil {
+		return E.Cause(err, "remove DNS servers")
+	}
+
+	for _, tag := range p.inbounds.updated {
+		inboundOptions := newInbounds[tag]
+		s.logger.Debug("reload: create inbound[", tag, "]")
+		err = s.inbound.Create(
+			ctx,
+			s.router,
+			s.logFactory.NewLogger(F.ToString("inbound/", inboundOptions.Type, "[", tag, "]")),
+			tag,
+			inboundOptions.Type,
+			inboundOptions.Options,
+		)
+		if err != nil {
+			return E.Cause(err, "create inbound[", tag, "]")
+		}
+	}
+	for _, tag := range p.services.updated {
+		serviceOptions := newServices[tag]
+		s.logger.Debug("reload: create service[", tag, "]")
+		err = s.service.Create(
+			ctx,
+			s.logFactory.NewLogger(F.ToString("service/", serviceOptions.Type, "[", tag, "]")),
+			tag,
+			serviceOptions.Type,
+			serviceOptions.Options,
+		)
+		if err != nil {
+			return E.Cause(err, "create service[", tag, "]")
+		}
+	}
+	return nil
+}
+
+type reloadNode struct {
+	tag          string
+	dependencies []string
+}
+
+// reloadSequence orders recreation of components that depend on each other.
+// A component is recreated again whenever one of its dependencies was
+// recreated after it, since it may still hold the replaced instance.
+type reloadSequence struct {
+	counter  int
+	sequence map[string]int
+}
+
+func newReloadSequence() *reloadSequence {
+	return &reloadSequence{
+		sequence: make(map[string]int),
+	}
+}
+
+func (s *reloadSequence) created(tag string) {
+	s.counter++
+	s.sequence[tag] = s.counter
+}
+
+func (s *reloadSequence) createAll(pending []string, create func(tag string) error, nodes func() []*reloadNode) error {
+	pending = slices.Clone(pending)
+	var maxRounds int
+	for round := 0; ; round++ {
+		currentNodes := nodes()
+		if round == 0 {
+			maxRounds = len(currentNodes) + len(pending) + 1
+		}
+		for _, node := range currentNodes {
+			if common.Contains(pending, node.tag) {
+				continue
+			}
+			for _, dependency := range node.dependencies {
+				if s.sequence[dependency] > s.sequence[node.tag] {
+					pending = append(pending, node.tag)
+					break
+				}
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		if round >= maxRounds {
+			return E.New("circular dependency between: ", strings.Join(pending, ", "))
+		}
+		var (
+			failed []string
+			errors []error
+		)
+		for _, tag := range pending {
+			err := create(tag)
+			if err != nil {
+				failed = append(failed, tag)
+				errors = append(errors, E.Cause(err, "create [", tag, "]"))
+				continue
+			}
+			s.created(tag)
+		}
+		if len(failed) == len(pending) {
+			return E.Errors(errors...)
+		}
+		pending = failed
+	}
+}
+
+// removeAll removes components, retrying those still depended on by others
+// that are removed later.
+func removeAll(tags []string, remove func(tag string) error) error {
+	pending := tags
+	for len(pending) > 0 {
+		var (
+			failed []string
+			errors []error
+		)
+		for _, tag := range pending {
+			err := remove(tag)
+			if err != nil {
+				failed = append(failed, tag)
+				errors = append(errors, E.Cause(err, "remove [", tag, "]"))
+			}
+		}
+		if len(failed) == len(pending) {
+			return E.Errors(errors...)
+		}
+		pending = failed
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package box
+
+import (
+	"context"
+	"testing"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testReloadOptions struct {
+	Tag   string `json:"tag,omitempty"`
+	Value int    `json:"value,omitempty"`
+}
+
+func testReloadTag(it *testReloadOptions) string {
+	return it.Tag
+}
+
+func TestDiffOptions(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name    string
+		oldList []testReloadOptions
+		newList []testReloadOptions
+		removed []string
+		updated []string
+	}{
+		{
+			name:    "unchanged",
+			oldList: []testReloadOptions{{Tag: "a", Value: 1}, {Tag: "b"}},
+			newList: []testReloadOptions{{Tag: "b"}, {Tag: "a", Value: 1}},
+		},
+		{
+			name:    "added",
+			oldList: []testReloadOptions{{Tag: "a"}},
+			newList: []testReloadOptions{{Tag: "a"}, {Tag: "b"}},
+			updated: []string{"b"},
+		},
+		{
+			name:    "removed",
+			oldList: []testReloadOptions{{Tag: "a"}, {Tag: "b"}},
+			newList: []testReloadOptions{{Tag: "b"}},
+			removed: []string{"a"},
+		},
+		{
+			name:    "updated",
+			oldList: []testReloadOptions{{Tag: "a", Value: 1}, {Tag: "b"}},
+			newList: []testReloadOptions{{Tag: "a", Value: 2}, {Tag: "b"}},
+			updated: []string{"a"},
+		},
+		{
+			name:    "untagged by index",
+			oldList: []testReloadOptions{{Value: 1}, {Value: 2}},
+			newList: []testReloadOptions{{Value: 2}},
+			removed: []string{"1"},
+			updated: []string{"0"},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			diff, err := diffOptions(context.Background(), testCase.oldList, testCase.newList, testReloadTag)
+			require.NoError(t, err)
+			require.Equal(t, testCase.removed, diff.removed)
+			require.Equal(t, testCase.updated, diff.updated)
+		})
+	}
+}
+
+func TestOptionsDiffReverse(t *testing.T) {
+	t.Parallel()
+	diff := optionsDiff{
+		removed: []string{"a"},
+		updated: []string{"b", "c"},
+	}
+	reversed := diff.reverse([]string{"a", "b", "d"})
+	require.Equal(t, []string{"c"}, reversed.removed)
+	require.Equal(t, []string{"a", "b"}, reversed.updated)
+}
+
+func TestReloadSequence(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name         string
+		created      []string
+		pending      []string
+		dependencies map[string][]string
+		// failOnce fails the first creation of a tag
+		failOnce []string
+		order    []string
+		err      bool
+	}{
+		{
+			name:         "independent",
+			pending:      []string{"a", "b"},
+			dependencies: map[string][]string{"a": nil, "b": nil, "c": nil},
+			order:        []string{"a", "b"},
+		},
+		{
+			name:         "dependents recreated",
+			pending:      []string{"c"},
+			dependencies: map[string][]string{"a": {"b"}, "b": {"c"}, "c": nil},
+			order:        []string{"c", "b", "a"},
+		},
+		{
+			name:         "dependents of created",
+			created:      []string{"endpoint"},
+			dependencies: map[string][]string{"a": {"endpoint"}, "b": nil},
+			order:        []string{"a"},
+		},
+		{
+			name:         "retry failed",
+			pending:      []string{"a", "b"},
+			dependencies: map[string][]string{"a": {"b"}, "b": nil},
+			failOnce:     []string{"a"},
+			order:        []string{"b", "a"},
+		},
+		{
+			name:         "circular",
+			pending:      []string{"a"},
+			dependencies: map[string][]string{"a": {"b"}, "b": {"a"}},
+			err:          true,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			sequence := newReloadSequence()
+			for _, tag := range testCase.created {
+				sequence.created(tag)
+			}
+			var order []string
+			failed := make(map[string]bool)
+			err := sequence.createAll(testCase.pending, func(tag string) error {
+				for _, failTag := range testCase.failOnce {
+					if tag == failTag && !failed[tag] {
+						failed[tag] = true
+						return E.New("dependency not ready")
+					}
+				}
+				order = append(order, tag)
+				return nil
+			}, func() []*reloadNode {
+				var nodes []*reloadNode
+				for tag, dependencies := range testCase.dependencies {
+					nodes = append(nodes, &reloadNode{tag: tag, dependencies: dependencies})
+				}
+				return nodes
+			})
+			if testCase.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testCase.order, order)
+		})
+	}
+}
+
+func TestRemoveAll(t *testing.T) {
+	t.Parallel()
+	dependents := map[string][]string{"b": {"a"}}
+	removed := make(map[string]bool)
+	var order []string
+	err := removeAll([]string{"b", "a"}, func(tag string) error {
+		for _, dependent := range dependents[tag] {
+			if !removed[dependent] {
+				return E.New("depended by ", dependent)
+			}
+		}
+		removed[tag] = true
+		order = append(order, tag)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, order)
+
+	err = removeAll([]string{"b"}, func(tag string) error {
+		return E.New("depended by a")
+	})
+	require.Error(t, err)
+}
@@ -4,8 +4,10 @@ import (
 	"net/netip"
 	"os"
 
+	"github.com/sagernet/sing-box/common/geoip"
 	"github.com/sagernet/sing-box/log"
 	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
 	N "github.com/sagernet/sing/common/network"
 
 	"github.com/spf13/cobra"
@@ -23,7 +25,10 @@ var commandGeoipLookup = &cobra.Command{
 	},
 }
 
+var commandGeoipLookupFlagASN string
+
 func init() {
+	commandGeoipLookup.Flags().StringVar(&commandGeoipLookupFlagASN, "asn", "", "ASN database file")
 	commandGeoip.AddCommand(commandGeoipLookup)
 }
 
@@ -40,8 +45,21 @@ func geoipLookup(address string) error {
 	_ = geoipReader.Lookup(addr.AsSlice(), &code)
 	if code != "" {
 		os.Stdout.WriteString(code + "\n")
-		return nil
+	} else {
+		os.Stdout.WriteString("unknown\n")
+	}
+	if commandGeoipLookupFlagASN != "" {
+		asnReader, err := geoip.OpenASN(commandGeoipLookupFlagASN)
+		if err != nil {
+			return E.Cause(err, "open ASN database")
+		}
+		defer asnReader.Close()
+		asn, organization, loaded := asnReader.Lookup(addr)
+		if loaded {
+			os.Stdout.WriteString(F.ToString("AS", asn, " ", organization, "\n"))
+		} else {
+			os.Stdout.WriteString("unknown ASN\n")
+		}
 	}
-	os.Stdout.WriteString("unknown\n")
 	return nil
 }
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/sagernet/sing-box"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	commandRouteTestRequest  adapter.RuleTestRequest
+	commandRouteTestFlagDNS  bool
+	commandRouteTestFlagJSON bool
+)
+
+var commandRouteTest = &cobra.Command{
+	Use:   "test <domain or IP address>",
+	Short: "Explain which rule matches a connection",
+	Long:  "Match a hypothetical connection against route and DNS rules. Inbounds and services are not started.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := routeTest(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	flags := commandRouteTest.Flags()
+	flags.StringVar(&commandRouteTestRequest.Inbound, "inbound", "", "inbound tag")
+	flags.StringVar(&commandRouteTestRequest.InboundType, "inbound-type", "", "inbound type")
+	flags.StringVarP(&commandRouteTestRequest.Network, "network", "n", "tcp", "network type")
+	flags.StringVar(&commandRouteTestRequest.Source, "source", "", "source IP address")
+	flags.Uint16Var(&commandRouteTestRequest.SourcePort, "source-port", 0, "source port")
+	flags.StringVarP(&commandRouteTestRequest.Domain, "domain", "d", "", "sniffed domain, if the argument is an IP address")
+	flags.Uint16VarP(&commandRouteTestRequest.Port, "port", "p", 443, "destination port")
+	flags.StringVar(&commandRouteTestRequest.Protocol, "protocol", "", "sniffed protocol")
+	flags.StringVar(&commandRouteTestRequest.Client, "client", "", "sniffed client")
+	flags.StringVar(&commandRouteTestRequest.QueryType, "query-type", "", "DNS query type")
+	flags.StringVarP(&commandRouteTestRequest.User, "user", "u", "", "authenticated user")
+	flags.StringVar(&commandRouteTestRequest.ProcessName, "process-name", "", "process name")
+	flags.StringVar(&commandRouteTestRequest.ProcessPath, "process-path", "", "process path")
+	flags.StringVar(&commandRouteTestRequest.PackageName, "package-name", "", "Android package name")
+	flags.StringVar(&commandRouteTestRequest.ProcessUser, "process-user", "", "process user name")
+	flags.BoolVar(&commandRouteTestFlagDNS, "dns", false, "test DNS rules too")
+	flags.BoolVar(&commandRouteTestFlagJSON, "json", false, "print result in JSON")
+	commandRoute.AddCommand(commandRouteTest)
+}
+
+func routeTest(destination string) error {
+	request := commandRouteTestRequest
+	if address := M.ParseAddr(destination); address.IsValid() {
+		request.IP = address.String()
+	} else {
+		request.Domain = destination
+	}
+	metadata, err := request.Metadata()
+	if err != nil {
+		return err
+	}
+	options, err := readConfigAndMerge()
+	if err != nil {
+		return err
+	}
+	options.Log = &option.LogOptions{Disabled: true}
+	options.Inbounds = nil
+	options.Services = nil
+	options.Experimental = nil
+	ctx, cancel := context.WithCancel(globalCtx)
+	defer cancel()
+	instance, err := box.New(box.Options{
+		Context: ctx,
+		Options: options,
+	})
+	if err != nil {
+		return E.Cause(err, "create service")
+	}
+	defer instance.Close()
+	err = instance.Start()
+	if err != nil {
+		return E.Cause(err, "start service")
+	}
+	var dnsResult *adapter.DNSTestResult
+	if commandRouteTestFlagDNS && request.Domain != "" {
+		dnsResult = instance.DNSRouter().TestDNS(ctx, metadata)
+	}
+	routeResult, err := instance.Router().TestRoute(ctx, metadata)
+	if err != nil {
+		return err
+	}
+	if commandRouteTestFlagJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(map[string]any{
+			"route": routeResult,
+			"dns":   dnsResult,
+		})
+	}
+	if dnsResult != nil {
+		os.Stdout.WriteString("dns:\n")
+		printRuleTrace(dnsResult.Trace)
+		if dnsResult.RuleIndex == -1 {
+			os.Stdout.WriteString(F.ToString("  final => server ", dnsResult.Server, "\n"))
+		} else {
+			os.Stdout.WriteString(F.ToString("  match[", dnsResult.RuleIndex, "] => ", dnsResult.Action, "\n"))
+		}
+		var dnsOptions []string
+		if dnsResult.Strategy != "" {
+			dnsOptions = append(dnsOptions, "strategy="+dnsResult.Strategy)
+		}
+		if dnsResult.DisableCache {
+			dnsOptions = append(dnsOptions, "disable_cache")
+		}
+		if dnsResult.RequireDNSSEC {
+			dnsOptions = append(dnsOptions, "require_dnssec")
+		}
+		if dnsResult.DNS64Prefix != "" {
+			dnsOptions = append(dnsOptions, "dns64_prefix="+dnsResult.DNS64Prefix)
+		}
+		if len(dnsOptions) > 0 {
+			os.Stdout.WriteString("  options: " + strings.Join(dnsOptions, ", ") + "\n")
+		}
+	}
+	os.Stdout.WriteString("route:\n")
+	printRuleTrace(routeResult.Trace)
+	var outbound string
+	if len(routeResult.Chain) > 0 {
+		outbound = F.ToString(" (", routeResult.Chain[len(routeResult.Chain)-1], ")")
+	}
+	if routeResult.RuleIndex == -1 {
+		os.Stdout.WriteString(F.ToString("  final => ", routeResult.Outbound, outbound, "\n"))
+	} else {
+		os.Stdout.WriteString(F.ToString("  match[", routeResult.RuleIndex, "] => ", routeResult.Action, outbound, "\n"))
+	}
+	return nil
+}
+
+func printRuleTrace(trace []adapter.RuleTrace) {
+	for _, item := range trace {
+		status := "miss"
+		if item.Matched {
+			status = "hit "
+		}
+		line := F.ToString("  ", status, " [", item.Index, "] ", item.Rule, " => ", item.Action)
+		if item.Note != "" {
+			line += " (" + item.Note + ")"
+		}
+		os.Stdout.WriteString(line + "\n")
+	}
+}
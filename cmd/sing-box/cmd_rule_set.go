@@ -1,6 +1,19 @@
 package main
 
 import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sagernet/sing-box/common/convertor"
+	"github.com/sagernet/sing-box/common/convertor/adguard"
+	"github.com/sagernet/sing-box/common/srs"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+
 	"github.com/spf13/cobra"
 )
 
@@ -12,3 +25,57 @@ var commandRuleSet = &cobra.Command{
 func init() {
 	mainCommand.AddCommand(commandRuleSet)
 }
+
+// readPlainRuleSet reads a rule-set in any supported format,
+// source and binary formats are detected by extension if format is empty.
+func readPlainRuleSet(sourcePath string, format string, category string) (option.PlainRuleSet, error) {
+	var (
+		reader io.Reader
+		err    error
+	)
+	if sourcePath == "stdin" {
+		reader = os.Stdin
+	} else {
+		file, err := os.Open(sourcePath)
+		if err != nil {
+			return option.PlainRuleSet{}, E.Cause(err, "read rule-set")
+		}
+		defer file.Close()
+		reader = file
+	}
+	if format == "" {
+		switch filepath.Ext(sourcePath) {
+		case ".json":
+			format = C.RuleSetFormatSource
+		case ".srs":
+			format = C.RuleSetFormatBinary
+		default:
+			return option.PlainRuleSet{}, E.New("unknown format of rule-set ", sourcePath, ", specify with --format")
+		}
+	}
+	var ruleSet option.PlainRuleSetCompat
+	switch format {
+	case C.RuleSetFormatSource:
+		var content []byte
+		content, err = io.ReadAll(reader)
+		if err != nil {
+			return option.PlainRuleSet{}, E.Cause(err, "read rule-set")
+		}
+		ruleSet, err = json.UnmarshalExtendedContext[option.PlainRuleSetCompat](globalCtx, content)
+	case C.RuleSetFormatBinary:
+		ruleSet, err = srs.Read(reader, true)
+	case "adguard":
+		var rules []option.HeadlessRule
+		rules, err = adguard.ToOptions(reader, log.StdLogger())
+		return option.PlainRuleSet{Rules: rules}, err
+	default:
+		if !convertor.IsSupported(format) {
+			return option.PlainRuleSet{}, E.New("unknown rule-set format: ", format)
+		}
+		return convertor.ToOptions(format, category, reader, log.StdLogger())
+	}
+	if err != nil {
+		return option.PlainRuleSet{}, E.Cause(err, "decode rule-set ", sourcePath)
+	}
+	return ruleSet.Upgrade()
+}
@@ -3,8 +3,10 @@ package main
 import (
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/sagernet/sing-box/common/convertor"
 	"github.com/sagernet/sing-box/common/convertor/adguard"
 	"github.com/sagernet/sing-box/common/srs"
 	C "github.com/sagernet/sing-box/constant"
@@ -16,13 +18,14 @@ import (
 )
 
 var (
-	flagRuleSetConvertType   string
-	flagRuleSetConvertOutput string
+	flagRuleSetConvertType     string
+	flagRuleSetConvertCategory string
+	flagRuleSetConvertOutput   string
 )
 
 var commandRuleSetConvert = &cobra.Command{
 	Use:   "convert [source-path]",
-	Short: "Convert adguard DNS filter to rule-set",
+	Short: "Convert adguard DNS filter or third-party rule list to rule-set",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		err := convertRuleSet(args[0])
@@ -34,7 +37,8 @@ var commandRuleSetConvert = &cobra.Command{
 
 func init() {
 	commandRuleSet.AddCommand(commandRuleSetConvert)
-	commandRuleSetConvert.Flags().StringVarP(&flagRuleSetConvertType, "type", "t", "", "Source type, available: adguard")
+	commandRuleSetConvert.Flags().StringVarP(&flagRuleSetConvertType, "type", "t", "", "Source type, available: adguard, clash-domain, clash-ipcidr, clash-classical, surge, quantumult, dnsmasq, geosite-dat, geoip-dat")
+	commandRuleSetConvert.Flags().StringVar(&flagRuleSetConvertCategory, "category", "", "Category for geosite-dat and geoip-dat")
 	commandRuleSetConvert.Flags().StringVarP(&flagRuleSetConvertOutput, "output", "o", flagRuleSetCompileDefaultOutput, "Output file")
 }
 
@@ -51,25 +55,33 @@ func convertRuleSet(sourcePath string) error {
 			return err
 		}
 	}
-	var rules []option.HeadlessRule
+	var plainRuleSet option.PlainRuleSet
 	switch flagRuleSetConvertType {
 	case "adguard":
-		rules, err = adguard.ToOptions(reader, log.StdLogger())
+		plainRuleSet.Rules, err = adguard.ToOptions(reader, log.StdLogger())
 	case "":
 		return E.New("source type is required")
 	default:
-		return E.New("unsupported source type: ", flagRuleSetConvertType)
+		if !convertor.IsSupported(flagRuleSetConvertType) {
+			return E.New("unsupported source type: ", flagRuleSetConvertType)
+		}
+		plainRuleSet, err = convertor.ToOptions(flagRuleSetConvertType, flagRuleSetConvertCategory, reader, log.StdLogger())
 	}
 	if err != nil {
 		return err
 	}
 	var outputPath string
 	if flagRuleSetConvertOutput == flagRuleSetCompileDefaultOutput {
-		if strings.HasSuffix(sourcePath, ".txt") {
-			outputPath = sourcePath[:len(sourcePath)-4] + ".srs"
-		} else {
-			outputPath = sourcePath + ".srs"
+		switch filepath.Ext(sourcePath) {
+		case ".txt", ".yaml", ".yml", ".list", ".conf", ".dat":
+			outputPath = strings.TrimSuffix(sourcePath, filepath.Ext(sourcePath))
+		default:
+			outputPath = sourcePath
+		}
+		if flagRuleSetConvertCategory != "" {
+			outputPath += "-" + flagRuleSetConvertCategory
 		}
+		outputPath += ".srs"
 	} else {
 		outputPath = flagRuleSetConvertOutput
 	}
@@ -78,7 +90,7 @@ func convertRuleSet(sourcePath string) error {
 		return err
 	}
 	defer outputFile.Close()
-	err = srs.Write(outputFile, option.PlainRuleSet{Rules: rules}, C.RuleSetVersion2)
+	err = srs.Write(outputFile, plainRuleSet, downgradeRuleSetVersion(C.RuleSetVersionCurrent, plainRuleSet))
 	if err != nil {
 		outputFile.Close()
 		os.Remove(outputPath)
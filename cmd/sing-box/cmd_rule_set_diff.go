@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+
+	"github.com/sagernet/sing-box/common/ruleset"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagRuleSetDiffFormats  []string
+	flagRuleSetDiffCategory string
+)
+
+var commandRuleSetDiff = &cobra.Command{
+	Use:   "diff <original-path> <new-path>",
+	Short: "Show domains and CIDRs added or removed between rule-sets",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := diffRuleSet(args[0], args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandRuleSet.AddCommand(commandRuleSetDiff)
+	commandRuleSetDiff.Flags().StringArrayVarP(&flagRuleSetDiffFormats, "format", "f", nil, "Rule-set format, detected by extension if empty, specify twice for different formats")
+	commandRuleSetDiff.Flags().StringVar(&flagRuleSetDiffCategory, "category", "", "Category for geosite-dat and geoip-dat")
+}
+
+func diffRuleSet(originalPath string, newPath string) error {
+	var originalFormat, newFormat string
+	switch len(flagRuleSetDiffFormats) {
+	case 0:
+	case 1:
+		originalFormat = flagRuleSetDiffFormats[0]
+		newFormat = flagRuleSetDiffFormats[0]
+	case 2:
+		originalFormat = flagRuleSetDiffFormats[0]
+		newFormat = flagRuleSetDiffFormats[1]
+	default:
+		return E.New("too many formats")
+	}
+	originalRuleSet, err := readPlainRuleSet(originalPath, originalFormat, flagRuleSetDiffCategory)
+	if err != nil {
+		return err
+	}
+	newRuleSet, err := readPlainRuleSet(newPath, newFormat, flagRuleSetDiffCategory)
+	if err != nil {
+		return err
+	}
+	changes, err := ruleset.Diff(originalRuleSet, newRuleSet)
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		os.Stdout.WriteString(change.String() + "\n")
+	}
+	return nil
+}
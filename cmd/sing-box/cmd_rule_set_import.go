@@ -0,0 +1,197 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/convertor/geodat"
+	"github.com/sagernet/sing-box/common/convertor/mmdb"
+	"github.com/sagernet/sing-box/common/srs"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagRuleSetImportType       string
+	flagRuleSetImportCategories []string
+	flagRuleSetImportOutput     string
+	flagRuleSetImportFormat     string
+)
+
+var commandRuleSetImport = &cobra.Command{
+	Use:   "import <source-path>",
+	Short: "Import geosite.dat, geoip.dat or mmdb database as rule-sets",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := importRuleSet(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandRuleSet.AddCommand(commandRuleSetImport)
+	commandRuleSetImport.Flags().StringVarP(&flagRuleSetImportType, "type", "t", "", "Source type, available: geosite-dat, geoip-dat, mmdb")
+	commandRuleSetImport.Flags().StringArrayVar(&flagRuleSetImportCategories, "category", nil, "Category to import, like cn or google@cn (default all)")
+	commandRuleSetImport.Flags().StringVarP(&flagRuleSetImportOutput, "output", "o", ".", "Output directory")
+	commandRuleSetImport.Flags().StringVarP(&flagRuleSetImportFormat, "format", "f", C.RuleSetFormatBinary, "Output format, available: source, binary")
+}
+
+func importRuleSet(sourcePath string) error {
+	switch flagRuleSetImportFormat {
+	case C.RuleSetFormatSource, C.RuleSetFormatBinary:
+	default:
+		return E.New("unsupported output format: ", flagRuleSetImportFormat)
+	}
+	var (
+		prefix   string
+		ruleSets map[string]option.PlainRuleSet
+		err      error
+	)
+	switch flagRuleSetImportType {
+	case C.RuleSetFormatGeoSiteDat:
+		prefix = "geosite"
+		ruleSets, err = importGeoSiteDat(sourcePath)
+	case C.RuleSetFormatGeoIPDat:
+		prefix = "geoip"
+		ruleSets, err = importGeoIPDat(sourcePath)
+	case "mmdb":
+		prefix = "geoip"
+		ruleSets, err = importMMDB(sourcePath)
+	case "":
+		return E.New("source type is required")
+	default:
+		return E.New("unsupported source type: ", flagRuleSetImportType)
+	}
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(flagRuleSetImportOutput, 0o755)
+	if err != nil {
+		return err
+	}
+	categories := make([]string, 0, len(ruleSets))
+	for category := range ruleSets {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		err = writeImportedRuleSet(filepath.Join(flagRuleSetImportOutput, prefix+"-"+category), ruleSets[category])
+		if err != nil {
+			return E.Cause(err, "write ", category)
+		}
+	}
+	log.Info("imported ", len(categories), " rule-sets")
+	return nil
+}
+
+func importGeoSiteDat(sourcePath string) (map[string]option.PlainRuleSet, error) {
+	content, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	ruleSets := make(map[string]option.PlainRuleSet)
+	if len(flagRuleSetImportCategories) == 0 {
+		geosites, err := geodat.ReadGeoSite(content)
+		if err != nil {
+			return nil, err
+		}
+		for _, geosite := range geosites {
+			ruleSets[geosite.Code] = geodat.GeoSiteToOptions(geosite.Domains, nil)
+		}
+		return ruleSets, nil
+	}
+	for _, category := range flagRuleSetImportCategories {
+		ruleSets[strings.ToLower(category)], err = geodat.GeoSiteCategoryToOptions(content, category)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ruleSets, nil
+}
+
+func importGeoIPDat(sourcePath string) (map[string]option.PlainRuleSet, error) {
+	content, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	ruleSets := make(map[string]option.PlainRuleSet)
+	if len(flagRuleSetImportCategories) == 0 {
+		geoips, err := geodat.ReadGeoIP(content)
+		if err != nil {
+			return nil, err
+		}
+		for _, geoip := range geoips {
+			ruleSets[geoip.Code] = geodat.GeoIPToOptions(geoip, false)
+		}
+		return ruleSets, nil
+	}
+	for _, category := range flagRuleSetImportCategories {
+		ruleSets[strings.ToLower(category)], err = geodat.GeoIPCategoryToOptions(content, category)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ruleSets, nil
+}
+
+func importMMDB(sourcePath string) (map[string]option.PlainRuleSet, error) {
+	countryMap, err := mmdb.ReadCountries(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	ruleSets := make(map[string]option.PlainRuleSet)
+	if len(flagRuleSetImportCategories) == 0 {
+		for code, prefixes := range countryMap {
+			ruleSets[code] = mmdb.ToOptions(prefixes)
+		}
+		return ruleSets, nil
+	}
+	for _, category := range flagRuleSetImportCategories {
+		code := strings.ToLower(category)
+		prefixes, loaded := countryMap[code]
+		if !loaded {
+			return nil, E.New("country code not found: ", category)
+		}
+		ruleSets[code] = mmdb.ToOptions(prefixes)
+	}
+	return ruleSets, nil
+}
+
+func writeImportedRuleSet(outputPath string, plainRuleSet option.PlainRuleSet) error {
+	version := downgradeRuleSetVersion(C.RuleSetVersionCurrent, plainRuleSet)
+	if flagRuleSetImportFormat == C.RuleSetFormatSource {
+		outputPath += ".json"
+	} else {
+		outputPath += ".srs"
+	}
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	if flagRuleSetImportFormat == C.RuleSetFormatSource {
+		encoder := json.NewEncoder(outputFile)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(option.PlainRuleSetCompat{
+			Version: version,
+			Options: plainRuleSet,
+		})
+	} else {
+		err = srs.Write(outputFile, plainRuleSet, version)
+	}
+	if err != nil {
+		outputFile.Close()
+		os.Remove(outputPath)
+		return err
+	}
+	outputFile.Close()
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+
+	"github.com/sagernet/sing-box/common/ruleset"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagRuleSetLintFormat   string
+	flagRuleSetLintCategory string
+)
+
+var commandRuleSetLint = &cobra.Command{
+	Use:   "lint <source-path>",
+	Short: "Check rule-set for redundant items, unreachable rules and invalid regular expressions",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := lintRuleSet(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandRuleSet.AddCommand(commandRuleSetLint)
+	commandRuleSetLint.Flags().StringVarP(&flagRuleSetLintFormat, "format", "f", "", "Rule-set format, detected by extension if empty")
+	commandRuleSetLint.Flags().StringVar(&flagRuleSetLintCategory, "category", "", "Category for geosite-dat and geoip-dat")
+}
+
+func lintRuleSet(sourcePath string) error {
+	plainRuleSet, err := readPlainRuleSet(sourcePath, flagRuleSetLintFormat, flagRuleSetLintCategory)
+	if err != nil {
+		return err
+	}
+	issues := ruleset.Lint(plainRuleSet)
+	for _, issue := range issues {
+		os.Stdout.WriteString(issue.String() + "\n")
+	}
+	if len(issues) > 0 {
+		return E.New(len(issues), " issues found")
+	}
+	return nil
+}
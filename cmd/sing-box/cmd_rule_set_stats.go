@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/sagernet/sing-box/common/ruleset"
+	"github.com/sagernet/sing-box/common/srs"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/common/byteformats"
+	F "github.com/sagernet/sing/common/format"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagRuleSetStatsFormat   string
+	flagRuleSetStatsCategory string
+)
+
+var commandRuleSetStats = &cobra.Command{
+	Use:   "stats <source-path>",
+	Short: "Count items per type and estimate memory usage of rule-set",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := statsRuleSet(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandRuleSet.AddCommand(commandRuleSetStats)
+	commandRuleSetStats.Flags().StringVarP(&flagRuleSetStatsFormat, "format", "f", "", "Rule-set format, detected by extension if empty")
+	commandRuleSetStats.Flags().StringVar(&flagRuleSetStatsCategory, "category", "", "Category for geosite-dat and geoip-dat")
+}
+
+func statsRuleSet(sourcePath string) error {
+	plainRuleSet, err := readPlainRuleSet(sourcePath, flagRuleSetStatsFormat, flagRuleSetStatsCategory)
+	if err != nil {
+		return err
+	}
+	statistics, err := ruleset.Stats(plainRuleSet)
+	if err != nil {
+		return err
+	}
+	var binaryContent bytes.Buffer
+	err = srs.Write(&binaryContent, plainRuleSet, downgradeRuleSetVersion(C.RuleSetVersionCurrent, plainRuleSet))
+	if err != nil {
+		return err
+	}
+	os.Stdout.WriteString(F.ToString("rules: ", statistics.DefaultRules+statistics.LogicalRules, " (default: ", statistics.DefaultRules, ", logical: ", statistics.LogicalRules, ")\n"))
+	for _, item := range statistics.Items {
+		os.Stdout.WriteString(F.ToString(item.Type, ": ", item.Count, " (", byteformats.FormatBytes(item.Memory), ")\n"))
+	}
+	os.Stdout.WriteString(F.ToString("estimated memory: ", byteformats.FormatBytes(statistics.Memory()), "\n"))
+	os.Stdout.WriteString(F.ToString("binary size: ", byteformats.FormatBytes(uint64(binaryContent.Len())), "\n"))
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -150,10 +151,10 @@ func jsonsMerge() (option.Options, error) {
 	return options, nil
 }
 
-func create() (*box.Box, context.CancelFunc, error) {
+func readRunOptions() (option.Options, error) {
 	options, err := readConfigAndMerge()
 	if err != nil {
-		return nil, nil, err
+		return option.Options{}, err
 	}
 	if disableColor {
 		if options.Log == nil {
@@ -161,6 +162,14 @@ func create() (*box.Box, context.CancelFunc, error) {
 		}
 		options.Log.DisableColor = true
 	}
+	return options, nil
+}
+
+func create() (*box.Box, context.CancelFunc, error) {
+	options, err := readRunOptions()
+	if err != nil {
+		return nil, nil, err
+	}
 	ctx, cancel := context.WithCancel(globalCtx)
 	instance, err := box.New(box.Options{
 		Context: ctx,
@@ -212,6 +221,16 @@ func run() error {
 					log.Error(E.Cause(err, "reload service"))
 					continue
 				}
+				err = reload(instance)
+				if err == nil {
+					runtimeDebug.FreeOSMemory()
+					continue
+				}
+				if errors.Is(err, box.ErrRestartRequired) {
+					log.Info(err, ", restarting")
+				} else {
+					log.Error(E.Cause(err, "reload service"), ", restarting")
+				}
 			}
 			cancel()
 			closeCtx, closed := context.WithCancel(context.Background())
@@ -229,6 +248,14 @@ func run() error {
 	}
 }
 
+func reload(instance *box.Box) error {
+	options, err := readRunOptions()
+	if err != nil {
+		return err
+	}
+	return instance.Reload(options)
+}
+
 func closeMonitor(ctx context.Context) {
 	time.Sleep(C.FatalStopTimeout)
 	select {
@@ -0,0 +1,160 @@
+package clash
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/convertor/internal/classical"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/goccy/go-yaml"
+)
+
+const (
+	BehaviorDomain    = "domain"
+	BehaviorIPCIDR    = "ipcidr"
+	BehaviorClassical = "classical"
+)
+
+type ruleProvider struct {
+	Payload []string `yaml:"payload"`
+}
+
+// ToOptions converts a Clash rule-provider in the YAML or text format.
+func ToOptions(reader io.Reader, behavior string, logger logger.Logger) ([]option.HeadlessRule, error) {
+	switch behavior {
+	case BehaviorDomain, BehaviorIPCIDR, BehaviorClassical:
+	default:
+		return nil, E.New("unknown rule-provider behavior: ", behavior)
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		lines    []string
+		itemName string
+	)
+	if isYAML(content) {
+		var provider ruleProvider
+		err = yaml.Unmarshal(content, &provider)
+		if err != nil {
+			return nil, E.Cause(err, "parse rule-provider")
+		}
+		lines = provider.Payload
+		itemName = "payload item "
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		err = scanner.Err()
+		if err != nil {
+			return nil, err
+		}
+		itemName = "line "
+	}
+	var (
+		rules        classical.Rules
+		plainRule    option.DefaultHeadlessRule
+		parsedLines  int
+		ignoredLines int
+	)
+	for index, line := range lines {
+		line = strings.TrimSpace(line)
+		if classical.IsComment(line) {
+			continue
+		}
+		switch behavior {
+		case BehaviorDomain:
+			err = addDomain(&plainRule, line)
+		case BehaviorIPCIDR:
+			err = addIPCIDR(&plainRule, line)
+		case BehaviorClassical:
+			err = rules.AddLine(line)
+		}
+		if err != nil {
+			ignoredLines++
+			logger.Warn("ignored ", itemName, index+1, ": ", err)
+			continue
+		}
+		parsedLines++
+	}
+	if ignoredLines > 0 {
+		logger.Info("parsed rules: ", parsedLines, "/", parsedLines+ignoredLines)
+	}
+	if behavior == BehaviorClassical {
+		return rules.Build(), nil
+	}
+	if !plainRule.IsValid() {
+		return nil, nil
+	}
+	return []option.HeadlessRule{{
+		Type:           C.RuleTypeDefault,
+		DefaultOptions: plainRule,
+	}}, nil
+}
+
+func isYAML(content []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "payload:") {
+			return true
+		}
+	}
+	return false
+}
+
+func addDomain(rule *option.DefaultHeadlessRule, line string) error {
+	switch {
+	case strings.HasPrefix(line, "+."):
+		domain := line[2:]
+		if !M.IsDomainName(domain) {
+			return E.New("invalid domain: ", line)
+		}
+		rule.DomainSuffix = append(rule.DomainSuffix, domain)
+	case strings.HasPrefix(line, ".") && !strings.Contains(line, "*"):
+		if !M.IsDomainName(line[1:]) {
+			return E.New("invalid domain: ", line)
+		}
+		rule.DomainSuffix = append(rule.DomainSuffix, line)
+	case strings.Contains(line, "*"):
+		labels := strings.Split(line, ".")
+		for i, label := range labels {
+			if label == "*" {
+				labels[i] = "[^.]+"
+			} else if strings.Contains(label, "*") {
+				return E.New("invalid wildcard domain: ", line)
+			} else {
+				labels[i] = regexp.QuoteMeta(label)
+			}
+		}
+		rule.DomainRegex = append(rule.DomainRegex, "^"+strings.Join(labels, `\.`)+"$")
+	default:
+		if !M.IsDomainName(line) {
+			return E.New("invalid domain: ", line)
+		}
+		rule.Domain = append(rule.Domain, line)
+	}
+	return nil
+}
+
+func addIPCIDR(rule *option.DefaultHeadlessRule, line string) error {
+	prefix, err := classical.ParsePrefix(line)
+	if err != nil {
+		return err
+	}
+	rule.IPCIDR = append(rule.IPCIDR, prefix.String())
+	return nil
+}
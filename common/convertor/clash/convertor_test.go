@@ -0,0 +1,69 @@
+package clash
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing/common/json/badoption"
+	"github.com/sagernet/sing/common/logger"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDomainProvider(t *testing.T) {
+	t.Parallel()
+	content := `payload:
+  - 'example.com'
+  - '+.example.org'
+  - '.example.net'
+  - '*.example.edu'
+  - 'invalid domain'
+`
+	rules, err := ToOptions(strings.NewReader(content), BehaviorDomain, logger.NOP())
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	rule := rules[0].DefaultOptions
+	require.Equal(t, badoption.Listable[string]{"example.com"}, rule.Domain)
+	require.Equal(t, badoption.Listable[string]{"example.org", ".example.net"}, rule.DomainSuffix)
+	require.Equal(t, badoption.Listable[string]{`^[^.]+\.example\.edu$`}, rule.DomainRegex)
+}
+
+func TestIPCIDRProvider(t *testing.T) {
+	t.Parallel()
+	content := `# comment
+1.1.1.0/24
+2001:db8::/32
+8.8.8.8
+`
+	rules, err := ToOptions(strings.NewReader(content), BehaviorIPCIDR, logger.NOP())
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	require.Equal(t, badoption.Listable[string]{"1.1.1.0/24", "2001:db8::/32", "8.8.8.8/32"}, rules[0].DefaultOptions.IPCIDR)
+}
+
+func TestClassicalProvider(t *testing.T) {
+	t.Parallel()
+	content := `payload:
+  - DOMAIN-SUFFIX,example.com
+  - DOMAIN,www.example.org
+  - IP-CIDR,10.0.0.0/8,no-resolve
+  - DST-PORT,443
+  - PROCESS-NAME,curl
+  - GEOIP,CN
+  - AND,((DOMAIN,example.com),(NETWORK,UDP))
+`
+	rules, err := ToOptions(strings.NewReader(content), BehaviorClassical, logger.NOP())
+	require.NoError(t, err)
+	require.Len(t, rules, 3)
+	require.Equal(t, badoption.Listable[string]{"example.com"}, rules[0].DefaultOptions.DomainSuffix)
+	require.Equal(t, badoption.Listable[string]{"www.example.org"}, rules[0].DefaultOptions.Domain)
+	require.Equal(t, badoption.Listable[string]{"10.0.0.0/8"}, rules[0].DefaultOptions.IPCIDR)
+	require.Equal(t, badoption.Listable[uint16]{443}, rules[1].DefaultOptions.Port)
+	require.Equal(t, badoption.Listable[string]{"curl"}, rules[2].DefaultOptions.ProcessName)
+}
+
+func TestUnknownBehavior(t *testing.T) {
+	t.Parallel()
+	_, err := ToOptions(strings.NewReader(""), "unknown", logger.NOP())
+	require.Error(t, err)
+}
@@ -0,0 +1,65 @@
+package convertor
+
+import (
+	"io"
+
+	"github.com/sagernet/sing-box/common/convertor/clash"
+	"github.com/sagernet/sing-box/common/convertor/dnsmasq"
+	"github.com/sagernet/sing-box/common/convertor/geodat"
+	"github.com/sagernet/sing-box/common/convertor/surge"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+)
+
+func IsSupported(format string) bool {
+	switch format {
+	case C.RuleSetFormatClashDomain, C.RuleSetFormatClashIPCIDR, C.RuleSetFormatClashClassical,
+		C.RuleSetFormatSurge, C.RuleSetFormatQuantumult, C.RuleSetFormatDnsmasq,
+		C.RuleSetFormatGeoSiteDat, C.RuleSetFormatGeoIPDat:
+		return true
+	default:
+		return false
+	}
+}
+
+// ToOptions converts a third-party rule list to a plain rule-set.
+//
+// category selects the entry of `geosite-dat` and `geoip-dat` files.
+func ToOptions(format string, category string, reader io.Reader, logger logger.Logger) (option.PlainRuleSet, error) {
+	var (
+		rules []option.HeadlessRule
+		err   error
+	)
+	switch format {
+	case C.RuleSetFormatClashDomain:
+		rules, err = clash.ToOptions(reader, clash.BehaviorDomain, logger)
+	case C.RuleSetFormatClashIPCIDR:
+		rules, err = clash.ToOptions(reader, clash.BehaviorIPCIDR, logger)
+	case C.RuleSetFormatClashClassical:
+		rules, err = clash.ToOptions(reader, clash.BehaviorClassical, logger)
+	case C.RuleSetFormatSurge, C.RuleSetFormatQuantumult:
+		rules, err = surge.ToOptions(reader, logger)
+	case C.RuleSetFormatDnsmasq:
+		rules, err = dnsmasq.ToOptions(reader, logger)
+	case C.RuleSetFormatGeoSiteDat:
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return option.PlainRuleSet{}, err
+		}
+		return geodat.GeoSiteCategoryToOptions(content, category)
+	case C.RuleSetFormatGeoIPDat:
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return option.PlainRuleSet{}, err
+		}
+		return geodat.GeoIPCategoryToOptions(content, category)
+	default:
+		return option.PlainRuleSet{}, E.New("unknown rule-set format: ", format)
+	}
+	if err != nil {
+		return option.PlainRuleSet{}, err
+	}
+	return option.PlainRuleSet{Rules: rules}, nil
+}
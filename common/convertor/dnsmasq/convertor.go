@@ -0,0 +1,89 @@
+package dnsmasq
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+// ToOptions converts domains from dnsmasq `server=/domain/...` style directives.
+//
+// dnsmasq matches the domain and all its subdomains, so each domain is
+// converted to a domain suffix.
+func ToOptions(reader io.Reader, logger logger.Logger) ([]option.HeadlessRule, error) {
+	scanner := bufio.NewScanner(reader)
+	var (
+		rule         option.DefaultHeadlessRule
+		lineNumber   int
+		parsedLines  int
+		ignoredLines int
+	)
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains, err := parseLine(line)
+		if err != nil {
+			ignoredLines++
+			logger.Warn("ignored line ", lineNumber, ": ", err)
+			continue
+		}
+		rule.DomainSuffix = append(rule.DomainSuffix, domains...)
+		parsedLines++
+	}
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+	if ignoredLines > 0 {
+		logger.Info("parsed rules: ", parsedLines, "/", parsedLines+ignoredLines)
+	}
+	if !rule.IsValid() {
+		return nil, nil
+	}
+	return []option.HeadlessRule{{
+		Type:           C.RuleTypeDefault,
+		DefaultOptions: rule,
+	}}, nil
+}
+
+func parseLine(line string) ([]string, error) {
+	directive, value, found := strings.Cut(line, "=")
+	if !found {
+		return nil, E.New("invalid directive: ", line)
+	}
+	switch directive {
+	case "server", "local", "address", "ipset", "nftset":
+	default:
+		return nil, E.New("unsupported directive: ", directive)
+	}
+	if !strings.HasPrefix(value, "/") {
+		return nil, E.New("missing domain: ", line)
+	}
+	parts := strings.Split(value[1:], "/")
+	if len(parts) < 2 {
+		return nil, E.New("missing domain: ", line)
+	}
+	var domains []string
+	for _, domain := range parts[:len(parts)-1] {
+		if domain == "" || domain == "#" {
+			continue
+		}
+		if !M.IsDomainName(domain) {
+			return nil, E.New("invalid domain: ", domain)
+		}
+		domains = append(domains, domain)
+	}
+	if len(domains) == 0 {
+		return nil, E.New("missing domain: ", line)
+	}
+	return domains, nil
+}
@@ -0,0 +1,33 @@
+package dnsmasq
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing/common/json/badoption"
+	"github.com/sagernet/sing/common/logger"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter(t *testing.T) {
+	t.Parallel()
+	content := `# dnsmasq-china-list
+server=/example.com/114.114.114.114
+server=/example.org/example.net/223.5.5.5#53
+address=/ads.example.com/0.0.0.0
+ipset=/example.edu/gfwlist
+no-resolv
+server=8.8.8.8
+`
+	rules, err := ToOptions(strings.NewReader(content), logger.NOP())
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	require.Equal(t, badoption.Listable[string]{
+		"example.com",
+		"example.org",
+		"example.net",
+		"ads.example.com",
+		"example.edu",
+	}, rules[0].DefaultOptions.DomainSuffix)
+}
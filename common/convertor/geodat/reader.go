@@ -0,0 +1,232 @@
+package geodat
+
+import (
+	"net/netip"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Decoder for the v2ray `geosite.dat` and `geoip.dat` protobuf messages:
+//
+//	message GeoSiteList { repeated GeoSite entry = 1; }
+//	message GeoSite { string country_code = 1; repeated Domain domain = 2; }
+//	message Domain { Type type = 1; string value = 2; repeated Attribute attribute = 3; }
+//	message Attribute { string key = 1; oneof typed_value { bool bool_value = 2; int64 int_value = 3; } }
+//	message GeoIPList { repeated GeoIP entry = 1; }
+//	message GeoIP { string country_code = 1; repeated CIDR cidr = 2; bool reverse_match = 3; }
+//	message CIDR { bytes ip = 1; uint32 prefix = 2; }
+
+type DomainType = uint8
+
+const (
+	DomainTypePlain DomainType = iota
+	DomainTypeRegex
+	DomainTypeDomain
+	DomainTypeFull
+)
+
+type Domain struct {
+	Type       DomainType
+	Value      string
+	Attributes []string
+}
+
+type GeoSite struct {
+	Code    string
+	Domains []Domain
+}
+
+type GeoIP struct {
+	Code         string
+	CIDR         []netip.Prefix
+	ReverseMatch bool
+}
+
+// ReadGeoSite reads entries with the given codes, or all entries if none is given.
+func ReadGeoSite(content []byte, codes ...string) ([]GeoSite, error) {
+	var geosites []GeoSite
+	err := readList(content, codes, func(code string, entry []byte) error {
+		geosite := GeoSite{Code: code}
+		err := readMessage(entry, func(number protowire.Number, value []byte) error {
+			if number != 2 {
+				return nil
+			}
+			domain, err := readDomain(value)
+			if err != nil {
+				return E.Cause(err, "read domain of ", code)
+			}
+			geosite.Domains = append(geosite.Domains, domain)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		geosites = append(geosites, geosite)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return geosites, nil
+}
+
+// ReadGeoIP reads entries with the given codes, or all entries if none is given.
+func ReadGeoIP(content []byte, codes ...string) ([]GeoIP, error) {
+	var geoips []GeoIP
+	err := readList(content, codes, func(code string, entry []byte) error {
+		geoip := GeoIP{Code: code}
+		err := readFields(entry, func(number protowire.Number, wireType protowire.Type, value []byte, varint uint64) error {
+			switch number {
+			case 2:
+				prefix, err := readCIDR(value)
+				if err != nil {
+					return E.Cause(err, "read CIDR of ", code)
+				}
+				geoip.CIDR = append(geoip.CIDR, prefix)
+			case 3:
+				geoip.ReverseMatch = varint != 0
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		geoips = append(geoips, geoip)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return geoips, nil
+}
+
+func readList(content []byte, codes []string, handler func(code string, entry []byte) error) error {
+	return readMessage(content, func(number protowire.Number, entry []byte) error {
+		if number != 1 {
+			return nil
+		}
+		var code string
+		err := readMessage(entry, func(number protowire.Number, value []byte) error {
+			if number == 1 {
+				code = strings.ToLower(string(value))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(codes) > 0 && !containsCode(codes, code) {
+			return nil
+		}
+		return handler(code, entry)
+	})
+}
+
+func readDomain(content []byte) (Domain, error) {
+	var domain Domain
+	err := readFields(content, func(number protowire.Number, wireType protowire.Type, value []byte, varint uint64) error {
+		switch number {
+		case 1:
+			if varint > uint64(DomainTypeFull) {
+				return E.New("unknown domain type: ", varint)
+			}
+			domain.Type = DomainType(varint)
+		case 2:
+			domain.Value = string(value)
+		case 3:
+			var attribute string
+			err := readMessage(value, func(number protowire.Number, value []byte) error {
+				if number == 1 {
+					attribute = strings.ToLower(string(value))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			domain.Attributes = append(domain.Attributes, attribute)
+		}
+		return nil
+	})
+	return domain, err
+}
+
+func readCIDR(content []byte) (netip.Prefix, error) {
+	var (
+		ip   []byte
+		bits uint64
+	)
+	err := readFields(content, func(number protowire.Number, wireType protowire.Type, value []byte, varint uint64) error {
+		switch number {
+		case 1:
+			ip = value
+		case 2:
+			bits = varint
+		}
+		return nil
+	})
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return netip.Prefix{}, E.New("invalid IP length: ", len(ip))
+	}
+	prefix, err := addr.Prefix(int(bits))
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return prefix, nil
+}
+
+func readMessage(content []byte, handler func(number protowire.Number, value []byte) error) error {
+	return readFields(content, func(number protowire.Number, wireType protowire.Type, value []byte, varint uint64) error {
+		if wireType != protowire.BytesType {
+			return nil
+		}
+		return handler(number, value)
+	})
+}
+
+func readFields(content []byte, handler func(number protowire.Number, wireType protowire.Type, value []byte, varint uint64) error) error {
+	for len(content) > 0 {
+		number, wireType, n := protowire.ConsumeTag(content)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		content = content[n:]
+		var (
+			value  []byte
+			varint uint64
+		)
+		switch wireType {
+		case protowire.VarintType:
+			varint, n = protowire.ConsumeVarint(content)
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(content)
+		default:
+			n = protowire.ConsumeFieldValue(number, wireType, content)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		content = content[n:]
+		err := handler(number, wireType, value, varint)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func containsCode(codes []string, code string) bool {
+	for _, it := range codes {
+		if strings.EqualFold(it, code) {
+			return true
+		}
+	}
+	return false
+}
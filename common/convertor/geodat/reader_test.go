@@ -0,0 +1,122 @@
+package geodat
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing/common/json/badoption"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func appendMessage(b []byte, number protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
+
+func appendString(b []byte, number protowire.Number, value string) []byte {
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+func appendVarint(b []byte, number protowire.Number, value uint64) []byte {
+	b = protowire.AppendTag(b, number, protowire.VarintType)
+	return protowire.AppendVarint(b, value)
+}
+
+func encodeDomain(domainType DomainType, value string, attributes ...string) []byte {
+	var domain []byte
+	domain = appendVarint(domain, 1, uint64(domainType))
+	domain = appendString(domain, 2, value)
+	for _, attribute := range attributes {
+		var message []byte
+		message = appendString(message, 1, attribute)
+		message = appendVarint(message, 2, 1)
+		domain = appendMessage(domain, 3, message)
+	}
+	return domain
+}
+
+func TestGeoSite(t *testing.T) {
+	t.Parallel()
+	var google []byte
+	google = appendString(google, 1, "GOOGLE")
+	google = appendMessage(google, 2, encodeDomain(DomainTypeDomain, "google.com"))
+	google = appendMessage(google, 2, encodeDomain(DomainTypeDomain, "google.cn", "cn"))
+	google = appendMessage(google, 2, encodeDomain(DomainTypeFull, "www.google.cn", "cn", "ads"))
+	google = appendMessage(google, 2, encodeDomain(DomainTypePlain, "google"))
+	google = appendMessage(google, 2, encodeDomain(DomainTypeRegex, `^google\.[a-z]+$`))
+	var other []byte
+	other = appendString(other, 1, "OTHER")
+	other = appendMessage(other, 2, encodeDomain(DomainTypeFull, "example.com"))
+	var content []byte
+	content = appendMessage(content, 1, other)
+	content = appendMessage(content, 1, google)
+
+	geosites, err := ReadGeoSite(content)
+	require.NoError(t, err)
+	require.Len(t, geosites, 2)
+	require.Equal(t, "other", geosites[0].Code)
+
+	ruleSet, err := GeoSiteCategoryToOptions(content, "google")
+	require.NoError(t, err)
+	require.Len(t, ruleSet.Rules, 1)
+	rule := ruleSet.Rules[0].DefaultOptions
+	require.Equal(t, badoption.Listable[string]{"google.com", "google.cn"}, rule.DomainSuffix)
+	require.Equal(t, badoption.Listable[string]{"www.google.cn"}, rule.Domain)
+	require.Equal(t, badoption.Listable[string]{"google"}, rule.DomainKeyword)
+	require.Equal(t, badoption.Listable[string]{`^google\.[a-z]+$`}, rule.DomainRegex)
+
+	ruleSet, err = GeoSiteCategoryToOptions(content, "Google@CN")
+	require.NoError(t, err)
+	rule = ruleSet.Rules[0].DefaultOptions
+	require.Equal(t, badoption.Listable[string]{"google.cn"}, rule.DomainSuffix)
+	require.Equal(t, badoption.Listable[string]{"www.google.cn"}, rule.Domain)
+	require.Empty(t, rule.DomainKeyword)
+
+	ruleSet, err = GeoSiteCategoryToOptions(content, "google@cn@ads")
+	require.NoError(t, err)
+	rule = ruleSet.Rules[0].DefaultOptions
+	require.Empty(t, rule.DomainSuffix)
+	require.Equal(t, badoption.Listable[string]{"www.google.cn"}, rule.Domain)
+
+	_, err = GeoSiteCategoryToOptions(content, "missing")
+	require.Error(t, err)
+}
+
+func TestGeoIP(t *testing.T) {
+	t.Parallel()
+	encodeCIDR := func(prefix netip.Prefix) []byte {
+		var cidr []byte
+		cidr = protowire.AppendTag(cidr, 1, protowire.BytesType)
+		cidr = protowire.AppendBytes(cidr, prefix.Addr().AsSlice())
+		return appendVarint(cidr, 2, uint64(prefix.Bits()))
+	}
+	var cn []byte
+	cn = appendString(cn, 1, "CN")
+	cn = appendMessage(cn, 2, encodeCIDR(netip.MustParsePrefix("1.0.1.0/24")))
+	cn = appendMessage(cn, 2, encodeCIDR(netip.MustParsePrefix("2001:250::/35")))
+	var private []byte
+	private = appendString(private, 1, "PRIVATE")
+	private = appendMessage(private, 2, encodeCIDR(netip.MustParsePrefix("10.0.0.0/8")))
+	private = appendVarint(private, 3, 1)
+	var content []byte
+	content = appendMessage(content, 1, cn)
+	content = appendMessage(content, 1, private)
+
+	ruleSet, err := GeoIPCategoryToOptions(content, "cn")
+	require.NoError(t, err)
+	rule := ruleSet.Rules[0].DefaultOptions
+	require.Equal(t, badoption.Listable[string]{"1.0.1.0/24", "2001:250::/35"}, rule.IPCIDR)
+	require.False(t, rule.Invert)
+
+	ruleSet, err = GeoIPCategoryToOptions(content, "!cn")
+	require.NoError(t, err)
+	require.True(t, ruleSet.Rules[0].DefaultOptions.Invert)
+
+	ruleSet, err = GeoIPCategoryToOptions(content, "private")
+	require.NoError(t, err)
+	require.Equal(t, badoption.Listable[string]{"10.0.0.0/8"}, ruleSet.Rules[0].DefaultOptions.IPCIDR)
+	require.True(t, ruleSet.Rules[0].DefaultOptions.Invert)
+}
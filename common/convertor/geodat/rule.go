@@ -0,0 +1,99 @@
+package geodat
+
+import (
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// ParseCategory parses a geosite category like `google@cn@ads` into the code
+// and the attributes that domains must all have.
+func ParseCategory(category string) (code string, attributes []string) {
+	parts := strings.Split(strings.ToLower(category), "@")
+	return parts[0], common.Filter(parts[1:], func(it string) bool {
+		return it != ""
+	})
+}
+
+// GeoSiteToOptions converts domains that have all the given attributes.
+func GeoSiteToOptions(domains []Domain, attributes []string) option.PlainRuleSet {
+	var rule option.DefaultHeadlessRule
+	for _, domain := range domains {
+		if !common.All(attributes, func(attribute string) bool {
+			return common.Contains(domain.Attributes, attribute)
+		}) {
+			continue
+		}
+		switch domain.Type {
+		case DomainTypePlain:
+			rule.DomainKeyword = append(rule.DomainKeyword, domain.Value)
+		case DomainTypeRegex:
+			rule.DomainRegex = append(rule.DomainRegex, domain.Value)
+		case DomainTypeDomain:
+			rule.DomainSuffix = append(rule.DomainSuffix, domain.Value)
+		case DomainTypeFull:
+			rule.Domain = append(rule.Domain, domain.Value)
+		}
+	}
+	return toPlainRuleSet(rule)
+}
+
+func GeoIPToOptions(geoip GeoIP, invert bool) option.PlainRuleSet {
+	var rule option.DefaultHeadlessRule
+	rule.IPCIDR = make([]string, 0, len(geoip.CIDR))
+	for _, prefix := range geoip.CIDR {
+		rule.IPCIDR = append(rule.IPCIDR, prefix.String())
+	}
+	if len(rule.IPCIDR) > 0 {
+		rule.Invert = geoip.ReverseMatch != invert
+	}
+	return toPlainRuleSet(rule)
+}
+
+// GeoSiteCategoryToOptions reads a single category from a `geosite.dat` file.
+func GeoSiteCategoryToOptions(content []byte, category string) (option.PlainRuleSet, error) {
+	code, attributes := ParseCategory(category)
+	if code == "" {
+		return option.PlainRuleSet{}, E.New("missing geosite category")
+	}
+	geosites, err := ReadGeoSite(content, code)
+	if err != nil {
+		return option.PlainRuleSet{}, err
+	}
+	if len(geosites) == 0 {
+		return option.PlainRuleSet{}, E.New("geosite category not found: ", code)
+	}
+	return GeoSiteToOptions(geosites[0].Domains, attributes), nil
+}
+
+// GeoIPCategoryToOptions reads a single country code, optionally prefixed with `!`
+// to invert, from a `geoip.dat` file.
+func GeoIPCategoryToOptions(content []byte, category string) (option.PlainRuleSet, error) {
+	code, invert := strings.CutPrefix(strings.ToLower(category), "!")
+	if code == "" {
+		return option.PlainRuleSet{}, E.New("missing geoip code")
+	}
+	geoips, err := ReadGeoIP(content, code)
+	if err != nil {
+		return option.PlainRuleSet{}, err
+	}
+	if len(geoips) == 0 {
+		return option.PlainRuleSet{}, E.New("geoip code not found: ", code)
+	}
+	return GeoIPToOptions(geoips[0], invert), nil
+}
+
+func toPlainRuleSet(rule option.DefaultHeadlessRule) option.PlainRuleSet {
+	if !rule.IsValid() {
+		return option.PlainRuleSet{}
+	}
+	return option.PlainRuleSet{
+		Rules: []option.HeadlessRule{{
+			Type:           C.RuleTypeDefault,
+			DefaultOptions: rule,
+		}},
+	}
+}
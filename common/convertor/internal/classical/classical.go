@@ -0,0 +1,168 @@
+package classical
+
+import (
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json/badoption"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// Rules collects rules in the `TYPE,value[,policy][,options]` syntax shared
+// by Clash classical, Surge and Quantumult X lists.
+//
+// Items that sing-box evaluates with AND are kept in separate rules, so the
+// rule-set matches if any line matches.
+type Rules struct {
+	address     option.DefaultHeadlessRule
+	sourceIP    option.DefaultHeadlessRule
+	port        option.DefaultHeadlessRule
+	sourcePort  option.DefaultHeadlessRule
+	processName option.DefaultHeadlessRule
+	processPath option.DefaultHeadlessRule
+	network     option.DefaultHeadlessRule
+}
+
+func IsComment(line string) bool {
+	return line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "//")
+}
+
+func (r *Rules) AddLine(line string) error {
+	parts := strings.Split(line, ",")
+	if len(parts) < 2 {
+		return E.New("invalid rule: ", line)
+	}
+	return r.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+}
+
+func (r *Rules) Add(ruleType string, value string) error {
+	switch strings.ToUpper(ruleType) {
+	case "DOMAIN", "HOST":
+		r.address.Domain = append(r.address.Domain, value)
+	case "DOMAIN-SUFFIX", "HOST-SUFFIX":
+		r.address.DomainSuffix = append(r.address.DomainSuffix, value)
+	case "DOMAIN-KEYWORD", "HOST-KEYWORD":
+		r.address.DomainKeyword = append(r.address.DomainKeyword, value)
+	case "DOMAIN-REGEX":
+		_, err := regexp.Compile(value)
+		if err != nil {
+			return E.Cause(err, "invalid domain regex: ", value)
+		}
+		r.address.DomainRegex = append(r.address.DomainRegex, value)
+	case "DOMAIN-WILDCARD", "HOST-WILDCARD":
+		r.address.DomainRegex = append(r.address.DomainRegex, WildcardToRegex(value))
+	case "IP-CIDR", "IP-CIDR6", "IP6-CIDR":
+		prefix, err := ParsePrefix(value)
+		if err != nil {
+			return err
+		}
+		r.address.IPCIDR = append(r.address.IPCIDR, prefix.String())
+	case "IP-ASN":
+		asn, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(value), "AS"), 10, 32)
+		if err != nil {
+			return E.Cause(err, "invalid ASN: ", value)
+		}
+		r.address.IPASN = append(r.address.IPASN, uint32(asn))
+	case "SRC-IP-CIDR", "SRC-IP":
+		prefix, err := ParsePrefix(value)
+		if err != nil {
+			return err
+		}
+		r.sourceIP.SourceIPCIDR = append(r.sourceIP.SourceIPCIDR, prefix.String())
+	case "DST-PORT", "DEST-PORT":
+		return addPort(value, &r.port.Port, &r.port.PortRange)
+	case "SRC-PORT":
+		return addPort(value, &r.sourcePort.SourcePort, &r.sourcePort.SourcePortRange)
+	case "PROCESS-NAME":
+		r.processName.ProcessName = append(r.processName.ProcessName, value)
+	case "PROCESS-PATH":
+		r.processPath.ProcessPath = append(r.processPath.ProcessPath, value)
+	case "NETWORK", "PROTOCOL":
+		network := strings.ToLower(value)
+		if network != N.NetworkTCP && network != N.NetworkUDP {
+			return E.New("unsupported network: ", value)
+		}
+		r.network.Network = append(r.network.Network, network)
+	default:
+		return E.New("unsupported rule type: ", ruleType)
+	}
+	return nil
+}
+
+func (r *Rules) Build() []option.HeadlessRule {
+	var rules []option.HeadlessRule
+	for _, rule := range []option.DefaultHeadlessRule{r.address, r.sourceIP, r.port, r.sourcePort, r.processName, r.processPath, r.network} {
+		if !rule.IsValid() {
+			continue
+		}
+		rules = append(rules, option.HeadlessRule{
+			Type:           C.RuleTypeDefault,
+			DefaultOptions: rule,
+		})
+	}
+	return rules
+}
+
+// ParsePrefix parses an IP prefix or a single IP address.
+func ParsePrefix(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, E.Cause(err, "invalid IP CIDR: ", value)
+		}
+		return prefix, nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, E.Cause(err, "invalid IP address: ", value)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// WildcardToRegex converts a domain wildcard, where `*` matches any characters
+// and `?` matches a single character, to an anchored regex.
+func WildcardToRegex(pattern string) string {
+	var builder strings.Builder
+	builder.WriteByte('^')
+	for _, char := range pattern {
+		switch char {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteByte('.')
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	builder.WriteByte('$')
+	return builder.String()
+}
+
+func addPort(value string, ports *badoption.Listable[uint16], portRanges *badoption.Listable[string]) error {
+	for _, portString := range strings.Split(value, "/") {
+		if strings.Contains(portString, "-") {
+			start, end, _ := strings.Cut(portString, "-")
+			startPort, err := strconv.ParseUint(start, 10, 16)
+			if err != nil {
+				return E.Cause(err, "invalid port range: ", portString)
+			}
+			endPort, err := strconv.ParseUint(end, 10, 16)
+			if err != nil {
+				return E.Cause(err, "invalid port range: ", portString)
+			}
+			*portRanges = append(*portRanges, strconv.FormatUint(startPort, 10)+":"+strconv.FormatUint(endPort, 10))
+		} else {
+			port, err := strconv.ParseUint(portString, 10, 16)
+			if err != nil {
+				return E.Cause(err, "invalid port: ", portString)
+			}
+			*ports = append(*ports, uint16(port))
+		}
+	}
+	return nil
+}
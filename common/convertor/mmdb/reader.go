@@ -0,0 +1,86 @@
+package mmdb
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/oschwald/maxminddb-golang"
+	"go4.org/netipx"
+)
+
+type countryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
+
+// ReadCountries reads networks by lower-case country code from a sing-geoip
+// or MaxMind country database.
+func ReadCountries(path string) (map[string][]netip.Prefix, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	isSingGeoIP := reader.Metadata.DatabaseType == "sing-geoip"
+	if !isSingGeoIP && !strings.Contains(strings.ToLower(reader.Metadata.DatabaseType), "country") {
+		return nil, E.New("unsupported database type: ", reader.Metadata.DatabaseType)
+	}
+	countryMap := make(map[string][]netip.Prefix)
+	networks := reader.Networks(maxminddb.SkipAliasedNetworks)
+	for networks.Next() {
+		var (
+			code    string
+			network *net.IPNet
+		)
+		if isSingGeoIP {
+			network, err = networks.Network(&code)
+		} else {
+			var record countryRecord
+			network, err = networks.Network(&record)
+			code = record.Country.ISOCode
+			if code == "" {
+				code = record.RegisteredCountry.ISOCode
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if code == "" {
+			continue
+		}
+		prefix, ok := netipx.FromStdIPNet(network)
+		if !ok {
+			return nil, E.New("invalid network: ", network)
+		}
+		code = strings.ToLower(code)
+		countryMap[code] = append(countryMap[code], prefix)
+	}
+	err = networks.Err()
+	if err != nil {
+		return nil, err
+	}
+	return countryMap, nil
+}
+
+func ToOptions(prefixes []netip.Prefix) option.PlainRuleSet {
+	var rule option.DefaultHeadlessRule
+	rule.IPCIDR = make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		rule.IPCIDR = append(rule.IPCIDR, prefix.String())
+	}
+	return option.PlainRuleSet{
+		Rules: []option.HeadlessRule{{
+			Type:           C.RuleTypeDefault,
+			DefaultOptions: rule,
+		}},
+	}
+}
@@ -0,0 +1,47 @@
+package surge
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/convertor/internal/classical"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/logger"
+)
+
+// ToOptions converts a Surge ruleset or Quantumult X filter list.
+//
+// Policies and options after the value, such as `no-resolve` or the Quantumult X
+// policy name, are ignored.
+func ToOptions(reader io.Reader, logger logger.Logger) ([]option.HeadlessRule, error) {
+	scanner := bufio.NewScanner(reader)
+	var (
+		rules        classical.Rules
+		lineNumber   int
+		parsedLines  int
+		ignoredLines int
+	)
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if classical.IsComment(line) {
+			continue
+		}
+		err := rules.AddLine(line)
+		if err != nil {
+			ignoredLines++
+			logger.Warn("ignored line ", lineNumber, ": ", err)
+			continue
+		}
+		parsedLines++
+	}
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+	if ignoredLines > 0 {
+		logger.Info("parsed rules: ", parsedLines, "/", parsedLines+ignoredLines)
+	}
+	return rules.Build(), nil
+}
@@ -0,0 +1,59 @@
+package surge
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing/common/json/badoption"
+	"github.com/sagernet/sing/common/logger"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSurgeList(t *testing.T) {
+	t.Parallel()
+	content := `# Surge ruleset
+DOMAIN,example.com
+DOMAIN-SUFFIX,example.org
+DOMAIN-KEYWORD,google
+DOMAIN-WILDCARD,*.example.net
+IP-CIDR,192.168.0.0/16,no-resolve
+IP-CIDR6,2001:db8::/32
+IP-ASN,AS13335
+SRC-IP,10.0.0.1
+DEST-PORT,80/8000-8080
+USER-AGENT,curl*
+RULE-SET,https://example.com/list.txt
+`
+	rules, err := ToOptions(strings.NewReader(content), logger.NOP())
+	require.NoError(t, err)
+	require.Len(t, rules, 3)
+	address := rules[0].DefaultOptions
+	require.Equal(t, badoption.Listable[string]{"example.com"}, address.Domain)
+	require.Equal(t, badoption.Listable[string]{"example.org"}, address.DomainSuffix)
+	require.Equal(t, badoption.Listable[string]{"google"}, address.DomainKeyword)
+	require.Equal(t, badoption.Listable[string]{`^.*\.example\.net$`}, address.DomainRegex)
+	require.Equal(t, badoption.Listable[string]{"192.168.0.0/16", "2001:db8::/32"}, address.IPCIDR)
+	require.Equal(t, badoption.Listable[uint32]{13335}, address.IPASN)
+	require.Equal(t, badoption.Listable[string]{"10.0.0.1/32"}, rules[1].DefaultOptions.SourceIPCIDR)
+	require.Equal(t, badoption.Listable[uint16]{80}, rules[2].DefaultOptions.Port)
+	require.Equal(t, badoption.Listable[string]{"8000:8080"}, rules[2].DefaultOptions.PortRange)
+}
+
+func TestQuantumultList(t *testing.T) {
+	t.Parallel()
+	content := `host, example.com, proxy
+host-suffix, example.org, direct
+host-keyword, google, proxy
+ip-cidr, 10.0.0.0/8, direct
+geoip, cn, direct
+`
+	rules, err := ToOptions(strings.NewReader(content), logger.NOP())
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	rule := rules[0].DefaultOptions
+	require.Equal(t, badoption.Listable[string]{"example.com"}, rule.Domain)
+	require.Equal(t, badoption.Listable[string]{"example.org"}, rule.DomainSuffix)
+	require.Equal(t, badoption.Listable[string]{"google"}, rule.DomainKeyword)
+	require.Equal(t, badoption.Listable[string]{"10.0.0.0/8"}, rule.IPCIDR)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net"
 	"sync"
+	"sync/atomic"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing/common"
@@ -20,11 +21,16 @@ type DetourDialer struct {
 	outboundManager adapter.OutboundManager
 	detour          string
 	legacyDNSDialer bool
-	dialer          N.Dialer
+	state           atomic.Pointer[detourState]
 	initOnce        sync.Once
 	initErr         error
 }
 
+type detourState struct {
+	dialer   N.Dialer
+	revision uint64
+}
+
 func NewDetour(outboundManager adapter.OutboundManager, detour string, legacyDNSDialer bool) N.Dialer {
 	return &DetourDialer{
 		outboundManager: outboundManager,
@@ -41,12 +47,28 @@ func InitializeDetour(dialer N.Dialer) error {
 	return common.Error(detourDialer.Dialer())
 }
 
+// Dialer returns the detour outbound, looked up again
+// only after outbounds have been changed, e.g. by a configuration reload.
 func (d *DetourDialer) Dialer() (N.Dialer, error) {
 	d.initOnce.Do(d.init)
-	return d.dialer, d.initErr
+	if d.initErr != nil {
+		return nil, d.initErr
+	}
+	revision := d.outboundManager.Revision()
+	state := d.state.Load()
+	if state.revision == revision {
+		return state.dialer, nil
+	}
+	dialer, loaded := d.outboundManager.Outbound(d.detour)
+	if !loaded {
+		return nil, E.New("outbound detour not found: ", d.detour)
+	}
+	d.state.Store(&detourState{dialer, revision})
+	return dialer, nil
 }
 
 func (d *DetourDialer) init() {
+	revision := d.outboundManager.Revision()
 	dialer, loaded := d.outboundManager.Outbound(d.detour)
 	if !loaded {
 		d.initErr = E.New("outbound detour not found: ", d.detour)
@@ -60,7 +82,7 @@ func (d *DetourDialer) init() {
 			}
 		}
 	}
-	d.dialer = dialer
+	d.state.Store(&detourState{dialer, revision})
 }
 
 func (d *DetourDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
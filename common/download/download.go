@@ -0,0 +1,123 @@
+package download
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/ntp"
+)
+
+// Downloader fetches a remote file, revalidating it by ETag and keeping
+// the content in the cache file if available.
+type Downloader struct {
+	Logger      logger.ContextLogger
+	URL         string
+	LastUpdated time.Time
+	LastEtag    string
+	// LoadCache and SaveCache are nil if the cache file is disabled.
+	LoadCache func() *adapter.SavedBinary
+	SaveCache func(saved *adapter.SavedBinary) error
+}
+
+func NewHTTPClient(ctx context.Context, dialer N.Dialer) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: C.TCPTimeout,
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, M.ParseSocksaddr(addr))
+			},
+			TLSClientConfig: &tls.Config{
+				Time:    ntp.TimeFuncFromContext(ctx),
+				RootCAs: adapter.RootPoolFromContext(ctx),
+			},
+		},
+	}
+}
+
+// Restore passes cached content to load, and reports whether it is found.
+func (d *Downloader) Restore(load func(content []byte) error) (bool, error) {
+	if d.LoadCache == nil {
+		return false, nil
+	}
+	saved := d.LoadCache()
+	if saved == nil {
+		return false, nil
+	}
+	err := load(saved.Content)
+	if err != nil {
+		return false, err
+	}
+	d.LastUpdated = saved.LastUpdated
+	d.LastEtag = saved.LastEtag
+	return true, nil
+}
+
+// Fetch downloads the file and passes new content to load, and reports
+// whether the file is modified.
+func (d *Downloader) Fetch(ctx context.Context, client *http.Client, load func(content []byte) error) (bool, error) {
+	request, err := http.NewRequest("GET", d.URL, nil)
+	if err != nil {
+		return false, err
+	}
+	if d.LastEtag != "" {
+		request.Header.Set("If-None-Match", d.LastEtag)
+	}
+	response, err := client.Do(request.WithContext(ctx))
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		d.LastUpdated = time.Now()
+		if d.LoadCache != nil {
+			saved := d.LoadCache()
+			if saved != nil {
+				saved.LastUpdated = d.LastUpdated
+				err = d.SaveCache(saved)
+				if err != nil {
+					d.Logger.Error("save updated time: ", err)
+				}
+			}
+		}
+		return false, nil
+	default:
+		return false, E.New("unexpected status: ", response.Status)
+	}
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return false, err
+	}
+	err = load(content)
+	if err != nil {
+		return false, err
+	}
+	eTagHeader := response.Header.Get("Etag")
+	if eTagHeader != "" {
+		d.LastEtag = eTagHeader
+	}
+	d.LastUpdated = time.Now()
+	if d.SaveCache != nil {
+		err = d.SaveCache(&adapter.SavedBinary{
+			LastUpdated: d.LastUpdated,
+			Content:     content,
+			LastEtag:    d.LastEtag,
+		})
+		if err != nil {
+			d.Logger.Error("save cache: ", err)
+		}
+	}
+	return true, nil
+}
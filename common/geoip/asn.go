@@ -0,0 +1,65 @@
+package geoip
+
+import (
+	"net/netip"
+	"strconv"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+type ASNReader struct {
+	reader *maxminddb.Reader
+}
+
+// asnRecord covers both the MaxMind (GeoLite2-ASN, GeoIP2-ISP, DB-IP ASN Lite)
+// and the ipinfo ASN database layouts.
+type asnRecord struct {
+	ASN          uint32 `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+	IPInfoASN    string `maxminddb:"asn"`
+	IPInfoName   string `maxminddb:"name"`
+	IPInfoASName string `maxminddb:"as_name"`
+}
+
+func OpenASN(path string) (*ASNReader, error) {
+	database, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	databaseType := strings.ToLower(database.Metadata.DatabaseType)
+	if !strings.Contains(databaseType, "asn") && !strings.Contains(databaseType, "isp") {
+		database.Close()
+		return nil, E.New("incorrect database type, expected ASN database, got ", database.Metadata.DatabaseType)
+	}
+	return &ASNReader{database}, nil
+}
+
+func (r *ASNReader) Lookup(addr netip.Addr) (asn uint32, organization string, loaded bool) {
+	var record asnRecord
+	err := r.reader.Lookup(addr.AsSlice(), &record)
+	if err != nil {
+		return
+	}
+	if record.ASN != 0 {
+		return record.ASN, record.Organization, true
+	}
+	if record.IPInfoASN != "" {
+		number, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(record.IPInfoASN), "AS"), 10, 32)
+		if err != nil {
+			return
+		}
+		organization = record.IPInfoName
+		if organization == "" {
+			organization = record.IPInfoASName
+		}
+		return uint32(number), organization, true
+	}
+	return
+}
+
+func (r *ASNReader) Close() error {
+	return r.reader.Close()
+}
@@ -0,0 +1,125 @@
+package ja3
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// JA4 returns the JA4 TLS client fingerprint, see
+// https://github.com/FoxIO-LLC/ja4/blob/main/technical_details/JA4.md
+func (j *ClientHello) JA4(isQUIC bool) string {
+	var builder strings.Builder
+	if isQUIC {
+		builder.WriteByte('q')
+	} else {
+		builder.WriteByte('t')
+	}
+	builder.WriteString(ja4Version(j.maxVersion()))
+	if j.ServerName != "" {
+		builder.WriteByte('d')
+	} else {
+		builder.WriteByte('i')
+	}
+	cipherSuites := filterGrease(j.CipherSuites)
+	extensions := filterGrease(j.Extensions)
+	builder.WriteString(fmt.Sprintf("%02d%02d", min(len(cipherSuites), 99), min(len(extensions), 99)))
+	builder.WriteString(ja4ALPN(j.ALPN))
+	builder.WriteByte('_')
+
+	slices.Sort(cipherSuites)
+	builder.WriteString(ja4Hash(joinHex(cipherSuites)))
+	builder.WriteByte('_')
+
+	extensions = slices.DeleteFunc(extensions, func(it uint16) bool {
+		return it == sniExtensionType || it == alpnExtensionType
+	})
+	slices.Sort(extensions)
+	if len(extensions) == 0 {
+		builder.WriteString(ja4Hash(""))
+	} else {
+		extensionString := joinHex(extensions)
+		signatureAlgorithms := filterGrease(j.SignatureAlgorithms)
+		if len(signatureAlgorithms) > 0 {
+			extensionString += "_" + joinHex(signatureAlgorithms)
+		}
+		builder.WriteString(ja4Hash(extensionString))
+	}
+	return builder.String()
+}
+
+func (j *ClientHello) maxVersion() uint16 {
+	var version uint16
+	for _, it := range j.Versions {
+		if !isGrease(it) && it > version {
+			version = it
+		}
+	}
+	if version == 0 {
+		version = j.Version
+	}
+	return version
+}
+
+func ja4Version(version uint16) string {
+	switch version {
+	case 0x0304:
+		return "13"
+	case 0x0303:
+		return "12"
+	case 0x0302:
+		return "11"
+	case 0x0301:
+		return "10"
+	case 0x0300:
+		return "s3"
+	default:
+		return "00"
+	}
+}
+
+func ja4ALPN(alpn []string) string {
+	if len(alpn) == 0 || alpn[0] == "" {
+		return "00"
+	}
+	value := alpn[0]
+	first, last := value[0], value[len(value)-1]
+	if isAlphanumeric(first) && isAlphanumeric(last) {
+		return string([]byte{first, last})
+	}
+	firstHex, lastHex := hex.EncodeToString([]byte{first}), hex.EncodeToString([]byte{last})
+	return firstHex[:1] + lastHex[1:]
+}
+
+func isAlphanumeric(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func ja4Hash(value string) string {
+	if value == "" {
+		return "000000000000"
+	}
+	hash := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(hash[:6])
+}
+
+func filterGrease(values []uint16) []uint16 {
+	filtered := make([]uint16, 0, len(values))
+	for _, it := range values {
+		if !isGrease(it) {
+			filtered = append(filtered, it)
+		}
+	}
+	return filtered
+}
+
+func joinHex(values []uint16) string {
+	hexValues := make([]string, len(values))
+	for i, it := range values {
+		hexValues[i] = fmt.Sprintf("%04x", it)
+	}
+	return strings.Join(hexValues, ",")
+}
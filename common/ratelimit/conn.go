@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+
+	"github.com/sagernet/sing/common/buf"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// Conn limits an inbound connection, where reads count as upload and writes
+// count as download.
+//
+// Conn is intentionally not replaceable, so copies fall back to the plain
+// read and write loop instead of read waiting or splicing past the limit.
+type Conn struct {
+	net.Conn
+	limiters *limiters
+}
+
+func NewConn(ctx context.Context, conn net.Conn, limits []Limit) *Conn {
+	return &Conn{
+		Conn:     conn,
+		limiters: newLimiters(ctx, limits),
+	}
+}
+
+func (c *Conn) Read(p []byte) (n int, err error) {
+	n, err = c.Conn.Read(p)
+	if n > 0 {
+		waitErr := c.limiters.waitUpload(n)
+		if err == nil {
+			err = waitErr
+		}
+	}
+	return
+}
+
+func (c *Conn) Write(p []byte) (n int, err error) {
+	err = c.limiters.waitDownload(len(p))
+	if err != nil {
+		return
+	}
+	return c.Conn.Write(p)
+}
+
+func (c *Conn) Close() error {
+	c.limiters.close()
+	return c.Conn.Close()
+}
+
+func (c *Conn) Upstream() any {
+	return c.Conn
+}
+
+type PacketConn struct {
+	N.PacketConn
+	limiters *limiters
+}
+
+func NewPacketConn(ctx context.Context, conn N.PacketConn, limits []Limit) *PacketConn {
+	return &PacketConn{
+		PacketConn: conn,
+		limiters:   newLimiters(ctx, limits),
+	}
+}
+
+func (c *PacketConn) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	destination, err = c.PacketConn.ReadPacket(buffer)
+	if err != nil {
+		return
+	}
+	err = c.limiters.waitUpload(buffer.Len())
+	return
+}
+
+func (c *PacketConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	err := c.limiters.waitDownload(buffer.Len())
+	if err != nil {
+		buffer.Release()
+		return err
+	}
+	return c.PacketConn.WritePacket(buffer, destination)
+}
+
+func (c *PacketConn) Close() error {
+	c.limiters.close()
+	return c.PacketConn.Close()
+}
+
+func (c *PacketConn) Upstream() any {
+	return c.PacketConn
+}
@@ -0,0 +1,157 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+type Limiter struct {
+	upload   *rate.Limiter
+	download *rate.Limiter
+}
+
+func NewLimiter(upload uint64, download uint64) *Limiter {
+	return &Limiter{
+		upload:   newBucket(upload),
+		download: newBucket(download),
+	}
+}
+
+// newBucket creates a token bucket for the given bytes per second, holding at
+// most one second of traffic.
+func newBucket(bytesPerSecond uint64) *rate.Limiter {
+	if bytesPerSecond == 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), int(min(bytesPerSecond, math.MaxInt32)))
+}
+
+func waitN(ctx context.Context, bucket *rate.Limiter, n int) error {
+	if bucket == nil {
+		return nil
+	}
+	for n > 0 {
+		current := min(n, bucket.Burst())
+		err := bucket.WaitN(ctx, current)
+		if err != nil {
+			return err
+		}
+		n -= current
+	}
+	return nil
+}
+
+// Group holds the limiters of a limit rule, keyed by connection attribute.
+type Group struct {
+	upload        uint64
+	download      uint64
+	perConnection bool
+	access        sync.Mutex
+	limiters      map[string]*groupEntry
+}
+
+type groupEntry struct {
+	limiter    *Limiter
+	references int
+}
+
+// NewGroup creates a limiter group. If perConnection is set, every connection
+// gets its own limiter regardless of the key.
+func NewGroup(upload uint64, download uint64, perConnection bool) *Group {
+	return &Group{
+		upload:        upload,
+		download:      download,
+		perConnection: perConnection,
+		limiters:      make(map[string]*groupEntry),
+	}
+}
+
+func (g *Group) acquire(key string) *Limiter {
+	if g.perConnection {
+		return NewLimiter(g.upload, g.download)
+	}
+	g.access.Lock()
+	defer g.access.Unlock()
+	entry, loaded := g.limiters[key]
+	if !loaded {
+		entry = &groupEntry{limiter: NewLimiter(g.upload, g.download)}
+		g.limiters[key] = entry
+	}
+	entry.references++
+	return entry.limiter
+}
+
+func (g *Group) release(key string) {
+	if g.perConnection {
+		return
+	}
+	g.access.Lock()
+	defer g.access.Unlock()
+	entry, loaded := g.limiters[key]
+	if !loaded {
+		return
+	}
+	entry.references--
+	if entry.references == 0 {
+		delete(g.limiters, key)
+	}
+}
+
+// Limit selects the limiter in a group for a connection.
+type Limit struct {
+	Group *Group
+	Key   string
+}
+
+type limiters struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	limits    []Limit
+	limiters  []*Limiter
+	closeOnce sync.Once
+}
+
+func newLimiters(ctx context.Context, limits []Limit) *limiters {
+	ctx, cancel := context.WithCancel(ctx)
+	l := &limiters{
+		ctx:    ctx,
+		cancel: cancel,
+		limits: limits,
+	}
+	for _, limit := range limits {
+		l.limiters = append(l.limiters, limit.Group.acquire(limit.Key))
+	}
+	return l
+}
+
+func (l *limiters) waitUpload(n int) error {
+	for _, limiter := range l.limiters {
+		err := waitN(l.ctx, limiter.upload, n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *limiters) waitDownload(n int) error {
+	for _, limiter := range l.limiters {
+		err := waitN(l.ctx, limiter.download, n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *limiters) close() {
+	l.closeOnce.Do(func() {
+		l.cancel()
+		for _, limit := range l.limits {
+			limit.Group.release(limit.Key)
+		}
+	})
+}
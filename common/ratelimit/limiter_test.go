@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupShared(t *testing.T) {
+	t.Parallel()
+	group := NewGroup(1024, 0, false)
+	first := group.acquire("user")
+	require.Same(t, first, group.acquire("user"))
+	require.NotSame(t, first, group.acquire("other"))
+	group.release("user")
+	group.release("user")
+	group.release("other")
+	require.Empty(t, group.limiters)
+}
+
+func TestGroupPerConnection(t *testing.T) {
+	t.Parallel()
+	group := NewGroup(1024, 0, true)
+	require.NotSame(t, group.acquire(""), group.acquire(""))
+	require.Empty(t, group.limiters)
+}
+
+func TestConnDownload(t *testing.T) {
+	t.Parallel()
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	group := NewGroup(0, 16*1024, false)
+	conn := NewConn(context.Background(), clientConn, []Limit{{Group: group}})
+	go io.Copy(io.Discard, serverConn)
+	start := time.Now()
+	_, err := conn.Write(make([]byte, 24*1024))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+	require.NoError(t, conn.Close())
+	require.Empty(t, group.limiters)
+}
@@ -0,0 +1,304 @@
+package ruleset
+
+import (
+	"sort"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/json"
+
+	"go4.org/netipx"
+)
+
+type Change struct {
+	Added bool
+	// Condition is the JSON of other conditions of the rule containing the item, empty if none.
+	Condition string
+	Item
+}
+
+func (c Change) String() string {
+	var content string
+	if c.Added {
+		content = "+ "
+	} else {
+		content = "- "
+	}
+	content += c.Item.String()
+	if c.Condition != "" {
+		content += " (in rule " + c.Condition + ")"
+	}
+	return content
+}
+
+// Diff compares destination addresses of default rules grouped by their other conditions,
+// so domains covered by a suffix and CIDRs split or merged differently are not reported.
+// Logical rules are compared as a whole.
+func Diff(from option.PlainRuleSet, to option.PlainRuleSet) ([]Change, error) {
+	fromIndex, err := newDiffIndex(from)
+	if err != nil {
+		return nil, E.Cause(err, "read original rule-set")
+	}
+	toIndex, err := newDiffIndex(to)
+	if err != nil {
+		return nil, E.Cause(err, "read new rule-set")
+	}
+	conditions := make(map[string]bool)
+	for condition := range fromIndex.groups {
+		conditions[condition] = true
+	}
+	for condition := range toIndex.groups {
+		conditions[condition] = true
+	}
+	conditionList := make([]string, 0, len(conditions))
+	for condition := range conditions {
+		conditionList = append(conditionList, condition)
+	}
+	sort.Strings(conditionList)
+	var changes []Change
+	for _, condition := range conditionList {
+		fromGroup, toGroup := fromIndex.groups[condition], toIndex.groups[condition]
+		switch {
+		case fromGroup == nil && toGroup.isEmpty():
+			changes = append(changes, Change{Added: true, Item: Item{"rule", condition}})
+			continue
+		case toGroup == nil && fromGroup.isEmpty():
+			changes = append(changes, Change{Added: false, Item: Item{"rule", condition}})
+			continue
+		}
+		if fromGroup == nil {
+			fromGroup = newDiffGroup()
+		}
+		if toGroup == nil {
+			toGroup = newDiffGroup()
+		}
+		groupChanges, err := fromGroup.diff(toGroup)
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range groupChanges {
+			change.Condition = condition
+			changes = append(changes, change)
+		}
+	}
+	for _, content := range sortedDifference(fromIndex.logical, toIndex.logical) {
+		changes = append(changes, Change{Added: false, Item: Item{"logical", content}})
+	}
+	for _, content := range sortedDifference(toIndex.logical, fromIndex.logical) {
+		changes = append(changes, Change{Added: true, Item: Item{"logical", content}})
+	}
+	return changes, nil
+}
+
+type diffIndex struct {
+	groups  map[string]*diffGroup
+	logical map[string]bool
+}
+
+func newDiffIndex(ruleSet option.PlainRuleSet) (*diffIndex, error) {
+	index := &diffIndex{
+		groups:  make(map[string]*diffGroup),
+		logical: make(map[string]bool),
+	}
+	for i, rule := range ruleSet.Rules {
+		switch rule.Type {
+		case "", C.RuleTypeDefault:
+			condition, err := ruleCondition(rule.DefaultOptions)
+			if err != nil {
+				return nil, E.Cause(err, "rules.[", i, "]")
+			}
+			group := index.groups[condition]
+			if group == nil {
+				group = newDiffGroup()
+				index.groups[condition] = group
+			}
+			err = group.add(rule.DefaultOptions)
+			if err != nil {
+				return nil, E.Cause(err, "rules.[", i, "]")
+			}
+		case C.RuleTypeLogical:
+			content, err := json.Marshal(rule)
+			if err != nil {
+				return nil, E.Cause(err, "rules.[", i, "]")
+			}
+			index.logical[string(content)] = true
+		default:
+			return nil, E.New("rules.[", i, "]: unknown rule type: ", rule.Type)
+		}
+	}
+	return index, nil
+}
+
+var diffItemTypes = []string{"domain_keyword", "domain_regex", "ip_asn", "adguard_domain"}
+
+type diffGroup struct {
+	// domains holds exact domains and suffixes prefixed with a dot,
+	// a suffix without the leading dot is stored as both.
+	domains map[string]bool
+	items   map[string]map[string]bool
+	ipSet   netipx.IPSetBuilder
+	empty   bool
+}
+
+func newDiffGroup() *diffGroup {
+	group := &diffGroup{
+		domains: make(map[string]bool),
+		items:   make(map[string]map[string]bool),
+		empty:   true,
+	}
+	for _, itemType := range diffItemTypes {
+		group.items[itemType] = make(map[string]bool)
+	}
+	return group
+}
+
+func (g *diffGroup) isEmpty() bool {
+	return g != nil && g.empty
+}
+
+func (g *diffGroup) add(rule option.DefaultHeadlessRule) error {
+	for _, domain := range rule.Domain {
+		g.domains[domain] = true
+		g.empty = false
+	}
+	for _, suffix := range rule.DomainSuffix {
+		if !strings.HasPrefix(suffix, ".") {
+			g.domains[suffix] = true
+			suffix = "." + suffix
+		}
+		g.domains[suffix] = true
+		g.empty = false
+	}
+	g.addItems("domain_keyword", rule.DomainKeyword)
+	g.addItems("domain_regex", rule.DomainRegex)
+	for _, asn := range rule.IPASN {
+		g.addItems("ip_asn", []string{F.ToString(asn)})
+	}
+	g.addItems("adguard_domain", rule.AdGuardDomain)
+	for i, prefixString := range rule.IPCIDR {
+		prefix, err := parsePrefix(prefixString)
+		if err != nil {
+			return E.Cause(err, "ip_cidr: parse [", i, "]")
+		}
+		g.ipSet.AddPrefix(prefix)
+		g.empty = false
+	}
+	return nil
+}
+
+func (g *diffGroup) addItems(itemType string, values []string) {
+	for _, value := range values {
+		g.items[itemType][value] = true
+		g.empty = false
+	}
+}
+
+func (g *diffGroup) diff(to *diffGroup) ([]Change, error) {
+	var changes []Change
+	for _, domain := range renderDomains(domainDifference(g.domains, to.domains)) {
+		changes = append(changes, Change{Added: false, Item: domain})
+	}
+	for _, domain := range renderDomains(domainDifference(to.domains, g.domains)) {
+		changes = append(changes, Change{Added: true, Item: domain})
+	}
+	for _, itemType := range diffItemTypes {
+		for _, value := range sortedDifference(g.items[itemType], to.items[itemType]) {
+			changes = append(changes, Change{Added: false, Item: Item{itemType, value}})
+		}
+		for _, value := range sortedDifference(to.items[itemType], g.items[itemType]) {
+			changes = append(changes, Change{Added: true, Item: Item{itemType, value}})
+		}
+	}
+	fromIPSet, err := g.ipSet.IPSet()
+	if err != nil {
+		return nil, E.Cause(err, "ip_cidr")
+	}
+	toIPSet, err := to.ipSet.IPSet()
+	if err != nil {
+		return nil, E.Cause(err, "ip_cidr")
+	}
+	removed, err := ipSetDifference(fromIPSet, toIPSet)
+	if err != nil {
+		return nil, E.Cause(err, "ip_cidr")
+	}
+	for _, prefix := range removed.Prefixes() {
+		changes = append(changes, Change{Added: false, Item: Item{"ip_cidr", prefix.String()}})
+	}
+	added, err := ipSetDifference(toIPSet, fromIPSet)
+	if err != nil {
+		return nil, E.Cause(err, "ip_cidr")
+	}
+	for _, prefix := range added.Prefixes() {
+		changes = append(changes, Change{Added: true, Item: Item{"ip_cidr", prefix.String()}})
+	}
+	return changes, nil
+}
+
+// domainDifference returns domains in a but neither in b nor covered by a suffix in a or b.
+func domainDifference(a map[string]bool, b map[string]bool) map[string]bool {
+	suffixes := make(map[string]bool)
+	for _, domains := range []map[string]bool{a, b} {
+		for domain := range domains {
+			if strings.HasPrefix(domain, ".") {
+				suffixes[domain] = true
+			}
+		}
+	}
+	difference := make(map[string]bool)
+	for domain := range a {
+		if b[domain] {
+			continue
+		}
+		if _, covered := coveringSuffix(domain, strings.HasPrefix(domain, "."), suffixes); covered {
+			continue
+		}
+		difference[domain] = true
+	}
+	return difference
+}
+
+func renderDomains(domains map[string]bool) []Item {
+	var domainList, suffixList []string
+	for domain := range domains {
+		if strings.HasPrefix(domain, ".") {
+			if domains[domain[1:]] {
+				suffixList = append(suffixList, domain[1:])
+			} else {
+				suffixList = append(suffixList, domain)
+			}
+		} else if !domains["."+domain] {
+			domainList = append(domainList, domain)
+		}
+	}
+	sort.Strings(domainList)
+	sort.Strings(suffixList)
+	items := make([]Item, 0, len(domainList)+len(suffixList))
+	for _, domain := range domainList {
+		items = append(items, Item{"domain", domain})
+	}
+	for _, suffix := range suffixList {
+		items = append(items, Item{"domain_suffix", suffix})
+	}
+	return items
+}
+
+func sortedDifference(a map[string]bool, b map[string]bool) []string {
+	var difference []string
+	for value := range a {
+		if !b[value] {
+			difference = append(difference, value)
+		}
+	}
+	sort.Strings(difference)
+	return difference
+}
+
+func ipSetDifference(a *netipx.IPSet, b *netipx.IPSet) (*netipx.IPSet, error) {
+	var builder netipx.IPSetBuilder
+	builder.AddSet(a)
+	builder.RemoveSet(b)
+	return builder.IPSet()
+}
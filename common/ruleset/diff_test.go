@@ -0,0 +1,74 @@
+package ruleset
+
+import (
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func defaultRuleSet(rules ...option.DefaultHeadlessRule) option.PlainRuleSet {
+	var ruleSet option.PlainRuleSet
+	for _, rule := range rules {
+		ruleSet.Rules = append(ruleSet.Rules, option.HeadlessRule{
+			Type:           C.RuleTypeDefault,
+			DefaultOptions: rule,
+		})
+	}
+	return ruleSet
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	from := defaultRuleSet(option.DefaultHeadlessRule{
+		Domain:        []string{"example.org", "www.example.com"},
+		DomainSuffix:  []string{"example.com", ".example.net"},
+		DomainKeyword: []string{"google"},
+		IPCIDR:        []string{"10.0.0.0/8"},
+	})
+	to := defaultRuleSet(
+		option.DefaultHeadlessRule{
+			Domain:        []string{"example.com", "example.org"},
+			DomainSuffix:  []string{".example.com", "example.net"},
+			DomainKeyword: []string{"facebook"},
+			IPCIDR:        []string{"10.0.0.0/9", "10.128.0.0/10", "1.1.1.1"},
+		},
+		option.DefaultHeadlessRule{
+			Domain: []string{"example.com"},
+			Port:   []uint16{443},
+		},
+	)
+	changes, err := Diff(from, to)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"+ domain: example.net",
+		"- domain_keyword: google",
+		"+ domain_keyword: facebook",
+		"- ip_cidr: 10.192.0.0/10",
+		"+ ip_cidr: 1.1.1.1/32",
+		`+ domain: example.com (in rule {"port":443})`,
+	}, changeStrings(changes))
+}
+
+func TestDiffSameRuleSet(t *testing.T) {
+	t.Parallel()
+	ruleSet := defaultRuleSet(option.DefaultHeadlessRule{
+		Domain:       []string{"www.example.com"},
+		DomainSuffix: []string{"example.com"},
+	})
+	changes, err := Diff(ruleSet, defaultRuleSet(option.DefaultHeadlessRule{
+		DomainSuffix: []string{"example.com"},
+	}))
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
+
+func changeStrings(changes []Change) []string {
+	changeList := make([]string, 0, len(changes))
+	for _, change := range changes {
+		changeList = append(changeList, change.String())
+	}
+	return changeList
+}
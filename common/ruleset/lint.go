@@ -0,0 +1,358 @@
+package ruleset
+
+import (
+	"fmt"
+	"net/netip"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/json"
+)
+
+type Issue struct {
+	Path    string
+	Message string
+}
+
+func (i Issue) String() string {
+	return i.Path + ": " + i.Message
+}
+
+// Lint reports redundant items, overlapping CIDRs, unreachable logical rules and invalid regular expressions.
+// Destination addresses of non-inverted default rules with the same other conditions are checked together,
+// since they are matched as a whole.
+func Lint(ruleSet option.PlainRuleSet) []Issue {
+	var l linter
+	l.lintRules("rules", ruleSet.Rules, true)
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		return comparePath(l.issues[i].Path, l.issues[j].Path) < 0
+	})
+	return l.issues
+}
+
+// comparePath compares paths like rules.[1].domain.[10] with indexes in numeric order.
+func comparePath(a string, b string) int {
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		if aParts[i] == bParts[i] {
+			continue
+		}
+		aIndex, aErr := strconv.Atoi(strings.Trim(aParts[i], "[]"))
+		bIndex, bErr := strconv.Atoi(strings.Trim(bParts[i], "[]"))
+		if aErr == nil && bErr == nil {
+			return aIndex - bIndex
+		}
+		return strings.Compare(aParts[i], bParts[i])
+	}
+	return len(aParts) - len(bParts)
+}
+
+var destinationAddressFields = []string{"domain", "domain_suffix", "domain_keyword", "domain_regex", "ip_cidr", "ip_asn", "adguard_domain"}
+
+// exclusiveFields can only be matched by one value at a time.
+var exclusiveFields = []string{"query_type", "network", "domain", "port", "process_name", "package_name"}
+
+type linter struct {
+	issues []Issue
+}
+
+type lintEntry struct {
+	path  string
+	field string
+	value string
+}
+
+func (l *linter) report(path string, message ...any) {
+	l.issues = append(l.issues, Issue{path, F.ToString(message...)})
+}
+
+func (l *linter) lintRules(path string, rules []option.HeadlessRule, merge bool) []bool {
+	var (
+		neverMatches = make([]bool, len(rules))
+		groups       = make(map[string][]lintEntry)
+		groupList    []string
+	)
+	for i, rule := range rules {
+		rulePath := F.ToString(path, ".[", i, "]")
+		switch rule.Type {
+		case "", C.RuleTypeDefault:
+			l.lintDefault(rulePath, rule.DefaultOptions)
+			entries := destinationAddressEntries(rulePath, rule.DefaultOptions)
+			if !merge || rule.DefaultOptions.Invert {
+				l.lintAddresses(entries)
+				continue
+			}
+			condition, err := ruleCondition(rule.DefaultOptions)
+			if err != nil {
+				l.report(rulePath, err)
+				continue
+			}
+			if _, loaded := groups[condition]; !loaded {
+				groupList = append(groupList, condition)
+			}
+			groups[condition] = append(groups[condition], entries...)
+		case C.RuleTypeLogical:
+			neverMatches[i] = l.lintLogical(rulePath, rule.LogicalOptions)
+		default:
+			l.report(rulePath, "unknown rule type: ", rule.Type)
+		}
+	}
+	for _, condition := range groupList {
+		l.lintAddresses(groups[condition])
+	}
+	return neverMatches
+}
+
+func (l *linter) lintDefault(path string, rule option.DefaultHeadlessRule) {
+	if !rule.IsValid() {
+		l.report(path, "missing conditions")
+		return
+	}
+	for i, regex := range rule.ProcessPathRegex {
+		_, err := regexp.Compile(regex)
+		if err != nil {
+			l.report(F.ToString(path, ".process_path_regex.[", i, "]"), "invalid regular expression: ", err)
+		}
+	}
+	for _, field := range ruleFields(rule) {
+		if field.value.Kind() != reflect.Slice || common.Contains(destinationAddressFields, field.name) {
+			continue
+		}
+		seen := make(map[string]string)
+		for i := 0; i < field.value.Len(); i++ {
+			itemPath := F.ToString(path, ".", field.name, ".[", i, "]")
+			value := fmt.Sprint(field.value.Index(i).Interface())
+			if value == "" {
+				l.report(itemPath, "empty item")
+			} else if duplicatePath, loaded := seen[value]; loaded {
+				l.report(itemPath, value, " is duplicate of ", duplicatePath)
+			} else {
+				seen[value] = itemPath
+			}
+		}
+	}
+	var sourceEntries []lintEntry
+	for i, prefix := range rule.SourceIPCIDR {
+		sourceEntries = append(sourceEntries, lintEntry{F.ToString(path, ".source_ip_cidr.[", i, "]"), "source_ip_cidr", prefix})
+	}
+	l.lintCIDRs(sourceEntries)
+}
+
+func destinationAddressEntries(path string, rule option.DefaultHeadlessRule) []lintEntry {
+	var entries []lintEntry
+	for _, field := range ruleFields(rule) {
+		if !common.Contains(destinationAddressFields, field.name) {
+			continue
+		}
+		for i := 0; i < field.value.Len(); i++ {
+			entries = append(entries, lintEntry{
+				path:  F.ToString(path, ".", field.name, ".[", i, "]"),
+				field: field.name,
+				value: fmt.Sprint(field.value.Index(i).Interface()),
+			})
+		}
+	}
+	return entries
+}
+
+func (l *linter) lintAddresses(entries []lintEntry) {
+	var (
+		seen      = make(map[lintEntry]string)
+		suffixes  = make(map[string]bool)
+		suffixMap = make(map[string]string)
+		keywords  []lintEntry
+		domains   []lintEntry
+		prefixes  []lintEntry
+	)
+	for _, entry := range entries {
+		key := lintEntry{field: entry.field, value: entry.value}
+		if entry.value == "" {
+			l.report(entry.path, "empty item")
+			continue
+		} else if duplicatePath, loaded := seen[key]; loaded {
+			l.report(entry.path, entry.value, " is duplicate of ", duplicatePath)
+			continue
+		}
+		seen[key] = entry.path
+		switch entry.field {
+		case "domain":
+			domains = append(domains, entry)
+		case "domain_suffix":
+			domains = append(domains, entry)
+			suffixes[entry.value] = true
+			suffixMap[entry.value] = entry.path
+		case "domain_keyword":
+			keywords = append(keywords, entry)
+		case "domain_regex":
+			_, err := regexp.Compile(entry.value)
+			if err != nil {
+				l.report(entry.path, "invalid regular expression: ", err)
+			}
+		case "ip_cidr":
+			prefixes = append(prefixes, entry)
+		}
+	}
+	for _, entry := range domains {
+		suffix, covered := coveringSuffix(entry.value, entry.field == "domain_suffix", suffixes)
+		if covered {
+			l.report(entry.path, entry.value, " is covered by domain_suffix ", suffix, " at ", suffixMap[suffix])
+			continue
+		}
+		for _, keyword := range keywords {
+			if strings.Contains(entry.value, keyword.value) {
+				l.report(entry.path, entry.value, " is covered by domain_keyword ", keyword.value, " at ", keyword.path)
+				break
+			}
+		}
+	}
+	for _, entry := range keywords {
+		for _, keyword := range keywords {
+			if keyword.value != entry.value && strings.Contains(entry.value, keyword.value) {
+				l.report(entry.path, entry.value, " is covered by domain_keyword ", keyword.value, " at ", keyword.path)
+				break
+			}
+		}
+	}
+	l.lintCIDRs(prefixes)
+}
+
+func (l *linter) lintCIDRs(entries []lintEntry) {
+	type prefixEntry struct {
+		lintEntry
+		prefix netip.Prefix
+	}
+	var prefixes []prefixEntry
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		prefix, err := parsePrefix(entry.value)
+		if err != nil {
+			l.report(entry.path, "invalid CIDR: ", err)
+			continue
+		}
+		// duplicates are reported by callers
+		if seen[entry.value] {
+			continue
+		}
+		seen[entry.value] = true
+		prefixes = append(prefixes, prefixEntry{entry, prefix})
+	}
+	sort.SliceStable(prefixes, func(i, j int) bool {
+		if compare := prefixes[i].prefix.Addr().Compare(prefixes[j].prefix.Addr()); compare != 0 {
+			return compare < 0
+		}
+		return prefixes[i].prefix.Bits() < prefixes[j].prefix.Bits()
+	})
+	// CIDRs are either nested or disjoint, so sorted by address and length,
+	// each one can only be covered by the last uncovered one.
+	var cover *prefixEntry
+	for i := range prefixes {
+		current := &prefixes[i]
+		if cover != nil && cover.prefix.Contains(current.prefix.Addr()) {
+			l.report(current.path, current.value, " is covered by ", cover.field, " ", cover.value, " at ", cover.path)
+			continue
+		}
+		cover = current
+	}
+}
+
+func (l *linter) lintLogical(path string, rule option.LogicalHeadlessRule) bool {
+	if len(rule.Rules) == 0 {
+		l.report(path, "missing conditions")
+		return false
+	}
+	neverMatches := l.lintRules(path+".rules", rule.Rules, rule.Mode == C.LogicalTypeOr)
+	switch rule.Mode {
+	case C.LogicalTypeAnd:
+		for _, subNeverMatches := range neverMatches {
+			if subNeverMatches {
+				return !rule.Invert
+			}
+		}
+		a, b, found := contradictoryRules(rule.Rules, true)
+		if !found {
+			return false
+		}
+		if !rule.Invert {
+			l.report(path, "unreachable: ", path, ".rules.[", a, "] and ", path, ".rules.[", b, "] can never match at the same time")
+			return true
+		}
+	case C.LogicalTypeOr:
+		if common.All(neverMatches, func(it bool) bool { return it }) {
+			return !rule.Invert
+		}
+		a, b, found := contradictoryRules(rule.Rules, false)
+		if !found {
+			return false
+		}
+		if rule.Invert {
+			l.report(path, "unreachable: ", path, ".rules.[", a, "] and ", path, ".rules.[", b, "] always match one of them, but the rule is inverted")
+			return true
+		}
+	default:
+		l.report(path, "unknown logical mode: ", rule.Mode)
+	}
+	return false
+}
+
+// contradictoryRules finds two rules where one is the inversion of the other,
+// or for intersect, two single exclusive field rules without common values.
+func contradictoryRules(rules []option.HeadlessRule, intersect bool) (int, int, bool) {
+	contents := make([]string, len(rules))
+	invertedContents := make([]string, len(rules))
+	for i, rule := range rules {
+		content, err := json.Marshal(rule)
+		if err != nil {
+			continue
+		}
+		contents[i] = string(content)
+		switch rule.Type {
+		case "", C.RuleTypeDefault:
+			rule.DefaultOptions.Invert = !rule.DefaultOptions.Invert
+		case C.RuleTypeLogical:
+			rule.LogicalOptions.Invert = !rule.LogicalOptions.Invert
+		}
+		content, err = json.Marshal(rule)
+		if err != nil {
+			continue
+		}
+		invertedContents[i] = string(content)
+	}
+	for i := range rules {
+		for j := i + 1; j < len(rules); j++ {
+			if contents[i] != "" && contents[i] == invertedContents[j] {
+				return i, j, true
+			}
+			if intersect && disjointRules(rules[i], rules[j]) {
+				return i, j, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+func disjointRules(a option.HeadlessRule, b option.HeadlessRule) bool {
+	if a.Type == C.RuleTypeLogical || b.Type == C.RuleTypeLogical || a.DefaultOptions.Invert || b.DefaultOptions.Invert {
+		return false
+	}
+	aFields, bFields := ruleFields(a.DefaultOptions), ruleFields(b.DefaultOptions)
+	if len(aFields) != 1 || len(bFields) != 1 || aFields[0].name != bFields[0].name || !common.Contains(exclusiveFields, aFields[0].name) {
+		return false
+	}
+	values := make(map[string]bool)
+	for i := 0; i < aFields[0].value.Len(); i++ {
+		values[fmt.Sprint(aFields[0].value.Index(i).Interface())] = true
+	}
+	for i := 0; i < bFields[0].value.Len(); i++ {
+		if values[fmt.Sprint(bFields[0].value.Index(i).Interface())] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package ruleset
+
+import (
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+	ruleSet := defaultRuleSet(
+		option.DefaultHeadlessRule{
+			Domain:        []string{"www.example.com", "example.org", "example.org"},
+			DomainSuffix:  []string{"example.com", "a.example.net", ".example.net"},
+			DomainKeyword: []string{"google"},
+			DomainRegex:   []string{"^google\\.", "(["},
+			IPCIDR:        []string{"10.0.0.0/8", "10.1.0.0/16", "1.1.1.1"},
+		},
+		option.DefaultHeadlessRule{
+			Domain: []string{"google.com"},
+		},
+		option.DefaultHeadlessRule{
+			Domain: []string{"www.example.com"},
+			Port:   []uint16{443, 443},
+		},
+	)
+	ruleSet.Rules = append(ruleSet.Rules, option.HeadlessRule{
+		Type: C.RuleTypeLogical,
+		LogicalOptions: option.LogicalHeadlessRule{
+			Mode: C.LogicalTypeAnd,
+			Rules: []option.HeadlessRule{
+				{Type: C.RuleTypeDefault, DefaultOptions: option.DefaultHeadlessRule{Network: []string{"tcp"}}},
+				{Type: C.RuleTypeDefault, DefaultOptions: option.DefaultHeadlessRule{Network: []string{"udp"}}},
+			},
+		},
+	})
+	require.Equal(t, []string{
+		"rules.[0].domain.[0]: www.example.com is covered by domain_suffix example.com at rules.[0].domain_suffix.[0]",
+		"rules.[0].domain.[2]: example.org is duplicate of rules.[0].domain.[1]",
+		"rules.[0].domain_regex.[1]: invalid regular expression: error parsing regexp: missing closing ]: `[`",
+		"rules.[0].domain_suffix.[1]: a.example.net is covered by domain_suffix .example.net at rules.[0].domain_suffix.[2]",
+		"rules.[0].ip_cidr.[1]: 10.1.0.0/16 is covered by ip_cidr 10.0.0.0/8 at rules.[0].ip_cidr.[0]",
+		"rules.[1].domain.[0]: google.com is covered by domain_keyword google at rules.[0].domain_keyword.[0]",
+		"rules.[2].port.[1]: 443 is duplicate of rules.[2].port.[0]",
+		"rules.[3]: unreachable: rules.[3].rules.[0] and rules.[3].rules.[1] can never match at the same time",
+	}, issueStrings(Lint(ruleSet)))
+}
+
+func TestLintInvertedRule(t *testing.T) {
+	t.Parallel()
+	ruleSet := defaultRuleSet(
+		option.DefaultHeadlessRule{
+			DomainSuffix: []string{"example.com"},
+			Invert:       true,
+		},
+		option.DefaultHeadlessRule{
+			Domain: []string{"www.example.com"},
+			Invert: true,
+		},
+	)
+	require.Empty(t, Lint(ruleSet))
+}
+
+func issueStrings(issues []Issue) []string {
+	issueList := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		issueList = append(issueList, issue.String())
+	}
+	return issueList
+}
@@ -0,0 +1,132 @@
+package ruleset
+
+import (
+	"net/netip"
+	"reflect"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+
+	"go4.org/netipx"
+)
+
+type Item struct {
+	Type  string
+	Value string
+}
+
+func (i Item) String() string {
+	return i.Type + ": " + i.Value
+}
+
+// ruleCondition returns conditions of the rule other than destination addresses,
+// so that rules with the same condition can be compared and merged item by item.
+// Binary rules must be read with recovery.
+func ruleCondition(rule option.DefaultHeadlessRule) (string, error) {
+	rule.Domain = nil
+	rule.DomainSuffix = nil
+	rule.DomainKeyword = nil
+	rule.DomainRegex = nil
+	rule.IPCIDR = nil
+	rule.IPASN = nil
+	rule.AdGuardDomain = nil
+	rule.DomainMatcher = nil
+	rule.IPSet = nil
+	rule.AdGuardDomainMatcher = nil
+	rule.SourceIPSet = nil
+	content, err := json.Marshal(rule)
+	if err != nil {
+		return "", err
+	}
+	if string(content) == "{}" {
+		return "", nil
+	}
+	return string(content), nil
+}
+
+func parsePrefix(prefixString string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(prefixString)
+	if err == nil {
+		return prefix.Masked(), nil
+	}
+	addr, addrErr := netip.ParseAddr(prefixString)
+	if addrErr == nil {
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	return netip.Prefix{}, err
+}
+
+func buildIPSet(prefixStrings []string) (*netipx.IPSet, error) {
+	var builder netipx.IPSetBuilder
+	for i, prefixString := range prefixStrings {
+		prefix, err := parsePrefix(prefixString)
+		if err != nil {
+			return nil, E.Cause(err, "parse [", i, "]")
+		}
+		builder.AddPrefix(prefix)
+	}
+	return builder.IPSet()
+}
+
+// coveringSuffix returns the domain suffix item covering the domain or domain suffix value,
+// as matched by domain.Matcher.
+func coveringSuffix(value string, isSuffix bool, suffixes map[string]bool) (string, bool) {
+	if isSuffix && strings.HasPrefix(value, ".") {
+		if suffixes[value[1:]] {
+			return value[1:], true
+		}
+		value = value[1:]
+	} else if !isSuffix && suffixes[value] {
+		return value, true
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] != '.' {
+			continue
+		}
+		if suffixes[value[i+1:]] {
+			return value[i+1:], true
+		}
+		if suffixes[value[i:]] {
+			return value[i:], true
+		}
+	}
+	return "", false
+}
+
+type ruleField struct {
+	name  string
+	value reflect.Value
+}
+
+// ruleFields returns non-empty fields of the rule in declaration order, keyed by JSON names.
+func ruleFields(rule option.DefaultHeadlessRule) []ruleField {
+	var fields []ruleField
+	ruleValue := reflect.ValueOf(rule)
+	ruleType := ruleValue.Type()
+	for i := 0; i < ruleType.NumField(); i++ {
+		name := ruleFieldName(ruleType.Field(i))
+		if name == "" {
+			continue
+		}
+		value := ruleValue.Field(i)
+		if value.IsZero() || (value.Kind() == reflect.Slice && value.Len() == 0) {
+			continue
+		}
+		fields = append(fields, ruleField{name, value})
+	}
+	return fields
+}
+
+func ruleFieldName(field reflect.StructField) string {
+	if field.Name == "AdGuardDomain" {
+		return "adguard_domain"
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-", "invert":
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,136 @@
+package ruleset
+
+import (
+	"bytes"
+	"reflect"
+	"unsafe"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/domain"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+
+	"go4.org/netipx"
+)
+
+type Statistics struct {
+	DefaultRules int
+	LogicalRules int
+	Items        []ItemStatistics
+}
+
+type ItemStatistics struct {
+	Type  string
+	Count int
+	// Memory is the estimated size of the item after loaded in bytes,
+	// compiled regular expressions are counted as strings.
+	Memory uint64
+}
+
+func (s *Statistics) Memory() uint64 {
+	var memory uint64
+	for _, item := range s.Items {
+		memory += item.Memory
+	}
+	return memory
+}
+
+// Stats counts items per type in default rules, including those nested in logical rules.
+func Stats(ruleSet option.PlainRuleSet) (*Statistics, error) {
+	items := make(map[string]*ItemStatistics)
+	var statistics Statistics
+	err := statistics.addRules("rules", ruleSet.Rules, items)
+	if err != nil {
+		return nil, err
+	}
+	ruleType := reflect.TypeOf(option.DefaultHeadlessRule{})
+	for i := 0; i < ruleType.NumField(); i++ {
+		item := items[ruleFieldName(ruleType.Field(i))]
+		if item != nil {
+			statistics.Items = append(statistics.Items, *item)
+		}
+	}
+	return &statistics, nil
+}
+
+func (s *Statistics) addRules(path string, rules []option.HeadlessRule, items map[string]*ItemStatistics) error {
+	for i, rule := range rules {
+		switch rule.Type {
+		case "", C.RuleTypeDefault:
+			s.DefaultRules++
+			err := addItemStatistics(rule.DefaultOptions, items)
+			if err != nil {
+				return E.Cause(err, path, ".[", i, "]")
+			}
+		case C.RuleTypeLogical:
+			s.LogicalRules++
+			err := s.addRules(F.ToString(path, ".[", i, "].rules"), rule.LogicalOptions.Rules, items)
+			if err != nil {
+				return err
+			}
+		default:
+			return E.New(path, ".[", i, "]: unknown rule type: ", rule.Type)
+		}
+	}
+	return nil
+}
+
+func addItemStatistics(rule option.DefaultHeadlessRule, items map[string]*ItemStatistics) error {
+	for _, field := range ruleFields(rule) {
+		item := items[field.name]
+		if item == nil {
+			item = &ItemStatistics{Type: field.name}
+			items[field.name] = item
+		}
+		if field.value.Kind() != reflect.Slice {
+			item.Count++
+			continue
+		}
+		item.Count += field.value.Len()
+		switch field.name {
+		case "domain":
+			item.Memory += domainMatcherSize(rule.Domain, nil)
+		case "domain_suffix":
+			item.Memory += domainMatcherSize(nil, rule.DomainSuffix)
+		case "adguard_domain":
+			var buffer bytes.Buffer
+			_ = domain.NewAdGuardMatcher(rule.AdGuardDomain).Write(&buffer)
+			item.Memory += uint64(buffer.Len())
+		case "ip_cidr", "source_ip_cidr":
+			prefixes := rule.IPCIDR
+			if field.name == "source_ip_cidr" {
+				prefixes = rule.SourceIPCIDR
+			}
+			ipSet, err := buildIPSet(prefixes)
+			if err != nil {
+				return E.Cause(err, field.name)
+			}
+			item.Memory += uint64(len(ipSet.Ranges())) * uint64(unsafe.Sizeof(netipx.IPRange{}))
+		default:
+			elemType := field.value.Type().Elem()
+			item.Memory += uint64(field.value.Len()) * uint64(elemType.Size())
+			if elemType.Kind() == reflect.String {
+				for i := 0; i < field.value.Len(); i++ {
+					item.Memory += uint64(field.value.Index(i).Len())
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func domainMatcherSize(domains []string, domainSuffix []string) uint64 {
+	var buffer bytes.Buffer
+	_ = domain.NewMatcher(
+		common.Filter(domains, isNotEmpty),
+		common.Filter(domainSuffix, isNotEmpty),
+		false,
+	).Write(&buffer)
+	return uint64(buffer.Len())
+}
+
+func isNotEmpty(it string) bool {
+	return it != ""
+}
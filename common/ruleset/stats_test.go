@@ -0,0 +1,35 @@
+package ruleset
+
+import (
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+	statistics, err := Stats(defaultRuleSet(
+		option.DefaultHeadlessRule{
+			Domain:       []string{"example.com", "example.org"},
+			DomainSuffix: []string{"example.net"},
+			IPCIDR:       []string{"10.0.0.0/8", "1.1.1.1"},
+		},
+		option.DefaultHeadlessRule{
+			DomainKeyword: []string{"google"},
+			Port:          []uint16{80, 443},
+		},
+	))
+	require.NoError(t, err)
+	require.Equal(t, 2, statistics.DefaultRules)
+	require.Equal(t, 0, statistics.LogicalRules)
+	var itemTypes []string
+	for _, item := range statistics.Items {
+		itemTypes = append(itemTypes, item.Type)
+		require.NotZero(t, item.Memory, item.Type)
+	}
+	require.Equal(t, []string{"domain", "domain_suffix", "domain_keyword", "ip_cidr", "port"}, itemTypes)
+	require.Equal(t, 2, statistics.Items[0].Count)
+	require.Equal(t, 2, statistics.Items[3].Count)
+}
@@ -0,0 +1,34 @@
+package sniff
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/ja3"
+)
+
+type clientHelloContextKey struct{}
+
+// ContextWithClientHello makes TLS and QUIC sniffers keep the client hello
+// in metadata for ClientFingerprint.
+func ContextWithClientHello(ctx context.Context) context.Context {
+	return context.WithValue(ctx, clientHelloContextKey{}, true)
+}
+
+func keepClientHello(ctx context.Context) bool {
+	return ctx.Value(clientHelloContextKey{}) == true
+}
+
+// ClientFingerprint returns JA3 and JA4 fingerprints of the sniffed client
+// hello, computed on first use.
+func ClientFingerprint(metadata *adapter.InboundContext) (string, string) {
+	if metadata.ClientHello != nil {
+		fingerprint, err := ja3.Compute(metadata.ClientHello)
+		if err == nil {
+			metadata.JA3 = fingerprint.Hash()
+			metadata.JA4 = fingerprint.JA4(metadata.ClientHelloQUIC)
+		}
+		metadata.ClientHello = nil
+	}
+	return metadata.JA3, metadata.JA4
+}
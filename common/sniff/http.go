@@ -24,5 +24,9 @@ func HTTPHost(_ context.Context, metadata *adapter.InboundContext, reader io.Rea
 	}
 	metadata.Protocol = C.ProtocolHTTP
 	metadata.Domain = M.ParseSocksaddr(request.Host).AddrString()
+	metadata.HTTPMethod = request.Method
+	metadata.HTTPPath = request.URL.Path
+	metadata.HTTPHost = request.Host
+	metadata.HTTPUserAgent = request.UserAgent()
 	return nil
 }
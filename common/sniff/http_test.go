@@ -28,3 +28,15 @@ func TestSniffHTTP1WithPort(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, metadata.Domain, "www.gov.cn")
 }
+
+func TestSniffHTTP1Attributes(t *testing.T) {
+	t.Parallel()
+	pkt := "POST /api/v1/upload?id=1 HTTP/1.1\r\nHost: example.com\r\nUser-Agent: curl/8.5.0\r\n\r\n"
+	var metadata adapter.InboundContext
+	err := sniff.HTTPHost(context.Background(), &metadata, strings.NewReader(pkt))
+	require.NoError(t, err)
+	require.Equal(t, "POST", metadata.HTTPMethod)
+	require.Equal(t, "/api/v1/upload", metadata.HTTPPath)
+	require.Equal(t, "example.com", metadata.HTTPHost)
+	require.Equal(t, "curl/8.5.0", metadata.HTTPUserAgent)
+}
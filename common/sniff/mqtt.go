@@ -0,0 +1,105 @@
+package sniff
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/rw"
+)
+
+func MQTT(_ context.Context, metadata *adapter.InboundContext, reader io.Reader) error {
+	bReader := bufio.NewReader(reader)
+	packetType, err := bReader.ReadByte()
+	if err != nil {
+		return E.Cause1(ErrNeedMoreData, err)
+	}
+	// CONNECT with reserved flags
+	if packetType != 0x10 {
+		return os.ErrInvalid
+	}
+	remainingLength, err := readMQTTVariableInteger(bReader)
+	if err != nil {
+		return err
+	}
+	if remainingLength < 12 {
+		return os.ErrInvalid
+	}
+	protocolName, err := readMQTTString(bReader)
+	if err != nil {
+		return err
+	}
+	protocolLevel, err := bReader.ReadByte()
+	if err != nil {
+		return E.Cause1(ErrNeedMoreData, err)
+	}
+	switch {
+	case protocolName == "MQIsdp" && protocolLevel == 3:
+	case protocolName == "MQTT" && (protocolLevel == 4 || protocolLevel == 5):
+	default:
+		return os.ErrInvalid
+	}
+	connectFlags, err := bReader.ReadByte()
+	if err != nil {
+		return E.Cause1(ErrNeedMoreData, err)
+	}
+	if connectFlags&0x01 != 0 {
+		return os.ErrInvalid
+	}
+	// keep alive
+	err = rw.SkipN(bReader, 2)
+	if err != nil {
+		return E.Cause1(ErrNeedMoreData, err)
+	}
+	if protocolLevel == 5 {
+		propertiesLength, err := readMQTTVariableInteger(bReader)
+		if err != nil {
+			return err
+		}
+		err = rw.SkipN(bReader, int(propertiesLength))
+		if err != nil {
+			return E.Cause1(ErrNeedMoreData, err)
+		}
+	}
+	clientID, err := readMQTTString(bReader)
+	if err != nil {
+		return err
+	}
+	metadata.Protocol = C.ProtocolMQTT
+	metadata.Client = clientID
+	return nil
+}
+
+func readMQTTVariableInteger(reader io.ByteReader) (uint32, error) {
+	var value uint32
+	for i := 0; i < 4; i++ {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, E.Cause1(ErrNeedMoreData, err)
+		}
+		value |= uint32(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			return value, nil
+		}
+	}
+	return 0, os.ErrInvalid
+}
+
+func readMQTTString(reader io.Reader) (string, error) {
+	var length uint16
+	err := binary.Read(reader, binary.BigEndian, &length)
+	if err != nil {
+		return "", E.Cause1(ErrNeedMoreData, err)
+	}
+	value := make([]byte, length)
+	_, err = io.ReadFull(reader, value)
+	if err != nil {
+		return "", E.Cause1(ErrNeedMoreData, err)
+	}
+	return string(value), nil
+}
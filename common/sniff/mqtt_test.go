@@ -0,0 +1,55 @@
+package sniff_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSniffMQTT(t *testing.T) {
+	t.Parallel()
+	pkt, err := hex.DecodeString("102300044d5154540402003c00176d6f73712d513863597a5a786b62534a4a375253357963")
+	require.NoError(t, err)
+	var metadata adapter.InboundContext
+	err = sniff.MQTT(context.Background(), &metadata, bytes.NewReader(pkt))
+	require.NoError(t, err)
+	require.Equal(t, C.ProtocolMQTT, metadata.Protocol)
+	require.Equal(t, "mosq-Q8cYzZxkbSJJ7RS5yc", metadata.Client)
+}
+
+func TestSniffMQTT5(t *testing.T) {
+	t.Parallel()
+	pkt, err := hex.DecodeString("101d00044d5154540502003c03210014000d7061686f2d636c69656e742d31")
+	require.NoError(t, err)
+	var metadata adapter.InboundContext
+	err = sniff.MQTT(context.Background(), &metadata, bytes.NewReader(pkt))
+	require.NoError(t, err)
+	require.Equal(t, C.ProtocolMQTT, metadata.Protocol)
+	require.Equal(t, "paho-client-1", metadata.Client)
+}
+
+func TestSniffIncompleteMQTT(t *testing.T) {
+	t.Parallel()
+	pkt, err := hex.DecodeString("102300044d5154540402")
+	require.NoError(t, err)
+	var metadata adapter.InboundContext
+	err = sniff.MQTT(context.Background(), &metadata, bytes.NewReader(pkt))
+	require.ErrorIs(t, err, sniff.ErrNeedMoreData)
+}
+
+func TestSniffNotMQTT(t *testing.T) {
+	t.Parallel()
+	pkt, err := hex.DecodeString("102300044d5154540902003c00176d6f73712d513863597a5a786b62534a4a375253357963")
+	require.NoError(t, err)
+	var metadata adapter.InboundContext
+	err = sniff.MQTT(context.Background(), &metadata, bytes.NewReader(pkt))
+	require.NotEmpty(t, err)
+	require.NotErrorIs(t, err, sniff.ErrNeedMoreData)
+}
@@ -0,0 +1,82 @@
+package sniff
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+const (
+	openVPNHardResetClientV2 = 7
+	openVPNHardResetClientV3 = 10
+	openVPNMaxPacketSize     = 1600
+)
+
+func OpenVPN(_ context.Context, metadata *adapter.InboundContext, packet []byte) error {
+	if !isOpenVPNHardResetClient(packet) {
+		return os.ErrInvalid
+	}
+	metadata.Protocol = C.ProtocolOpenVPN
+	return nil
+}
+
+func StreamOpenVPN(_ context.Context, metadata *adapter.InboundContext, reader io.Reader) error {
+	var length uint16
+	err := binary.Read(reader, binary.BigEndian, &length)
+	if err != nil {
+		return E.Cause1(ErrNeedMoreData, err)
+	}
+	if length == 0 || length > openVPNMaxPacketSize {
+		return os.ErrInvalid
+	}
+	packet := make([]byte, length)
+	n, err := io.ReadFull(reader, packet)
+	if n > 0 && packet[0]>>3 != openVPNHardResetClientV2 && packet[0]>>3 != openVPNHardResetClientV3 {
+		return os.ErrInvalid
+	}
+	if err != nil {
+		return E.Cause1(ErrNeedMoreData, err)
+	}
+	if !isOpenVPNHardResetClient(packet) {
+		return os.ErrInvalid
+	}
+	metadata.Protocol = C.ProtocolOpenVPN
+	return nil
+}
+
+// isOpenVPNHardResetClient checks the first packet sent by a client:
+// opcode and key id (1) + session id (8), followed by either the plain
+// ack array length (1) and message packet id (4), or, with tls-auth or
+// tls-crypt, the HMAC and a replay packet id of 1.
+func isOpenVPNHardResetClient(packet []byte) bool {
+	const headerSize = 1 + 8
+	if len(packet) < headerSize+5 || len(packet) > openVPNMaxPacketSize {
+		return false
+	}
+	opcode, keyID := packet[0]>>3, packet[0]&0x07
+	if opcode != openVPNHardResetClientV2 && opcode != openVPNHardResetClientV3 || keyID != 0 {
+		return false
+	}
+	if packet[headerSize] == 0 && binary.BigEndian.Uint32(packet[headerSize+1:]) == 0 {
+		return true
+	}
+	// tls-crypt puts the replay packet id right after the session id
+	if binary.BigEndian.Uint32(packet[headerSize:]) == 1 {
+		return true
+	}
+	for _, hmacSize := range []int{20, 32, 48, 64} {
+		offset := headerSize + hmacSize
+		if len(packet) < offset+4+4+1+4 {
+			break
+		}
+		if binary.BigEndian.Uint32(packet[offset:]) == 1 && packet[offset+8] == 0 {
+			return true
+		}
+	}
+	return false
+}
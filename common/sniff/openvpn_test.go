@@ -0,0 +1,64 @@
+package sniff_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSniffOpenVPN(t *testing.T) {
+	t.Parallel()
+	packet, err := hex.DecodeString("38a593feaed27248b70000000000")
+	require.NoError(t, err)
+	var metadata adapter.InboundContext
+	err = sniff.OpenVPN(context.Background(), &metadata, packet)
+	require.NoError(t, err)
+	require.Equal(t, C.ProtocolOpenVPN, metadata.Protocol)
+}
+
+func TestSniffOpenVPNTLSAuth(t *testing.T) {
+	t.Parallel()
+	packet, err := hex.DecodeString("3862e3ab5805f0765a2b9c1d7e0f37c44921bd3f6564eadf7f142a726600000001665c1a2b0000000000")
+	require.NoError(t, err)
+	var metadata adapter.InboundContext
+	err = sniff.OpenVPN(context.Background(), &metadata, packet)
+	require.NoError(t, err)
+	require.Equal(t, C.ProtocolOpenVPN, metadata.Protocol)
+}
+
+func TestSniffOpenVPNStream(t *testing.T) {
+	t.Parallel()
+	packet, err := hex.DecodeString("000e38a593feaed27248b70000000000")
+	require.NoError(t, err)
+	var metadata adapter.InboundContext
+	err = sniff.StreamOpenVPN(context.Background(), &metadata, bytes.NewReader(packet))
+	require.NoError(t, err)
+	require.Equal(t, C.ProtocolOpenVPN, metadata.Protocol)
+}
+
+func TestSniffIncompleteOpenVPNStream(t *testing.T) {
+	t.Parallel()
+	packet, err := hex.DecodeString("000e38a593feae")
+	require.NoError(t, err)
+	var metadata adapter.InboundContext
+	err = sniff.StreamOpenVPN(context.Background(), &metadata, bytes.NewReader(packet))
+	require.ErrorIs(t, err, sniff.ErrNeedMoreData)
+}
+
+func TestSniffNotOpenVPN(t *testing.T) {
+	t.Parallel()
+	// P_DATA_V2
+	packet, err := hex.DecodeString("48000001a593feaed27248b70123456789")
+	require.NoError(t, err)
+	var metadata adapter.InboundContext
+	err = sniff.OpenVPN(context.Background(), &metadata, packet)
+	require.ErrorIs(t, err, os.ErrInvalid)
+}
@@ -307,6 +307,11 @@ find:
 		return E.Cause1(ErrNeedMoreData, err)
 	}
 	metadata.Domain = fingerprint.ServerName
+	if keepClientHello(ctx) {
+		metadata.ClientHello = bytes.Clone(buffer.Bytes())
+		metadata.ClientHelloQUIC = true
+		metadata.JA3, metadata.JA4 = "", ""
+	}
 	for metadata.Client == "" {
 		if len(frameTypeList) == 1 {
 			metadata.Client = C.ClientFirefox
@@ -0,0 +1,67 @@
+package sniff
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+var rtspMethods = []string{
+	"OPTIONS", "DESCRIBE", "ANNOUNCE", "SETUP", "PLAY", "PAUSE", "RECORD",
+	"TEARDOWN", "GET_PARAMETER", "SET_PARAMETER", "REDIRECT",
+}
+
+func RTSP(_ context.Context, metadata *adapter.InboundContext, reader io.Reader) error {
+	bReader := bufio.NewReader(reader)
+	requestLine, err := bReader.ReadString('\n')
+	if err != nil {
+		if !isRTSPMethodPrefix(requestLine) {
+			return os.ErrInvalid
+		}
+		return E.Cause1(ErrNeedMoreData, err)
+	}
+	parts := strings.Fields(requestLine)
+	if len(parts) != 3 || !strings.HasPrefix(parts[2], "RTSP/") || !isRTSPMethod(parts[0]) {
+		return os.ErrInvalid
+	}
+	requestURL, err := url.Parse(parts[1])
+	if err != nil {
+		return os.ErrInvalid
+	}
+	switch requestURL.Scheme {
+	case "rtsp", "rtsps", "rtspu":
+	default:
+		if parts[1] != "*" {
+			return os.ErrInvalid
+		}
+	}
+	metadata.Protocol = C.ProtocolRTSP
+	metadata.Domain = requestURL.Hostname()
+	return nil
+}
+
+func isRTSPMethod(method string) bool {
+	for _, it := range rtspMethods {
+		if method == it {
+			return true
+		}
+	}
+	return false
+}
+
+func isRTSPMethodPrefix(line string) bool {
+	method, _, hasSpace := strings.Cut(line, " ")
+	for _, it := range rtspMethods {
+		if hasSpace && method == it || !hasSpace && strings.HasPrefix(it, method) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package sniff_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSniffRTSP(t *testing.T) {
+	t.Parallel()
+	pkt := "OPTIONS rtsp://camera.example.com:554/stream1 RTSP/1.0\r\nCSeq: 2\r\nUser-Agent: LibVLC/3.0.20 (LIVE555 Streaming Media v2016.11.28)\r\n\r\n"
+	var metadata adapter.InboundContext
+	err := sniff.RTSP(context.Background(), &metadata, strings.NewReader(pkt))
+	require.NoError(t, err)
+	require.Equal(t, C.ProtocolRTSP, metadata.Protocol)
+	require.Equal(t, "camera.example.com", metadata.Domain)
+}
+
+func TestSniffIncompleteRTSP(t *testing.T) {
+	t.Parallel()
+	pkt := "DESCRIBE rtsp://camera.exam"
+	var metadata adapter.InboundContext
+	err := sniff.RTSP(context.Background(), &metadata, strings.NewReader(pkt))
+	require.ErrorIs(t, err, sniff.ErrNeedMoreData)
+}
+
+func TestSniffNotRTSP(t *testing.T) {
+	t.Parallel()
+	pkt := "GET / HTTP/1.1\r\nHost: www.example.com\r\n\r\n"
+	var metadata adapter.InboundContext
+	err := sniff.RTSP(context.Background(), &metadata, strings.NewReader(pkt))
+	require.NotEmpty(t, err)
+	require.NotErrorIs(t, err, sniff.ErrNeedMoreData)
+}
@@ -0,0 +1,50 @@
+package sniff
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func SMB(_ context.Context, metadata *adapter.InboundContext, reader io.Reader) error {
+	// NetBIOS session message: type (1) + length (3)
+	// SMB2 header: protocol id (4) + structure size (2) + credit charge (2) +
+	// status (4) + command (2)
+	// SMB1 header: protocol id (4) + command (1)
+	var header [18]byte
+	n, err := io.ReadFull(reader, header[:])
+	if n > 0 && header[0] != 0x00 {
+		return os.ErrInvalid
+	}
+	if n >= 8 && string(header[5:8]) != "SMB" {
+		return os.ErrInvalid
+	}
+	if n >= 5 && header[4] != 0xfe && header[4] != 0xff {
+		return os.ErrInvalid
+	}
+	if err != nil {
+		return E.Cause1(ErrNeedMoreData, err)
+	}
+	sessionLength := uint32(header[1])<<16 | uint32(header[2])<<8 | uint32(header[3])
+	switch header[4] {
+	case 0xfe:
+		if sessionLength < 64 || binary.LittleEndian.Uint16(header[8:]) != 64 {
+			return os.ErrInvalid
+		}
+		if binary.LittleEndian.Uint16(header[16:]) != 0x0000 {
+			return os.ErrInvalid
+		}
+	case 0xff:
+		// SMB_COM_NEGOTIATE, sent by clients to negotiate SMB2 dialects
+		if sessionLength < 32 || header[8] != 0x72 {
+			return os.ErrInvalid
+		}
+	}
+	metadata.Protocol = C.ProtocolSMB
+	return nil
+}
@@ -0,0 +1,44 @@
+package sniff_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSniffSMB2(t *testing.T) {
+	t.Parallel()
+	pkt, err := hex.DecodeString("0000006efe534d4240000000000000000000000000000000000000000000000000000000fffe00000000000000000000000000000000000000000000000000000000000024000500010000007f0000008c47e223d16edd8c47b46afc5baee261000000000000000002021002000302031103")
+	require.NoError(t, err)
+	var metadata adapter.InboundContext
+	err = sniff.SMB(context.Background(), &metadata, bytes.NewReader(pkt))
+	require.NoError(t, err)
+	require.Equal(t, C.ProtocolSMB, metadata.Protocol)
+}
+
+func TestSniffIncompleteSMB2(t *testing.T) {
+	t.Parallel()
+	pkt, err := hex.DecodeString("0000006efe534d42400000")
+	require.NoError(t, err)
+	var metadata adapter.InboundContext
+	err = sniff.SMB(context.Background(), &metadata, bytes.NewReader(pkt))
+	require.ErrorIs(t, err, sniff.ErrNeedMoreData)
+}
+
+func TestSniffNotSMB2(t *testing.T) {
+	t.Parallel()
+	// SESSION_SETUP
+	pkt, err := hex.DecodeString("0000006efe534d4240000000000000000100000000000000000000000000000000000000fffe0000")
+	require.NoError(t, err)
+	var metadata adapter.InboundContext
+	err = sniff.SMB(context.Background(), &metadata, bytes.NewReader(pkt))
+	require.NotEmpty(t, err)
+	require.NotErrorIs(t, err, sniff.ErrNeedMoreData)
+}
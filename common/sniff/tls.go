@@ -1,8 +1,10 @@
 package sniff
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/binary"
 	"errors"
 	"io"
 
@@ -13,7 +15,14 @@ import (
 )
 
 func TLSClientHello(ctx context.Context, metadata *adapter.InboundContext, reader io.Reader) error {
-	var clientHello *tls.ClientHelloInfo
+	var (
+		clientHello *tls.ClientHelloInfo
+		content     *bytes.Buffer
+	)
+	if keepClientHello(ctx) {
+		content = new(bytes.Buffer)
+		reader = io.TeeReader(reader, content)
+	}
 	err := tls.Server(bufio.NewReadOnlyConn(reader), &tls.Config{
 		GetConfigForClient: func(argHello *tls.ClientHelloInfo) (*tls.Config, error) {
 			clientHello = argHello
@@ -23,6 +32,11 @@ func TLSClientHello(ctx context.Context, metadata *adapter.InboundContext, reade
 	if clientHello != nil {
 		metadata.Protocol = C.ProtocolTLS
 		metadata.Domain = clientHello.ServerName
+		if content != nil {
+			metadata.ClientHello = joinHandshakeRecords(content.Bytes())
+			metadata.ClientHelloQUIC = false
+			metadata.JA3, metadata.JA4 = "", ""
+		}
 		return nil
 	}
 	if errors.Is(err, io.ErrUnexpectedEOF) {
@@ -31,3 +45,25 @@ func TLSClientHello(ctx context.Context, metadata *adapter.InboundContext, reade
 		return err
 	}
 }
+
+// joinHandshakeRecords merges a client hello fragmented into multiple TLS
+// records into a single record.
+func joinHandshakeRecords(content []byte) []byte {
+	if len(content) < 5 {
+		return content
+	}
+	record := []byte{content[0], content[1], content[2], 0, 0}
+	for len(content) >= 5 && content[0] == 0x16 {
+		recordLen := int(binary.BigEndian.Uint16(content[3:5]))
+		if len(content) < 5+recordLen {
+			break
+		}
+		record = append(record, content[5:5+recordLen]...)
+		content = content[5+recordLen:]
+	}
+	if len(record) > 5+0xFFFF {
+		return nil
+	}
+	binary.BigEndian.PutUint16(record[3:5], uint16(len(record)-5))
+	return record
+}
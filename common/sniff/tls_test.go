@@ -0,0 +1,58 @@
+package sniff_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSniffTLSFingerprint(t *testing.T) {
+	t.Parallel()
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	go func() {
+		defer clientConn.Close()
+		tls.Client(clientConn, &tls.Config{
+			ServerName: "www.google.com",
+			NextProtos: []string{"h2", "http/1.1"},
+		}).Handshake()
+	}()
+	var (
+		metadata adapter.InboundContext
+		content  bytes.Buffer
+	)
+	buffer := make([]byte, 4096)
+	for {
+		n, err := serverConn.Read(buffer)
+		require.NoError(t, err)
+		content.Write(buffer[:n])
+		err = sniff.TLSClientHello(sniff.ContextWithClientHello(context.Background()), &metadata, bytes.NewReader(content.Bytes()))
+		if err == nil {
+			break
+		}
+		require.ErrorIs(t, err, sniff.ErrNeedMoreData)
+	}
+	require.Equal(t, C.ProtocolTLS, metadata.Protocol)
+	require.Equal(t, "www.google.com", metadata.Domain)
+	require.NotNil(t, metadata.ClientHello)
+	ja3, ja4 := sniff.ClientFingerprint(&metadata)
+	require.Len(t, ja3, 32)
+	require.Regexp(t, `^t13d\d{4}h2_[0-9a-f]{12}_[0-9a-f]{12}$`, ja4)
+	require.Nil(t, metadata.ClientHello)
+
+	var plainMetadata adapter.InboundContext
+	require.NoError(t, sniff.TLSClientHello(context.Background(), &plainMetadata, bytes.NewReader(content.Bytes())))
+	require.Equal(t, "www.google.com", plainMetadata.Domain)
+	require.Nil(t, plainMetadata.ClientHello)
+	ja3, ja4 = sniff.ClientFingerprint(&plainMetadata)
+	require.Empty(t, ja3)
+	require.Empty(t, ja4)
+}
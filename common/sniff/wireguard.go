@@ -0,0 +1,23 @@
+package sniff
+
+import (
+	"context"
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+)
+
+func WireGuard(_ context.Context, metadata *adapter.InboundContext, packet []byte) error {
+	// handshake initiation: type (1) + reserved (3) + sender (4) + ephemeral (32) +
+	// static (48) + timestamp (28) + mac1 (16) + mac2 (16)
+	const handshakeInitiationSize = 148
+	if len(packet) != handshakeInitiationSize {
+		return os.ErrInvalid
+	}
+	if packet[0] != 1 || packet[1] != 0 || packet[2] != 0 || packet[3] != 0 {
+		return os.ErrInvalid
+	}
+	metadata.Protocol = C.ProtocolWireGuard
+	return nil
+}
@@ -0,0 +1,34 @@
+package sniff_test
+
+import (
+	"context"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSniffWireGuard(t *testing.T) {
+	t.Parallel()
+	packet, err := hex.DecodeString("0100000052f22665a60c12d289185d950ee8813609166f6b113d178d6c0fd3901ff239a1a095f20f9395650cf9380b8edb224a6b248a1e924e8fd0ae2e1a9492a3305f188cb610900f9e347fae886dc6507795ec745c4c3fcb2eb2c73e14934c867ee057ba72499bfa121e836b2ac15726ee7d6b0af6ab13c38e92cae0d15057b159987f94cc7411d717f14579b2aa100fbbb34f")
+	require.NoError(t, err)
+	var metadata adapter.InboundContext
+	err = sniff.WireGuard(context.Background(), &metadata, packet)
+	require.NoError(t, err)
+	require.Equal(t, C.ProtocolWireGuard, metadata.Protocol)
+}
+
+func TestSniffNotWireGuard(t *testing.T) {
+	t.Parallel()
+	// handshake response
+	packet, err := hex.DecodeString("0200000052f22665a60c12d289185d950ee8813609166f6b113d178d6c0fd3901ff239a1a095f20f9395650cf9380b8edb224a6b248a1e924e8fd0ae2e1a9492a3305f188cb610900f9e347fae886dc6507795ec745c4c3fcb2eb2")
+	require.NoError(t, err)
+	var metadata adapter.InboundContext
+	err = sniff.WireGuard(context.Background(), &metadata, packet)
+	require.ErrorIs(t, err, os.ErrInvalid)
+}
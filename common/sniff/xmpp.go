@@ -0,0 +1,74 @@
+package sniff
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+const xmppStreamNamespace = "http://etherx.jabber.org/streams"
+
+func XMPP(_ context.Context, metadata *adapter.InboundContext, reader io.Reader) error {
+	bReader := bufio.NewReader(reader)
+	first, err := bReader.Peek(1)
+	if err != nil {
+		return E.Cause1(ErrNeedMoreData, err)
+	}
+	if first[0] != '<' {
+		return os.ErrInvalid
+	}
+	decoder := xml.NewDecoder(bReader)
+	for {
+		token, err := decoder.RawToken()
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return E.Cause1(ErrNeedMoreData, err)
+			}
+			var syntaxErr *xml.SyntaxError
+			if errors.As(err, &syntaxErr) && syntaxErr.Msg == "unexpected EOF" {
+				return E.Cause1(ErrNeedMoreData, err)
+			}
+			return os.ErrInvalid
+		}
+		switch element := token.(type) {
+		case xml.ProcInst, xml.Comment, xml.CharData:
+			continue
+		case xml.StartElement:
+			if element.Name.Local != "stream" {
+				return os.ErrInvalid
+			}
+			var (
+				streamNamespace string
+				domain          string
+			)
+			for _, attr := range element.Attr {
+				switch {
+				case attr.Name.Space == "xmlns" && attr.Name.Local == element.Name.Space:
+					streamNamespace = attr.Value
+				case attr.Name.Space == "" && attr.Name.Local == "xmlns" && element.Name.Space == "":
+					streamNamespace = attr.Value
+				case attr.Name.Space == "" && attr.Name.Local == "to":
+					domain = attr.Value
+				}
+			}
+			if streamNamespace != xmppStreamNamespace {
+				return os.ErrInvalid
+			}
+			metadata.Protocol = C.ProtocolXMPP
+			if M.IsDomainName(domain) {
+				metadata.Domain = domain
+			}
+			return nil
+		default:
+			return os.ErrInvalid
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package sniff_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSniffXMPP(t *testing.T) {
+	t.Parallel()
+	pkt := "<?xml version='1.0'?><stream:stream to='jabber.example.org' xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams' xml:lang='en' version='1.0'>"
+	var metadata adapter.InboundContext
+	err := sniff.XMPP(context.Background(), &metadata, strings.NewReader(pkt))
+	require.NoError(t, err)
+	require.Equal(t, C.ProtocolXMPP, metadata.Protocol)
+	require.Equal(t, "jabber.example.org", metadata.Domain)
+}
+
+func TestSniffIncompleteXMPP(t *testing.T) {
+	t.Parallel()
+	pkt := "<?xml version='1.0'?><stream:stream to='jabber.exa"
+	var metadata adapter.InboundContext
+	err := sniff.XMPP(context.Background(), &metadata, strings.NewReader(pkt))
+	require.ErrorIs(t, err, sniff.ErrNeedMoreData)
+}
+
+func TestSniffNotXMPP(t *testing.T) {
+	t.Parallel()
+	pkt := "<html><head><title>example</title></head></html>"
+	var metadata adapter.InboundContext
+	err := sniff.XMPP(context.Background(), &metadata, strings.NewReader(pkt))
+	require.NotEmpty(t, err)
+	require.NotErrorIs(t, err, sniff.ErrNeedMoreData)
+}
@@ -46,6 +46,9 @@ const (
 	ruleItemNetworkIsConstrained
 	ruleItemNetworkInterfaceAddress
 	ruleItemDefaultInterfaceAddress
+	ruleItemSourceIPASN
+	ruleItemIPASN
+	ruleItemTime
 	ruleItemFinal uint8 = 0xFF
 )
 
@@ -280,6 +283,12 @@ func readDefaultRule(reader varbin.Reader, recover bool) (rule option.DefaultHea
 				value = append(value, common.Ptr(badoption.Prefixable(prefix)))
 			}
 			rule.DefaultInterfaceAddress = value
+		case ruleItemSourceIPASN:
+			rule.SourceIPASN, err = readRuleItemUint32(reader)
+		case ruleItemIPASN:
+			rule.IPASN, err = readRuleItemUint32(reader)
+		case ruleItemTime:
+			rule.Time, err = readRuleItemTime(reader)
 		case ruleItemFinal:
 			err = binary.Read(reader, binary.BigEndian, &rule.Invert)
 			return
@@ -469,6 +478,33 @@ func writeDefaultRule(writer varbin.Writer, rule option.DefaultHeadlessRule, gen
 			}
 		}
 	}
+	if len(rule.SourceIPASN) > 0 {
+		if generateVersion < C.RuleSetVersion5 {
+			return E.New("`source_ip_asn` rule item is only supported in version 5 or later")
+		}
+		err = writeRuleItemUint32(writer, ruleItemSourceIPASN, rule.SourceIPASN)
+		if err != nil {
+			return err
+		}
+	}
+	if len(rule.IPASN) > 0 {
+		if generateVersion < C.RuleSetVersion5 {
+			return E.New("`ip_asn` rule item is only supported in version 5 or later")
+		}
+		err = writeRuleItemUint32(writer, ruleItemIPASN, rule.IPASN)
+		if err != nil {
+			return err
+		}
+	}
+	if rule.Time != nil {
+		if generateVersion < C.RuleSetVersion5 {
+			return E.New("`time` rule item is only supported in version 5 or later")
+		}
+		err = writeRuleItemTime(writer, rule.Time)
+		if err != nil {
+			return err
+		}
+	}
 	if len(rule.WIFISSID) > 0 {
 		err = writeRuleItemString(writer, ruleItemWIFISSID, rule.WIFISSID)
 		if err != nil {
@@ -531,7 +567,11 @@ func writeRuleItemString(writer varbin.Writer, itemType uint8, value []string) e
 	if err != nil {
 		return err
 	}
-	_, err = varbin.WriteUvarint(writer, uint64(len(value)))
+	return writeStringSlice(writer, value)
+}
+
+func writeStringSlice(writer varbin.Writer, value []string) error {
+	_, err := varbin.WriteUvarint(writer, uint64(len(value)))
 	if err != nil {
 		return err
 	}
@@ -548,6 +588,45 @@ func writeRuleItemString(writer varbin.Writer, itemType uint8, value []string) e
 	return nil
 }
 
+func readRuleItemTime(reader varbin.Reader) (*option.TimeRuleOptions, error) {
+	var (
+		options option.TimeRuleOptions
+		err     error
+	)
+	options.Weekday, err = readRuleItemString(reader)
+	if err != nil {
+		return nil, err
+	}
+	options.Range, err = readRuleItemString(reader)
+	if err != nil {
+		return nil, err
+	}
+	timezone, err := readRuleItemString(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(timezone) > 0 {
+		options.Timezone = timezone[0]
+	}
+	return &options, nil
+}
+
+func writeRuleItemTime(writer varbin.Writer, options *option.TimeRuleOptions) error {
+	err := writeRuleItemString(writer, ruleItemTime, options.Weekday)
+	if err != nil {
+		return err
+	}
+	err = writeStringSlice(writer, options.Range)
+	if err != nil {
+		return err
+	}
+	var timezone []string
+	if options.Timezone != "" {
+		timezone = []string{options.Timezone}
+	}
+	return writeStringSlice(writer, timezone)
+}
+
 func readRuleItemUint8[E ~uint8](reader varbin.Reader) ([]E, error) {
 	length, err := binary.ReadUvarint(reader)
 	if err != nil {
@@ -599,6 +678,31 @@ func writeRuleItemUint16(writer varbin.Writer, itemType uint8, value []uint16) e
 	return binary.Write(writer, binary.BigEndian, value)
 }
 
+func readRuleItemUint32(reader varbin.Reader) ([]uint32, error) {
+	length, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]uint32, length)
+	err = binary.Read(reader, binary.BigEndian, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func writeRuleItemUint32(writer varbin.Writer, itemType uint8, value []uint32) error {
+	err := writer.WriteByte(itemType)
+	if err != nil {
+		return err
+	}
+	_, err = varbin.WriteUvarint(writer, uint64(len(value)))
+	if err != nil {
+		return err
+	}
+	return binary.Write(writer, binary.BigEndian, value)
+}
+
 func writeRuleItemCIDR(writer varbin.Writer, itemType uint8, value []string) error {
 	var builder netipx.IPSetBuilder
 	for i, prefixString := range value {
@@ -0,0 +1,42 @@
+package srs
+
+import (
+	"bytes"
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersion5RuleItems(t *testing.T) {
+	t.Parallel()
+	ruleSet := option.PlainRuleSet{
+		Rules: []option.HeadlessRule{
+			{
+				Type: C.RuleTypeDefault,
+				DefaultOptions: option.DefaultHeadlessRule{
+					SourceIPASN: []uint32{64512},
+					IPASN:       []uint32{13335, 62041},
+					Time: &option.TimeRuleOptions{
+						Weekday:  []string{"mon", "fri"},
+						Range:    []string{"22:00-07:00"},
+						Timezone: "Asia/Shanghai",
+					},
+				},
+			},
+		},
+	}
+	var buffer bytes.Buffer
+	require.Error(t, Write(&buffer, ruleSet, C.RuleSetVersion4))
+	buffer.Reset()
+	require.NoError(t, Write(&buffer, ruleSet, C.RuleSetVersion5))
+	ruleSetCompat, err := Read(&buffer, true)
+	require.NoError(t, err)
+	require.Equal(t, uint8(C.RuleSetVersion5), ruleSetCompat.Version)
+	rule := ruleSetCompat.Options.Rules[0].DefaultOptions
+	require.Equal(t, []uint32{64512}, []uint32(rule.SourceIPASN))
+	require.Equal(t, []uint32{13335, 62041}, []uint32(rule.IPASN))
+	require.Equal(t, ruleSet.Rules[0].DefaultOptions.Time, rule.Time)
+}
@@ -28,6 +28,21 @@ const (
 	DNSTypeFakeIP      = "fakeip"
 	DNSTypeDHCP        = "dhcp"
 	DNSTypeTailscale   = "tailscale"
+	DNSTypeGroup       = "group"
+	DNSTypeDNSCrypt    = "dnscrypt"
+	DNSTypeODoH        = "odoh"
+)
+
+const (
+	DNSGroupModeRace       = "race"
+	DNSGroupModeFallback   = "fallback"
+	DNSGroupModeRoundRobin = "round-robin"
+)
+
+const (
+	HostsFormatHosts   = "hosts"
+	HostsFormatDomain  = "domain"
+	HostsFormatAdGuard = "adguard"
 )
 
 const (
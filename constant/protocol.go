@@ -11,6 +11,12 @@ const (
 	ProtocolSSH        = "ssh"
 	ProtocolRDP        = "rdp"
 	ProtocolNTP        = "ntp"
+	ProtocolWireGuard  = "wireguard"
+	ProtocolOpenVPN    = "openvpn"
+	ProtocolMQTT       = "mqtt"
+	ProtocolSMB        = "smb"
+	ProtocolXMPP       = "xmpp"
+	ProtocolRTSP       = "rtsp"
 )
 
 const (
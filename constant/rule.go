@@ -18,12 +18,24 @@ const (
 	RuleSetFormatBinary = "binary"
 )
 
+const (
+	RuleSetFormatClashDomain    = "clash-domain"
+	RuleSetFormatClashIPCIDR    = "clash-ipcidr"
+	RuleSetFormatClashClassical = "clash-classical"
+	RuleSetFormatSurge          = "surge"
+	RuleSetFormatQuantumult     = "quantumult"
+	RuleSetFormatDnsmasq        = "dnsmasq"
+	RuleSetFormatGeoSiteDat     = "geosite-dat"
+	RuleSetFormatGeoIPDat       = "geoip-dat"
+)
+
 const (
 	RuleSetVersion1 = 1 + iota
 	RuleSetVersion2
 	RuleSetVersion3
 	RuleSetVersion4
-	RuleSetVersionCurrent = RuleSetVersion4
+	RuleSetVersion5
+	RuleSetVersionCurrent = RuleSetVersion5
 )
 
 const (
@@ -35,9 +47,18 @@ const (
 	RuleActionTypeHijackDNS    = "hijack-dns"
 	RuleActionTypeSniff        = "sniff"
 	RuleActionTypeResolve      = "resolve"
+	RuleActionTypeLimit        = "limit"
+	RuleActionTypeHTTPRewrite  = "http-rewrite"
 	RuleActionTypePredefined   = "predefined"
 )
 
+const (
+	RuleActionLimitKeyConnection = "connection"
+	RuleActionLimitKeyUser       = "user"
+	RuleActionLimitKeySourceIP   = "source_ip"
+	RuleActionLimitKeyRule       = "rule"
+)
+
 const (
 	RuleActionRejectMethodDefault = "default"
 	RuleActionRejectMethodDrop    = "drop"
@@ -3,21 +3,25 @@ package constant
 import "time"
 
 const (
-	TCPKeepAliveInitial        = 5 * time.Minute
-	TCPKeepAliveInterval       = 75 * time.Second
-	TCPConnectTimeout          = 5 * time.Second
-	TCPTimeout                 = 15 * time.Second
-	ReadPayloadTimeout         = 300 * time.Millisecond
-	DNSTimeout                 = 10 * time.Second
-	UDPTimeout                 = 5 * time.Minute
-	ICMPTimeout                = 10 * time.Second
-	DefaultURLTestInterval     = 3 * time.Minute
-	DefaultURLTestIdleTimeout  = 30 * time.Minute
-	StartTimeout               = 10 * time.Second
-	StopTimeout                = 5 * time.Second
-	FatalStopTimeout           = 10 * time.Second
-	FakeIPMetadataSaveInterval = 10 * time.Second
-	TLSFragmentFallbackDelay   = 500 * time.Millisecond
+	TCPKeepAliveInitial           = 5 * time.Minute
+	TCPKeepAliveInterval          = 75 * time.Second
+	TCPConnectTimeout             = 5 * time.Second
+	TCPTimeout                    = 15 * time.Second
+	ReadPayloadTimeout            = 300 * time.Millisecond
+	DNSTimeout                    = 10 * time.Second
+	DNSStaleTTL                   = 24 * time.Hour
+	DNSStaleAnswerTimeout         = 1800 * time.Millisecond
+	DNSCacheSaveInterval          = 5 * time.Minute
+	UDPTimeout                    = 5 * time.Minute
+	ICMPTimeout                   = 10 * time.Second
+	DefaultURLTestInterval        = 3 * time.Minute
+	DefaultURLTestIdleTimeout     = 30 * time.Minute
+	StartTimeout                  = 10 * time.Second
+	StopTimeout                   = 5 * time.Second
+	FatalStopTimeout              = 10 * time.Second
+	FakeIPMetadataSaveInterval    = 10 * time.Second
+	ConnectionHistorySaveInterval = 10 * time.Second
+	TLSFragmentFallbackDelay      = 500 * time.Millisecond
 )
 
 var PortProtocols = map[uint16]string{
@@ -68,14 +68,10 @@ type OverrideOptions struct {
 	ExcludePackage []string
 }
 
-func (s *StartedService) newInstance(profileContent string, overrideOptions *OverrideOptions) (*Instance, error) {
-	ctx := service.ExtendContext(s.ctx)
-	service.MustRegister[deprecated.Manager](ctx, new(deprecatedManager))
-	ctx, cancel := context.WithCancel(include.Context(ctx))
+func (s *StartedService) prepareOptions(ctx context.Context, profileContent string, overrideOptions *OverrideOptions) (option.Options, error) {
 	options, err := parseConfig(ctx, profileContent)
 	if err != nil {
-		cancel()
-		return nil, err
+		return option.Options{}, err
 	}
 	if overrideOptions != nil {
 		for _, inbound := range options.Inbounds {
@@ -96,6 +92,18 @@ func (s *StartedService) newInstance(profileContent string, overrideOptions *Ove
 			})
 		}
 	}
+	return options, nil
+}
+
+func (s *StartedService) newInstance(profileContent string, overrideOptions *OverrideOptions) (*Instance, error) {
+	ctx := service.ExtendContext(s.ctx)
+	service.MustRegister[deprecated.Manager](ctx, new(deprecatedManager))
+	ctx, cancel := context.WithCancel(include.Context(ctx))
+	options, err := s.prepareOptions(ctx, profileContent, overrideOptions)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
 	urlTestHistoryStorage := urltest.NewHistoryStorage()
 	ctx = service.ContextWithPtr(ctx, urlTestHistoryStorage)
 	i := &Instance{
@@ -125,6 +133,14 @@ func (i *Instance) Start() error {
 	return i.instance.Start()
 }
 
+func (i *Instance) Reload(s *StartedService, profileContent string, overrideOptions *OverrideOptions) error {
+	options, err := s.prepareOptions(i.ctx, profileContent, overrideOptions)
+	if err != nil {
+		return err
+	}
+	return i.instance.Reload(options)
+}
+
 func (i *Instance) Close() error {
 	i.cancel()
 	i.urlTestHistoryStorage.Close()
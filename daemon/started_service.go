@@ -2,11 +2,13 @@ package daemon
 
 import (
 	"context"
+	"errors"
 	"os"
 	"runtime"
 	"sync"
 	"time"
 
+	"github.com/sagernet/sing-box"
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/urltest"
 	"github.com/sagernet/sing-box/experimental/clashapi"
@@ -174,6 +176,17 @@ func (s *StartedService) StartOrReloadService(profileContent string, options *Ov
 		return os.ErrInvalid
 	}
 	oldInstance := s.instance
+	if oldInstance != nil && s.serviceStatus.Status == ServiceStatus_STARTED {
+		err := oldInstance.Reload(s, profileContent, options)
+		if err == nil {
+			s.serviceAccess.Unlock()
+			runtime.GC()
+			return nil
+		}
+		if !errors.Is(err, box.ErrRestartRequired) {
+			log.Error(E.Cause(err, "reload service"), ", restarting")
+		}
+	}
 	if oldInstance != nil {
 		s.updateStatus(ServiceStatus_STOPPING)
 		s.serviceAccess.Unlock()
@@ -1034,6 +1047,70 @@ func (s *StartedService) GetStartedAt(ctx context.Context, empty *emptypb.Empty)
 	return &StartedAt{StartedAt: s.startedAt.UnixMilli()}, nil
 }
 
+func (s *StartedService) GetDNSQueryLogs(ctx context.Context, request *GetDNSQueryLogsRequest) (*DNSQueryLogs, error) {
+	queryLog, err := s.dnsQueryLog()
+	if err != nil {
+		return nil, err
+	}
+	entries := queryLog.Entries(adapter.DNSQueryLogFilter{
+		Domain: request.Domain,
+		Client: request.Client,
+		Limit:  int(request.Limit),
+	})
+	return &DNSQueryLogs{
+		Logs: common.Map(entries, func(it adapter.DNSQueryLogEntry) *DNSQueryLog {
+			return &DNSQueryLog{
+				Time:    it.Time.UnixMilli(),
+				Client:  it.Client,
+				Inbound: it.Inbound,
+				Domain:  it.Domain,
+				Type:    it.QueryType,
+				Rule:    it.Rule,
+				Server:  it.Server,
+				Rcode:   it.Rcode,
+				Answers: it.Answers,
+				Latency: it.Latency.Milliseconds(),
+				Cached:  it.Cached,
+				Blocked: it.Blocked,
+			}
+		}),
+	}, nil
+}
+
+func (s *StartedService) GetDNSStats(ctx context.Context, request *GetDNSStatsRequest) (*DNSStats, error) {
+	queryLog, err := s.dnsQueryLog()
+	if err != nil {
+		return nil, err
+	}
+	stats := queryLog.Stats(int(request.Top))
+	convertItem := func(it adapter.DNSQueryCount) *DNSStatsItem {
+		return &DNSStatsItem{Name: it.Name, Count: int64(it.Count)}
+	}
+	return &DNSStats{
+		Queries:    int64(stats.Queries),
+		Cached:     int64(stats.Cached),
+		Blocked:    int64(stats.Blocked),
+		TopDomains: common.Map(stats.TopDomains, convertItem),
+		TopBlocked: common.Map(stats.TopBlocked, convertItem),
+		Clients:    common.Map(stats.Clients, convertItem),
+	}, nil
+}
+
+func (s *StartedService) dnsQueryLog() (adapter.DNSQueryLog, error) {
+	s.serviceAccess.RLock()
+	if s.serviceStatus.Status != ServiceStatus_STARTED {
+		s.serviceAccess.RUnlock()
+		return nil, os.ErrInvalid
+	}
+	boxService := s.instance
+	s.serviceAccess.RUnlock()
+	queryLog := service.FromContext[adapter.DNSRouter](boxService.ctx).QueryLog()
+	if queryLog == nil {
+		return nil, E.New("DNS query log is not enabled")
+	}
+	return queryLog, nil
+}
+
 func (s *StartedService) mustEmbedUnimplementedStartedServiceServer() {
 }
 
@@ -1722,6 +1722,422 @@ func (x *StartedAt) GetStartedAt() int64 {
 	return 0
 }
 
+type GetDNSQueryLogsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Limit         int32                  `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	Domain        string                 `protobuf:"bytes,2,opt,name=domain,proto3" json:"domain,omitempty"`
+	Client        string                 `protobuf:"bytes,3,opt,name=client,proto3" json:"client,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDNSQueryLogsRequest) Reset() {
+	*x = GetDNSQueryLogsRequest{}
+	mi := &file_daemon_started_service_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDNSQueryLogsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDNSQueryLogsRequest) ProtoMessage() {}
+
+func (x *GetDNSQueryLogsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_daemon_started_service_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDNSQueryLogsRequest.ProtoReflect.Descriptor instead.
+func (*GetDNSQueryLogsRequest) Descriptor() ([]byte, []int) {
+	return file_daemon_started_service_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *GetDNSQueryLogsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *GetDNSQueryLogsRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *GetDNSQueryLogsRequest) GetClient() string {
+	if x != nil {
+		return x.Client
+	}
+	return ""
+}
+
+type DNSQueryLogs struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Logs          []*DNSQueryLog         `protobuf:"bytes,1,rep,name=logs,proto3" json:"logs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DNSQueryLogs) Reset() {
+	*x = DNSQueryLogs{}
+	mi := &file_daemon_started_service_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DNSQueryLogs) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DNSQueryLogs) ProtoMessage() {}
+
+func (x *DNSQueryLogs) ProtoReflect() protoreflect.Message {
+	mi := &file_daemon_started_service_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DNSQueryLogs.ProtoReflect.Descriptor instead.
+func (*DNSQueryLogs) Descriptor() ([]byte, []int) {
+	return file_daemon_started_service_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *DNSQueryLogs) GetLogs() []*DNSQueryLog {
+	if x != nil {
+		return x.Logs
+	}
+	return nil
+}
+
+type DNSQueryLog struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Time          int64                  `protobuf:"varint,1,opt,name=time,proto3" json:"time,omitempty"`
+	Client        string                 `protobuf:"bytes,2,opt,name=client,proto3" json:"client,omitempty"`
+	Inbound       string                 `protobuf:"bytes,3,opt,name=inbound,proto3" json:"inbound,omitempty"`
+	Domain        string                 `protobuf:"bytes,4,opt,name=domain,proto3" json:"domain,omitempty"`
+	Type          string                 `protobuf:"bytes,5,opt,name=type,proto3" json:"type,omitempty"`
+	Rule          string                 `protobuf:"bytes,6,opt,name=rule,proto3" json:"rule,omitempty"`
+	Server        string                 `protobuf:"bytes,7,opt,name=server,proto3" json:"server,omitempty"`
+	Rcode         string                 `protobuf:"bytes,8,opt,name=rcode,proto3" json:"rcode,omitempty"`
+	Answers       []string               `protobuf:"bytes,9,rep,name=answers,proto3" json:"answers,omitempty"`
+	Latency       int64                  `protobuf:"varint,10,opt,name=latency,proto3" json:"latency,omitempty"`
+	Cached        bool                   `protobuf:"varint,11,opt,name=cached,proto3" json:"cached,omitempty"`
+	Blocked       bool                   `protobuf:"varint,12,opt,name=blocked,proto3" json:"blocked,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DNSQueryLog) Reset() {
+	*x = DNSQueryLog{}
+	mi := &file_daemon_started_service_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DNSQueryLog) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DNSQueryLog) ProtoMessage() {}
+
+func (x *DNSQueryLog) ProtoReflect() protoreflect.Message {
+	mi := &file_daemon_started_service_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DNSQueryLog.ProtoReflect.Descriptor instead.
+func (*DNSQueryLog) Descriptor() ([]byte, []int) {
+	return file_daemon_started_service_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *DNSQueryLog) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *DNSQueryLog) GetClient() string {
+	if x != nil {
+		return x.Client
+	}
+	return ""
+}
+
+func (x *DNSQueryLog) GetInbound() string {
+	if x != nil {
+		return x.Inbound
+	}
+	return ""
+}
+
+func (x *DNSQueryLog) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *DNSQueryLog) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *DNSQueryLog) GetRule() string {
+	if x != nil {
+		return x.Rule
+	}
+	return ""
+}
+
+func (x *DNSQueryLog) GetServer() string {
+	if x != nil {
+		return x.Server
+	}
+	return ""
+}
+
+func (x *DNSQueryLog) GetRcode() string {
+	if x != nil {
+		return x.Rcode
+	}
+	return ""
+}
+
+func (x *DNSQueryLog) GetAnswers() []string {
+	if x != nil {
+		return x.Answers
+	}
+	return nil
+}
+
+func (x *DNSQueryLog) GetLatency() int64 {
+	if x != nil {
+		return x.Latency
+	}
+	return 0
+}
+
+func (x *DNSQueryLog) GetCached() bool {
+	if x != nil {
+		return x.Cached
+	}
+	return false
+}
+
+func (x *DNSQueryLog) GetBlocked() bool {
+	if x != nil {
+		return x.Blocked
+	}
+	return false
+}
+
+type GetDNSStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Top           int32                  `protobuf:"varint,1,opt,name=top,proto3" json:"top,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDNSStatsRequest) Reset() {
+	*x = GetDNSStatsRequest{}
+	mi := &file_daemon_started_service_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDNSStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDNSStatsRequest) ProtoMessage() {}
+
+func (x *GetDNSStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_daemon_started_service_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDNSStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetDNSStatsRequest) Descriptor() ([]byte, []int) {
+	return file_daemon_started_service_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *GetDNSStatsRequest) GetTop() int32 {
+	if x != nil {
+		return x.Top
+	}
+	return 0
+}
+
+type DNSStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Queries       int64                  `protobuf:"varint,1,opt,name=queries,proto3" json:"queries,omitempty"`
+	Cached        int64                  `protobuf:"varint,2,opt,name=cached,proto3" json:"cached,omitempty"`
+	Blocked       int64                  `protobuf:"varint,3,opt,name=blocked,proto3" json:"blocked,omitempty"`
+	TopDomains    []*DNSStatsItem        `protobuf:"bytes,4,rep,name=topDomains,proto3" json:"topDomains,omitempty"`
+	TopBlocked    []*DNSStatsItem        `protobuf:"bytes,5,rep,name=topBlocked,proto3" json:"topBlocked,omitempty"`
+	Clients       []*DNSStatsItem        `protobuf:"bytes,6,rep,name=clients,proto3" json:"clients,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DNSStats) Reset() {
+	*x = DNSStats{}
+	mi := &file_daemon_started_service_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DNSStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DNSStats) ProtoMessage() {}
+
+func (x *DNSStats) ProtoReflect() protoreflect.Message {
+	mi := &file_daemon_started_service_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DNSStats.ProtoReflect.Descriptor instead.
+func (*DNSStats) Descriptor() ([]byte, []int) {
+	return file_daemon_started_service_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *DNSStats) GetQueries() int64 {
+	if x != nil {
+		return x.Queries
+	}
+	return 0
+}
+
+func (x *DNSStats) GetCached() int64 {
+	if x != nil {
+		return x.Cached
+	}
+	return 0
+}
+
+func (x *DNSStats) GetBlocked() int64 {
+	if x != nil {
+		return x.Blocked
+	}
+	return 0
+}
+
+func (x *DNSStats) GetTopDomains() []*DNSStatsItem {
+	if x != nil {
+		return x.TopDomains
+	}
+	return nil
+}
+
+func (x *DNSStats) GetTopBlocked() []*DNSStatsItem {
+	if x != nil {
+		return x.TopBlocked
+	}
+	return nil
+}
+
+func (x *DNSStats) GetClients() []*DNSStatsItem {
+	if x != nil {
+		return x.Clients
+	}
+	return nil
+}
+
+type DNSStatsItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Count         int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DNSStatsItem) Reset() {
+	*x = DNSStatsItem{}
+	mi := &file_daemon_started_service_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DNSStatsItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DNSStatsItem) ProtoMessage() {}
+
+func (x *DNSStatsItem) ProtoReflect() protoreflect.Message {
+	mi := &file_daemon_started_service_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DNSStatsItem.ProtoReflect.Descriptor instead.
+func (*DNSStatsItem) Descriptor() ([]byte, []int) {
+	return file_daemon_started_service_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *DNSStatsItem) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DNSStatsItem) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
 type Log_Message struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Level         LogLevel               `protobuf:"varint,1,opt,name=level,proto3,enum=daemon.LogLevel" json:"level,omitempty"`
@@ -1732,7 +2148,7 @@ type Log_Message struct {
 
 func (x *Log_Message) Reset() {
 	*x = Log_Message{}
-	mi := &file_daemon_started_service_proto_msgTypes[25]
+	mi := &file_daemon_started_service_proto_msgTypes[31]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1744,7 +2160,7 @@ func (x *Log_Message) String() string {
 func (*Log_Message) ProtoMessage() {}
 
 func (x *Log_Message) ProtoReflect() protoreflect.Message {
-	mi := &file_daemon_started_service_proto_msgTypes[25]
+	mi := &file_daemon_started_service_proto_msgTypes[31]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1900,7 +2316,43 @@ const file_daemon_started_service_proto_rawDesc = "" +
 	"\timpending\x18\x02 \x01(\bR\timpending\x12$\n" +
 	"\rmigrationLink\x18\x03 \x01(\tR\rmigrationLink\")\n" +
 	"\tStartedAt\x12\x1c\n" +
-	"\tstartedAt\x18\x01 \x01(\x03R\tstartedAt*U\n" +
+	"\tstartedAt\x18\x01 \x01(\x03R\tstartedAt\"^\n" +
+	"\x16GetDNSQueryLogsRequest\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06domain\x18\x02 \x01(\tR\x06domain\x12\x16\n" +
+	"\x06client\x18\x03 \x01(\tR\x06client\"7\n" +
+	"\fDNSQueryLogs\x12'\n" +
+	"\x04logs\x18\x01 \x03(\v2\x13.daemon.DNSQueryLogR\x04logs\"\xa7\x02\n" +
+	"\vDNSQueryLog\x12\x12\n" +
+	"\x04time\x18\x01 \x01(\x03R\x04time\x12\x16\n" +
+	"\x06client\x18\x02 \x01(\tR\x06client\x12\x18\n" +
+	"\ainbound\x18\x03 \x01(\tR\ainbound\x12\x16\n" +
+	"\x06domain\x18\x04 \x01(\tR\x06domain\x12\x12\n" +
+	"\x04type\x18\x05 \x01(\tR\x04type\x12\x12\n" +
+	"\x04rule\x18\x06 \x01(\tR\x04rule\x12\x16\n" +
+	"\x06server\x18\a \x01(\tR\x06server\x12\x14\n" +
+	"\x05rcode\x18\b \x01(\tR\x05rcode\x12\x18\n" +
+	"\aanswers\x18\t \x03(\tR\aanswers\x12\x18\n" +
+	"\alatency\x18\n" +
+	" \x01(\x03R\alatency\x12\x16\n" +
+	"\x06cached\x18\v \x01(\bR\x06cached\x12\x18\n" +
+	"\ablocked\x18\f \x01(\bR\ablocked\"&\n" +
+	"\x12GetDNSStatsRequest\x12\x10\n" +
+	"\x03top\x18\x01 \x01(\x05R\x03top\"\xf2\x01\n" +
+	"\bDNSStats\x12\x18\n" +
+	"\aqueries\x18\x01 \x01(\x03R\aqueries\x12\x16\n" +
+	"\x06cached\x18\x02 \x01(\x03R\x06cached\x12\x18\n" +
+	"\ablocked\x18\x03 \x01(\x03R\ablocked\x124\n" +
+	"\n" +
+	"topDomains\x18\x04 \x03(\v2\x14.daemon.DNSStatsItemR\n" +
+	"topDomains\x124\n" +
+	"\n" +
+	"topBlocked\x18\x05 \x03(\v2\x14.daemon.DNSStatsItemR\n" +
+	"topBlocked\x12.\n" +
+	"\aclients\x18\x06 \x03(\v2\x14.daemon.DNSStatsItemR\aclients\"8\n" +
+	"\fDNSStatsItem\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count*U\n" +
 	"\bLogLevel\x12\t\n" +
 	"\x05PANIC\x10\x00\x12\t\n" +
 	"\x05FATAL\x10\x01\x12\t\n" +
@@ -1912,7 +2364,7 @@ const file_daemon_started_service_proto_rawDesc = "" +
 	"\x13ConnectionEventType\x12\x18\n" +
 	"\x14CONNECTION_EVENT_NEW\x10\x00\x12\x1b\n" +
 	"\x17CONNECTION_EVENT_UPDATE\x10\x01\x12\x1b\n" +
-	"\x17CONNECTION_EVENT_CLOSED\x10\x022\xe5\v\n" +
+	"\x17CONNECTION_EVENT_CLOSED\x10\x022\xef\f\n" +
 	"\x0eStartedService\x12=\n" +
 	"\vStopService\x12\x16.google.protobuf.Empty\x1a\x16.google.protobuf.Empty\x12?\n" +
 	"\rReloadService\x12\x16.google.protobuf.Empty\x1a\x16.google.protobuf.Empty\x12K\n" +
@@ -1934,7 +2386,9 @@ const file_daemon_started_service_proto_rawDesc = "" +
 	"\x0fCloseConnection\x12\x1e.daemon.CloseConnectionRequest\x1a\x16.google.protobuf.Empty\"\x00\x12G\n" +
 	"\x13CloseAllConnections\x12\x16.google.protobuf.Empty\x1a\x16.google.protobuf.Empty\"\x00\x12M\n" +
 	"\x15GetDeprecatedWarnings\x12\x16.google.protobuf.Empty\x1a\x1a.daemon.DeprecatedWarnings\"\x00\x12;\n" +
-	"\fGetStartedAt\x12\x16.google.protobuf.Empty\x1a\x11.daemon.StartedAt\"\x00B%Z#github.com/sagernet/sing-box/daemonb\x06proto3"
+	"\fGetStartedAt\x12\x16.google.protobuf.Empty\x1a\x11.daemon.StartedAt\"\x00\x12I\n" +
+	"\x0fGetDNSQueryLogs\x12\x1e.daemon.GetDNSQueryLogsRequest\x1a\x14.daemon.DNSQueryLogs\"\x00\x12=\n" +
+	"\vGetDNSStats\x12\x1a.daemon.GetDNSStatsRequest\x1a\x10.daemon.DNSStats\"\x00B%Z#github.com/sagernet/sing-box/daemonb\x06proto3"
 
 var (
 	file_daemon_started_service_proto_rawDescOnce sync.Once
@@ -1950,7 +2404,7 @@ func file_daemon_started_service_proto_rawDescGZIP() []byte {
 
 var (
 	file_daemon_started_service_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
-	file_daemon_started_service_proto_msgTypes  = make([]protoimpl.MessageInfo, 26)
+	file_daemon_started_service_proto_msgTypes  = make([]protoimpl.MessageInfo, 32)
 	file_daemon_started_service_proto_goTypes   = []any{
 		(LogLevel)(0),                        // 0: daemon.LogLevel
 		(ConnectionEventType)(0),             // 1: daemon.ConnectionEventType
@@ -1980,14 +2434,19 @@ var (
 		(*DeprecatedWarnings)(nil),           // 25: daemon.DeprecatedWarnings
 		(*DeprecatedWarning)(nil),            // 26: daemon.DeprecatedWarning
 		(*StartedAt)(nil),                    // 27: daemon.StartedAt
-		(*Log_Message)(nil),                  // 28: daemon.Log.Message
-		(*emptypb.Empty)(nil),                // 29: google.protobuf.Empty
+		(*GetDNSQueryLogsRequest)(nil),       // 28: daemon.GetDNSQueryLogsRequest
+		(*DNSQueryLogs)(nil),                 // 29: daemon.DNSQueryLogs
+		(*DNSQueryLog)(nil),                  // 30: daemon.DNSQueryLog
+		(*GetDNSStatsRequest)(nil),           // 31: daemon.GetDNSStatsRequest
+		(*DNSStats)(nil),                     // 32: daemon.DNSStats
+		(*DNSStatsItem)(nil),                 // 33: daemon.DNSStatsItem
+		(*Log_Message)(nil),                  // 34: daemon.Log.Message
+		(*emptypb.Empty)(nil),                // 35: google.protobuf.Empty
 	}
 )
-
 var file_daemon_started_service_proto_depIdxs = []int32{
 	2,  // 0: daemon.ServiceStatus.status:type_name -> daemon.ServiceStatus.Type
-	28, // 1: daemon.Log.messages:type_name -> daemon.Log.Message
+	34, // 1: daemon.Log.messages:type_name -> daemon.Log.Message
 	0,  // 2: daemon.DefaultLogLevel.level:type_name -> daemon.LogLevel
 	10, // 3: daemon.Groups.group:type_name -> daemon.Group
 	11, // 4: daemon.Group.items:type_name -> daemon.GroupItem
@@ -1996,54 +2455,62 @@ var file_daemon_started_service_proto_depIdxs = []int32{
 	20, // 7: daemon.ConnectionEvents.events:type_name -> daemon.ConnectionEvent
 	23, // 8: daemon.Connection.processInfo:type_name -> daemon.ProcessInfo
 	26, // 9: daemon.DeprecatedWarnings.warnings:type_name -> daemon.DeprecatedWarning
-	0,  // 10: daemon.Log.Message.level:type_name -> daemon.LogLevel
-	29, // 11: daemon.StartedService.StopService:input_type -> google.protobuf.Empty
-	29, // 12: daemon.StartedService.ReloadService:input_type -> google.protobuf.Empty
-	29, // 13: daemon.StartedService.SubscribeServiceStatus:input_type -> google.protobuf.Empty
-	29, // 14: daemon.StartedService.SubscribeLog:input_type -> google.protobuf.Empty
-	29, // 15: daemon.StartedService.GetDefaultLogLevel:input_type -> google.protobuf.Empty
-	29, // 16: daemon.StartedService.ClearLogs:input_type -> google.protobuf.Empty
-	5,  // 17: daemon.StartedService.SubscribeStatus:input_type -> daemon.SubscribeStatusRequest
-	29, // 18: daemon.StartedService.SubscribeGroups:input_type -> google.protobuf.Empty
-	29, // 19: daemon.StartedService.GetClashModeStatus:input_type -> google.protobuf.Empty
-	29, // 20: daemon.StartedService.SubscribeClashMode:input_type -> google.protobuf.Empty
-	15, // 21: daemon.StartedService.SetClashMode:input_type -> daemon.ClashMode
-	12, // 22: daemon.StartedService.URLTest:input_type -> daemon.URLTestRequest
-	13, // 23: daemon.StartedService.SelectOutbound:input_type -> daemon.SelectOutboundRequest
-	14, // 24: daemon.StartedService.SetGroupExpand:input_type -> daemon.SetGroupExpandRequest
-	29, // 25: daemon.StartedService.GetSystemProxyStatus:input_type -> google.protobuf.Empty
-	18, // 26: daemon.StartedService.SetSystemProxyEnabled:input_type -> daemon.SetSystemProxyEnabledRequest
-	19, // 27: daemon.StartedService.SubscribeConnections:input_type -> daemon.SubscribeConnectionsRequest
-	24, // 28: daemon.StartedService.CloseConnection:input_type -> daemon.CloseConnectionRequest
-	29, // 29: daemon.StartedService.CloseAllConnections:input_type -> google.protobuf.Empty
-	29, // 30: daemon.StartedService.GetDeprecatedWarnings:input_type -> google.protobuf.Empty
-	29, // 31: daemon.StartedService.GetStartedAt:input_type -> google.protobuf.Empty
-	29, // 32: daemon.StartedService.StopService:output_type -> google.protobuf.Empty
-	29, // 33: daemon.StartedService.ReloadService:output_type -> google.protobuf.Empty
-	3,  // 34: daemon.StartedService.SubscribeServiceStatus:output_type -> daemon.ServiceStatus
-	6,  // 35: daemon.StartedService.SubscribeLog:output_type -> daemon.Log
-	7,  // 36: daemon.StartedService.GetDefaultLogLevel:output_type -> daemon.DefaultLogLevel
-	29, // 37: daemon.StartedService.ClearLogs:output_type -> google.protobuf.Empty
-	8,  // 38: daemon.StartedService.SubscribeStatus:output_type -> daemon.Status
-	9,  // 39: daemon.StartedService.SubscribeGroups:output_type -> daemon.Groups
-	16, // 40: daemon.StartedService.GetClashModeStatus:output_type -> daemon.ClashModeStatus
-	15, // 41: daemon.StartedService.SubscribeClashMode:output_type -> daemon.ClashMode
-	29, // 42: daemon.StartedService.SetClashMode:output_type -> google.protobuf.Empty
-	29, // 43: daemon.StartedService.URLTest:output_type -> google.protobuf.Empty
-	29, // 44: daemon.StartedService.SelectOutbound:output_type -> google.protobuf.Empty
-	29, // 45: daemon.StartedService.SetGroupExpand:output_type -> google.protobuf.Empty
-	17, // 46: daemon.StartedService.GetSystemProxyStatus:output_type -> daemon.SystemProxyStatus
-	29, // 47: daemon.StartedService.SetSystemProxyEnabled:output_type -> google.protobuf.Empty
-	21, // 48: daemon.StartedService.SubscribeConnections:output_type -> daemon.ConnectionEvents
-	29, // 49: daemon.StartedService.CloseConnection:output_type -> google.protobuf.Empty
-	29, // 50: daemon.StartedService.CloseAllConnections:output_type -> google.protobuf.Empty
-	25, // 51: daemon.StartedService.GetDeprecatedWarnings:output_type -> daemon.DeprecatedWarnings
-	27, // 52: daemon.StartedService.GetStartedAt:output_type -> daemon.StartedAt
-	32, // [32:53] is the sub-list for method output_type
-	11, // [11:32] is the sub-list for method input_type
-	11, // [11:11] is the sub-list for extension type_name
-	11, // [11:11] is the sub-list for extension extendee
-	0,  // [0:11] is the sub-list for field type_name
+	30, // 10: daemon.DNSQueryLogs.logs:type_name -> daemon.DNSQueryLog
+	33, // 11: daemon.DNSStats.topDomains:type_name -> daemon.DNSStatsItem
+	33, // 12: daemon.DNSStats.topBlocked:type_name -> daemon.DNSStatsItem
+	33, // 13: daemon.DNSStats.clients:type_name -> daemon.DNSStatsItem
+	0,  // 14: daemon.Log.Message.level:type_name -> daemon.LogLevel
+	35, // 15: daemon.StartedService.StopService:input_type -> google.protobuf.Empty
+	35, // 16: daemon.StartedService.ReloadService:input_type -> google.protobuf.Empty
+	35, // 17: daemon.StartedService.SubscribeServiceStatus:input_type -> google.protobuf.Empty
+	35, // 18: daemon.StartedService.SubscribeLog:input_type -> google.protobuf.Empty
+	35, // 19: daemon.StartedService.GetDefaultLogLevel:input_type -> google.protobuf.Empty
+	35, // 20: daemon.StartedService.ClearLogs:input_type -> google.protobuf.Empty
+	5,  // 21: daemon.StartedService.SubscribeStatus:input_type -> daemon.SubscribeStatusRequest
+	35, // 22: daemon.StartedService.SubscribeGroups:input_type -> google.protobuf.Empty
+	35, // 23: daemon.StartedService.GetClashModeStatus:input_type -> google.protobuf.Empty
+	35, // 24: daemon.StartedService.SubscribeClashMode:input_type -> google.protobuf.Empty
+	15, // 25: daemon.StartedService.SetClashMode:input_type -> daemon.ClashMode
+	12, // 26: daemon.StartedService.URLTest:input_type -> daemon.URLTestRequest
+	13, // 27: daemon.StartedService.SelectOutbound:input_type -> daemon.SelectOutboundRequest
+	14, // 28: daemon.StartedService.SetGroupExpand:input_type -> daemon.SetGroupExpandRequest
+	35, // 29: daemon.StartedService.GetSystemProxyStatus:input_type -> google.protobuf.Empty
+	18, // 30: daemon.StartedService.SetSystemProxyEnabled:input_type -> daemon.SetSystemProxyEnabledRequest
+	19, // 31: daemon.StartedService.SubscribeConnections:input_type -> daemon.SubscribeConnectionsRequest
+	24, // 32: daemon.StartedService.CloseConnection:input_type -> daemon.CloseConnectionRequest
+	35, // 33: daemon.StartedService.CloseAllConnections:input_type -> google.protobuf.Empty
+	35, // 34: daemon.StartedService.GetDeprecatedWarnings:input_type -> google.protobuf.Empty
+	35, // 35: daemon.StartedService.GetStartedAt:input_type -> google.protobuf.Empty
+	28, // 36: daemon.StartedService.GetDNSQueryLogs:input_type -> daemon.GetDNSQueryLogsRequest
+	31, // 37: daemon.StartedService.GetDNSStats:input_type -> daemon.GetDNSStatsRequest
+	35, // 38: daemon.StartedService.StopService:output_type -> google.protobuf.Empty
+	35, // 39: daemon.StartedService.ReloadService:output_type -> google.protobuf.Empty
+	3,  // 40: daemon.StartedService.SubscribeServiceStatus:output_type -> daemon.ServiceStatus
+	6,  // 41: daemon.StartedService.SubscribeLog:output_type -> daemon.Log
+	7,  // 42: daemon.StartedService.GetDefaultLogLevel:output_type -> daemon.DefaultLogLevel
+	35, // 43: daemon.StartedService.ClearLogs:output_type -> google.protobuf.Empty
+	8,  // 44: daemon.StartedService.SubscribeStatus:output_type -> daemon.Status
+	9,  // 45: daemon.StartedService.SubscribeGroups:output_type -> daemon.Groups
+	16, // 46: daemon.StartedService.GetClashModeStatus:output_type -> daemon.ClashModeStatus
+	15, // 47: daemon.StartedService.SubscribeClashMode:output_type -> daemon.ClashMode
+	35, // 48: daemon.StartedService.SetClashMode:output_type -> google.protobuf.Empty
+	35, // 49: daemon.StartedService.URLTest:output_type -> google.protobuf.Empty
+	35, // 50: daemon.StartedService.SelectOutbound:output_type -> google.protobuf.Empty
+	35, // 51: daemon.StartedService.SetGroupExpand:output_type -> google.protobuf.Empty
+	17, // 52: daemon.StartedService.GetSystemProxyStatus:output_type -> daemon.SystemProxyStatus
+	35, // 53: daemon.StartedService.SetSystemProxyEnabled:output_type -> google.protobuf.Empty
+	21, // 54: daemon.StartedService.SubscribeConnections:output_type -> daemon.ConnectionEvents
+	35, // 55: daemon.StartedService.CloseConnection:output_type -> google.protobuf.Empty
+	35, // 56: daemon.StartedService.CloseAllConnections:output_type -> google.protobuf.Empty
+	25, // 57: daemon.StartedService.GetDeprecatedWarnings:output_type -> daemon.DeprecatedWarnings
+	27, // 58: daemon.StartedService.GetStartedAt:output_type -> daemon.StartedAt
+	29, // 59: daemon.StartedService.GetDNSQueryLogs:output_type -> daemon.DNSQueryLogs
+	32, // 60: daemon.StartedService.GetDNSStats:output_type -> daemon.DNSStats
+	38, // [38:61] is the sub-list for method output_type
+	15, // [15:38] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
 }
 
 func init() { file_daemon_started_service_proto_init() }
@@ -2057,7 +2524,7 @@ func file_daemon_started_service_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_daemon_started_service_proto_rawDesc), len(file_daemon_started_service_proto_rawDesc)),
 			NumEnums:      3,
-			NumMessages:   26,
+			NumMessages:   32,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
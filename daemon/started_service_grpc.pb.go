@@ -36,6 +36,8 @@ const (
 	StartedService_CloseAllConnections_FullMethodName    = "/daemon.StartedService/CloseAllConnections"
 	StartedService_GetDeprecatedWarnings_FullMethodName  = "/daemon.StartedService/GetDeprecatedWarnings"
 	StartedService_GetStartedAt_FullMethodName           = "/daemon.StartedService/GetStartedAt"
+	StartedService_GetDNSQueryLogs_FullMethodName        = "/daemon.StartedService/GetDNSQueryLogs"
+	StartedService_GetDNSStats_FullMethodName            = "/daemon.StartedService/GetDNSStats"
 )
 
 // StartedServiceClient is the client API for StartedService service.
@@ -63,6 +65,8 @@ type StartedServiceClient interface {
 	CloseAllConnections(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	GetDeprecatedWarnings(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*DeprecatedWarnings, error)
 	GetStartedAt(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*StartedAt, error)
+	GetDNSQueryLogs(ctx context.Context, in *GetDNSQueryLogsRequest, opts ...grpc.CallOption) (*DNSQueryLogs, error)
+	GetDNSStats(ctx context.Context, in *GetDNSStatsRequest, opts ...grpc.CallOption) (*DNSStats, error)
 }
 
 type startedServiceClient struct {
@@ -337,6 +341,26 @@ func (c *startedServiceClient) GetStartedAt(ctx context.Context, in *emptypb.Emp
 	return out, nil
 }
 
+func (c *startedServiceClient) GetDNSQueryLogs(ctx context.Context, in *GetDNSQueryLogsRequest, opts ...grpc.CallOption) (*DNSQueryLogs, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DNSQueryLogs)
+	err := c.cc.Invoke(ctx, StartedService_GetDNSQueryLogs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *startedServiceClient) GetDNSStats(ctx context.Context, in *GetDNSStatsRequest, opts ...grpc.CallOption) (*DNSStats, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DNSStats)
+	err := c.cc.Invoke(ctx, StartedService_GetDNSStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // StartedServiceServer is the server API for StartedService service.
 // All implementations must embed UnimplementedStartedServiceServer
 // for forward compatibility.
@@ -362,6 +386,8 @@ type StartedServiceServer interface {
 	CloseAllConnections(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
 	GetDeprecatedWarnings(context.Context, *emptypb.Empty) (*DeprecatedWarnings, error)
 	GetStartedAt(context.Context, *emptypb.Empty) (*StartedAt, error)
+	GetDNSQueryLogs(context.Context, *GetDNSQueryLogsRequest) (*DNSQueryLogs, error)
+	GetDNSStats(context.Context, *GetDNSStatsRequest) (*DNSStats, error)
 	mustEmbedUnimplementedStartedServiceServer()
 }
 
@@ -455,6 +481,14 @@ func (UnimplementedStartedServiceServer) GetDeprecatedWarnings(context.Context,
 func (UnimplementedStartedServiceServer) GetStartedAt(context.Context, *emptypb.Empty) (*StartedAt, error) {
 	return nil, status.Error(codes.Unimplemented, "method GetStartedAt not implemented")
 }
+
+func (UnimplementedStartedServiceServer) GetDNSQueryLogs(context.Context, *GetDNSQueryLogsRequest) (*DNSQueryLogs, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetDNSQueryLogs not implemented")
+}
+
+func (UnimplementedStartedServiceServer) GetDNSStats(context.Context, *GetDNSStatsRequest) (*DNSStats, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetDNSStats not implemented")
+}
 func (UnimplementedStartedServiceServer) mustEmbedUnimplementedStartedServiceServer() {}
 func (UnimplementedStartedServiceServer) testEmbeddedByValue()                        {}
 
@@ -812,6 +846,42 @@ func _StartedService_GetStartedAt_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StartedService_GetDNSQueryLogs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDNSQueryLogsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StartedServiceServer).GetDNSQueryLogs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StartedService_GetDNSQueryLogs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StartedServiceServer).GetDNSQueryLogs(ctx, req.(*GetDNSQueryLogsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StartedService_GetDNSStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDNSStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StartedServiceServer).GetDNSStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StartedService_GetDNSStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StartedServiceServer).GetDNSStats(ctx, req.(*GetDNSStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // StartedService_ServiceDesc is the grpc.ServiceDesc for StartedService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -879,6 +949,14 @@ var StartedService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetStartedAt",
 			Handler:    _StartedService_GetStartedAt_Handler,
 		},
+		{
+			MethodName: "GetDNSQueryLogs",
+			Handler:    _StartedService_GetDNSQueryLogs_Handler,
+		},
+		{
+			MethodName: "GetDNSStats",
+			Handler:    _StartedService_GetDNSStats_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
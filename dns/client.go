@@ -6,6 +6,8 @@ import (
 	"net"
 	"net/netip"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
@@ -31,18 +33,34 @@ var (
 
 var _ adapter.DNSClient = (*Client)(nil)
 
+// staleAnswerTTL is the TTL of stale answers, as recommended by RFC 8767.
+const staleAnswerTTL = 30
+
 type Client struct {
 	timeout            time.Duration
 	disableCache       bool
 	disableExpire      bool
 	independentCache   bool
+	serveStale         bool
+	staleTTL           time.Duration
+	staleAnswerTimeout time.Duration
+	optimistic         bool
+	prefetchHits       uint32
 	clientSubnet       netip.Prefix
+	dnssec             bool
+	dnssecLogOnly      bool
+	dnssecValidator    *dnssecValidator
+	nat64Access        sync.RWMutex
+	nat64Prefixes      []netip.Prefix
 	rdrc               adapter.RDRCStore
 	initRDRCFunc       func() adapter.RDRCStore
+	store              adapter.DNSCacheStore
+	initStoreFunc      func() adapter.DNSCacheStore
+	storeDone          chan struct{}
 	logger             logger.ContextLogger
-	cache              freelru.Cache[dns.Question, *dns.Msg]
+	cache              freelru.Cache[dns.Question, *cacheEntry]
 	cacheLock          compatible.Map[transportCacheKey, chan struct{}]
-	transportCache     freelru.Cache[transportCacheKey, *dns.Msg]
+	transportCache     freelru.Cache[transportCacheKey, *cacheEntry]
 	transportCacheLock compatible.Map[transportCacheKey, chan struct{}]
 }
 
@@ -52,30 +70,66 @@ type ClientOptions struct {
 	DisableExpire    bool
 	IndependentCache bool
 	CacheCapacity    uint32
-	ClientSubnet     netip.Prefix
-	RDRC             func() adapter.RDRCStore
-	Logger           logger.ContextLogger
+	// ServeStale keeps expired responses for StaleTTL, and answers with them
+	// if the upstream fails or does not respond in StaleAnswerTimeout.
+	ServeStale         bool
+	StaleTTL           time.Duration
+	StaleAnswerTimeout time.Duration
+	// Optimistic answers with expired responses immediately and refreshes
+	// them in background.
+	Optimistic bool
+	// PrefetchHits refreshes responses hit at least PrefetchHits times
+	// before they expire, zero disables prefetch.
+	PrefetchHits uint32
+	ClientSubnet netip.Prefix
+	// DNSSEC validates all responses, otherwise only queries with
+	// RequireDNSSEC are validated. Bogus responses are answered with
+	// SERVFAIL unless DNSSECLogOnly is set.
+	DNSSEC        bool
+	DNSSECLogOnly bool
+	// TrustAnchors are DS or DNSKEY records, the root KSKs are used if
+	// there is no trust anchor for the root zone.
+	TrustAnchors []dns.RR
+	RDRC         func() adapter.RDRCStore
+	// Store persists the cache on close and periodically.
+	Store  func() adapter.DNSCacheStore
+	Logger logger.ContextLogger
 }
 
 func NewClient(options ClientOptions) *Client {
 	client := &Client{
-		timeout:          options.Timeout,
-		disableCache:     options.DisableCache,
-		disableExpire:    options.DisableExpire,
-		independentCache: options.IndependentCache,
-		clientSubnet:     options.ClientSubnet,
-		initRDRCFunc:     options.RDRC,
-		logger:           options.Logger,
+		timeout:            options.Timeout,
+		disableCache:       options.DisableCache,
+		disableExpire:      options.DisableExpire,
+		independentCache:   options.IndependentCache,
+		serveStale:         options.ServeStale || options.Optimistic,
+		staleTTL:           options.StaleTTL,
+		staleAnswerTimeout: options.StaleAnswerTimeout,
+		optimistic:         options.Optimistic,
+		prefetchHits:       options.PrefetchHits,
+		clientSubnet:       options.ClientSubnet,
+		dnssec:             options.DNSSEC,
+		dnssecLogOnly:      options.DNSSECLogOnly,
+		initRDRCFunc:       options.RDRC,
+		initStoreFunc:      options.Store,
+		logger:             options.Logger,
 	}
 	if client.timeout == 0 {
 		client.timeout = C.DNSTimeout
 	}
+	if client.staleTTL == 0 {
+		client.staleTTL = C.DNSStaleTTL
+	}
+	if client.staleAnswerTimeout == 0 {
+		client.staleAnswerTimeout = C.DNSStaleAnswerTimeout
+	}
+	client.dnssecValidator = newDNSSECValidator(client.timeout, options.TrustAnchors)
 	cacheCapacity := max(options.CacheCapacity, 1024)
 	if !client.disableCache {
 		if !client.independentCache {
-			client.cache = common.Must1(freelru.NewSharded[dns.Question, *dns.Msg](cacheCapacity, maphash.NewHasher[dns.Question]().Hash32))
+			client.cache = common.Must1(freelru.NewSharded[dns.Question, *cacheEntry](cacheCapacity, maphash.NewHasher[dns.Question]().Hash32))
 		} else {
-			client.transportCache = common.Must1(freelru.NewSharded[transportCacheKey, *dns.Msg](cacheCapacity, maphash.NewHasher[transportCacheKey]().Hash32))
+			client.transportCache = common.Must1(freelru.NewSharded[transportCacheKey, *cacheEntry](cacheCapacity, maphash.NewHasher[transportCacheKey]().Hash32))
 		}
 	}
 	return client
@@ -86,10 +140,120 @@ type transportCacheKey struct {
 	transportTag string
 }
 
+type cacheEntry struct {
+	message    *dns.Msg
+	expireAt   time.Time
+	hits       atomic.Uint32
+	refreshing atomic.Bool
+}
+
+type refreshResult struct {
+	response *dns.Msg
+	err      error
+}
+
 func (c *Client) Start() {
 	if c.initRDRCFunc != nil {
 		c.rdrc = c.initRDRCFunc()
 	}
+	if c.initStoreFunc != nil && !c.disableCache {
+		c.store = c.initStoreFunc()
+		if c.store != nil {
+			c.loadCache()
+			c.storeDone = make(chan struct{})
+			go c.loopSaveCache()
+		}
+	}
+}
+
+func (c *Client) Close() error {
+	if c.store == nil {
+		return nil
+	}
+	close(c.storeDone)
+	return c.saveCache()
+}
+
+func (c *Client) loadCache() {
+	entries, err := c.store.LoadDNSCache()
+	if err != nil {
+		if c.logger != nil {
+			c.logger.Warn(E.Cause(err, "load DNS cache"))
+		}
+		return
+	}
+	timeNow := time.Now()
+	for _, savedEntry := range entries {
+		question := savedEntry.Message.Question[0]
+		entry := &cacheEntry{message: savedEntry.Message}
+		var lifetime time.Duration
+		if !c.disableExpire {
+			if savedEntry.ExpireAt.IsZero() {
+				continue
+			}
+			entry.expireAt = savedEntry.ExpireAt
+			lifetime = savedEntry.ExpireAt.Sub(timeNow)
+			if c.serveStale {
+				lifetime += c.staleTTL
+			}
+			if lifetime <= 0 {
+				continue
+			}
+		}
+		if !c.independentCache {
+			if lifetime > 0 {
+				c.cache.AddWithLifetime(question, entry, lifetime)
+			} else {
+				c.cache.Add(question, entry)
+			}
+		} else {
+			if savedEntry.Transport == "" {
+				continue
+			}
+			cacheKey := transportCacheKey{Question: question, transportTag: savedEntry.Transport}
+			if lifetime > 0 {
+				c.transportCache.AddWithLifetime(cacheKey, entry, lifetime)
+			} else {
+				c.transportCache.Add(cacheKey, entry)
+			}
+		}
+	}
+}
+
+func (c *Client) loopSaveCache() {
+	ticker := time.NewTicker(C.DNSCacheSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			err := c.saveCache()
+			if err != nil && c.logger != nil {
+				c.logger.Warn(E.Cause(err, "save DNS cache"))
+			}
+		case <-c.storeDone:
+			return
+		}
+	}
+}
+
+func (c *Client) saveCache() error {
+	var entries []adapter.DNSCacheEntry
+	if !c.independentCache {
+		for _, question := range c.cache.Keys() {
+			entry, loaded := c.cache.Peek(question)
+			if loaded {
+				entries = append(entries, adapter.DNSCacheEntry{ExpireAt: entry.expireAt, Message: entry.message})
+			}
+		}
+	} else {
+		for _, cacheKey := range c.transportCache.Keys() {
+			entry, loaded := c.transportCache.Peek(cacheKey)
+			if loaded {
+				entries = append(entries, adapter.DNSCacheEntry{Transport: cacheKey.transportTag, ExpireAt: entry.expireAt, Message: entry.message})
+			}
+		}
+	}
+	return c.store.SaveDNSCache(entries)
 }
 
 func extractNegativeTTL(response *dns.Msg) (uint32, bool) {
@@ -120,6 +284,9 @@ func (c *Client) Exchange(ctx context.Context, transport adapter.DNSTransport, m
 		}
 		return FixedResponseStatus(message, dns.RcodeSuccess), nil
 	}
+	if question.Qtype == dns.TypeAAAA && options.DNS64Prefix.IsValid() {
+		return c.exchangeDNS64(ctx, transport, message, options, responseChecker)
+	}
 	clientSubnet := options.ClientSubnet
 	if !clientSubnet.IsValid() {
 		clientSubnet = c.clientSubnet
@@ -136,7 +303,8 @@ func (c *Client) Exchange(ctx context.Context, transport adapter.DNSTransport, m
 			message.Extra[0].Header().Ttl == 0 &&
 			len(message.Extra[0].(*dns.OPT).Option) == 0) &&
 		!options.ClientSubnet.IsValid()
-	disableCache := !isSimpleRequest || c.disableCache || options.DisableCache
+	// cached responses may not be validated, so responses to queries requiring DNSSEC are neither loaded nor stored
+	disableCache := !isSimpleRequest || c.disableCache || options.DisableCache || options.RequireDNSSEC
 	if !disableCache {
 		cacheKey := transportCacheKey{Question: question, transportTag: transport.Tag()}
 		if c.cache != nil {
@@ -168,14 +336,71 @@ func (c *Client) Exchange(ctx context.Context, transport adapter.DNSTransport, m
 				}()
 			}
 		}
-		response, ttl := c.loadResponse(question, transport)
+		response, ttl, entry, stale := c.loadResponse(question, transport)
 		if response != nil {
-			logCachedResponse(c.logger, ctx, response, ttl)
+			entry.hits.Add(1)
 			response.Id = message.Id
+			if !stale {
+				markCached(ctx)
+				if c.needPrefetch(entry, ttl) {
+					c.refresh(ctx, transport, message, options, responseChecker, entry)
+				}
+				logCachedResponse(c.logger, ctx, response, ttl)
+				return response, nil
+			}
+			done := c.refresh(ctx, transport, message, options, responseChecker, entry)
+			if done != nil && !c.optimistic {
+				timer := time.NewTimer(c.staleAnswerTimeout)
+				select {
+				case result := <-done:
+					timer.Stop()
+					if result.err == nil {
+						return result.response, nil
+					}
+					if c.logger != nil {
+						c.logger.DebugContext(ctx, E.Cause(result.err, "refresh stale response"))
+					}
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, ctx.Err()
+				}
+			}
+			markCached(ctx)
+			logStaleResponse(c.logger, ctx, response)
 			return response, nil
 		}
 	}
+	return c.exchange(ctx, transport, message, options, responseChecker, disableCache)
+}
+
+// refresh exchanges the message in background to replace the cached entry.
+// It returns nil if the entry is already being refreshed.
+func (c *Client) refresh(ctx context.Context, transport adapter.DNSTransport, message *dns.Msg, options adapter.DNSQueryOptions, responseChecker func(responseAddrs []netip.Addr) bool, entry *cacheEntry) <-chan refreshResult {
+	if !entry.refreshing.CompareAndSwap(false, true) {
+		return nil
+	}
+	done := make(chan refreshResult, 1)
+	message = message.Copy()
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		response, err := c.exchange(ctx, transport, message, options, responseChecker, false)
+		entry.refreshing.Store(false)
+		done <- refreshResult{response, err}
+	}()
+	return done
+}
+
+func (c *Client) needPrefetch(entry *cacheEntry, ttl int) bool {
+	if c.prefetchHits == 0 || entry.expireAt.IsZero() || entry.hits.Load() < c.prefetchHits {
+		return false
+	}
+	originTTL := messageTTL(entry.message)
+	return originTTL > 0 && ttl*10 <= originTTL
+}
 
+func (c *Client) exchange(ctx context.Context, transport adapter.DNSTransport, message *dns.Msg, options adapter.DNSQueryOptions, responseChecker func(responseAddrs []netip.Addr) bool, disableCache bool) (*dns.Msg, error) {
+	question := message.Question[0]
 	messageId := message.Id
 	contextTransport, clientSubnetLoaded := transportTagFromContext(ctx)
 	if clientSubnetLoaded && transport.Tag() == contextTransport {
@@ -188,8 +413,14 @@ func (c *Client) Exchange(ctx context.Context, transport adapter.DNSTransport, m
 			return nil, ErrResponseRejectedCached
 		}
 	}
-	ctx, cancel := context.WithTimeout(ctx, c.timeout)
-	response, err := transport.Exchange(ctx, message)
+	validate := (c.dnssec || options.RequireDNSSEC) && isUpstreamTransport(transport)
+	exchangeMessage := message
+	requestDNSSECOK := isDNSSECOK(message)
+	if validate && !requestDNSSECOK {
+		exchangeMessage = setDNSSECOK(message)
+	}
+	exchangeCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	response, err := transport.Exchange(exchangeCtx, exchangeMessage)
 	cancel()
 	if err != nil {
 		var rcodeError RcodeError
@@ -234,6 +465,30 @@ func (c *Client) Exchange(ctx context.Context, transport adapter.DNSTransport, m
 			response.Answer = append(response.Answer, validResponse.Answer...)
 		}
 	}*/
+	if validate && (response.Rcode == dns.RcodeSuccess || response.Rcode == dns.RcodeNameError) {
+		secure, err := c.dnssecValidator.Validate(ctx, transport, question, response)
+		if err != nil {
+			err = E.Cause(err, "DNSSEC validation failed for ", FormatQuestion(question.String()))
+			if !c.dnssecLogOnly || options.RequireDNSSEC {
+				if c.logger != nil {
+					c.logger.ErrorContext(ctx, err)
+				}
+				return FixedResponseStatus(message, dns.RcodeServerFailure), nil
+			}
+			if c.logger != nil {
+				c.logger.WarnContext(ctx, err)
+			}
+		} else if !secure && options.RequireDNSSEC {
+			if c.logger != nil {
+				c.logger.ErrorContext(ctx, "DNSSEC required but insecure response for ", FormatQuestion(question.String()))
+			}
+			return FixedResponseStatus(message, dns.RcodeServerFailure), nil
+		}
+		response.AuthenticatedData = err == nil && secure
+		if !requestDNSSECOK {
+			stripDNSSEC(response, question.Qtype)
+		}
+	}
 	disableCache = disableCache || (response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError)
 	if responseChecker != nil {
 		var rejected bool
@@ -370,6 +625,12 @@ func (c *Client) ClearCache() {
 	} else if c.transportCache != nil {
 		c.transportCache.Purge()
 	}
+	if c.store != nil {
+		err := c.store.ClearDNSCache()
+		if err != nil && c.logger != nil {
+			c.logger.Warn(E.Cause(err, "clear saved DNS cache"))
+		}
+	}
 }
 
 func sortAddresses(response4 []netip.Addr, response6 []netip.Addr, strategy C.DomainStrategy) []netip.Addr {
@@ -384,23 +645,29 @@ func (c *Client) storeCache(transport adapter.DNSTransport, question dns.Questio
 	if timeToLive == 0 {
 		return
 	}
+	entry := &cacheEntry{message: message.Copy()}
 	if c.disableExpire {
 		if !c.independentCache {
-			c.cache.Add(question, message.Copy())
+			c.cache.Add(question, entry)
 		} else {
 			c.transportCache.Add(transportCacheKey{
 				Question:     question,
 				transportTag: transport.Tag(),
-			}, message.Copy())
+			}, entry)
 		}
 	} else {
+		lifetime := time.Second * time.Duration(timeToLive)
+		entry.expireAt = time.Now().Add(lifetime)
+		if c.serveStale {
+			lifetime += c.staleTTL
+		}
 		if !c.independentCache {
-			c.cache.AddWithLifetime(question, message.Copy(), time.Second*time.Duration(timeToLive))
+			c.cache.AddWithLifetime(question, entry, lifetime)
 		} else {
 			c.transportCache.AddWithLifetime(transportCacheKey{
 				Question:     question,
 				transportTag: transport.Tag(),
-			}, message.Copy(), time.Second*time.Duration(timeToLive))
+			}, entry, lifetime)
 		}
 	}
 }
@@ -411,7 +678,8 @@ func (c *Client) lookupToExchange(ctx context.Context, transport adapter.DNSTran
 		Qtype:  qType,
 		Qclass: dns.ClassINET,
 	}
-	disableCache := c.disableCache || options.DisableCache
+	// DNS64 responses are synthesized by Exchange
+	disableCache := c.disableCache || options.DisableCache || options.RequireDNSSEC || qType == dns.TypeAAAA && options.DNS64Prefix.IsValid()
 	if !disableCache {
 		cachedAddresses, err := c.questionCache(question, transport)
 		if err != ErrNotCached {
@@ -435,49 +703,43 @@ func (c *Client) lookupToExchange(ctx context.Context, transport adapter.DNSTran
 }
 
 func (c *Client) questionCache(question dns.Question, transport adapter.DNSTransport) ([]netip.Addr, error) {
-	response, _ := c.loadResponse(question, transport)
-	if response == nil {
+	response, ttl, entry, stale := c.loadResponse(question, transport)
+	if response == nil || stale || c.needPrefetch(entry, ttl) {
 		return nil, ErrNotCached
 	}
+	entry.hits.Add(1)
 	if response.Rcode != dns.RcodeSuccess {
 		return nil, RcodeError(response.Rcode)
 	}
 	return MessageToAddresses(response), nil
 }
 
-func (c *Client) loadResponse(question dns.Question, transport adapter.DNSTransport) (*dns.Msg, int) {
+// loadResponse returns a copy of the cached response with TTL decreased, and
+// the remaining TTL. Expired responses are returned only if serve-stale is
+// enabled, with stale set and TTL rewritten to staleAnswerTTL.
+func (c *Client) loadResponse(question dns.Question, transport adapter.DNSTransport) (*dns.Msg, int, *cacheEntry, bool) {
 	var (
-		response *dns.Msg
-		loaded   bool
+		entry  *cacheEntry
+		loaded bool
 	)
-	if c.disableExpire {
-		if !c.independentCache {
-			response, loaded = c.cache.Get(question)
-		} else {
-			response, loaded = c.transportCache.Get(transportCacheKey{
-				Question:     question,
-				transportTag: transport.Tag(),
-			})
-		}
-		if !loaded {
-			return nil, 0
-		}
-		return response.Copy(), 0
+	if !c.independentCache {
+		entry, loaded = c.cache.Get(question)
 	} else {
-		var expireAt time.Time
-		if !c.independentCache {
-			response, expireAt, loaded = c.cache.GetWithLifetime(question)
-		} else {
-			response, expireAt, loaded = c.transportCache.GetWithLifetime(transportCacheKey{
-				Question:     question,
-				transportTag: transport.Tag(),
-			})
-		}
-		if !loaded {
-			return nil, 0
-		}
-		timeNow := time.Now()
-		if timeNow.After(expireAt) {
+		entry, loaded = c.transportCache.Get(transportCacheKey{
+			Question:     question,
+			transportTag: transport.Tag(),
+		})
+	}
+	if !loaded {
+		return nil, 0, nil, false
+	}
+	response := entry.message.Copy()
+	if entry.expireAt.IsZero() {
+		return response, 0, entry, false
+	}
+	timeNow := time.Now()
+	if timeNow.After(entry.expireAt) {
+		if !c.serveStale {
 			if !c.independentCache {
 				c.cache.Remove(question)
 			} else {
@@ -486,42 +748,52 @@ func (c *Client) loadResponse(question dns.Question, transport adapter.DNSTransp
 					transportTag: transport.Tag(),
 				})
 			}
-			return nil, 0
+			return nil, 0, nil, false
 		}
-		var originTTL int
+		setMessageTTL(response, staleAnswerTTL)
+		return response, 0, entry, true
+	}
+	originTTL := messageTTL(response)
+	nowTTL := max(int(entry.expireAt.Sub(timeNow).Seconds()), 0)
+	if originTTL > 0 {
+		duration := uint32(originTTL - nowTTL)
 		for _, recordList := range [][]dns.RR{response.Answer, response.Ns, response.Extra} {
 			for _, record := range recordList {
 				if record.Header().Rrtype == dns.TypeOPT {
 					continue
 				}
-				if originTTL == 0 || record.Header().Ttl > 0 && int(record.Header().Ttl) < originTTL {
-					originTTL = int(record.Header().Ttl)
-				}
+				record.Header().Ttl = record.Header().Ttl - duration
 			}
 		}
-		nowTTL := max(int(expireAt.Sub(timeNow).Seconds()), 0)
-		response = response.Copy()
-		if originTTL > 0 {
-			duration := uint32(originTTL - nowTTL)
-			for _, recordList := range [][]dns.RR{response.Answer, response.Ns, response.Extra} {
-				for _, record := range recordList {
-					if record.Header().Rrtype == dns.TypeOPT {
-						continue
-					}
-					record.Header().Ttl = record.Header().Ttl - duration
-				}
+	} else {
+		setMessageTTL(response, uint32(nowTTL))
+	}
+	return response, nowTTL, entry, false
+}
+
+func messageTTL(message *dns.Msg) int {
+	var timeToLive int
+	for _, recordList := range [][]dns.RR{message.Answer, message.Ns, message.Extra} {
+		for _, record := range recordList {
+			if record.Header().Rrtype == dns.TypeOPT {
+				continue
 			}
-		} else {
-			for _, recordList := range [][]dns.RR{response.Answer, response.Ns, response.Extra} {
-				for _, record := range recordList {
-					if record.Header().Rrtype == dns.TypeOPT {
-						continue
-					}
-					record.Header().Ttl = uint32(nowTTL)
-				}
+			if timeToLive == 0 || record.Header().Ttl > 0 && int(record.Header().Ttl) < timeToLive {
+				timeToLive = int(record.Header().Ttl)
 			}
 		}
-		return response, nowTTL
+	}
+	return timeToLive
+}
+
+func setMessageTTL(message *dns.Msg, timeToLive uint32) {
+	for _, recordList := range [][]dns.RR{message.Answer, message.Ns, message.Extra} {
+		for _, record := range recordList {
+			if record.Header().Rrtype == dns.TypeOPT {
+				continue
+			}
+			record.Header().Ttl = timeToLive
+		}
 	}
 }
 
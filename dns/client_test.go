@@ -0,0 +1,224 @@
+package dns
+
+import (
+	"context"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+type testTransport struct {
+	TransportAdapter
+	address  atomic.Pointer[netip.Addr]
+	delay    time.Duration
+	requests atomic.Int32
+}
+
+func newTestTransport(address netip.Addr) *testTransport {
+	transport := &testTransport{TransportAdapter: NewTransportAdapter("test", "test", nil)}
+	transport.address.Store(&address)
+	return transport
+}
+
+func (t *testTransport) Start(stage adapter.StartStage) error {
+	return nil
+}
+
+func (t *testTransport) Close() error {
+	return nil
+}
+
+func (t *testTransport) Reset() {
+}
+
+func (t *testTransport) Exchange(ctx context.Context, message *dns.Msg) (*dns.Msg, error) {
+	t.requests.Add(1)
+	if t.delay > 0 {
+		time.Sleep(t.delay)
+	}
+	address := t.address.Load()
+	if address == nil {
+		return nil, E.New("upstream failed")
+	}
+	return FixedResponse(message.Id, message.Question[0], []netip.Addr{*address}, 60), nil
+}
+
+func testQuery() *dns.Msg {
+	message := new(dns.Msg)
+	message.SetQuestion("example.com.", dns.TypeA)
+	return message
+}
+
+func expireCache(client *Client) {
+	question := testQuery().Question[0]
+	entry, loaded := client.cache.Get(question)
+	if loaded {
+		entry.expireAt = time.Now().Add(-time.Second)
+	}
+}
+
+func TestClientServeStale(t *testing.T) {
+	t.Parallel()
+	oldAddress := netip.MustParseAddr("1.1.1.1")
+	transport := newTestTransport(oldAddress)
+	client := NewClient(ClientOptions{
+		ServeStale:         true,
+		StaleAnswerTimeout: 100 * time.Millisecond,
+	})
+	ctx := context.Background()
+	response, err := client.Exchange(ctx, transport, testQuery(), adapter.DNSQueryOptions{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []netip.Addr{oldAddress}, MessageToAddresses(response))
+	expireCache(client)
+	transport.address.Store(nil)
+	response, err = client.Exchange(ctx, transport, testQuery(), adapter.DNSQueryOptions{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []netip.Addr{oldAddress}, MessageToAddresses(response))
+	require.Equal(t, uint32(staleAnswerTTL), response.Answer[0].Header().Ttl)
+	newAddress := netip.MustParseAddr("2.2.2.2")
+	transport.address.Store(&newAddress)
+	response, err = client.Exchange(ctx, transport, testQuery(), adapter.DNSQueryOptions{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []netip.Addr{newAddress}, MessageToAddresses(response))
+}
+
+func TestClientServeStaleTimeout(t *testing.T) {
+	t.Parallel()
+	oldAddress := netip.MustParseAddr("1.1.1.1")
+	transport := newTestTransport(oldAddress)
+	client := NewClient(ClientOptions{
+		ServeStale:         true,
+		StaleAnswerTimeout: 50 * time.Millisecond,
+	})
+	ctx := context.Background()
+	_, err := client.Exchange(ctx, transport, testQuery(), adapter.DNSQueryOptions{}, nil)
+	require.NoError(t, err)
+	expireCache(client)
+	newAddress := netip.MustParseAddr("2.2.2.2")
+	transport.address.Store(&newAddress)
+	transport.delay = 200 * time.Millisecond
+	response, err := client.Exchange(ctx, transport, testQuery(), adapter.DNSQueryOptions{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []netip.Addr{oldAddress}, MessageToAddresses(response))
+	require.Eventually(t, func() bool {
+		response, _, _, stale := client.loadResponse(testQuery().Question[0], transport)
+		return !stale && response != nil && MessageToAddresses(response)[0] == newAddress
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestClientOptimistic(t *testing.T) {
+	t.Parallel()
+	oldAddress := netip.MustParseAddr("1.1.1.1")
+	transport := newTestTransport(oldAddress)
+	client := NewClient(ClientOptions{
+		Optimistic: true,
+	})
+	ctx := context.Background()
+	_, err := client.Exchange(ctx, transport, testQuery(), adapter.DNSQueryOptions{}, nil)
+	require.NoError(t, err)
+	expireCache(client)
+	newAddress := netip.MustParseAddr("2.2.2.2")
+	transport.address.Store(&newAddress)
+	response, err := client.Exchange(ctx, transport, testQuery(), adapter.DNSQueryOptions{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []netip.Addr{oldAddress}, MessageToAddresses(response))
+	require.Eventually(t, func() bool {
+		addresses, err := client.questionCache(testQuery().Question[0], transport)
+		return err == nil && addresses[0] == newAddress
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, int32(2), transport.requests.Load())
+}
+
+func TestClientExpireWithoutStale(t *testing.T) {
+	t.Parallel()
+	transport := newTestTransport(netip.MustParseAddr("1.1.1.1"))
+	client := NewClient(ClientOptions{})
+	ctx := context.Background()
+	_, err := client.Exchange(ctx, transport, testQuery(), adapter.DNSQueryOptions{}, nil)
+	require.NoError(t, err)
+	expireCache(client)
+	transport.address.Store(nil)
+	_, err = client.Exchange(ctx, transport, testQuery(), adapter.DNSQueryOptions{}, nil)
+	require.Error(t, err)
+}
+
+func TestClientPrefetch(t *testing.T) {
+	t.Parallel()
+	transport := newTestTransport(netip.MustParseAddr("1.1.1.1"))
+	client := NewClient(ClientOptions{
+		PrefetchHits: 2,
+	})
+	ctx := context.Background()
+	_, err := client.Exchange(ctx, transport, testQuery(), adapter.DNSQueryOptions{}, nil)
+	require.NoError(t, err)
+	entry, _ := client.cache.Get(testQuery().Question[0])
+	entry.expireAt = time.Now().Add(3 * time.Second)
+	for range 2 {
+		_, err = client.Exchange(ctx, transport, testQuery(), adapter.DNSQueryOptions{}, nil)
+		require.NoError(t, err)
+	}
+	require.Eventually(t, func() bool {
+		return transport.requests.Load() == 2
+	}, time.Second, 10*time.Millisecond)
+	_, ttl, _, _ := client.loadResponse(testQuery().Question[0], transport)
+	require.Greater(t, ttl, 50)
+}
+
+type testCacheStore struct {
+	entries []adapter.DNSCacheEntry
+}
+
+func (s *testCacheStore) LoadDNSCache() ([]adapter.DNSCacheEntry, error) {
+	return s.entries, nil
+}
+
+func (s *testCacheStore) SaveDNSCache(entries []adapter.DNSCacheEntry) error {
+	s.entries = entries
+	return nil
+}
+
+func (s *testCacheStore) ClearDNSCache() error {
+	s.entries = nil
+	return nil
+}
+
+func TestClientStore(t *testing.T) {
+	t.Parallel()
+	address := netip.MustParseAddr("1.1.1.1")
+	store := &testCacheStore{}
+	for _, independentCache := range []bool{false, true} {
+		transport := newTestTransport(address)
+		client := NewClient(ClientOptions{
+			IndependentCache: independentCache,
+			Store: func() adapter.DNSCacheStore {
+				return store
+			},
+		})
+		client.Start()
+		_, err := client.Exchange(context.Background(), transport, testQuery(), adapter.DNSQueryOptions{}, nil)
+		require.NoError(t, err)
+		require.NoError(t, client.Close())
+		require.Len(t, store.entries, 1)
+
+		client = NewClient(ClientOptions{
+			IndependentCache: independentCache,
+			Store: func() adapter.DNSCacheStore {
+				return store
+			},
+		})
+		client.Start()
+		addresses, err := client.questionCache(testQuery().Question[0], transport)
+		require.NoError(t, err)
+		require.Equal(t, []netip.Addr{address}, addresses)
+		client.ClearCache()
+		require.Empty(t, store.entries)
+		require.NoError(t, client.Close())
+	}
+}
@@ -0,0 +1,132 @@
+package dns
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/miekg/dns"
+)
+
+// exchangeDNS64 synthesizes AAAA records from A records if the name has no
+// AAAA records, as specified in RFC 6147.
+func (c *Client) exchangeDNS64(ctx context.Context, transport adapter.DNSTransport, message *dns.Msg, options adapter.DNSQueryOptions, responseChecker func(responseAddrs []netip.Addr) bool) (*dns.Msg, error) {
+	prefix := options.DNS64Prefix
+	options.DNS64Prefix = netip.Prefix{}
+	response, err := c.Exchange(ctx, transport, message, options, responseChecker)
+	if err != nil || response.Rcode != dns.RcodeSuccess || hasNativeAAAA(response) {
+		return response, err
+	}
+	exchangeMessage := message.Copy()
+	exchangeMessage.Question[0].Qtype = dns.TypeA
+	if options.Strategy == C.DomainStrategyIPv6Only {
+		options.Strategy = C.DomainStrategyAsIS
+	}
+	aResponse, err := c.Exchange(ctx, transport, exchangeMessage, options, nil)
+	if err != nil || aResponse.Rcode != dns.RcodeSuccess {
+		return response, nil
+	}
+	c.RegisterNAT64Prefix(prefix)
+	return synthesizeDNS64(response, aResponse, prefix), nil
+}
+
+// hasNativeAAAA reports whether the response has AAAA records outside the
+// IPv4-mapped range, which RFC 6147 excludes.
+func hasNativeAAAA(response *dns.Msg) bool {
+	for _, record := range response.Answer {
+		if aaaa, isAAAA := record.(*dns.AAAA); isAAAA {
+			address, _ := netip.AddrFromSlice(aaaa.AAAA)
+			if !address.Is4In6() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func synthesizeDNS64(response *dns.Msg, aResponse *dns.Msg, prefix netip.Prefix) *dns.Msg {
+	synthesized := response.Copy()
+	synthesized.Answer = nil
+	synthesized.AuthenticatedData = false
+	for _, record := range aResponse.Answer {
+		switch answer := record.(type) {
+		case *dns.CNAME:
+			synthesized.Answer = append(synthesized.Answer, dns.Copy(answer))
+		case *dns.A:
+			address, _ := netip.AddrFromSlice(answer.A.To4())
+			synthesized.Answer = append(synthesized.Answer, &dns.AAAA{
+				Hdr: dns.RR_Header{
+					Name:   answer.Hdr.Name,
+					Rrtype: dns.TypeAAAA,
+					Class:  answer.Hdr.Class,
+					Ttl:    answer.Hdr.Ttl,
+				},
+				AAAA: embedIPv4(prefix, address).AsSlice(),
+			})
+		}
+	}
+	if len(synthesized.Answer) > 0 {
+		synthesized.Ns = nil
+	}
+	return synthesized
+}
+
+func (c *Client) RegisterNAT64Prefix(prefix netip.Prefix) {
+	c.nat64Access.RLock()
+	registered := slices.Contains(c.nat64Prefixes, prefix)
+	c.nat64Access.RUnlock()
+	if registered {
+		return
+	}
+	c.nat64Access.Lock()
+	if !slices.Contains(c.nat64Prefixes, prefix) {
+		c.nat64Prefixes = append(c.nat64Prefixes, prefix)
+	}
+	c.nat64Access.Unlock()
+}
+
+func (c *Client) LookupNAT64(ip netip.Addr) (netip.Addr, bool) {
+	if !ip.Is6() || ip.Is4In6() {
+		return netip.Addr{}, false
+	}
+	c.nat64Access.RLock()
+	defer c.nat64Access.RUnlock()
+	for _, prefix := range c.nat64Prefixes {
+		if prefix.Contains(ip) {
+			return extractIPv4(prefix, ip), true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// embedIPv4 embeds address into prefix as specified in RFC 6052, where bits
+// 64 to 71 are reserved.
+func embedIPv4(prefix netip.Prefix, address netip.Addr) netip.Addr {
+	ip := prefix.Masked().Addr().As16()
+	offset := prefix.Bits() / 8
+	for _, b := range address.As4() {
+		if offset == 8 {
+			offset++
+		}
+		ip[offset] = b
+		offset++
+	}
+	return netip.AddrFrom16(ip)
+}
+
+func extractIPv4(prefix netip.Prefix, address netip.Addr) netip.Addr {
+	ip := address.As16()
+	var v4 [4]byte
+	offset := prefix.Bits() / 8
+	for i := range v4 {
+		if offset == 8 {
+			offset++
+		}
+		v4[i] = ip[offset]
+		offset++
+	}
+	return netip.AddrFrom4(v4)
+}
@@ -0,0 +1,92 @@
+package dns
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNS64Embed(t *testing.T) {
+	t.Parallel()
+	address := netip.MustParseAddr("192.0.2.33")
+	// RFC 6052 section 2.4
+	for prefix, expected := range map[string]string{
+		"2001:db8::/32":         "2001:db8:c000:221::",
+		"2001:db8:100::/40":     "2001:db8:1c0:2:21::",
+		"2001:db8:122::/48":     "2001:db8:122:c000:2:2100::",
+		"2001:db8:122:300::/56": "2001:db8:122:3c0:0:221::",
+		"2001:db8:122:344::/64": "2001:db8:122:344:c0:2:2100:0",
+		"2001:db8:122:344::/96": "2001:db8:122:344::c000:221",
+		"64:ff9b::/96":          "64:ff9b::c000:221",
+	} {
+		synthesized := embedIPv4(netip.MustParsePrefix(prefix), address)
+		require.Equal(t, netip.MustParseAddr(expected), synthesized, prefix)
+		require.Equal(t, address, extractIPv4(netip.MustParsePrefix(prefix), synthesized), prefix)
+	}
+}
+
+func TestClientDNS64(t *testing.T) {
+	t.Parallel()
+	transport := newTestTransport(netip.MustParseAddr("1.1.1.1"))
+	client := NewClient(ClientOptions{})
+	prefix := netip.MustParsePrefix("64:ff9b::/96")
+	synthesized := netip.MustParseAddr("64:ff9b::101:101")
+	_, loaded := client.LookupNAT64(synthesized)
+	require.False(t, loaded)
+
+	message := new(dns.Msg)
+	message.SetQuestion("example.com.", dns.TypeAAAA)
+	response, err := client.Exchange(context.Background(), transport, message, adapter.DNSQueryOptions{DNS64Prefix: prefix}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []netip.Addr{synthesized}, MessageToAddresses(response))
+
+	response, err = client.Exchange(context.Background(), transport, message, adapter.DNSQueryOptions{}, nil)
+	require.NoError(t, err)
+	require.Empty(t, response.Answer)
+
+	addresses, err := client.Lookup(context.Background(), transport, "example.com", adapter.DNSQueryOptions{
+		Strategy:    C.DomainStrategyIPv6Only,
+		DNS64Prefix: prefix,
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []netip.Addr{synthesized}, addresses)
+
+	address, loaded := client.LookupNAT64(synthesized)
+	require.True(t, loaded)
+	require.Equal(t, netip.MustParseAddr("1.1.1.1"), address)
+	_, loaded = client.LookupNAT64(netip.MustParseAddr("2001:db8::1"))
+	require.False(t, loaded)
+}
+
+func TestRouterRegisterNAT64Prefixes(t *testing.T) {
+	t.Parallel()
+	ruleOptions, err := json.UnmarshalExtendedContext[[]option.DNSRule](context.Background(), []byte(`[
+		{"domain_suffix": "example.com", "server": "local", "dns64_prefix": "64:ff9b::/96"},
+		{"domain_suffix": "example.org", "action": "route-options", "dns64_prefix": "2001:db8:122::/48"}
+	]`))
+	require.NoError(t, err)
+	router := &Router{
+		ctx:    context.Background(),
+		logger: log.NewNOPFactory().NewLogger("dns"),
+		client: NewClient(ClientOptions{}),
+	}
+	require.NoError(t, router.Initialize(ruleOptions))
+	// addresses synthesized before a restart are mapped without a new synthesis
+	for synthesized, expected := range map[string]string{
+		"64:ff9b::101:101":           "1.1.1.1",
+		"2001:db8:122:c000:2:2100::": "192.0.2.33",
+	} {
+		address, loaded := router.LookupNAT64(netip.MustParseAddr(synthesized))
+		require.True(t, loaded, synthesized)
+		require.Equal(t, netip.MustParseAddr(expected), address)
+	}
+}
@@ -0,0 +1,736 @@
+package dns
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/contrab/freelru"
+	"github.com/sagernet/sing/contrab/maphash"
+
+	"github.com/miekg/dns"
+)
+
+// rootTrustAnchors are the DS records of the root zone KSKs published by IANA.
+var rootTrustAnchors = []string{
+	". 172800 IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D",
+	". 172800 IN DS 38696 8 2 683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16",
+}
+
+const (
+	dnssecUDPSize     = 1232
+	dnssecMinLifetime = time.Minute
+	dnssecMaxLifetime = 24 * time.Hour
+)
+
+// isUpstreamTransport returns whether the transport answers with records from upstream servers.
+// Responses from other transports are synthesized and carry no signatures,
+// so they and the DS and DNSKEY queries for them cannot be validated.
+func isUpstreamTransport(transport adapter.DNSTransport) bool {
+	switch transport.Type() {
+	case C.DNSTypeLocal, C.DNSTypeHosts, C.DNSTypeFakeIP, C.DNSTypeTailscale, C.TypeResolved:
+		return false
+	default:
+		return true
+	}
+}
+
+// dnssecValidator validates responses by building the chain of trust
+// from the trust anchors down to the signer of each RRset, querying the
+// missing DS and DNSKEY records through the transport that answered.
+type dnssecValidator struct {
+	timeout   time.Duration
+	anchors   map[string][]*dns.DS
+	zoneCache freelru.Cache[dnssecCacheKey, *dnssecZone]
+	nameCache freelru.Cache[dnssecCacheKey, *dnssecZone]
+}
+
+type dnssecCacheKey struct {
+	transport string
+	name      string
+}
+
+// dnssecZone is a validated zone, keys is nil if the zone is proven insecure.
+type dnssecZone struct {
+	name     string
+	keys     []*dns.DNSKEY
+	expireAt time.Time
+}
+
+type dnssecRRSet struct {
+	name       string
+	rrType     uint16
+	records    []dns.RR
+	signatures []*dns.RRSIG
+	// signature is the one verified by verifyRRSet.
+	signature *dns.RRSIG
+}
+
+// dnssecDenial is the validated NSEC and NSEC3 records of a response.
+type dnssecDenial struct {
+	nsecs  []dnssecNSEC
+	nsec3s []dnssecNSEC3
+}
+
+// dnssecNSEC and dnssecNSEC3 hold the zone that signed the record, which
+// bounds the names the record can deny.
+type dnssecNSEC struct {
+	*dns.NSEC
+	zone string
+}
+
+type dnssecNSEC3 struct {
+	*dns.NSEC3
+	zone string
+}
+
+func newDNSSECValidator(timeout time.Duration, trustAnchors []dns.RR) *dnssecValidator {
+	anchors := make(map[string][]*dns.DS)
+	for _, record := range trustAnchors {
+		var ds *dns.DS
+		switch anchor := record.(type) {
+		case *dns.DS:
+			ds = anchor
+		case *dns.DNSKEY:
+			ds = anchor.ToDS(dns.SHA256)
+		}
+		if ds == nil {
+			continue
+		}
+		zone := dns.CanonicalName(ds.Hdr.Name)
+		anchors[zone] = append(anchors[zone], ds)
+	}
+	if _, loaded := anchors["."]; !loaded {
+		for _, anchor := range rootTrustAnchors {
+			anchors["."] = append(anchors["."], common.Must1(dns.NewRR(anchor)).(*dns.DS))
+		}
+	}
+	return &dnssecValidator{
+		timeout:   timeout,
+		anchors:   anchors,
+		zoneCache: common.Must1(freelru.NewSharded[dnssecCacheKey, *dnssecZone](1024, maphash.NewHasher[dnssecCacheKey]().Hash32)),
+		nameCache: common.Must1(freelru.NewSharded[dnssecCacheKey, *dnssecZone](4096, maphash.NewHasher[dnssecCacheKey]().Hash32)),
+	}
+}
+
+// Validate returns whether the response is secure, or an error if it is bogus.
+// Responses from zones proven to be unsigned are insecure.
+func (v *dnssecValidator) Validate(ctx context.Context, transport adapter.DNSTransport, question dns.Question, response *dns.Msg) (bool, error) {
+	var (
+		secure   = true
+		expanded []*dnssecRRSet
+	)
+	for _, rrSet := range splitRRSets(response.Answer) {
+		rrSetSecure, err := v.verifyRRSet(ctx, transport, rrSet, "")
+		if err != nil {
+			return false, E.Cause(err, "verify ", rrSet.name, " ", dns.TypeToString[rrSet.rrType])
+		}
+		secure = secure && rrSetSecure
+		if rrSetSecure && int(rrSet.signature.Labels) < nameLabels(rrSet.name) {
+			expanded = append(expanded, rrSet)
+		}
+	}
+	target := dns.CanonicalName(question.Name)
+	for range response.Answer {
+		var cname *dns.CNAME
+		for _, record := range response.Answer {
+			if record.Header().Rrtype == dns.TypeCNAME && dns.CanonicalName(record.Header().Name) == target {
+				cname = record.(*dns.CNAME)
+				break
+			}
+		}
+		if cname == nil || question.Qtype == dns.TypeCNAME {
+			break
+		}
+		target = dns.CanonicalName(cname.Target)
+	}
+	positive := response.Rcode == dns.RcodeSuccess && common.Any(response.Answer, func(it dns.RR) bool {
+		return dns.CanonicalName(it.Header().Name) == target && it.Header().Rrtype != dns.TypeRRSIG
+	})
+	if positive && len(expanded) == 0 {
+		return secure, nil
+	}
+	denial, denialSecure, err := v.verifyDenial(ctx, transport, response.Ns, "")
+	if err != nil {
+		return false, err
+	}
+	if denial == nil {
+		if len(expanded) > 0 {
+			return false, E.New("missing wildcard proof for ", expanded[0].name)
+		}
+		zone, err := v.findZone(ctx, transport, target)
+		if err != nil {
+			return false, err
+		}
+		if zone.keys != nil {
+			return false, E.New("missing denial of existence for ", target)
+		}
+		return false, nil
+	}
+	if !denialSecure {
+		return false, nil
+	}
+	// RFC 4035 5.3.4, RFC 5155 8.8: the name of an answer expanded from a
+	// wildcard must be proven not to exist
+	for _, rrSet := range expanded {
+		if !denial.wildcardAnswer(rrSet.name, int(rrSet.signature.Labels)) {
+			return false, E.New("missing wildcard proof for ", rrSet.name)
+		}
+	}
+	if positive {
+		return secure, nil
+	}
+	if !denial.denied(target, question.Qtype, response.Rcode) {
+		return false, E.New("invalid denial of existence for ", target)
+	}
+	return secure, nil
+}
+
+// verifyDenial verifies the SOA, NSEC and NSEC3 RRsets in records, and
+// returns nil if there are none. The result is insecure if any of them is
+// not signed by a secure zone.
+func (v *dnssecValidator) verifyDenial(ctx context.Context, transport adapter.DNSTransport, records []dns.RR, child string) (*dnssecDenial, bool, error) {
+	var denial *dnssecDenial
+	for _, rrSet := range splitRRSets(records) {
+		switch rrSet.rrType {
+		case dns.TypeSOA, dns.TypeNSEC, dns.TypeNSEC3:
+		default:
+			continue
+		}
+		if denial == nil {
+			denial = &dnssecDenial{}
+		}
+		secure, err := v.verifyRRSet(ctx, transport, rrSet, child)
+		if err != nil {
+			return nil, false, E.Cause(err, "verify ", rrSet.name, " ", dns.TypeToString[rrSet.rrType])
+		}
+		if !secure {
+			return denial, false, nil
+		}
+		zone := dns.CanonicalName(rrSet.signature.SignerName)
+		for _, record := range rrSet.records {
+			switch denialRecord := record.(type) {
+			case *dns.NSEC:
+				denial.nsecs = append(denial.nsecs, dnssecNSEC{denialRecord, zone})
+			case *dns.NSEC3:
+				denial.nsec3s = append(denial.nsec3s, dnssecNSEC3{denialRecord, zone})
+			}
+		}
+	}
+	return denial, true, nil
+}
+
+// verifyRRSet returns whether the RRset is signed by a secure zone, or an
+// error if it is bogus. If child is not empty, the signer must be a proper
+// ancestor of child, which is the zone whose DS RRset is being verified.
+func (v *dnssecValidator) verifyRRSet(ctx context.Context, transport adapter.DNSTransport, rrSet *dnssecRRSet, child string) (bool, error) {
+	if len(rrSet.signatures) == 0 {
+		name := rrSet.name
+		if child != "" {
+			name = parentName(child)
+		}
+		zone, err := v.findZone(ctx, transport, name)
+		if err != nil {
+			return false, err
+		}
+		if zone.keys != nil {
+			return false, E.New("missing signature")
+		}
+		return false, nil
+	}
+	now := time.Now()
+	var errors []error
+	for _, signature := range rrSet.signatures {
+		signer := dns.CanonicalName(signature.SignerName)
+		if !dns.IsSubDomain(signer, rrSet.name) || child != "" && dns.IsSubDomain(child, signer) {
+			errors = append(errors, E.New("unexpected signer ", signer))
+			continue
+		}
+		if !signature.ValidityPeriod(now) {
+			errors = append(errors, E.New("signature by ", signer, " expired"))
+			continue
+		}
+		zone, err := v.loadZone(ctx, transport, signer)
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		if zone.keys == nil {
+			return false, nil
+		}
+		for _, key := range zone.keys {
+			if key.Algorithm == signature.Algorithm && key.KeyTag() == signature.KeyTag && signature.Verify(key, rrSet.records) == nil {
+				rrSet.signature = signature
+				return true, nil
+			}
+		}
+		errors = append(errors, E.New("no valid signature by ", signer, " with key ", signature.KeyTag))
+	}
+	return false, E.Errors(errors...)
+}
+
+// findZone returns the zone containing name, found by the SOA record in
+// response to a SOA query.
+func (v *dnssecValidator) findZone(ctx context.Context, transport adapter.DNSTransport, name string) (*dnssecZone, error) {
+	cacheKey := dnssecCacheKey{transport.Tag(), name}
+	if zone, loaded := v.nameCache.Get(cacheKey); loaded {
+		return zone, nil
+	}
+	var apex string
+	for current := name; apex == ""; current = parentName(current) {
+		if _, anchored := v.anchors[current]; anchored {
+			apex = current
+			break
+		}
+		response, err := v.exchange(ctx, transport, current, dns.TypeSOA)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range append(response.Answer, response.Ns...) {
+			soa, isSOA := record.(*dns.SOA)
+			if isSOA && dns.IsSubDomain(dns.CanonicalName(soa.Hdr.Name), current) {
+				apex = dns.CanonicalName(soa.Hdr.Name)
+				break
+			}
+		}
+		if current == "." {
+			break
+		}
+	}
+	if apex == "" {
+		return nil, E.New("missing SOA for ", name)
+	}
+	zone, err := v.loadZone(ctx, transport, apex)
+	if err != nil {
+		return nil, err
+	}
+	v.nameCache.AddWithLifetime(cacheKey, zone, time.Until(zone.expireAt))
+	return zone, nil
+}
+
+// loadZone returns the zone with its DNSKEYs validated by the DS RRset of
+// the parent zone or the trust anchors.
+func (v *dnssecValidator) loadZone(ctx context.Context, transport adapter.DNSTransport, name string) (*dnssecZone, error) {
+	cacheKey := dnssecCacheKey{transport.Tag(), name}
+	if zone, loaded := v.zoneCache.Get(cacheKey); loaded {
+		return zone, nil
+	}
+	zone, err := v.fetchZone(ctx, transport, name)
+	if err != nil {
+		return nil, E.Cause(err, "validate zone ", name)
+	}
+	v.zoneCache.AddWithLifetime(cacheKey, zone, time.Until(zone.expireAt))
+	return zone, nil
+}
+
+func (v *dnssecValidator) fetchZone(ctx context.Context, transport adapter.DNSTransport, name string) (*dnssecZone, error) {
+	now := time.Now()
+	zone := &dnssecZone{name: name, expireAt: now.Add(dnssecMaxLifetime)}
+	dsSet, anchored := v.anchors[name]
+	if !anchored {
+		if name == "." {
+			return nil, E.New("missing trust anchor")
+		}
+		response, err := v.exchange(ctx, transport, name, dns.TypeDS)
+		if err != nil {
+			return nil, err
+		}
+		rrSet := findRRSet(response.Answer, name, dns.TypeDS)
+		if rrSet == nil {
+			insecure, err := v.verifyInsecureDelegation(ctx, transport, name, response)
+			if err != nil {
+				return nil, err
+			}
+			zone.expireAt = rrSetExpireAt(now, response.Ns, nil)
+			if !insecure {
+				return nil, E.New("missing DS")
+			}
+			return zone, nil
+		}
+		secure, err := v.verifyRRSet(ctx, transport, rrSet, name)
+		if err != nil {
+			return nil, E.Cause(err, "verify DS")
+		}
+		zone.expireAt = rrSetExpireAt(now, rrSet.records, rrSet.signatures)
+		if !secure {
+			return zone, nil
+		}
+		for _, record := range rrSet.records {
+			ds := record.(*dns.DS)
+			if dnssecAlgorithmSupported(ds.Algorithm) && dnssecDigestSupported(ds.DigestType) {
+				dsSet = append(dsSet, ds)
+			}
+		}
+		// RFC 4035 5.2: zones signed only with unsupported algorithms are insecure
+		if len(dsSet) == 0 {
+			return zone, nil
+		}
+	}
+	response, err := v.exchange(ctx, transport, name, dns.TypeDNSKEY)
+	if err != nil {
+		return nil, err
+	}
+	rrSet := findRRSet(response.Answer, name, dns.TypeDNSKEY)
+	if rrSet == nil {
+		return nil, E.New("missing DNSKEY")
+	}
+	for _, signature := range rrSet.signatures {
+		if !signature.ValidityPeriod(now) {
+			continue
+		}
+		for _, record := range rrSet.records {
+			key := record.(*dns.DNSKEY)
+			if key.Algorithm != signature.Algorithm || key.KeyTag() != signature.KeyTag || !dsMatchKey(dsSet, key) {
+				continue
+			}
+			if signature.Verify(key, rrSet.records) != nil {
+				continue
+			}
+			for _, keyRecord := range rrSet.records {
+				zoneKey := keyRecord.(*dns.DNSKEY)
+				if zoneKey.Flags&dns.ZONE != 0 {
+					zone.keys = append(zone.keys, zoneKey)
+				}
+			}
+			zone.expireAt = rrSetExpireAt(now, rrSet.records, rrSet.signatures)
+			return zone, nil
+		}
+	}
+	return nil, E.New("no DNSKEY matches DS")
+}
+
+// verifyInsecureDelegation returns whether the negative response to a DS
+// query proves that name is an unsigned delegation.
+func (v *dnssecValidator) verifyInsecureDelegation(ctx context.Context, transport adapter.DNSTransport, name string, response *dns.Msg) (bool, error) {
+	denial, secure, err := v.verifyDenial(ctx, transport, response.Ns, name)
+	if err != nil {
+		return false, err
+	}
+	if denial == nil {
+		parent, err := v.findZone(ctx, transport, parentName(name))
+		if err != nil {
+			return false, err
+		}
+		return parent.keys == nil, nil
+	}
+	if !secure {
+		return true, nil
+	}
+	for _, nsec := range denial.nsecs {
+		if dns.CanonicalName(nsec.Hdr.Name) == name && isDelegation(nsec.TypeBitMap) {
+			return true, nil
+		}
+	}
+	for _, nsec3 := range denial.nsec3s {
+		if nsec3.match(name) && isDelegation(nsec3.TypeBitMap) {
+			return true, nil
+		}
+		// opt-out ranges may only contain unsigned delegations
+		if nsec3.Flags&1 != 0 && nsec3.cover(name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (v *dnssecValidator) exchange(ctx context.Context, transport adapter.DNSTransport, name string, qType uint16) (*dns.Msg, error) {
+	request := new(dns.Msg)
+	request.SetQuestion(name, qType)
+	request.CheckingDisabled = true
+	request.SetEdns0(dnssecUDPSize, true)
+	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+	response, err := transport.Exchange(ctx, request)
+	if err != nil {
+		return nil, E.Cause(err, "query ", dns.TypeToString[qType], " ", name)
+	}
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		return nil, E.Cause(RcodeError(response.Rcode), "query ", dns.TypeToString[qType], " ", name)
+	}
+	return response, nil
+}
+
+func splitRRSets(records []dns.RR) []*dnssecRRSet {
+	var rrSets []*dnssecRRSet
+	findSet := func(name string, rrType uint16) *dnssecRRSet {
+		for _, rrSet := range rrSets {
+			if rrSet.name == name && rrSet.rrType == rrType {
+				return rrSet
+			}
+		}
+		rrSet := &dnssecRRSet{name: name, rrType: rrType}
+		rrSets = append(rrSets, rrSet)
+		return rrSet
+	}
+	for _, record := range records {
+		name := dns.CanonicalName(record.Header().Name)
+		switch rr := record.(type) {
+		case *dns.OPT:
+		case *dns.RRSIG:
+			rrSet := findSet(name, rr.TypeCovered)
+			rrSet.signatures = append(rrSet.signatures, rr)
+		default:
+			rrSet := findSet(name, rr.Header().Rrtype)
+			rrSet.records = append(rrSet.records, rr)
+		}
+	}
+	return common.Filter(rrSets, func(it *dnssecRRSet) bool {
+		return len(it.records) > 0
+	})
+}
+
+func findRRSet(records []dns.RR, name string, rrType uint16) *dnssecRRSet {
+	for _, rrSet := range splitRRSets(records) {
+		if rrSet.name == name && rrSet.rrType == rrType {
+			return rrSet
+		}
+	}
+	return nil
+}
+
+// rrSetExpireAt returns when validated data expires, bounded by the TTL of
+// records and the expiration of signatures.
+func rrSetExpireAt(now time.Time, records []dns.RR, signatures []*dns.RRSIG) time.Time {
+	lifetime := dnssecMaxLifetime
+	for _, record := range records {
+		lifetime = min(lifetime, time.Duration(record.Header().Ttl)*time.Second)
+	}
+	for _, signature := range signatures {
+		lifetime = min(lifetime, time.Unix(int64(signature.Expiration), 0).Sub(now))
+	}
+	return now.Add(max(lifetime, dnssecMinLifetime))
+}
+
+func dsMatchKey(dsSet []*dns.DS, key *dns.DNSKEY) bool {
+	for _, ds := range dsSet {
+		if ds.KeyTag != key.KeyTag() || ds.Algorithm != key.Algorithm {
+			continue
+		}
+		keyDS := key.ToDS(ds.DigestType)
+		if keyDS != nil && strings.EqualFold(keyDS.Digest, ds.Digest) {
+			return true
+		}
+	}
+	return false
+}
+
+func dnssecAlgorithmSupported(algorithm uint8) bool {
+	switch algorithm {
+	case dns.RSASHA1, dns.RSASHA1NSEC3SHA1, dns.RSASHA256, dns.RSASHA512, dns.ECDSAP256SHA256, dns.ECDSAP384SHA384, dns.ED25519:
+		return true
+	default:
+		return false
+	}
+}
+
+func dnssecDigestSupported(digestType uint8) bool {
+	switch digestType {
+	case dns.SHA1, dns.SHA256, dns.SHA384:
+		return true
+	default:
+		return false
+	}
+}
+
+// denied returns whether the records prove that name does not exist, or
+// has no record of qType.
+func (d *dnssecDenial) denied(name string, qType uint16, rcode int) bool {
+	if rcode == dns.RcodeNameError {
+		// RFC 4035 5.4, RFC 5155 8.4: the wildcard at the closest encloser
+		// must not exist either
+		for _, nsec := range d.nsecs {
+			if nsec.cover(name) && d.nsecCover(wildcardName(nsec.closestEncloser(name))) {
+				return true
+			}
+		}
+		closestEncloser, _, loaded := d.nsec3ClosestEncloser(name)
+		return loaded && d.nsec3Cover(wildcardName(closestEncloser))
+	}
+	for _, nsec := range d.nsecs {
+		if dns.CanonicalName(nsec.Hdr.Name) == name {
+			if !slices.Contains(nsec.TypeBitMap, qType) && !slices.Contains(nsec.TypeBitMap, dns.TypeCNAME) {
+				return true
+			}
+		} else if nsec.cover(name) && dns.IsSubDomain(name, dns.CanonicalName(nsec.NextDomain)) {
+			// empty non-terminal
+			return true
+		}
+	}
+	for _, nsec3 := range d.nsec3s {
+		if nsec3.match(name) {
+			if !slices.Contains(nsec3.TypeBitMap, qType) && !slices.Contains(nsec3.TypeBitMap, dns.TypeCNAME) {
+				return true
+			}
+		} else if qType == dns.TypeDS && nsec3.Flags&1 != 0 && nsec3.cover(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardAnswer returns whether the records prove that name, which is
+// expanded from a wildcard with the given number of labels, does not exist.
+func (d *dnssecDenial) wildcardAnswer(name string, labels int) bool {
+	if d.nsecCover(name) {
+		return true
+	}
+	return d.nsec3Cover(ancestorName(name, labels+1))
+}
+
+func (d *dnssecDenial) nsecCover(name string) bool {
+	return common.Any(d.nsecs, func(it dnssecNSEC) bool {
+		return it.cover(name)
+	})
+}
+
+func (d *dnssecDenial) nsec3Cover(name string) bool {
+	return common.Any(d.nsec3s, func(it dnssecNSEC3) bool {
+		return it.cover(name)
+	})
+}
+
+// nsec3ClosestEncloser returns the closest encloser of name and the next
+// closer name covered by the records (RFC 5155 8.3).
+func (d *dnssecDenial) nsec3ClosestEncloser(name string) (string, string, bool) {
+	for nextCloser := name; nextCloser != "."; nextCloser = parentName(nextCloser) {
+		closestEncloser := parentName(nextCloser)
+		if common.Any(d.nsec3s, func(it dnssecNSEC3) bool {
+			return it.match(closestEncloser) && !isDelegation(it.TypeBitMap)
+		}) {
+			return closestEncloser, nextCloser, d.nsec3Cover(nextCloser)
+		}
+	}
+	return "", "", false
+}
+
+func isDelegation(typeBitMap []uint16) bool {
+	return slices.Contains(typeBitMap, dns.TypeNS) && !slices.Contains(typeBitMap, dns.TypeDS) && !slices.Contains(typeBitMap, dns.TypeSOA)
+}
+
+// cover returns whether name is in the zone of the NSEC record, and between
+// the owner and the next name in canonical order.
+func (n dnssecNSEC) cover(name string) bool {
+	if !dns.IsSubDomain(n.zone, name) {
+		return false
+	}
+	owner := dns.CanonicalName(n.Hdr.Name)
+	next := dns.CanonicalName(n.NextDomain)
+	if compareCanonical(owner, name) >= 0 {
+		return false
+	}
+	if compareCanonical(owner, next) < 0 {
+		return compareCanonical(name, next) < 0
+	}
+	// the last NSEC of the zone points back to the apex
+	return next == n.zone
+}
+
+// closestEncloser returns the closest encloser of name, which is covered
+// by the NSEC record, as the longer ancestor shared with the owner or the
+// next name.
+func (n dnssecNSEC) closestEncloser(name string) string {
+	labels := max(dns.CompareDomainName(name, n.Hdr.Name), dns.CompareDomainName(name, n.NextDomain))
+	return ancestorName(name, labels)
+}
+
+func (n dnssecNSEC3) match(name string) bool {
+	return dns.IsSubDomain(n.zone, name) && n.Match(name)
+}
+
+func (n dnssecNSEC3) cover(name string) bool {
+	return dns.IsSubDomain(n.zone, name) && n.Cover(name)
+}
+
+func compareCanonical(a string, b string) int {
+	labelsA := dns.SplitDomainName(a)
+	labelsB := dns.SplitDomainName(b)
+	for i := 1; i <= len(labelsA) && i <= len(labelsB); i++ {
+		result := strings.Compare(strings.ToLower(labelsA[len(labelsA)-i]), strings.ToLower(labelsB[len(labelsB)-i]))
+		if result != 0 {
+			return result
+		}
+	}
+	return len(labelsA) - len(labelsB)
+}
+
+// ancestorName returns the ancestor of name with the given number of labels.
+func ancestorName(name string, labels int) string {
+	indexes := dns.Split(name)
+	if labels <= 0 || len(indexes) == 0 {
+		return "."
+	}
+	if labels >= len(indexes) {
+		return name
+	}
+	return name[indexes[len(indexes)-labels]:]
+}
+
+// nameLabels returns the number of labels of name as counted by RRSIG
+// records, which excludes the leading wildcard label.
+func nameLabels(name string) int {
+	labels := dns.CountLabel(name)
+	if strings.HasPrefix(name, "*.") {
+		labels--
+	}
+	return labels
+}
+
+func wildcardName(name string) string {
+	if name == "." {
+		return "*."
+	}
+	return "*." + name
+}
+
+func parentName(name string) string {
+	offset, end := dns.NextLabel(name, 0)
+	if end {
+		return "."
+	}
+	return name[offset:]
+}
+
+func isDNSSECOK(message *dns.Msg) bool {
+	optRecord := message.IsEdns0()
+	return optRecord != nil && optRecord.Do()
+}
+
+// setDNSSECOK returns a copy of message with the DO bit set.
+func setDNSSECOK(message *dns.Msg) *dns.Msg {
+	message = message.Copy()
+	if optRecord := message.IsEdns0(); optRecord != nil {
+		optRecord.SetDo()
+	} else {
+		message.SetEdns0(dnssecUDPSize, true)
+	}
+	return message
+}
+
+// stripDNSSEC removes DNSSEC records not requested by the client.
+func stripDNSSEC(response *dns.Msg, qType uint16) {
+	isDNSSECRecord := func(it dns.RR) bool {
+		switch it.Header().Rrtype {
+		case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3:
+			return it.Header().Rrtype != qType
+		default:
+			return false
+		}
+	}
+	response.Answer = common.Filter(response.Answer, func(it dns.RR) bool {
+		return !isDNSSECRecord(it)
+	})
+	response.Ns = common.Filter(response.Ns, func(it dns.RR) bool {
+		return !isDNSSECRecord(it)
+	})
+	response.Extra = common.Filter(response.Extra, func(it dns.RR) bool {
+		return !isDNSSECRecord(it)
+	})
+	if optRecord := response.IsEdns0(); optRecord != nil {
+		optRecord.SetDo(false)
+	}
+}
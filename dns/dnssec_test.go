@@ -0,0 +1,296 @@
+package dns
+
+import (
+	"context"
+	"crypto"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+type testZoneSigner struct {
+	key        *dns.DNSKEY
+	privateKey crypto.Signer
+}
+
+func newTestZoneSigner(t *testing.T, zone string) *testZoneSigner {
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: zone, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     dns.ZONE | dns.SEP,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	privateKey, err := key.Generate(256)
+	require.NoError(t, err)
+	return &testZoneSigner{key: key, privateKey: privateKey.(crypto.Signer)}
+}
+
+func (s *testZoneSigner) sign(t *testing.T, records ...dns.RR) []dns.RR {
+	header := records[0].Header()
+	signature := &dns.RRSIG{
+		Hdr:         dns.RR_Header{Name: header.Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: header.Ttl},
+		TypeCovered: header.Rrtype,
+		Algorithm:   s.key.Algorithm,
+		SignerName:  s.key.Hdr.Name,
+		KeyTag:      s.key.KeyTag(),
+		Inception:   uint32(time.Now().Add(-time.Hour).Unix()),
+		Expiration:  uint32(time.Now().Add(time.Hour).Unix()),
+	}
+	require.NoError(t, signature.Sign(s.privateKey, records))
+	return append(records, signature)
+}
+
+type testSignedResponse struct {
+	rcode  int
+	answer []dns.RR
+	ns     []dns.RR
+}
+
+type testSignedTransport struct {
+	TransportAdapter
+	responses map[dns.Question]testSignedResponse
+}
+
+func (t *testSignedTransport) Start(stage adapter.StartStage) error {
+	return nil
+}
+
+func (t *testSignedTransport) Close() error {
+	return nil
+}
+
+func (t *testSignedTransport) Reset() {
+}
+
+func (t *testSignedTransport) Exchange(ctx context.Context, message *dns.Msg) (*dns.Msg, error) {
+	question := message.Question[0]
+	question.Name = dns.CanonicalName(question.Name)
+	response := new(dns.Msg)
+	response.SetReply(message)
+	content, loaded := t.responses[question]
+	if !loaded {
+		response.Rcode = dns.RcodeRefused
+		return response, nil
+	}
+	response.Rcode = content.rcode
+	for _, record := range content.answer {
+		response.Answer = append(response.Answer, dns.Copy(record))
+	}
+	for _, record := range content.ns {
+		response.Ns = append(response.Ns, dns.Copy(record))
+	}
+	response.SetEdns0(dnssecUDPSize, true)
+	return response, nil
+}
+
+func testRR(t *testing.T, content string) dns.RR {
+	record, err := dns.NewRR(content)
+	require.NoError(t, err)
+	if a, isA := record.(*dns.A); isA {
+		a.A = a.A.To4()
+	}
+	return record
+}
+
+// newTestSignedTransport serves a signed hierarchy of the root, com. and
+// example.com., and an unsigned delegation of insecure.com.
+func newTestSignedTransport(t *testing.T) (*testSignedTransport, *dns.DS) {
+	root := newTestZoneSigner(t, ".")
+	com := newTestZoneSigner(t, "com.")
+	example := newTestZoneSigner(t, "example.com.")
+	responses := make(map[dns.Question]testSignedResponse)
+	answer := func(name string, qType uint16, records ...dns.RR) {
+		responses[dns.Question{Name: name, Qtype: qType, Qclass: dns.ClassINET}] = testSignedResponse{answer: records}
+	}
+	negative := func(name string, qType uint16, rcode int, records ...dns.RR) {
+		responses[dns.Question{Name: name, Qtype: qType, Qclass: dns.ClassINET}] = testSignedResponse{rcode: rcode, ns: records}
+	}
+	answer(".", dns.TypeDNSKEY, root.sign(t, root.key)...)
+	answer("com.", dns.TypeDS, root.sign(t, com.key.ToDS(dns.SHA256))...)
+	answer("com.", dns.TypeDNSKEY, com.sign(t, com.key)...)
+	answer("example.com.", dns.TypeDS, com.sign(t, example.key.ToDS(dns.SHA256))...)
+	answer("example.com.", dns.TypeDNSKEY, example.sign(t, example.key)...)
+	answer("www.example.com.", dns.TypeA, example.sign(t, testRR(t, "www.example.com. 300 IN A 1.1.1.1"))...)
+	tampered := example.sign(t, testRR(t, "bad.example.com. 300 IN A 1.1.1.1"))
+	tampered[0].(*dns.A).A = net.ParseIP("2.2.2.2").To4()
+	answer("bad.example.com.", dns.TypeA, tampered...)
+	exampleSOA := example.sign(t, testRR(t, "example.com. 300 IN SOA ns.example.com. admin.example.com. 1 3600 600 86400 300"))
+	negative("nx.example.com.", dns.TypeA, dns.RcodeNameError, append(exampleSOA, example.sign(t, testRR(t, "example.com. 300 IN NSEC www.example.com. A NS SOA RRSIG NSEC DNSKEY"))...)...)
+	negative("forged.example.com.", dns.TypeA, dns.RcodeNameError, exampleSOA...)
+	// NSEC of a foreign zone, which must not deny names in example.com.
+	attacker := newTestZoneSigner(t, "attacker.com.")
+	answer("attacker.com.", dns.TypeDS, com.sign(t, attacker.key.ToDS(dns.SHA256))...)
+	answer("attacker.com.", dns.TypeDNSKEY, attacker.sign(t, attacker.key)...)
+	attackerSOA := attacker.sign(t, testRR(t, "attacker.com. 300 IN SOA ns.attacker.com. admin.attacker.com. 1 3600 600 86400 300"))
+	negative("evil.example.com.", dns.TypeA, dns.RcodeNameError, append(attackerSOA, attacker.sign(t, testRR(t, "zzz.attacker.com. 300 IN NSEC attacker.com. A RRSIG NSEC"))...)...)
+	// NSEC covering the name but not the wildcard
+	negative("nowild.example.com.", dns.TypeA, dns.RcodeNameError, append(exampleSOA, example.sign(t, testRR(t, "mmm.example.com. 300 IN NSEC www.example.com. A RRSIG NSEC"))...)...)
+	// answers expanded from *.example.com.
+	wildcardAnswer := func(name string) []dns.RR {
+		records := example.sign(t, testRR(t, "*.example.com. 300 IN A 4.4.4.4"))
+		for _, record := range records {
+			record.Header().Name = name
+		}
+		return records
+	}
+	responses[dns.Question{Name: "wild.example.com.", Qtype: dns.TypeA, Qclass: dns.ClassINET}] = testSignedResponse{
+		answer: wildcardAnswer("wild.example.com."),
+		ns:     example.sign(t, testRR(t, "*.example.com. 300 IN NSEC www.example.com. A RRSIG NSEC")),
+	}
+	answer("wildnoproof.example.com.", dns.TypeA, wildcardAnswer("wildnoproof.example.com.")...)
+	// NSEC3 closest encloser proofs
+	apexHash := dns.HashName("example.com.", dns.SHA1, 0, "")
+	nsec3Apex := example.sign(t, testRR(t, apexHash+".example.com. 300 IN NSEC3 1 0 0 - "+apexHash[:24]+strings.Repeat("V", 8)+" A NS SOA RRSIG DNSKEY NSEC3PARAM"))
+	nsec3Cover := func(name string) []dns.RR {
+		hash := dns.HashName(name, dns.SHA1, 0, "")
+		return example.sign(t, testRR(t, hash[:8]+strings.Repeat("0", 24)+".example.com. 300 IN NSEC3 1 0 0 - "+hash[:8]+strings.Repeat("V", 24)+" A RRSIG"))
+	}
+	negative("nx3.example.com.", dns.TypeA, dns.RcodeNameError, slices.Concat(exampleSOA, nsec3Apex, nsec3Cover("nx3.example.com."), nsec3Cover("*.example.com."))...)
+	negative("nx3nowild.example.com.", dns.TypeA, dns.RcodeNameError, slices.Concat(exampleSOA, nsec3Apex, nsec3Cover("nx3nowild.example.com."))...)
+	comSOA := com.sign(t, testRR(t, "com. 300 IN SOA ns.com. admin.com. 1 3600 600 86400 300"))
+	negative("insecure.com.", dns.TypeDS, dns.RcodeSuccess, append(comSOA, com.sign(t, testRR(t, "insecure.com. 300 IN NSEC zzz.com. NS RRSIG NSEC"))...)...)
+	negative("www.insecure.com.", dns.TypeSOA, dns.RcodeSuccess, testRR(t, "insecure.com. 300 IN SOA ns.insecure.com. admin.insecure.com. 1 3600 600 86400 300"))
+	answer("www.insecure.com.", dns.TypeA, testRR(t, "www.insecure.com. 300 IN A 3.3.3.3"))
+	return &testSignedTransport{
+		TransportAdapter: NewTransportAdapter("test", "test", nil),
+		responses:        responses,
+	}, root.key.ToDS(dns.SHA256)
+}
+
+func testExchange(t *testing.T, client *Client, transport adapter.DNSTransport, name string, options adapter.DNSQueryOptions) *dns.Msg {
+	message := new(dns.Msg)
+	message.SetQuestion(name, dns.TypeA)
+	response, err := client.Exchange(context.Background(), transport, message, options, nil)
+	require.NoError(t, err)
+	return response
+}
+
+func TestDNSSECValidation(t *testing.T) {
+	t.Parallel()
+	transport, anchor := newTestSignedTransport(t)
+	client := NewClient(ClientOptions{
+		DisableCache: true,
+		DNSSEC:       true,
+		TrustAnchors: []dns.RR{anchor},
+	})
+	response := testExchange(t, client, transport, "www.example.com.", adapter.DNSQueryOptions{})
+	require.Equal(t, dns.RcodeSuccess, response.Rcode)
+	require.True(t, response.AuthenticatedData)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("1.1.1.1")}, MessageToAddresses(response))
+	require.Len(t, response.Answer, 1)
+
+	response = testExchange(t, client, transport, "nx.example.com.", adapter.DNSQueryOptions{})
+	require.Equal(t, dns.RcodeNameError, response.Rcode)
+	require.True(t, response.AuthenticatedData)
+
+	response = testExchange(t, client, transport, "www.insecure.com.", adapter.DNSQueryOptions{})
+	require.Equal(t, dns.RcodeSuccess, response.Rcode)
+	require.False(t, response.AuthenticatedData)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("3.3.3.3")}, MessageToAddresses(response))
+
+	response = testExchange(t, client, transport, "bad.example.com.", adapter.DNSQueryOptions{})
+	require.Equal(t, dns.RcodeServerFailure, response.Rcode)
+
+	response = testExchange(t, client, transport, "forged.example.com.", adapter.DNSQueryOptions{})
+	require.Equal(t, dns.RcodeServerFailure, response.Rcode)
+}
+
+func TestDNSSECDenialOfExistence(t *testing.T) {
+	t.Parallel()
+	transport, anchor := newTestSignedTransport(t)
+	client := NewClient(ClientOptions{
+		DisableCache: true,
+		DNSSEC:       true,
+		TrustAnchors: []dns.RR{anchor},
+	})
+	response := testExchange(t, client, transport, "wild.example.com.", adapter.DNSQueryOptions{})
+	require.Equal(t, dns.RcodeSuccess, response.Rcode)
+	require.True(t, response.AuthenticatedData)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("4.4.4.4")}, MessageToAddresses(response))
+
+	response = testExchange(t, client, transport, "nx3.example.com.", adapter.DNSQueryOptions{})
+	require.Equal(t, dns.RcodeNameError, response.Rcode)
+	require.True(t, response.AuthenticatedData)
+
+	for _, name := range []string{"evil.example.com.", "nowild.example.com.", "wildnoproof.example.com.", "nx3nowild.example.com."} {
+		response = testExchange(t, client, transport, name, adapter.DNSQueryOptions{})
+		require.Equal(t, dns.RcodeServerFailure, response.Rcode, name)
+	}
+}
+
+func TestDNSSECLogOnly(t *testing.T) {
+	t.Parallel()
+	transport, anchor := newTestSignedTransport(t)
+	client := NewClient(ClientOptions{
+		DisableCache:  true,
+		DNSSEC:        true,
+		DNSSECLogOnly: true,
+		TrustAnchors:  []dns.RR{anchor},
+	})
+	response := testExchange(t, client, transport, "bad.example.com.", adapter.DNSQueryOptions{})
+	require.Equal(t, dns.RcodeSuccess, response.Rcode)
+	require.False(t, response.AuthenticatedData)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("2.2.2.2")}, MessageToAddresses(response))
+
+	response = testExchange(t, client, transport, "bad.example.com.", adapter.DNSQueryOptions{RequireDNSSEC: true})
+	require.Equal(t, dns.RcodeServerFailure, response.Rcode)
+}
+
+func TestDNSSECRequire(t *testing.T) {
+	t.Parallel()
+	transport, anchor := newTestSignedTransport(t)
+	client := NewClient(ClientOptions{
+		TrustAnchors: []dns.RR{anchor},
+	})
+	response := testExchange(t, client, transport, "www.insecure.com.", adapter.DNSQueryOptions{})
+	require.Equal(t, dns.RcodeSuccess, response.Rcode)
+
+	response = testExchange(t, client, transport, "www.insecure.com.", adapter.DNSQueryOptions{RequireDNSSEC: true})
+	require.Equal(t, dns.RcodeServerFailure, response.Rcode)
+
+	response = testExchange(t, client, transport, "www.example.com.", adapter.DNSQueryOptions{RequireDNSSEC: true})
+	require.Equal(t, dns.RcodeSuccess, response.Rcode)
+	require.True(t, response.AuthenticatedData)
+}
+
+func TestDNSSECSkipSynthesizedTransport(t *testing.T) {
+	t.Parallel()
+	_, anchor := newTestSignedTransport(t)
+	client := NewClient(ClientOptions{
+		DisableCache: true,
+		DNSSEC:       true,
+		TrustAnchors: []dns.RR{anchor},
+	})
+	for _, transportType := range []string{C.DNSTypeFakeIP, C.DNSTypeHosts} {
+		transport := newTestTransport(netip.MustParseAddr("198.18.0.1"))
+		transport.TransportAdapter = NewTransportAdapter(transportType, transportType, nil)
+		response := testExchange(t, client, transport, "www.example.com.", adapter.DNSQueryOptions{})
+		require.Equal(t, dns.RcodeSuccess, response.Rcode, transportType)
+		require.False(t, response.AuthenticatedData)
+		require.Equal(t, []netip.Addr{netip.MustParseAddr("198.18.0.1")}, MessageToAddresses(response))
+		require.Equal(t, int32(1), transport.requests.Load(), transportType)
+	}
+}
+
+func TestDNSSECRequireNotCached(t *testing.T) {
+	t.Parallel()
+	transport := newTestTransport(netip.MustParseAddr("1.1.1.1"))
+	transport.TransportAdapter = NewTransportAdapter(C.DNSTypeHosts, "hosts", nil)
+	client := NewClient(ClientOptions{})
+	for i := 0; i < 2; i++ {
+		response := testExchange(t, client, transport, "example.com.", adapter.DNSQueryOptions{RequireDNSSEC: true})
+		require.Equal(t, dns.RcodeSuccess, response.Rcode)
+	}
+	require.Equal(t, int32(2), transport.requests.Load())
+	response, _, _, _ := client.loadResponse(testQuery().Question[0], transport)
+	require.Nil(t, response)
+}
@@ -0,0 +1,362 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	defaultQueryLogCapacity   = 1000
+	defaultQueryLogMaxSize    = 10 * 1024 * 1024
+	defaultQueryLogMaxBackups = 3
+	// queryLogFileQueueSize bounds entries waiting to be written to the
+	// file, new entries are dropped when exceeded.
+	queryLogFileQueueSize = 1024
+	// queryLogMaxCounters bounds each counter map, the least counted half
+	// is dropped when exceeded.
+	queryLogMaxCounters = 10000
+)
+
+var _ adapter.DNSQueryLog = (*QueryLog)(nil)
+
+// QueryLog keeps recent queries in a ring buffer, optionally appending them
+// to a rotated JSON lines file in background, and counts queries by domain
+// and client. Only queries exchanged by the DNS router are recorded, domains
+// resolved internally for routing and outbounds are not.
+type QueryLog struct {
+	logger     logger.ContextLogger
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	access         sync.Mutex
+	entries        []adapter.DNSQueryLogEntry
+	next           int
+	full           bool
+	queries        uint64
+	cached         uint64
+	blocked        uint64
+	domains        map[string]uint64
+	blockedDomains map[string]uint64
+	clients        map[string]uint64
+
+	fileQueue   chan adapter.DNSQueryLogEntry
+	fileDropped atomic.Uint64
+	fileDone    chan struct{}
+	fileClosed  chan struct{}
+	closeOnce   sync.Once
+	file        *os.File
+	fileSize    int64
+}
+
+func NewQueryLog(logger logger.ContextLogger, options option.DNSQueryLogOptions) *QueryLog {
+	capacity := int(options.Capacity)
+	if capacity == 0 {
+		capacity = defaultQueryLogCapacity
+	}
+	maxSize := int64(options.MaxSize.Value())
+	if maxSize == 0 {
+		maxSize = defaultQueryLogMaxSize
+	}
+	maxBackups := int(options.MaxBackups)
+	if maxBackups == 0 {
+		maxBackups = defaultQueryLogMaxBackups
+	}
+	return &QueryLog{
+		logger:         logger,
+		path:           options.Path,
+		maxSize:        maxSize,
+		maxBackups:     maxBackups,
+		entries:        make([]adapter.DNSQueryLogEntry, capacity),
+		domains:        make(map[string]uint64),
+		blockedDomains: make(map[string]uint64),
+		clients:        make(map[string]uint64),
+	}
+}
+
+func (l *QueryLog) Start() error {
+	if l.path == "" {
+		return nil
+	}
+	err := os.MkdirAll(filepath.Dir(l.path), 0o755)
+	if err != nil {
+		return E.Cause(err, "create query log directory")
+	}
+	err = l.openFile()
+	if err != nil {
+		return err
+	}
+	l.fileQueue = make(chan adapter.DNSQueryLogEntry, queryLogFileQueueSize)
+	l.fileDone = make(chan struct{})
+	l.fileClosed = make(chan struct{})
+	go l.loopWrite()
+	return nil
+}
+
+// Close stops the writer after queued entries are written.
+func (l *QueryLog) Close() error {
+	if l.fileQueue == nil {
+		return nil
+	}
+	l.closeOnce.Do(func() {
+		close(l.fileDone)
+	})
+	<-l.fileClosed
+	return nil
+}
+
+func (l *QueryLog) loopWrite() {
+	defer close(l.fileClosed)
+	for {
+		select {
+		case entry := <-l.fileQueue:
+			l.writeEntry(entry)
+		case <-l.fileDone:
+			for {
+				select {
+				case entry := <-l.fileQueue:
+					l.writeEntry(entry)
+				default:
+					if l.file != nil {
+						err := l.file.Close()
+						if err != nil {
+							l.logger.Warn(E.Cause(err, "close query log"))
+						}
+						l.file = nil
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+func (l *QueryLog) writeEntry(entry adapter.DNSQueryLogEntry) {
+	if dropped := l.fileDropped.Swap(0); dropped > 0 {
+		l.logger.Warn("query log file is too slow, dropped ", dropped, " entries")
+	}
+	err := l.writeFile(entry)
+	if err != nil {
+		l.logger.Warn(E.Cause(err, "write query log"))
+	}
+}
+
+func (l *QueryLog) openFile() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return E.Cause(err, "open query log file")
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	l.file = file
+	l.fileSize = info.Size()
+	return nil
+}
+
+func (l *QueryLog) Record(entry adapter.DNSQueryLogEntry) {
+	l.access.Lock()
+	l.entries[l.next] = entry
+	l.next++
+	if l.next == len(l.entries) {
+		l.next = 0
+		l.full = true
+	}
+	l.queries++
+	if entry.Cached {
+		l.cached++
+	}
+	increaseCounter(l.domains, entry.Domain)
+	if entry.Blocked {
+		l.blocked++
+		increaseCounter(l.blockedDomains, entry.Domain)
+	}
+	client := entry.Client
+	if client == "" {
+		client = entry.Inbound
+	}
+	if client != "" {
+		increaseCounter(l.clients, client)
+	}
+	l.access.Unlock()
+	if l.fileQueue != nil {
+		select {
+		case l.fileQueue <- entry:
+		default:
+			l.fileDropped.Add(1)
+		}
+	}
+}
+
+func (l *QueryLog) writeFile(entry adapter.DNSQueryLogEntry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	content = append(content, '\n')
+	if l.file == nil {
+		return nil
+	}
+	if l.fileSize > 0 && l.fileSize+int64(len(content)) > l.maxSize {
+		err = l.rotate()
+		if err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(content)
+	l.fileSize += int64(n)
+	return err
+}
+
+// rotate renames the file to path.1, shifting existing backups and
+// removing the ones beyond maxBackups.
+func (l *QueryLog) rotate() error {
+	err := l.file.Close()
+	l.file = nil
+	if err != nil {
+		return err
+	}
+	os.Remove(l.path + "." + strconv.Itoa(l.maxBackups))
+	for i := l.maxBackups - 1; i > 0; i-- {
+		os.Rename(l.path+"."+strconv.Itoa(i), l.path+"."+strconv.Itoa(i+1))
+	}
+	err = os.Rename(l.path, l.path+".1")
+	if err != nil {
+		return err
+	}
+	return l.openFile()
+}
+
+func (l *QueryLog) Entries(filter adapter.DNSQueryLogFilter) []adapter.DNSQueryLogEntry {
+	l.access.Lock()
+	defer l.access.Unlock()
+	count := l.next
+	if l.full {
+		count = len(l.entries)
+	}
+	var entries []adapter.DNSQueryLogEntry
+	for i := 0; i < count; i++ {
+		entry := l.entries[(l.next-1-i+len(l.entries))%len(l.entries)]
+		if filter.Domain != "" && !strings.Contains(entry.Domain, filter.Domain) {
+			continue
+		}
+		if filter.Client != "" && entry.Client != filter.Client {
+			continue
+		}
+		entries = append(entries, entry)
+		if filter.Limit > 0 && len(entries) == filter.Limit {
+			break
+		}
+	}
+	return entries
+}
+
+func (l *QueryLog) Stats(top int) adapter.DNSQueryStats {
+	l.access.Lock()
+	defer l.access.Unlock()
+	return adapter.DNSQueryStats{
+		Queries:    l.queries,
+		Cached:     l.cached,
+		Blocked:    l.blocked,
+		TopDomains: topCounters(l.domains, top),
+		TopBlocked: topCounters(l.blockedDomains, top),
+		Clients:    topCounters(l.clients, top),
+	}
+}
+
+func (l *QueryLog) Reset() {
+	l.access.Lock()
+	defer l.access.Unlock()
+	clear(l.entries)
+	l.next = 0
+	l.full = false
+	l.queries = 0
+	l.cached = 0
+	l.blocked = 0
+	clear(l.domains)
+	clear(l.blockedDomains)
+	clear(l.clients)
+}
+
+func increaseCounter(counters map[string]uint64, key string) {
+	counters[key]++
+	if len(counters) <= queryLogMaxCounters {
+		return
+	}
+	for _, counter := range topCounters(counters, 0)[queryLogMaxCounters/2:] {
+		delete(counters, counter.Name)
+	}
+}
+
+// topCounters returns counters sorted by count, limited to top if not zero.
+func topCounters(counters map[string]uint64, top int) []adapter.DNSQueryCount {
+	result := make([]adapter.DNSQueryCount, 0, len(counters))
+	for name, count := range counters {
+		result = append(result, adapter.DNSQueryCount{Name: name, Count: count})
+	}
+	slices.SortFunc(result, func(a, b adapter.DNSQueryCount) int {
+		if a.Count != b.Count {
+			if a.Count > b.Count {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	if top > 0 && len(result) > top {
+		result = result[:top]
+	}
+	return result
+}
+
+type queryLogContextKey struct{}
+
+func contextWithQueryLogEntry(ctx context.Context, entry *adapter.DNSQueryLogEntry) context.Context {
+	return context.WithValue(ctx, queryLogContextKey{}, entry)
+}
+
+func queryLogEntryFromContext(ctx context.Context) *adapter.DNSQueryLogEntry {
+	entry, _ := ctx.Value(queryLogContextKey{}).(*adapter.DNSQueryLogEntry)
+	return entry
+}
+
+// markCached marks the query in context as answered from cache.
+func markCached(ctx context.Context) {
+	if entry := queryLogEntryFromContext(ctx); entry != nil {
+		entry.Cached = true
+	}
+}
+
+func formatAnswers(response *dns.Msg) []string {
+	var answers []string
+	for _, record := range response.Answer {
+		switch answer := record.(type) {
+		case *dns.A:
+			answers = append(answers, answer.A.String())
+		case *dns.AAAA:
+			answers = append(answers, answer.AAAA.String())
+		case *dns.CNAME:
+			answers = append(answers, FqdnToDomain(answer.Target))
+		case *dns.RRSIG:
+		default:
+			answers = append(answers, dns.TypeToString[record.Header().Rrtype]+" "+strings.TrimPrefix(record.String(), record.Header().String()))
+		}
+	}
+	return answers
+}
@@ -0,0 +1,96 @@
+package dns
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/byteformats"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryLogEntries(t *testing.T) {
+	t.Parallel()
+	queryLog := NewQueryLog(log.NewNOPFactory().Logger(), option.DNSQueryLogOptions{Capacity: 3})
+	for i := range 5 {
+		queryLog.Record(adapter.DNSQueryLogEntry{
+			Domain:  "domain" + strconv.Itoa(i) + ".com",
+			Client:  "client" + strconv.Itoa(i%2),
+			Blocked: i == 4,
+			Cached:  i == 3,
+		})
+	}
+	entries := queryLog.Entries(adapter.DNSQueryLogFilter{})
+	require.Len(t, entries, 3)
+	require.Equal(t, "domain4.com", entries[0].Domain)
+	require.Equal(t, "domain2.com", entries[2].Domain)
+	entries = queryLog.Entries(adapter.DNSQueryLogFilter{Client: "client0"})
+	require.Len(t, entries, 2)
+	entries = queryLog.Entries(adapter.DNSQueryLogFilter{Domain: "domain3", Limit: 1})
+	require.Len(t, entries, 1)
+	require.Equal(t, "domain3.com", entries[0].Domain)
+
+	stats := queryLog.Stats(1)
+	require.Equal(t, uint64(5), stats.Queries)
+	require.Equal(t, uint64(1), stats.Cached)
+	require.Equal(t, uint64(1), stats.Blocked)
+	require.Equal(t, []adapter.DNSQueryCount{{Name: "domain4.com", Count: 1}}, stats.TopBlocked)
+	require.Equal(t, []adapter.DNSQueryCount{{Name: "client0", Count: 3}}, stats.Clients)
+
+	queryLog.Reset()
+	require.Empty(t, queryLog.Entries(adapter.DNSQueryLogFilter{}))
+	require.Zero(t, queryLog.Stats(0).Queries)
+}
+
+func TestQueryLogRotate(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "query.log")
+	var maxSize byteformats.MemoryBytes
+	require.NoError(t, maxSize.UnmarshalJSON([]byte(`"100B"`)))
+	queryLog := NewQueryLog(log.NewNOPFactory().Logger(), option.DNSQueryLogOptions{
+		Path:       path,
+		MaxSize:    &maxSize,
+		MaxBackups: 1,
+	})
+	require.NoError(t, queryLog.Start())
+	for range 10 {
+		queryLog.Record(adapter.DNSQueryLogEntry{Domain: "example.com"})
+	}
+	require.NoError(t, queryLog.Close())
+	for _, name := range []string{path, path + ".1"} {
+		info, err := os.Stat(name)
+		require.NoError(t, err)
+		require.LessOrEqual(t, info.Size(), int64(maxSize.Value()))
+	}
+	require.NoFileExists(t, path+".2")
+}
+
+func TestQueryLogFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "query.log")
+	queryLog := NewQueryLog(log.NewNOPFactory().Logger(), option.DNSQueryLogOptions{Path: path})
+	require.NoError(t, queryLog.Start())
+	for i := range 3 {
+		queryLog.Record(adapter.DNSQueryLogEntry{Domain: "domain" + strconv.Itoa(i) + ".com"})
+	}
+	require.NoError(t, queryLog.Close())
+	require.NoError(t, queryLog.Close())
+	queryLog.Record(adapter.DNSQueryLogEntry{Domain: "closed.com"})
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 3)
+	for i, line := range lines {
+		var entry adapter.DNSQueryLogEntry
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		require.Equal(t, "domain"+strconv.Itoa(i)+".com", entry.Domain)
+	}
+	require.Len(t, queryLog.Entries(adapter.DNSQueryLogFilter{}), 4)
+}
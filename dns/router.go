@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/netip"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
@@ -34,9 +35,12 @@ type Router struct {
 	transport             adapter.DNSTransportManager
 	outbound              adapter.OutboundManager
 	client                adapter.DNSClient
+	access                sync.RWMutex
 	rules                 []adapter.DNSRule
 	defaultDomainStrategy C.DomainStrategy
+	ruleStatistics        bool
 	dnsReverseMapping     freelru.Cache[netip.Addr, string]
+	queryLog              *QueryLog
 	platformInterface     adapter.PlatformInterface
 }
 
@@ -48,13 +52,23 @@ func NewRouter(ctx context.Context, logFactory log.Factory, options option.DNSOp
 		outbound:              service.FromContext[adapter.OutboundManager](ctx),
 		rules:                 make([]adapter.DNSRule, 0, len(options.Rules)),
 		defaultDomainStrategy: C.DomainStrategy(options.Strategy),
+		ruleStatistics:        options.RuleStatistics,
 	}
+	dnssecOptions := common.PtrValueOrDefault(options.DNSClientOptions.DNSSEC)
 	router.client = NewClient(ClientOptions{
-		DisableCache:     options.DNSClientOptions.DisableCache,
-		DisableExpire:    options.DNSClientOptions.DisableExpire,
-		IndependentCache: options.DNSClientOptions.IndependentCache,
-		CacheCapacity:    options.DNSClientOptions.CacheCapacity,
-		ClientSubnet:     options.DNSClientOptions.ClientSubnet.Build(netip.Prefix{}),
+		DisableCache:       options.DNSClientOptions.DisableCache,
+		DisableExpire:      options.DNSClientOptions.DisableExpire,
+		IndependentCache:   options.DNSClientOptions.IndependentCache,
+		CacheCapacity:      options.DNSClientOptions.CacheCapacity,
+		ServeStale:         options.DNSClientOptions.ServeStale,
+		StaleTTL:           time.Duration(options.DNSClientOptions.StaleTTL),
+		StaleAnswerTimeout: time.Duration(options.DNSClientOptions.StaleAnswerTimeout),
+		Optimistic:         options.DNSClientOptions.OptimisticCache,
+		PrefetchHits:       options.DNSClientOptions.PrefetchHits,
+		ClientSubnet:       options.DNSClientOptions.ClientSubnet.Build(netip.Prefix{}),
+		DNSSEC:             dnssecOptions.Enabled,
+		DNSSECLogOnly:      dnssecOptions.LogOnly,
+		TrustAnchors:       common.Map(dnssecOptions.TrustAnchors, option.DNSRecordOptions.Build),
 		RDRC: func() adapter.RDRCStore {
 			cacheFile := service.FromContext[adapter.CacheFile](ctx)
 			if cacheFile == nil {
@@ -65,11 +79,24 @@ func NewRouter(ctx context.Context, logFactory log.Factory, options option.DNSOp
 			}
 			return cacheFile
 		},
+		Store: func() adapter.DNSCacheStore {
+			cacheFile := service.FromContext[adapter.CacheFile](ctx)
+			if cacheFile == nil {
+				return nil
+			}
+			if !cacheFile.StoreDNS() {
+				return nil
+			}
+			return cacheFile
+		},
 		Logger: router.logger,
 	})
 	if options.ReverseMapping {
 		router.dnsReverseMapping = common.Must1(freelru.NewSharded[netip.Addr, string](1024, maphash.NewHasher[netip.Addr]().Hash32))
 	}
+	if options.QueryLog != nil && options.QueryLog.Enabled {
+		router.queryLog = NewQueryLog(router.logger, *options.QueryLog)
+	}
 	return router
 }
 
@@ -81,9 +108,27 @@ func (r *Router) Initialize(rules []option.DNSRule) error {
 		}
 		r.rules = append(r.rules, dnsRule)
 	}
+	r.registerNAT64Prefixes(r.rules)
 	return nil
 }
 
+// registerNAT64Prefixes registers the DNS64 prefixes of rules before any
+// synthesis, so that addresses clients cached before a restart are mapped.
+func (r *Router) registerNAT64Prefixes(rules []adapter.DNSRule) {
+	for _, rule := range rules {
+		var prefix netip.Prefix
+		switch action := rule.Action().(type) {
+		case *R.RuleActionDNSRoute:
+			prefix = action.DNS64Prefix
+		case *R.RuleActionDNSRouteOptions:
+			prefix = action.DNS64Prefix
+		}
+		if prefix.IsValid() {
+			r.client.RegisterNAT64Prefix(prefix)
+		}
+	}
+}
+
 func (r *Router) Start(stage adapter.StartStage) error {
 	monitor := taskmonitor.New(r.logger, C.StartTimeout)
 	switch stage {
@@ -92,6 +137,13 @@ func (r *Router) Start(stage adapter.StartStage) error {
 		r.client.Start()
 		monitor.Finish()
 
+		if r.queryLog != nil {
+			err := r.queryLog.Start()
+			if err != nil {
+				return E.Cause(err, "initialize DNS query log")
+			}
+		}
+
 		for i, rule := range r.rules {
 			monitor.Start("initialize DNS rule[", i, "]")
 			err := rule.Start()
@@ -114,25 +166,96 @@ func (r *Router) Close() error {
 		})
 		monitor.Finish()
 	}
+	err = E.Append(err, r.client.Close(), func(err error) error {
+		return E.Cause(err, "save DNS cache")
+	})
+	if r.queryLog != nil {
+		err = E.Append(err, r.queryLog.Close(), func(err error) error {
+			return E.Cause(err, "close DNS query log")
+		})
+	}
 	return err
 }
 
-func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, ruleIndex int, isAddressQuery bool, options *adapter.DNSQueryOptions) (adapter.DNSTransport, adapter.DNSRule, int) {
+// PrepareReload creates and starts new DNS rules with ctx, which must resolve
+// rule-sets the rules are going to use. The returned function swaps them in.
+func (r *Router) PrepareReload(ctx context.Context, rules []option.DNSRule) (func(), error) {
+	newRules := make([]adapter.DNSRule, 0, len(rules))
+	closeRules := func(rules []adapter.DNSRule) {
+		for i, rule := range rules {
+			err := rule.Close()
+			if err != nil {
+				r.logger.Error(E.Cause(err, "close dns rule[", i, "]"))
+			}
+		}
+	}
+	for i, ruleOptions := range rules {
+		dnsRule, err := R.NewDNSRule(ctx, r.logger, ruleOptions, true)
+		if err != nil {
+			closeRules(newRules)
+			return nil, E.Cause(err, "parse dns rule[", i, "]")
+		}
+		newRules = append(newRules, dnsRule)
+	}
+	for i, rule := range newRules {
+		err := rule.Start()
+		if err != nil {
+			closeRules(newRules)
+			return nil, E.Cause(err, "initialize DNS rule[", i, "]")
+		}
+	}
+	return func() {
+		r.registerNAT64Prefixes(newRules)
+		r.access.Lock()
+		oldRules := r.rules
+		r.rules = newRules
+		r.access.Unlock()
+		closeRules(oldRules)
+		r.ClearCache()
+	}, nil
+}
+
+// matchDNS matches query rules, or match_response rules against response if not nil.
+func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, ruleIndex int, isAddressQuery bool, response *mDNS.Msg, options *adapter.DNSQueryOptions) (adapter.DNSTransport, adapter.DNSRule, int) {
 	metadata := adapter.ContextFrom(ctx)
 	if metadata == nil {
 		panic("no context")
 	}
+	if response != nil {
+		responseMetadata := *metadata
+		responseMetadata.DNSResponse = response
+		responseMetadata.DestinationAddresses = MessageToAddresses(response)
+		metadata = &responseMetadata
+	}
+	tracer := ruleTracerFromContext(ctx)
+	r.access.RLock()
+	rules := r.rules
+	r.access.RUnlock()
 	var currentRuleIndex int
 	if ruleIndex != -1 {
 		currentRuleIndex = ruleIndex + 1
 	}
-	for ; currentRuleIndex < len(r.rules); currentRuleIndex++ {
-		currentRule := r.rules[currentRuleIndex]
-		if currentRule.WithAddressLimit() && !isAddressQuery {
+	for ; currentRuleIndex < len(rules); currentRuleIndex++ {
+		currentRule := rules[currentRuleIndex]
+		if currentRule.MatchResponse() != (response != nil) {
+			continue
+		}
+		if response == nil && currentRule.WithAddressLimit() && !isAddressQuery {
+			tracer.add(currentRuleIndex, currentRule, false, "skipped for non-address query")
 			continue
 		}
 		metadata.ResetRuleCache()
-		if currentRule.Match(metadata) {
+		var matched bool
+		if response != nil {
+			matched = currentRule.MatchAddressLimit(metadata)
+		} else {
+			matched = currentRule.Match(metadata)
+		}
+		tracer.add(currentRuleIndex, currentRule, matched, "")
+		if matched {
+			if r.ruleStatistics && tracer == nil {
+				currentRule.Statistics().Hit()
+			}
 			displayRuleIndex := currentRuleIndex
 			if displayRuleIndex != -1 {
 				displayRuleIndex += displayRuleIndex + 1
@@ -148,10 +271,12 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, ruleIndex int,
 				transport, loaded := r.transport.Transport(action.Server)
 				if !loaded {
 					r.logger.ErrorContext(ctx, "transport not found: ", action.Server)
+					tracer.note("transport not found: " + action.Server)
 					continue
 				}
 				isFakeIP := transport.Type() == C.DNSTypeFakeIP
 				if isFakeIP && !allowFakeIP {
+					tracer.note("fakeip skipped")
 					continue
 				}
 				if action.Strategy != C.DomainStrategyAsIS {
@@ -166,6 +291,12 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, ruleIndex int,
 				if action.ClientSubnet.IsValid() {
 					options.ClientSubnet = action.ClientSubnet
 				}
+				if action.RequireDNSSEC {
+					options.RequireDNSSEC = true
+				}
+				if action.DNS64Prefix.IsValid() {
+					options.DNS64Prefix = action.DNS64Prefix
+				}
 				if legacyTransport, isLegacy := transport.(adapter.LegacyDNSTransport); isLegacy {
 					if options.Strategy == C.DomainStrategyAsIS {
 						options.Strategy = legacyTransport.LegacyStrategy()
@@ -188,6 +319,12 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, ruleIndex int,
 				if action.ClientSubnet.IsValid() {
 					options.ClientSubnet = action.ClientSubnet
 				}
+				if action.RequireDNSSEC {
+					options.RequireDNSSEC = true
+				}
+				if action.DNS64Prefix.IsValid() {
+					options.DNS64Prefix = action.DNS64Prefix
+				}
 			case *R.RuleActionReject:
 				return nil, currentRule, currentRuleIndex
 			case *R.RuleActionPredefined:
@@ -195,6 +332,9 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, ruleIndex int,
 			}
 		}
 	}
+	if response != nil {
+		return nil, nil, -1
+	}
 	transport := r.transport.Default()
 	if legacyTransport, isLegacy := transport.(adapter.LegacyDNSTransport); isLegacy {
 		if options.Strategy == C.DomainStrategyAsIS {
@@ -220,6 +360,36 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg, options adapte
 		}
 		return &responseMessage, nil
 	}
+	if r.queryLog == nil {
+		return r.exchange(ctx, message, options)
+	}
+	question := message.Question[0]
+	entry := &adapter.DNSQueryLogEntry{
+		Time:      time.Now(),
+		Domain:    FqdnToDomain(question.Name),
+		QueryType: mDNS.Type(question.Qtype).String(),
+	}
+	if metadata := adapter.ContextFrom(ctx); metadata != nil {
+		entry.Inbound = metadata.Inbound
+		if metadata.Source.Addr.IsValid() {
+			entry.Client = metadata.Source.Addr.String()
+		}
+	}
+	response, err := r.exchange(contextWithQueryLogEntry(ctx, entry), message, options)
+	entry.Latency = time.Since(entry.Time)
+	if response != nil {
+		entry.Rcode = mDNS.RcodeToString[response.Rcode]
+		entry.Answers = formatAnswers(response)
+	} else if errors.Is(err, tun.ErrDrop) {
+		entry.Rcode = "DROP"
+	} else {
+		entry.Rcode = mDNS.RcodeToString[mDNS.RcodeServerFailure]
+	}
+	r.queryLog.Record(*entry)
+	return response, err
+}
+
+func (r *Router) exchange(ctx context.Context, message *mDNS.Msg, options adapter.DNSQueryOptions) (*mDNS.Msg, error) {
 	r.logger.DebugContext(ctx, "exchange ", FormatQuestion(message.Question[0].String()))
 	var (
 		response  *mDNS.Msg
@@ -250,6 +420,9 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg, options adapte
 		if options.Strategy == C.DomainStrategyAsIS {
 			options.Strategy = r.defaultDomainStrategy
 		}
+		if entry := queryLogEntryFromContext(ctx); entry != nil {
+			entry.Server = transport.Tag()
+		}
 		response, err = r.client.Exchange(ctx, transport, message, options, nil)
 	} else {
 		var (
@@ -260,25 +433,22 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg, options adapte
 		for {
 			dnsCtx := adapter.OverrideContext(ctx)
 			dnsOptions := options
-			transport, rule, ruleIndex = r.matchDNS(ctx, true, ruleIndex, isAddressQuery(message), &dnsOptions)
+			transport, rule, ruleIndex = r.matchDNS(ctx, true, ruleIndex, isAddressQuery(message), nil, &dnsOptions)
+			entry := queryLogEntryFromContext(ctx)
+			if entry != nil {
+				entry.Rule = ""
+				entry.Server = ""
+				if rule != nil {
+					entry.Rule = F.ToString(rule, " => ", rule.Action())
+				}
+				if transport != nil {
+					entry.Server = transport.Tag()
+				}
+			}
 			if rule != nil {
-				switch action := rule.Action().(type) {
-				case *R.RuleActionReject:
-					switch action.Method {
-					case C.RuleActionRejectMethodDefault:
-						return &mDNS.Msg{
-							MsgHdr: mDNS.MsgHdr{
-								Id:       message.Id,
-								Rcode:    mDNS.RcodeRefused,
-								Response: true,
-							},
-							Question: []mDNS.Question{message.Question[0]},
-						}, nil
-					case C.RuleActionRejectMethodDrop:
-						return nil, tun.ErrDrop
-					}
-				case *R.RuleActionPredefined:
-					return action.Response(message), nil
+				response, err = actionResponse(message, rule.Action(), entry)
+				if response != nil || err != nil {
+					return response, err
 				}
 			}
 			responseCheck := addressLimitResponseCheck(rule, metadata)
@@ -305,6 +475,12 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg, options adapte
 			}
 			break
 		}
+		if err == nil && transport.Type() != C.DNSTypeFakeIP {
+			response, transport, err = r.exchangeResponse(ctx, message, response, transport, options)
+			if transport == nil {
+				return response, err
+			}
+		}
 	}
 	if err != nil {
 		return nil, err
@@ -324,6 +500,7 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg, options adapte
 	return response, nil
 }
 
+// Lookup resolves domains for internal use, which are not recorded by the query log.
 func (r *Router) Lookup(ctx context.Context, domain string, options adapter.DNSQueryOptions) ([]netip.Addr, error) {
 	var (
 		responseAddrs []netip.Addr
@@ -374,26 +551,13 @@ func (r *Router) Lookup(ctx context.Context, domain string, options adapter.DNSQ
 		for {
 			dnsCtx := adapter.OverrideContext(ctx)
 			dnsOptions := options
-			transport, rule, ruleIndex = r.matchDNS(ctx, false, ruleIndex, true, &dnsOptions)
+			transport, rule, ruleIndex = r.matchDNS(ctx, false, ruleIndex, true, nil, &dnsOptions)
 			if rule != nil {
 				switch action := rule.Action().(type) {
 				case *R.RuleActionReject:
 					return nil, &R.RejectedError{Cause: action.Error(ctx)}
 				case *R.RuleActionPredefined:
-					responseAddrs = nil
-					if action.Rcode != mDNS.RcodeSuccess {
-						err = RcodeError(action.Rcode)
-					} else {
-						err = nil
-						for _, answer := range action.Answer {
-							switch record := answer.(type) {
-							case *mDNS.A:
-								responseAddrs = append(responseAddrs, M.AddrFromIP(record.A))
-							case *mDNS.AAAA:
-								responseAddrs = append(responseAddrs, M.AddrFromIP(record.AAAA))
-							}
-						}
-					}
+					responseAddrs, err = predefinedAddresses(action)
 					goto response
 				}
 			}
@@ -407,6 +571,7 @@ func (r *Router) Lookup(ctx context.Context, domain string, options adapter.DNSQ
 			}
 			printResult()
 		}
+		responseAddrs, err = r.lookupResponse(ctx, domain, responseAddrs, err, options)
 	}
 response:
 	printResult()
@@ -416,6 +581,137 @@ response:
 	return responseAddrs, err
 }
 
+// exchangeResponse evaluates match_response rules against response, querying
+// again with the server of matched route rules. The returned transport is nil
+// if the response is replaced by a reject or predefined action.
+func (r *Router) exchangeResponse(ctx context.Context, message *mDNS.Msg, response *mDNS.Msg, transport adapter.DNSTransport, options adapter.DNSQueryOptions) (*mDNS.Msg, adapter.DNSTransport, error) {
+	var (
+		rule      adapter.DNSRule
+		ruleIndex = -1
+		err       error
+	)
+	for {
+		dnsOptions := options
+		var newTransport adapter.DNSTransport
+		newTransport, rule, ruleIndex = r.matchDNS(ctx, false, ruleIndex, isAddressQuery(message), response, &dnsOptions)
+		if rule == nil {
+			return response, transport, nil
+		}
+		entry := queryLogEntryFromContext(ctx)
+		if entry != nil {
+			entry.Rule = F.ToString(rule, " => ", rule.Action())
+		}
+		if newTransport == nil {
+			response, err = actionResponse(message, rule.Action(), entry)
+			return response, nil, err
+		}
+		if entry != nil {
+			entry.Server = newTransport.Tag()
+		}
+		if dnsOptions.Strategy == C.DomainStrategyAsIS {
+			dnsOptions.Strategy = r.defaultDomainStrategy
+		}
+		transport = newTransport
+		response, err = r.client.Exchange(adapter.OverrideContext(ctx), transport, message, dnsOptions, nil)
+		if err != nil {
+			r.logger.ErrorContext(ctx, E.Cause(err, "exchange failed for ", FormatQuestion(message.Question[0].String())))
+			return nil, transport, err
+		}
+	}
+}
+
+// lookupResponse evaluates match_response rules against the result of a lookup.
+func (r *Router) lookupResponse(ctx context.Context, domain string, responseAddrs []netip.Addr, err error, options adapter.DNSQueryOptions) ([]netip.Addr, error) {
+	var (
+		transport adapter.DNSTransport
+		rule      adapter.DNSRule
+		ruleIndex = -1
+	)
+	for {
+		var response *mDNS.Msg
+		var rcodeError RcodeError
+		if errors.As(err, &rcodeError) {
+			response = &mDNS.Msg{MsgHdr: mDNS.MsgHdr{Response: true, Rcode: int(rcodeError)}}
+		} else if err != nil {
+			return responseAddrs, err
+		} else {
+			response = lookupMessage(domain, responseAddrs)
+		}
+		dnsOptions := options
+		transport, rule, ruleIndex = r.matchDNS(ctx, false, ruleIndex, true, response, &dnsOptions)
+		if rule == nil {
+			return responseAddrs, err
+		}
+		if transport == nil {
+			switch action := rule.Action().(type) {
+			case *R.RuleActionReject:
+				return nil, &R.RejectedError{Cause: action.Error(ctx)}
+			case *R.RuleActionPredefined:
+				return predefinedAddresses(action)
+			}
+		}
+		if dnsOptions.Strategy == C.DomainStrategyAsIS {
+			dnsOptions.Strategy = r.defaultDomainStrategy
+		}
+		responseAddrs, err = r.client.Lookup(adapter.OverrideContext(ctx), transport, domain, dnsOptions, nil)
+	}
+}
+
+// lookupMessage builds a response from lookup results for matching.
+func lookupMessage(domain string, addresses []netip.Addr) *mDNS.Msg {
+	name := mDNS.Fqdn(domain)
+	response := FixedResponse(0, mDNS.Question{Name: name, Qtype: mDNS.TypeA, Qclass: mDNS.ClassINET}, addresses, 0)
+	response.Answer = append(response.Answer, FixedResponse(0, mDNS.Question{Name: name, Qtype: mDNS.TypeAAAA, Qclass: mDNS.ClassINET}, addresses, 0).Answer...)
+	return response
+}
+
+func predefinedAddresses(action *R.RuleActionPredefined) ([]netip.Addr, error) {
+	if action.Rcode != mDNS.RcodeSuccess {
+		return nil, RcodeError(action.Rcode)
+	}
+	var addresses []netip.Addr
+	for _, answer := range action.Answer {
+		switch record := answer.(type) {
+		case *mDNS.A:
+			addresses = append(addresses, M.AddrFromIP(record.A))
+		case *mDNS.AAAA:
+			addresses = append(addresses, M.AddrFromIP(record.AAAA))
+		}
+	}
+	return addresses, nil
+}
+
+// actionResponse returns the response of reject and predefined actions,
+// or nil if the action routes the query.
+func actionResponse(message *mDNS.Msg, action adapter.RuleAction, entry *adapter.DNSQueryLogEntry) (*mDNS.Msg, error) {
+	switch action := action.(type) {
+	case *R.RuleActionReject:
+		if entry != nil {
+			entry.Blocked = true
+		}
+		switch action.Method {
+		case C.RuleActionRejectMethodDefault:
+			return &mDNS.Msg{
+				MsgHdr: mDNS.MsgHdr{
+					Id:       message.Id,
+					Rcode:    mDNS.RcodeRefused,
+					Response: true,
+				},
+				Question: []mDNS.Question{message.Question[0]},
+			}, nil
+		case C.RuleActionRejectMethodDrop:
+			return nil, tun.ErrDrop
+		}
+	case *R.RuleActionPredefined:
+		response := action.Response(message)
+		if entry != nil {
+			entry.Blocked = response.Rcode != mDNS.RcodeSuccess || len(response.Answer) == 0
+		}
+		return response, nil
+	}
+	return nil, nil
+}
+
 func isAddressQuery(message *mDNS.Msg) bool {
 	for _, question := range message.Question {
 		if question.Qtype == mDNS.TypeA || question.Qtype == mDNS.TypeAAAA || question.Qtype == mDNS.TypeHTTPS {
@@ -437,6 +733,13 @@ func addressLimitResponseCheck(rule adapter.DNSRule, metadata *adapter.InboundCo
 	}
 }
 
+func (r *Router) QueryLog() adapter.DNSQueryLog {
+	if r.queryLog == nil {
+		return nil
+	}
+	return r.queryLog
+}
+
 func (r *Router) ClearCache() {
 	r.client.ClearCache()
 	if r.platformInterface != nil {
@@ -444,6 +747,12 @@ func (r *Router) ClearCache() {
 	}
 }
 
+func (r *Router) Rules() []adapter.DNSRule {
+	r.access.RLock()
+	defer r.access.RUnlock()
+	return r.rules
+}
+
 func (r *Router) LookupReverseMapping(ip netip.Addr) (string, bool) {
 	if r.dnsReverseMapping == nil {
 		return "", false
@@ -452,6 +761,10 @@ func (r *Router) LookupReverseMapping(ip netip.Addr) (string, bool) {
 	return domain, loaded
 }
 
+func (r *Router) LookupNAT64(ip netip.Addr) (netip.Addr, bool) {
+	return r.client.LookupNAT64(ip)
+}
+
 func (r *Router) ResetNetwork() {
 	r.ClearCache()
 	for _, transport := range r.transport.Transports() {
@@ -0,0 +1,147 @@
+package dns
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+
+	mDNS "github.com/miekg/dns"
+)
+
+// TestDNS matches metadata against DNS rules the same way queries are
+// matched, without sending them. The destination IP, if any, is used as
+// the response to evaluate address limited and match_response rules,
+// which are reported after query rules.
+func (r *Router) TestDNS(ctx context.Context, metadata adapter.InboundContext) *adapter.DNSTestResult {
+	var responseAddrs []netip.Addr
+	if metadata.Destination.Addr.IsValid() {
+		responseAddrs = []netip.Addr{metadata.Destination.Addr}
+	}
+	if metadata.Domain == "" {
+		metadata.Domain = metadata.Destination.Fqdn
+	}
+	metadata.Destination = M.Socksaddr{}
+	metadata.IPVersion = 0
+	switch metadata.QueryType {
+	case mDNS.TypeA:
+		metadata.IPVersion = 4
+	case mDNS.TypeAAAA:
+		metadata.IPVersion = 6
+	}
+	addressQuery := metadata.QueryType == 0 || metadata.QueryType == mDNS.TypeA || metadata.QueryType == mDNS.TypeAAAA || metadata.QueryType == mDNS.TypeHTTPS
+	tracer := new(ruleTracer)
+	ctx = contextWithRuleTracer(adapter.WithContext(ctx, &metadata), tracer)
+	var (
+		transport adapter.DNSTransport
+		rule      adapter.DNSRule
+		options   adapter.DNSQueryOptions
+		ruleIndex = -1
+	)
+	for {
+		options = adapter.DNSQueryOptions{}
+		transport, rule, ruleIndex = r.matchDNS(ctx, true, ruleIndex, addressQuery, nil, &options)
+		if transport == nil || rule == nil || !rule.WithAddressLimit() {
+			break
+		}
+		if len(responseAddrs) == 0 {
+			tracer.note("response not checked, set ip to simulate the response")
+			break
+		}
+		checkMetadata := metadata
+		checkMetadata.DestinationAddresses = responseAddrs
+		if rule.MatchAddressLimit(&checkMetadata) {
+			break
+		}
+		tracer.reject("response rejected")
+	}
+	if transport != nil && transport.Type() != C.DNSTypeFakeIP && len(responseAddrs) > 0 {
+		response := lookupMessage(metadata.Domain, responseAddrs)
+		responseRuleIndex := -1
+		for {
+			responseOptions := adapter.DNSQueryOptions{}
+			var responseRule adapter.DNSRule
+			var responseTransport adapter.DNSTransport
+			responseTransport, responseRule, responseRuleIndex = r.matchDNS(ctx, false, responseRuleIndex, addressQuery, response, &responseOptions)
+			if responseRule == nil {
+				break
+			}
+			transport, rule, ruleIndex, options = responseTransport, responseRule, responseRuleIndex, responseOptions
+			if transport == nil {
+				break
+			}
+		}
+	}
+	result := &adapter.DNSTestResult{
+		RuleIndex:     -1,
+		Trace:         tracer.trace,
+		RequireDNSSEC: options.RequireDNSSEC,
+		DisableCache:  options.DisableCache,
+	}
+	if options.Strategy != C.DomainStrategyAsIS {
+		result.Strategy = option.DomainStrategy(options.Strategy).String()
+	}
+	if options.DNS64Prefix.IsValid() {
+		result.DNS64Prefix = options.DNS64Prefix.String()
+	}
+	if transport != nil {
+		result.Server = transport.Tag()
+	}
+	if rule != nil {
+		result.RuleIndex = ruleIndex
+		result.Rule = rule.String()
+		result.Action = rule.Action().String()
+	} else {
+		result.Action = C.RuleActionTypeRoute
+	}
+	return result
+}
+
+type ruleTracerContextKey struct{}
+
+// ruleTracer records DNS rules evaluated by matchDNS for TestDNS.
+type ruleTracer struct {
+	trace []adapter.RuleTrace
+}
+
+func contextWithRuleTracer(ctx context.Context, tracer *ruleTracer) context.Context {
+	return context.WithValue(ctx, ruleTracerContextKey{}, tracer)
+}
+
+func ruleTracerFromContext(ctx context.Context) *ruleTracer {
+	tracer, _ := ctx.Value(ruleTracerContextKey{}).(*ruleTracer)
+	return tracer
+}
+
+func (t *ruleTracer) add(index int, rule adapter.DNSRule, matched bool, note string) {
+	if t == nil {
+		return
+	}
+	t.trace = append(t.trace, adapter.RuleTrace{
+		Index:   index,
+		Rule:    rule.String(),
+		Action:  rule.Action().String(),
+		Matched: matched,
+		Note:    note,
+	})
+}
+
+// note annotates the last evaluated rule.
+func (t *ruleTracer) note(note string) {
+	if t == nil || len(t.trace) == 0 {
+		return
+	}
+	t.trace[len(t.trace)-1].Note = note
+}
+
+// reject marks the last evaluated rule as not matched.
+func (t *ruleTracer) reject(note string) {
+	if t == nil || len(t.trace) == 0 {
+		return
+	}
+	t.trace[len(t.trace)-1].Matched = false
+	t.note(note)
+}
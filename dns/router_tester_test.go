@@ -0,0 +1,178 @@
+package dns
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	R "github.com/sagernet/sing-box/route/rule"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+type testTransportManager struct {
+	transports map[string]adapter.DNSTransport
+	fallback   adapter.DNSTransport
+}
+
+func (m *testTransportManager) Start(stage adapter.StartStage) error {
+	return nil
+}
+
+func (m *testTransportManager) Close() error {
+	return nil
+}
+
+func (m *testTransportManager) Transports() []adapter.DNSTransport {
+	return nil
+}
+
+func (m *testTransportManager) Transport(tag string) (adapter.DNSTransport, bool) {
+	transport, loaded := m.transports[tag]
+	return transport, loaded
+}
+
+func (m *testTransportManager) Default() adapter.DNSTransport {
+	return m.fallback
+}
+
+func (m *testTransportManager) FakeIP() adapter.FakeIPTransport {
+	return nil
+}
+
+func (m *testTransportManager) Remove(tag string) error {
+	return E.New("not implemented")
+}
+
+func (m *testTransportManager) Create(ctx context.Context, logger log.ContextLogger, tag string, outboundType string, options any) error {
+	return E.New("not implemented")
+}
+
+func newTestTransportAs(tag string, transportType string) *testTransport {
+	transport := newTestTransport(netip.MustParseAddr("1.1.1.1"))
+	transport.TransportAdapter = NewTransportAdapter(transportType, tag, nil)
+	return transport
+}
+
+func newTestRouter(t *testing.T, rules string) *Router {
+	ruleOptions, err := json.UnmarshalExtendedContext[[]option.DNSRule](context.Background(), []byte(rules))
+	require.NoError(t, err)
+	logger := log.NewNOPFactory().NewLogger("dns")
+	manager := &testTransportManager{
+		transports: map[string]adapter.DNSTransport{
+			"local":  newTestTransportAs("local", C.DNSTypeUDP),
+			"remote": newTestTransportAs("remote", C.DNSTypeUDP),
+			"fakeip": newTestTransportAs("fakeip", C.DNSTypeFakeIP),
+		},
+	}
+	manager.fallback = manager.transports["remote"]
+	router := &Router{
+		logger:    logger,
+		transport: manager,
+	}
+	for i, options := range ruleOptions {
+		rule, err := R.NewDNSRule(context.Background(), logger, options, false)
+		require.NoError(t, err, "rule[", i, "]")
+		router.rules = append(router.rules, rule)
+	}
+	return router
+}
+
+func TestTestDNS(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, `[
+		{"domain": "blocked.example", "action": "reject"},
+		{"domain_suffix": "example.com", "action": "route-options", "require_dnssec": true},
+		{"domain": "fake.example.com", "server": "fakeip"},
+		{"ip_cidr": "10.0.0.0/8", "server": "local"},
+		{"match_response": true, "ip_cidr": "192.0.2.0/24", "server": "local"},
+		{"domain_keyword": "example", "query_type": "TXT", "server": "local"}
+	]`)
+	for _, testCase := range []struct {
+		name      string
+		domain    string
+		address   string
+		queryType uint16
+		ruleIndex int
+		server    string
+		dnssec    bool
+		trace     []bool
+	}{
+		{
+			name:      "reject",
+			domain:    "blocked.example",
+			ruleIndex: 0,
+			trace:     []bool{true},
+		},
+		{
+			name:      "fakeip",
+			domain:    "fake.example.com",
+			ruleIndex: 2,
+			server:    "fakeip",
+			dnssec:    true,
+			trace:     []bool{false, true, true},
+		},
+		{
+			name:      "address limit accepted",
+			domain:    "www.example.com",
+			address:   "10.0.0.1",
+			ruleIndex: 3,
+			server:    "local",
+			dnssec:    true,
+			trace:     []bool{false, true, false, true, false},
+		},
+		{
+			name:      "address limit rejected",
+			domain:    "www.example.com",
+			address:   "198.51.100.1",
+			ruleIndex: -1,
+			server:    "remote",
+			trace:     []bool{false, true, false, false, false, false},
+		},
+		{
+			name:      "match response",
+			domain:    "example.org",
+			address:   "192.0.2.1",
+			ruleIndex: 4,
+			server:    "local",
+			trace:     []bool{false, false, false, false, false, true},
+		},
+		{
+			name:      "non-address query",
+			domain:    "www.example.com",
+			queryType: dns.TypeTXT,
+			ruleIndex: 5,
+			server:    "local",
+			dnssec:    true,
+			trace:     []bool{false, true, false, false, true},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			metadata := adapter.InboundContext{
+				Domain:    testCase.domain,
+				QueryType: testCase.queryType,
+			}
+			if testCase.address != "" {
+				metadata.Destination = M.ParseSocksaddrHostPort(testCase.address, 443)
+			}
+			result := router.TestDNS(context.Background(), metadata)
+			require.Equal(t, testCase.ruleIndex, result.RuleIndex)
+			require.Equal(t, testCase.server, result.Server)
+			require.Equal(t, testCase.dnssec, result.RequireDNSSEC)
+			trace := make([]bool, 0, len(result.Trace))
+			for _, item := range result.Trace {
+				trace = append(trace, item.Matched)
+			}
+			require.Equal(t, testCase.trace, trace)
+		})
+	}
+}
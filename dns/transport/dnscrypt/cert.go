@@ -0,0 +1,164 @@
+package dnscrypt
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/binary"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/poly1305"
+)
+
+const (
+	esVersionXSalsa20Poly1305  = 1
+	esVersionXChaCha20Poly1305 = 2
+
+	certMinLength     = 124
+	clientMagicLength = 8
+	nonceLength       = 24
+	halfNonceLength   = nonceLength / 2
+	tagLength         = poly1305.TagSize
+	minQueryLength    = 256
+	queryPadBlockSize = 64
+)
+
+var (
+	certMagic     = []byte{0x44, 0x4e, 0x53, 0x43}
+	resolverMagic = []byte{0x72, 0x36, 0x66, 0x6e, 0x76, 0x57, 0x6a, 0x38}
+)
+
+type Certificate struct {
+	ESVersion   uint16
+	ResolverKey [32]byte
+	ClientMagic [clientMagicLength]byte
+	Serial      uint32
+	NotBefore   time.Time
+	NotAfter    time.Time
+}
+
+// ParseCertificate parses a resolver certificate and verifies its signature
+// with the provider public key.
+func ParseCertificate(content []byte, publicKey ed25519.PublicKey) (*Certificate, error) {
+	if len(content) < certMinLength {
+		return nil, E.New("certificate too short")
+	}
+	if !bytes.Equal(content[:4], certMagic) {
+		return nil, E.New("invalid certificate magic")
+	}
+	esVersion := binary.BigEndian.Uint16(content[4:6])
+	if esVersion != esVersionXSalsa20Poly1305 && esVersion != esVersionXChaCha20Poly1305 {
+		return nil, E.New("unsupported encryption system: ", esVersion)
+	}
+	signature := content[8:72]
+	signed := content[72:]
+	if !ed25519.Verify(publicKey, signed, signature) {
+		return nil, E.New("invalid certificate signature")
+	}
+	certificate := &Certificate{
+		ESVersion: esVersion,
+		Serial:    binary.BigEndian.Uint32(signed[40:44]),
+		NotBefore: time.Unix(int64(binary.BigEndian.Uint32(signed[44:48])), 0),
+		NotAfter:  time.Unix(int64(binary.BigEndian.Uint32(signed[48:52])), 0),
+	}
+	copy(certificate.ResolverKey[:], signed[:32])
+	copy(certificate.ClientMagic[:], signed[32:40])
+	return certificate, nil
+}
+
+func (c *Certificate) Valid(now time.Time) bool {
+	return !now.Before(c.NotBefore) && now.Before(c.NotAfter)
+}
+
+// SharedKey computes the key shared between the client secret key
+// and the resolver public key for the encryption system of the certificate.
+func (c *Certificate) SharedKey(secretKey *[32]byte) (*[32]byte, error) {
+	var sharedKey [32]byte
+	switch c.ESVersion {
+	case esVersionXSalsa20Poly1305:
+		box.Precompute(&sharedKey, &c.ResolverKey, secretKey)
+	case esVersionXChaCha20Poly1305:
+		dhKey, err := curve25519.X25519(secretKey[:], c.ResolverKey[:])
+		if err != nil {
+			return nil, err
+		}
+		hKey, err := chacha20.HChaCha20(dhKey, make([]byte, 16))
+		if err != nil {
+			return nil, err
+		}
+		copy(sharedKey[:], hKey)
+	}
+	return &sharedKey, nil
+}
+
+func seal(esVersion uint16, sharedKey *[32]byte, nonce *[nonceLength]byte, message []byte) []byte {
+	if esVersion == esVersionXSalsa20Poly1305 {
+		return secretbox.Seal(nil, message, nonce, sharedKey)
+	}
+	cipher, err := chacha20.NewUnauthenticatedCipher(sharedKey[:], nonce[:])
+	if err != nil {
+		panic(err)
+	}
+	var polyKey [32]byte
+	cipher.XORKeyStream(polyKey[:], polyKey[:])
+	sealed := make([]byte, tagLength+len(message))
+	cipher.XORKeyStream(sealed[tagLength:], message)
+	var tag [tagLength]byte
+	poly1305.Sum(&tag, sealed[tagLength:], &polyKey)
+	copy(sealed, tag[:])
+	return sealed
+}
+
+func open(esVersion uint16, sharedKey *[32]byte, nonce *[nonceLength]byte, sealed []byte) ([]byte, error) {
+	if len(sealed) < tagLength {
+		return nil, E.New("encrypted message too short")
+	}
+	if esVersion == esVersionXSalsa20Poly1305 {
+		message, loaded := secretbox.Open(nil, sealed, nonce, sharedKey)
+		if !loaded {
+			return nil, E.New("decrypt failed")
+		}
+		return message, nil
+	}
+	cipher, err := chacha20.NewUnauthenticatedCipher(sharedKey[:], nonce[:])
+	if err != nil {
+		return nil, err
+	}
+	var polyKey [32]byte
+	cipher.XORKeyStream(polyKey[:], polyKey[:])
+	var tag [tagLength]byte
+	poly1305.Sum(&tag, sealed[tagLength:], &polyKey)
+	if subtle.ConstantTimeCompare(tag[:], sealed[:tagLength]) != 1 {
+		return nil, E.New("decrypt failed")
+	}
+	message := make([]byte, len(sealed)-tagLength)
+	cipher.XORKeyStream(message, sealed[tagLength:])
+	return message, nil
+}
+
+func pad(message []byte, minLength int) []byte {
+	length := max(minLength, (len(message)+1+queryPadBlockSize-1)/queryPadBlockSize*queryPadBlockSize)
+	padded := make([]byte, length)
+	copy(padded, message)
+	padded[len(message)] = 0x80
+	return padded
+}
+
+func unpad(padded []byte) ([]byte, error) {
+	for index := len(padded) - 1; index >= 0; index-- {
+		switch padded[index] {
+		case 0x00:
+		case 0x80:
+			return padded[:index], nil
+		default:
+			return nil, E.New("invalid padding")
+		}
+	}
+	return nil, E.New("invalid padding")
+}
@@ -0,0 +1,345 @@
+package dnscrypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/dialer"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/dns"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	mDNS "github.com/miekg/dns"
+	"golang.org/x/crypto/curve25519"
+)
+
+const certRefreshInterval = 4 * time.Hour
+
+var _ adapter.DNSTransport = (*Transport)(nil)
+
+func RegisterTransport(registry *dns.TransportRegistry) {
+	dns.RegisterTransport[option.DNSCryptDNSServerOptions](registry, C.DNSTypeDNSCrypt, NewTransport)
+}
+
+type Transport struct {
+	dns.TransportAdapter
+	logger     logger.ContextLogger
+	dialer     N.Dialer
+	serverAddr M.Socksaddr
+	stamp      *Stamp
+
+	access  sync.Mutex
+	session *session
+}
+
+type session struct {
+	certificate *Certificate
+	fetchedAt   time.Time
+	publicKey   [32]byte
+	sharedKey   *[32]byte
+}
+
+func NewTransport(ctx context.Context, logger log.ContextLogger, tag string, options option.DNSCryptDNSServerOptions) (adapter.DNSTransport, error) {
+	stamp, err := ParseStamp(options.Stamp)
+	if err != nil {
+		return nil, err
+	}
+	if options.Server == "" {
+		options.Server = stamp.ServerAddr.AddrString()
+		if options.ServerPort == 0 {
+			options.ServerPort = stamp.ServerAddr.Port
+		}
+	}
+	transportDialer, err := dns.NewRemoteDialer(ctx, options.RemoteDNSServerOptions)
+	if err != nil {
+		return nil, err
+	}
+	serverAddr := options.DNSServerAddressOptions.Build()
+	if serverAddr.Port == 0 {
+		serverAddr.Port = 443
+	}
+	if !serverAddr.IsValid() {
+		return nil, E.New("invalid server address: ", serverAddr)
+	}
+	return &Transport{
+		TransportAdapter: dns.NewTransportAdapterWithRemoteOptions(C.DNSTypeDNSCrypt, tag, options.RemoteDNSServerOptions),
+		logger:           logger,
+		dialer:           transportDialer,
+		serverAddr:       serverAddr,
+		stamp:            stamp,
+	}, nil
+}
+
+func (t *Transport) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart {
+		return nil
+	}
+	return dialer.InitializeDetour(t.dialer)
+}
+
+func (t *Transport) Close() error {
+	return nil
+}
+
+func (t *Transport) Reset() {
+	t.access.Lock()
+	defer t.access.Unlock()
+	t.session = nil
+}
+
+func (t *Transport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	session, err := t.loadSession(ctx)
+	if err != nil {
+		return nil, E.Cause(err, "fetch certificate")
+	}
+	response, err := t.exchange(ctx, N.NetworkUDP, session, message)
+	if err == nil && response.Truncated {
+		response, err = t.exchange(ctx, N.NetworkTCP, session, message)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// loadSession returns the session of the current resolver certificate,
+// fetching a new certificate if there is none, it has expired,
+// or the refresh interval has passed.
+func (t *Transport) loadSession(ctx context.Context) (*session, error) {
+	t.access.Lock()
+	defer t.access.Unlock()
+	now := time.Now()
+	current := t.session
+	if current != nil && current.certificate.Valid(now) && now.Sub(current.fetchedAt) < certRefreshInterval {
+		return current, nil
+	}
+	certificate, err := t.fetchCertificate(ctx)
+	if err != nil {
+		if current != nil && current.certificate.Valid(now) {
+			t.logger.WarnContext(ctx, "refresh certificate: ", err)
+			return current, nil
+		}
+		return nil, err
+	}
+	var secretKey [32]byte
+	_, err = rand.Read(secretKey[:])
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := curve25519.X25519(secretKey[:], curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	sharedKey, err := certificate.SharedKey(&secretKey)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil || current.certificate.Serial != certificate.Serial {
+		t.logger.DebugContext(ctx, "loaded certificate ", certificate.Serial, " valid until ", certificate.NotAfter.Format(time.DateTime))
+	}
+	newSession := &session{
+		certificate: certificate,
+		fetchedAt:   now,
+		sharedKey:   sharedKey,
+	}
+	copy(newSession.publicKey[:], publicKey)
+	t.session = newSession
+	return newSession, nil
+}
+
+func (t *Transport) fetchCertificate(ctx context.Context) (*Certificate, error) {
+	request := new(mDNS.Msg)
+	request.SetQuestion(mDNS.Fqdn(t.stamp.ProviderName), mDNS.TypeTXT)
+	request.RecursionDesired = false
+	rawRequest, err := request.Pack()
+	if err != nil {
+		return nil, err
+	}
+	rawResponse, err := t.roundTrip(ctx, N.NetworkUDP, rawRequest)
+	if err != nil {
+		return nil, err
+	}
+	var response mDNS.Msg
+	err = response.Unpack(rawResponse)
+	if err != nil {
+		return nil, err
+	}
+	if response.Truncated {
+		rawResponse, err = t.roundTrip(ctx, N.NetworkTCP, rawRequest)
+		if err != nil {
+			return nil, err
+		}
+		err = response.Unpack(rawResponse)
+		if err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now()
+	var (
+		certificate *Certificate
+		errors      []error
+	)
+	for _, answer := range response.Answer {
+		txt, isTXT := answer.(*mDNS.TXT)
+		if !isTXT {
+			continue
+		}
+		content, err := txtContent(txt)
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		current, err := ParseCertificate(content, t.stamp.PublicKey)
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		if !current.Valid(now) {
+			continue
+		}
+		if certificate == nil || current.Serial > certificate.Serial ||
+			current.Serial == certificate.Serial && current.ESVersion > certificate.ESVersion {
+			certificate = current
+		}
+	}
+	if certificate == nil {
+		if len(errors) > 0 {
+			return nil, E.Errors(errors...)
+		}
+		return nil, E.New("no valid certificate found for ", t.stamp.ProviderName)
+	}
+	return certificate, nil
+}
+
+// txtContent returns the raw bytes of a TXT record, since the text form
+// of miekg/dns escapes non-printable characters.
+func txtContent(txt *mDNS.TXT) ([]byte, error) {
+	// pack with the root name so that the header has a fixed length
+	record := *txt
+	record.Hdr.Name = "."
+	packed := make([]byte, mDNS.Len(&record))
+	offset, err := mDNS.PackRR(&record, packed, 0, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	const headerLength = 1 + 2 + 2 + 4 + 2
+	rdata := packed[headerLength:offset]
+	var content []byte
+	for len(rdata) > 0 {
+		length := int(rdata[0])
+		if len(rdata) < 1+length {
+			return nil, E.New("invalid TXT record")
+		}
+		content = append(content, rdata[1:1+length]...)
+		rdata = rdata[1+length:]
+	}
+	return content, nil
+}
+
+func (t *Transport) exchange(ctx context.Context, network string, session *session, message *mDNS.Msg) (*mDNS.Msg, error) {
+	certificate := session.certificate
+	exMessage := *message
+	exMessage.Compress = true
+	rawMessage, err := exMessage.Pack()
+	if err != nil {
+		return nil, err
+	}
+	var nonce [nonceLength]byte
+	_, err = rand.Read(nonce[:halfNonceLength])
+	if err != nil {
+		return nil, err
+	}
+	minLength := minQueryLength
+	if network == N.NetworkTCP {
+		minLength = 0
+	}
+	request := make([]byte, 0, clientMagicLength+32+halfNonceLength+tagLength+len(rawMessage)+queryPadBlockSize+minQueryLength)
+	request = append(request, certificate.ClientMagic[:]...)
+	request = append(request, session.publicKey[:]...)
+	request = append(request, nonce[:halfNonceLength]...)
+	request = append(request, seal(certificate.ESVersion, session.sharedKey, &nonce, pad(rawMessage, minLength))...)
+	rawResponse, err := t.roundTrip(ctx, network, request)
+	if err != nil {
+		return nil, err
+	}
+	if len(rawResponse) < len(resolverMagic)+nonceLength+tagLength || !bytes.Equal(rawResponse[:len(resolverMagic)], resolverMagic) {
+		return nil, E.New("invalid response")
+	}
+	rawResponse = rawResponse[len(resolverMagic):]
+	if !bytes.Equal(rawResponse[:halfNonceLength], nonce[:halfNonceLength]) {
+		return nil, E.New("response nonce mismatch")
+	}
+	copy(nonce[:], rawResponse[:nonceLength])
+	padded, err := open(certificate.ESVersion, session.sharedKey, &nonce, rawResponse[nonceLength:])
+	if err != nil {
+		return nil, err
+	}
+	rawMessage, err = unpad(padded)
+	if err != nil {
+		return nil, err
+	}
+	var response mDNS.Msg
+	err = response.Unpack(rawMessage)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (t *Transport) roundTrip(ctx context.Context, network string, request []byte) ([]byte, error) {
+	conn, err := t.dialer.DialContext(ctx, network, t.serverAddr)
+	if err != nil {
+		return nil, E.Cause(err, "dial ", network, " connection")
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+	if deadline, loaded := ctx.Deadline(); loaded {
+		conn.SetDeadline(deadline)
+	}
+	if network == N.NetworkUDP {
+		_, err = conn.Write(request)
+		if err != nil {
+			return nil, E.Cause(err, "write request")
+		}
+		buffer := buf.NewSize(buf.UDPBufferSize)
+		defer buffer.Release()
+		_, err = buffer.ReadOnceFrom(conn)
+		if err != nil {
+			return nil, E.Cause(err, "read response")
+		}
+		return bytes.Clone(buffer.Bytes()), nil
+	}
+	packet := make([]byte, 2+len(request))
+	binary.BigEndian.PutUint16(packet, uint16(len(request)))
+	copy(packet[2:], request)
+	_, err = conn.Write(packet)
+	if err != nil {
+		return nil, E.Cause(err, "write request")
+	}
+	var length uint16
+	err = binary.Read(conn, binary.BigEndian, &length)
+	if err != nil {
+		return nil, E.Cause(err, "read response")
+	}
+	response := make([]byte, length)
+	_, err = io.ReadFull(conn, response)
+	if err != nil {
+		return nil, E.Cause(err, "read response")
+	}
+	return response, nil
+}
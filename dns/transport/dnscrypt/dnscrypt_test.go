@@ -0,0 +1,195 @@
+package dnscrypt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/dns"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/curve25519"
+)
+
+const testProviderName = "2.dnscrypt-cert.example.com."
+
+type testServer struct {
+	conn        net.PacketConn
+	esVersion   uint16
+	providerKey ed25519.PrivateKey
+	secretKey   [32]byte
+	clientMagic [clientMagicLength]byte
+}
+
+func newTestServer(t *testing.T, esVersion uint16) *testServer {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	_, providerKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	server := &testServer{
+		conn:        conn,
+		esVersion:   esVersion,
+		providerKey: providerKey,
+	}
+	rand.Read(server.secretKey[:])
+	rand.Read(server.clientMagic[:])
+	go server.loop()
+	return server
+}
+
+func (s *testServer) stamp() string {
+	address := s.conn.LocalAddr().String()
+	publicKey := s.providerKey.Public().(ed25519.PublicKey)
+	content := []byte{stampProtocolDNSCrypt, 0, 0, 0, 0, 0, 0, 0, 0}
+	content = append(content, byte(len(address)))
+	content = append(content, address...)
+	content = append(content, byte(len(publicKey)))
+	content = append(content, publicKey...)
+	content = append(content, byte(len(testProviderName)))
+	content = append(content, testProviderName...)
+	return "sdns://" + base64.RawURLEncoding.EncodeToString(content)
+}
+
+func (s *testServer) certificate() []byte {
+	publicKey, _ := curve25519.X25519(s.secretKey[:], curve25519.Basepoint)
+	signed := append([]byte{}, publicKey...)
+	signed = append(signed, s.clientMagic[:]...)
+	signed = binary.BigEndian.AppendUint32(signed, 1)
+	signed = binary.BigEndian.AppendUint32(signed, uint32(time.Now().Add(-time.Hour).Unix()))
+	signed = binary.BigEndian.AppendUint32(signed, uint32(time.Now().Add(time.Hour).Unix()))
+	content := append([]byte{}, certMagic...)
+	content = binary.BigEndian.AppendUint16(content, s.esVersion)
+	content = append(content, 0, 0)
+	content = append(content, ed25519.Sign(s.providerKey, signed)...)
+	return append(content, signed...)
+}
+
+func (s *testServer) loop() {
+	buffer := make([]byte, 65535)
+	for {
+		n, addr, err := s.conn.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		response, err := s.handle(buffer[:n])
+		if err != nil {
+			continue
+		}
+		s.conn.WriteTo(response, addr)
+	}
+}
+
+func (s *testServer) handle(packet []byte) ([]byte, error) {
+	if len(packet) > clientMagicLength && string(packet[:clientMagicLength]) == string(s.clientMagic[:]) {
+		return s.handleEncrypted(packet)
+	}
+	var request mDNS.Msg
+	err := request.Unpack(packet)
+	if err != nil {
+		return nil, err
+	}
+	response := new(mDNS.Msg)
+	response.SetReply(&request)
+	response.Answer = append(response.Answer, &mDNS.TXT{
+		Hdr: mDNS.RR_Header{Name: testProviderName, Rrtype: mDNS.TypeTXT, Class: mDNS.ClassINET, Ttl: 60},
+		Txt: []string{escapeTXT(s.certificate())},
+	})
+	return response.Pack()
+}
+
+func (s *testServer) handleEncrypted(packet []byte) ([]byte, error) {
+	client := &Certificate{ESVersion: s.esVersion}
+	copy(client.ResolverKey[:], packet[clientMagicLength:clientMagicLength+32])
+	sharedKey, err := client.SharedKey(&s.secretKey)
+	if err != nil {
+		return nil, err
+	}
+	var nonce [nonceLength]byte
+	copy(nonce[:], packet[clientMagicLength+32:clientMagicLength+32+halfNonceLength])
+	padded, err := open(s.esVersion, sharedKey, &nonce, packet[clientMagicLength+32+halfNonceLength:])
+	if err != nil {
+		return nil, err
+	}
+	if len(padded) < minQueryLength {
+		return nil, E.New("query too short")
+	}
+	rawRequest, err := unpad(padded)
+	if err != nil {
+		return nil, err
+	}
+	var request mDNS.Msg
+	err = request.Unpack(rawRequest)
+	if err != nil {
+		return nil, err
+	}
+	rawResponse, err := dns.FixedResponse(request.Id, request.Question[0], []netip.Addr{netip.MustParseAddr("1.2.3.4")}, 60).Pack()
+	if err != nil {
+		return nil, err
+	}
+	rand.Read(nonce[halfNonceLength:])
+	response := append([]byte{}, resolverMagic...)
+	response = append(response, nonce[:]...)
+	return append(response, seal(s.esVersion, sharedKey, &nonce, pad(rawResponse, 0))...), nil
+}
+
+func escapeTXT(content []byte) string {
+	var builder strings.Builder
+	for _, b := range content {
+		if b >= 0x21 && b <= 0x7e && b != '"' && b != '\\' && b != ';' {
+			builder.WriteByte(b)
+		} else {
+			builder.WriteString("\\")
+			builder.WriteString(strconv.FormatUint(uint64(b)+1000, 10)[1:])
+		}
+	}
+	return builder.String()
+}
+
+func TestStamp(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t, esVersionXSalsa20Poly1305)
+	stamp, err := ParseStamp(server.stamp())
+	require.NoError(t, err)
+	require.Equal(t, server.conn.LocalAddr().String(), stamp.ServerAddr.String())
+	require.Equal(t, testProviderName, stamp.ProviderName)
+	require.Equal(t, server.providerKey.Public(), stamp.PublicKey)
+	_, err = ParseStamp("sdns://AgcAAAAAAAAABzEuMS4xLjEAEmRucy5leGFtcGxlLmNvbQovZG5zLXF1ZXJ5")
+	require.Error(t, err)
+}
+
+func TestTransport(t *testing.T) {
+	t.Parallel()
+	for _, esVersion := range []uint16{esVersionXSalsa20Poly1305, esVersionXChaCha20Poly1305} {
+		server := newTestServer(t, esVersion)
+		transport, err := NewTransport(context.Background(), log.NewNOPFactory().NewLogger("dns"), "dnscrypt", option.DNSCryptDNSServerOptions{
+			Stamp: server.stamp(),
+		})
+		require.NoError(t, err)
+		require.NoError(t, transport.(adapter.Lifecycle).Start(adapter.StartStateStart))
+		for range 2 {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			request := new(mDNS.Msg)
+			request.SetQuestion("example.com.", mDNS.TypeA)
+			response, err := transport.Exchange(ctx, request)
+			cancel()
+			require.NoError(t, err)
+			require.Equal(t, []netip.Addr{netip.MustParseAddr("1.2.3.4")}, dns.MessageToAddresses(response))
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package dnscrypt
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"net"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+const stampProtocolDNSCrypt = 0x01
+
+// Stamp is a DNSCrypt server stamp as defined in
+// https://dnscrypt.info/stamps-specifications.
+type Stamp struct {
+	ServerAddr   M.Socksaddr
+	PublicKey    ed25519.PublicKey
+	ProviderName string
+}
+
+func ParseStamp(stamp string) (*Stamp, error) {
+	encoded, loaded := strings.CutPrefix(stamp, "sdns://")
+	if !loaded {
+		return nil, E.New("invalid stamp: missing sdns:// prefix")
+	}
+	content, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return nil, E.Cause(err, "decode stamp")
+	}
+	reader := cryptobyte.String(content)
+	var (
+		protocol     uint8
+		address      cryptobyte.String
+		publicKey    cryptobyte.String
+		providerName cryptobyte.String
+	)
+	if !reader.ReadUint8(&protocol) {
+		return nil, E.New("invalid stamp: empty")
+	}
+	if protocol != stampProtocolDNSCrypt {
+		return nil, E.New("unsupported stamp protocol: ", protocol)
+	}
+	// properties are informational and ignored
+	if !reader.Skip(8) ||
+		!reader.ReadUint8LengthPrefixed(&address) ||
+		!reader.ReadUint8LengthPrefixed(&publicKey) ||
+		!reader.ReadUint8LengthPrefixed(&providerName) {
+		return nil, E.New("invalid stamp: truncated")
+	}
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, E.New("invalid stamp: bad public key length: ", len(publicKey))
+	}
+	serverAddr, err := parseStampAddress(string(address))
+	if err != nil {
+		return nil, err
+	}
+	if len(providerName) == 0 {
+		return nil, E.New("invalid stamp: missing provider name")
+	}
+	return &Stamp{
+		ServerAddr:   serverAddr,
+		PublicKey:    ed25519.PublicKey(publicKey),
+		ProviderName: string(providerName),
+	}, nil
+}
+
+func parseStampAddress(address string) (M.Socksaddr, error) {
+	if address == "" {
+		return M.Socksaddr{}, E.New("invalid stamp: missing server address")
+	}
+	if !strings.HasPrefix(address, "[") && strings.Count(address, ":") > 1 {
+		return M.ParseSocksaddrHostPort(address, 443), nil
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return M.ParseSocksaddrHostPort(strings.Trim(address, "[]"), 443), nil
+	}
+	serverAddr := M.ParseSocksaddrHostPortStr(host, port)
+	if !serverAddr.IsValid() || serverAddr.Port == 0 {
+		return M.Socksaddr{}, E.New("invalid stamp: bad server address: ", address)
+	}
+	return serverAddr, nil
+}
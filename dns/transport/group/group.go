@@ -0,0 +1,247 @@
+package group
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/dns"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service"
+
+	mDNS "github.com/miekg/dns"
+)
+
+const (
+	defaultTimeout     = 5 * time.Second
+	defaultMaxFail     = 3
+	defaultFailTimeout = 30 * time.Second
+)
+
+func RegisterTransport(registry *dns.TransportRegistry) {
+	dns.RegisterTransport[option.GroupDNSServerOptions](registry, C.DNSTypeGroup, NewTransport)
+}
+
+var _ adapter.DNSTransport = (*Transport)(nil)
+
+type Transport struct {
+	dns.TransportAdapter
+	ctx         context.Context
+	logger      log.ContextLogger
+	manager     adapter.DNSTransportManager
+	mode        string
+	timeout     time.Duration
+	maxFail     uint32
+	failTimeout time.Duration
+	members     []*member
+	index       atomic.Uint32
+}
+
+type member struct {
+	tag       string
+	failures  atomic.Uint32
+	downUntil atomic.Int64
+}
+
+func NewTransport(ctx context.Context, logger log.ContextLogger, tag string, options option.GroupDNSServerOptions) (adapter.DNSTransport, error) {
+	if len(options.Servers) == 0 {
+		return nil, E.New("missing servers")
+	}
+	switch options.Mode {
+	case "":
+		options.Mode = C.DNSGroupModeFallback
+	case C.DNSGroupModeRace, C.DNSGroupModeFallback, C.DNSGroupModeRoundRobin:
+	default:
+		return nil, E.New("unknown group mode: ", options.Mode)
+	}
+	members := make([]*member, 0, len(options.Servers))
+	for _, server := range options.Servers {
+		if server == tag {
+			return nil, E.New("group cannot contain itself")
+		}
+		if common.Any(members, func(it *member) bool {
+			return it.tag == server
+		}) {
+			return nil, E.New("duplicate server: ", server)
+		}
+		members = append(members, &member{tag: server})
+	}
+	timeout := time.Duration(options.Timeout)
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	maxFail := options.MaxFail
+	if maxFail == 0 {
+		maxFail = defaultMaxFail
+	}
+	failTimeout := time.Duration(options.FailTimeout)
+	if failTimeout == 0 {
+		failTimeout = defaultFailTimeout
+	}
+	return &Transport{
+		TransportAdapter: dns.NewTransportAdapter(C.DNSTypeGroup, tag, options.Servers),
+		ctx:              ctx,
+		logger:           logger,
+		mode:             options.Mode,
+		timeout:          timeout,
+		maxFail:          maxFail,
+		failTimeout:      failTimeout,
+		members:          members,
+	}, nil
+}
+
+func (t *Transport) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart {
+		return nil
+	}
+	t.manager = service.FromContext[adapter.DNSTransportManager](t.ctx)
+	for _, member := range t.members {
+		transport, loaded := t.manager.Transport(member.tag)
+		if !loaded {
+			return E.New("DNS server not found: ", member.tag)
+		}
+		if transport.Type() == C.DNSTypeFakeIP {
+			return E.New("fakeip server cannot be used in group: ", member.tag)
+		}
+	}
+	return nil
+}
+
+func (t *Transport) Close() error {
+	return nil
+}
+
+func (t *Transport) Reset() {
+	for _, member := range t.members {
+		member.failures.Store(0)
+		member.downUntil.Store(0)
+	}
+}
+
+func (t *Transport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	available, down := t.splitMembers()
+	switch t.mode {
+	case C.DNSGroupModeRace:
+		response, err := t.exchangeRace(ctx, available, message)
+		if err == nil || len(down) == 0 || ctx.Err() != nil {
+			return response, err
+		}
+		return t.exchangeFallback(ctx, down, message)
+	case C.DNSGroupModeRoundRobin:
+		offset := int(t.index.Add(1)-1) % len(available)
+		members := make([]*member, 0, len(t.members))
+		members = append(members, available[offset:]...)
+		members = append(members, available[:offset]...)
+		return t.exchangeFallback(ctx, append(members, down...), message)
+	default:
+		return t.exchangeFallback(ctx, append(available, down...), message)
+	}
+}
+
+// splitMembers separates members marked as down from the available ones.
+// Down members are only tried after all available members have failed,
+// and are used directly if no member is available.
+func (t *Transport) splitMembers() (available []*member, down []*member) {
+	now := time.Now().UnixNano()
+	for _, member := range t.members {
+		if member.downUntil.Load() <= now {
+			available = append(available, member)
+		} else {
+			down = append(down, member)
+		}
+	}
+	if len(available) == 0 {
+		return down, nil
+	}
+	return
+}
+
+func (t *Transport) exchangeFallback(ctx context.Context, members []*member, message *mDNS.Msg) (*mDNS.Msg, error) {
+	var errors []error
+	for _, member := range members {
+		exchangeCtx, cancel := context.WithTimeout(ctx, t.timeout)
+		response, err := t.exchangeOne(exchangeCtx, ctx, member, message)
+		cancel()
+		if err == nil {
+			return response, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		errors = append(errors, err)
+	}
+	return nil, E.Errors(errors...)
+}
+
+func (t *Transport) exchangeRace(ctx context.Context, members []*member, message *mDNS.Msg) (*mDNS.Msg, error) {
+	returned := make(chan struct{})
+	defer close(returned)
+	type queryResult struct {
+		response *mDNS.Msg
+		err      error
+	}
+	results := make(chan queryResult)
+	queryCtx, queryCancel := context.WithCancel(ctx)
+	defer queryCancel()
+	for _, member := range members {
+		go func() {
+			response, err := t.exchangeOne(queryCtx, queryCtx, member, message.Copy())
+			select {
+			case results <- queryResult{response, err}:
+			case <-returned:
+			}
+		}()
+	}
+	var errors []error
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case result := <-results:
+			if result.err == nil {
+				return result.response, nil
+			}
+			errors = append(errors, result.err)
+			if len(errors) == len(members) {
+				return nil, E.Errors(errors...)
+			}
+		}
+	}
+}
+
+// exchangeOne queries a single member and updates its health. Failures
+// caused by cancellation of parentCtx are not counted against the member.
+func (t *Transport) exchangeOne(ctx context.Context, parentCtx context.Context, member *member, message *mDNS.Msg) (*mDNS.Msg, error) {
+	transport, loaded := t.manager.Transport(member.tag)
+	if !loaded {
+		return nil, E.New("DNS server not found: ", member.tag)
+	}
+	response, err := transport.Exchange(ctx, message)
+	if err == nil && (response.Rcode == mDNS.RcodeServerFailure || response.Rcode == mDNS.RcodeRefused) {
+		err = dns.RcodeError(response.Rcode)
+	}
+	if err != nil {
+		if parentCtx.Err() == nil {
+			t.recordFailure(ctx, member)
+		}
+		return nil, E.Cause(err, "exchange ", member.tag)
+	}
+	member.failures.Store(0)
+	member.downUntil.Store(0)
+	return response, nil
+}
+
+func (t *Transport) recordFailure(ctx context.Context, member *member) {
+	if member.failures.Add(1) < t.maxFail {
+		return
+	}
+	member.failures.Store(0)
+	if member.downUntil.Swap(time.Now().Add(t.failTimeout).UnixNano()) <= time.Now().UnixNano() {
+		t.logger.WarnContext(ctx, "server[", member.tag, "] marked as down for ", t.failTimeout)
+	}
+}
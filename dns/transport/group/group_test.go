@@ -0,0 +1,168 @@
+package group
+
+import (
+	"context"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/dns"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json/badoption"
+	"github.com/sagernet/sing/service"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+type testTransport struct {
+	dns.TransportAdapter
+	address  netip.Addr
+	delay    time.Duration
+	failed   atomic.Bool
+	requests atomic.Int32
+}
+
+func newTestTransport(tag string, address string) *testTransport {
+	return &testTransport{
+		TransportAdapter: dns.NewTransportAdapter("test", tag, nil),
+		address:          netip.MustParseAddr(address),
+	}
+}
+
+func (t *testTransport) Start(stage adapter.StartStage) error {
+	return nil
+}
+
+func (t *testTransport) Close() error {
+	return nil
+}
+
+func (t *testTransport) Reset() {
+}
+
+func (t *testTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	t.requests.Add(1)
+	if t.delay > 0 {
+		select {
+		case <-time.After(t.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if t.failed.Load() {
+		return nil, E.New("upstream failed")
+	}
+	return dns.FixedResponse(message.Id, message.Question[0], []netip.Addr{t.address}, 60), nil
+}
+
+type testManager struct {
+	adapter.DNSTransportManager
+	transports map[string]adapter.DNSTransport
+}
+
+func (m *testManager) Transport(tag string) (adapter.DNSTransport, bool) {
+	transport, loaded := m.transports[tag]
+	return transport, loaded
+}
+
+func newTestGroup(t *testing.T, options option.GroupDNSServerOptions, transports ...*testTransport) adapter.DNSTransport {
+	manager := &testManager{transports: make(map[string]adapter.DNSTransport)}
+	for _, transport := range transports {
+		manager.transports[transport.Tag()] = transport
+		options.Servers = append(options.Servers, transport.Tag())
+	}
+	ctx := service.ContextWith[adapter.DNSTransportManager](context.Background(), manager)
+	group, err := NewTransport(ctx, log.NewNOPFactory().NewLogger("dns"), "group", options)
+	require.NoError(t, err)
+	require.NoError(t, group.(adapter.Lifecycle).Start(adapter.StartStateStart))
+	return group
+}
+
+func exchange(t *testing.T, transport adapter.DNSTransport) (netip.Addr, error) {
+	message := new(mDNS.Msg)
+	message.SetQuestion("example.com.", mDNS.TypeA)
+	response, err := transport.Exchange(context.Background(), message)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return dns.MessageToAddresses(response)[0], nil
+}
+
+func TestGroupFallback(t *testing.T) {
+	t.Parallel()
+	first := newTestTransport("first", "1.1.1.1")
+	second := newTestTransport("second", "2.2.2.2")
+	group := newTestGroup(t, option.GroupDNSServerOptions{
+		MaxFail:     2,
+		FailTimeout: badoption.Duration(time.Minute),
+	}, first, second)
+	address, err := exchange(t, group)
+	require.NoError(t, err)
+	require.Equal(t, first.address, address)
+	first.failed.Store(true)
+	for range 3 {
+		address, err = exchange(t, group)
+		require.NoError(t, err)
+		require.Equal(t, second.address, address)
+	}
+	require.Equal(t, int32(3), first.requests.Load())
+	second.failed.Store(true)
+	_, err = exchange(t, group)
+	require.Error(t, err)
+	first.failed.Store(false)
+	address, err = exchange(t, group)
+	require.NoError(t, err)
+	require.Equal(t, first.address, address)
+}
+
+func TestGroupFallbackTimeout(t *testing.T) {
+	t.Parallel()
+	first := newTestTransport("first", "1.1.1.1")
+	first.delay = time.Second
+	second := newTestTransport("second", "2.2.2.2")
+	group := newTestGroup(t, option.GroupDNSServerOptions{
+		Timeout: badoption.Duration(50 * time.Millisecond),
+	}, first, second)
+	address, err := exchange(t, group)
+	require.NoError(t, err)
+	require.Equal(t, second.address, address)
+}
+
+func TestGroupRace(t *testing.T) {
+	t.Parallel()
+	first := newTestTransport("first", "1.1.1.1")
+	first.delay = 100 * time.Millisecond
+	second := newTestTransport("second", "2.2.2.2")
+	second.delay = 10 * time.Millisecond
+	group := newTestGroup(t, option.GroupDNSServerOptions{
+		Mode: C.DNSGroupModeRace,
+	}, first, second)
+	address, err := exchange(t, group)
+	require.NoError(t, err)
+	require.Equal(t, second.address, address)
+	second.failed.Store(true)
+	address, err = exchange(t, group)
+	require.NoError(t, err)
+	require.Equal(t, first.address, address)
+}
+
+func TestGroupRoundRobin(t *testing.T) {
+	t.Parallel()
+	first := newTestTransport("first", "1.1.1.1")
+	second := newTestTransport("second", "2.2.2.2")
+	group := newTestGroup(t, option.GroupDNSServerOptions{
+		Mode: C.DNSGroupModeRoundRobin,
+	}, first, second)
+	for range 4 {
+		_, err := exchange(t, group)
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(2), first.requests.Load())
+	require.Equal(t, int32(2), second.requests.Load())
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/sagernet/sing-box/dns"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/service/filemanager"
 
 	mDNS "github.com/miekg/dns"
@@ -21,22 +22,49 @@ func RegisterTransport(registry *dns.TransportRegistry) {
 
 var _ adapter.DNSTransport = (*Transport)(nil)
 
+type source interface {
+	Lookup(name string) []netip.Addr
+}
+
 type Transport struct {
 	dns.TransportAdapter
-	files      []*File
-	predefined map[string][]netip.Addr
+	sources     []source
+	remoteFiles []*RemoteFile
+	predefined  map[string][]netip.Addr
 }
 
 func NewTransport(ctx context.Context, logger log.ContextLogger, tag string, options option.HostsDNSServerOptions) (adapter.DNSTransport, error) {
 	var (
-		files      []*File
-		predefined = make(map[string][]netip.Addr)
+		sources     []source
+		remoteFiles []*RemoteFile
+		predefined  = make(map[string][]netip.Addr)
 	)
-	if len(options.Path) == 0 {
-		files = append(files, NewFile(DefaultPath))
+	if len(options.Path) == 0 && len(options.Sources) == 0 {
+		sources = append(sources, NewFile(DefaultPath))
 	} else {
 		for _, path := range options.Path {
-			files = append(files, NewFile(filemanager.BasePath(ctx, os.ExpandEnv(path))))
+			sources = append(sources, NewFile(filemanager.BasePath(ctx, os.ExpandEnv(path))))
+		}
+	}
+	for i, sourceOptions := range options.Sources {
+		switch {
+		case sourceOptions.Path != "" && sourceOptions.URL != "":
+			return nil, E.New("sources[", i, "]: path and url are mutually exclusive")
+		case sourceOptions.Path != "":
+			file, err := NewFormatFile(ctx, logger, filemanager.BasePath(ctx, os.ExpandEnv(sourceOptions.Path)), sourceOptions.Format)
+			if err != nil {
+				return nil, E.Cause(err, "sources[", i, "]")
+			}
+			sources = append(sources, file)
+		case sourceOptions.URL != "":
+			remoteFile, err := NewRemoteFile(ctx, logger, tag, sourceOptions)
+			if err != nil {
+				return nil, E.Cause(err, "sources[", i, "]")
+			}
+			sources = append(sources, remoteFile)
+			remoteFiles = append(remoteFiles, remoteFile)
+		default:
+			return nil, E.New("sources[", i, "]: missing path or url")
 		}
 	}
 	if options.Predefined != nil {
@@ -46,16 +74,33 @@ func NewTransport(ctx context.Context, logger log.ContextLogger, tag string, opt
 	}
 	return &Transport{
 		TransportAdapter: dns.NewTransportAdapter(C.DNSTypeHosts, tag, nil),
-		files:            files,
+		sources:          sources,
+		remoteFiles:      remoteFiles,
 		predefined:       predefined,
 	}, nil
 }
 
 func (t *Transport) Start(stage adapter.StartStage) error {
+	switch stage {
+	case adapter.StartStateStart:
+		for _, remoteFile := range t.remoteFiles {
+			err := remoteFile.Start()
+			if err != nil {
+				return E.Cause(err, "start hosts from URL ", remoteFile.options.URL)
+			}
+		}
+	case adapter.StartStatePostStart:
+		for _, remoteFile := range t.remoteFiles {
+			remoteFile.PostStart()
+		}
+	}
 	return nil
 }
 
 func (t *Transport) Close() error {
+	for _, remoteFile := range t.remoteFiles {
+		remoteFile.Close()
+	}
 	return nil
 }
 
@@ -69,8 +114,8 @@ func (t *Transport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg,
 		if addresses, ok := t.predefined[domain]; ok {
 			return dns.FixedResponse(message.Id, question, addresses, C.DefaultDNSTTL), nil
 		}
-		for _, file := range t.files {
-			addresses := file.Lookup(domain)
+		for _, source := range t.sources {
+			addresses := source.Lookup(domain)
 			if len(addresses) > 0 {
 				return dns.FixedResponse(message.Id, question, addresses, C.DefaultDNSTTL), nil
 			}
@@ -1,15 +1,14 @@
 package hosts
 
 import (
-	"bufio"
-	"errors"
-	"io"
+	"context"
 	"net/netip"
 	"os"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/sagernet/sing/common/logger"
+
 	"github.com/miekg/dns"
 )
 
@@ -17,8 +16,9 @@ const cacheMaxAge = 5 * time.Second
 
 type File struct {
 	path    string
+	loader  loader
 	access  sync.Mutex
-	byName  map[string][]netip.Addr
+	table   *table
 	expire  time.Time
 	modTime time.Time
 	size    int64
@@ -26,20 +26,32 @@ type File struct {
 
 func NewFile(path string) *File {
 	return &File{
-		path: path,
+		path:   path,
+		loader: loadHosts,
+	}
+}
+
+func NewFormatFile(ctx context.Context, logger logger.Logger, path string, format string) (*File, error) {
+	loader, err := newLoader(ctx, logger, format)
+	if err != nil {
+		return nil, err
 	}
+	return &File{
+		path:   path,
+		loader: loader,
+	}, nil
 }
 
 func (f *File) Lookup(name string) []netip.Addr {
 	f.access.Lock()
 	defer f.access.Unlock()
 	f.update()
-	return f.byName[dns.CanonicalName(name)]
+	return f.table.lookup(dns.CanonicalName(name))
 }
 
 func (f *File) update() {
 	now := time.Now()
-	if now.Before(f.expire) && len(f.byName) > 0 {
+	if now.Before(f.expire) && f.table != nil {
 		return
 	}
 	stat, err := os.Stat(f.path)
@@ -50,53 +62,18 @@ func (f *File) update() {
 		f.expire = now.Add(cacheMaxAge)
 		return
 	}
-	byName := make(map[string][]netip.Addr)
 	file, err := os.Open(f.path)
 	if err != nil {
 		return
 	}
 	defer file.Close()
-	reader := bufio.NewReader(file)
-	var (
-		prefix   []byte
-		line     []byte
-		isPrefix bool
-	)
-	for {
-		line, isPrefix, err = reader.ReadLine()
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				break
-			}
-			return
-		}
-		if isPrefix {
-			prefix = append(prefix, line...)
-			continue
-		} else if len(prefix) > 0 {
-			line = append(prefix, line...)
-			prefix = nil
-		}
-		commentIndex := strings.IndexRune(string(line), '#')
-		if commentIndex != -1 {
-			line = line[:commentIndex]
-		}
-		fields := strings.Fields(string(line))
-		if len(fields) < 2 {
-			continue
-		}
-		var addr netip.Addr
-		addr, err = netip.ParseAddr(fields[0])
-		if err != nil {
-			continue
-		}
-		for index := 1; index < len(fields); index++ {
-			canonicalName := dns.CanonicalName(fields[index])
-			byName[canonicalName] = append(byName[canonicalName], addr)
-		}
-	}
+	table, err := f.loader(file)
 	f.expire = now.Add(cacheMaxAge)
 	f.modTime = stat.ModTime()
 	f.size = stat.Size()
-	f.byName = byName
+	if err != nil {
+		// keep the previous entries until the file changes again
+		return
+	}
+	f.table = table
 }
@@ -0,0 +1,147 @@
+package hosts
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/netip"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/convertor/adguard"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/route/rule"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/miekg/dns"
+)
+
+// blockedAddresses is the answer for names matched by domain and AdGuard lists.
+var blockedAddresses = []netip.Addr{netip.IPv4Unspecified(), netip.IPv6Unspecified()}
+
+type table struct {
+	byName map[string][]netip.Addr
+	rules  []adapter.HeadlessRule
+}
+
+func (t *table) lookup(name string) []netip.Addr {
+	if t == nil {
+		return nil
+	}
+	if addresses, loaded := t.byName[name]; loaded {
+		return addresses
+	}
+	if len(t.rules) > 0 {
+		metadata := adapter.InboundContext{
+			Domain: strings.TrimSuffix(name, "."),
+		}
+		for _, currentRule := range t.rules {
+			metadata.ResetRuleMatchCache()
+			if currentRule.Match(&metadata) {
+				return blockedAddresses
+			}
+		}
+	}
+	return nil
+}
+
+type loader func(reader io.Reader) (*table, error)
+
+func newLoader(ctx context.Context, logger logger.Logger, format string) (loader, error) {
+	switch format {
+	case "", C.HostsFormatHosts:
+		return loadHosts, nil
+	case C.HostsFormatDomain:
+		return loadDomain, nil
+	case C.HostsFormatAdGuard:
+		return func(reader io.Reader) (*table, error) {
+			return loadAdGuard(ctx, logger, reader)
+		}, nil
+	default:
+		return nil, E.New("unknown hosts format: ", format)
+	}
+}
+
+func loadHosts(reader io.Reader) (*table, error) {
+	byName := make(map[string][]netip.Addr)
+	bufferedReader := bufio.NewReader(reader)
+	var (
+		prefix   []byte
+		line     []byte
+		isPrefix bool
+		err      error
+	)
+	for {
+		line, isPrefix, err = bufferedReader.ReadLine()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if isPrefix {
+			prefix = append(prefix, line...)
+			continue
+		} else if len(prefix) > 0 {
+			line = append(prefix, line...)
+			prefix = nil
+		}
+		commentIndex := strings.IndexRune(string(line), '#')
+		if commentIndex != -1 {
+			line = line[:commentIndex]
+		}
+		fields := strings.Fields(string(line))
+		if len(fields) < 2 {
+			continue
+		}
+		var addr netip.Addr
+		addr, err = netip.ParseAddr(fields[0])
+		if err != nil {
+			continue
+		}
+		for index := 1; index < len(fields); index++ {
+			canonicalName := dns.CanonicalName(fields[index])
+			byName[canonicalName] = append(byName[canonicalName], addr)
+		}
+	}
+	return &table{byName: byName}, nil
+}
+
+func loadDomain(reader io.Reader) (*table, error) {
+	byName := make(map[string][]netip.Addr)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		commentIndex := strings.IndexRune(line, '#')
+		if commentIndex != -1 {
+			line = line[:commentIndex]
+		}
+		line = strings.TrimSpace(line)
+		if !M.IsDomainName(line) {
+			continue
+		}
+		byName[dns.CanonicalName(line)] = blockedAddresses
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &table{byName: byName}, nil
+}
+
+func loadAdGuard(ctx context.Context, logger logger.Logger, reader io.Reader) (*table, error) {
+	rulesOptions, err := adguard.ToOptions(reader, logger)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]adapter.HeadlessRule, len(rulesOptions))
+	for i, ruleOptions := range rulesOptions {
+		rules[i], err = rule.NewHeadlessRule(ctx, ruleOptions)
+		if err != nil {
+			return nil, E.Cause(err, "parse AdGuard rule")
+		}
+	}
+	return &table{rules: rules}, nil
+}
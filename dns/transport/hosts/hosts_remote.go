@@ -0,0 +1,155 @@
+package hosts
+
+import (
+	"bytes"
+	"context"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/download"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
+
+	"github.com/miekg/dns"
+)
+
+type RemoteFile struct {
+	ctx            context.Context
+	cancel         context.CancelFunc
+	logger         log.ContextLogger
+	outbound       adapter.OutboundManager
+	options        option.HostsSourceOptions
+	cacheKey       string
+	loader         loader
+	updateInterval time.Duration
+	dialer         N.Dialer
+	access         sync.RWMutex
+	table          *table
+	downloader     download.Downloader
+	updateTicker   *time.Ticker
+}
+
+func NewRemoteFile(ctx context.Context, logger log.ContextLogger, tag string, options option.HostsSourceOptions) (*RemoteFile, error) {
+	loader, err := newLoader(ctx, logger, options.Format)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	var updateInterval time.Duration
+	if options.UpdateInterval > 0 {
+		updateInterval = time.Duration(options.UpdateInterval)
+	} else {
+		updateInterval = 24 * time.Hour
+	}
+	return &RemoteFile{
+		ctx:            ctx,
+		cancel:         cancel,
+		logger:         logger,
+		outbound:       service.FromContext[adapter.OutboundManager](ctx),
+		options:        options,
+		cacheKey:       tag + "/" + options.URL,
+		loader:         loader,
+		updateInterval: updateInterval,
+		downloader: download.Downloader{
+			Logger: logger,
+			URL:    options.URL,
+		},
+	}, nil
+}
+
+func (f *RemoteFile) Start() error {
+	if f.options.DownloadDetour != "" {
+		outbound, loaded := f.outbound.Outbound(f.options.DownloadDetour)
+		if !loaded {
+			return E.New("download detour not found: ", f.options.DownloadDetour)
+		}
+		f.dialer = outbound
+	} else {
+		f.dialer = f.outbound.Default()
+	}
+	if cacheFile := service.FromContext[adapter.CacheFile](f.ctx); cacheFile != nil {
+		f.downloader.LoadCache = func() *adapter.SavedBinary {
+			return cacheFile.LoadHosts(f.cacheKey)
+		}
+		f.downloader.SaveCache = func(saved *adapter.SavedBinary) error {
+			return cacheFile.SaveHosts(f.cacheKey, saved)
+		}
+	}
+	_, err := f.downloader.Restore(f.loadBytes)
+	if err != nil {
+		return E.Cause(err, "restore cached hosts")
+	}
+	f.updateTicker = time.NewTicker(f.updateInterval)
+	return nil
+}
+
+func (f *RemoteFile) PostStart() {
+	go f.loopUpdate()
+}
+
+func (f *RemoteFile) Lookup(name string) []netip.Addr {
+	f.access.RLock()
+	defer f.access.RUnlock()
+	return f.table.lookup(dns.CanonicalName(name))
+}
+
+func (f *RemoteFile) loadBytes(content []byte) error {
+	table, err := f.loader(bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	f.access.Lock()
+	f.table = table
+	f.access.Unlock()
+	return nil
+}
+
+func (f *RemoteFile) loopUpdate() {
+	if time.Since(f.downloader.LastUpdated) > f.updateInterval {
+		f.updateOnce()
+	}
+	for {
+		select {
+		case <-f.ctx.Done():
+			return
+		case <-f.updateTicker.C:
+			f.updateOnce()
+		}
+	}
+}
+
+func (f *RemoteFile) updateOnce() {
+	err := f.fetch(f.ctx)
+	if err != nil {
+		f.logger.Error("fetch hosts from URL ", f.options.URL, ": ", err)
+	}
+}
+
+func (f *RemoteFile) fetch(ctx context.Context) error {
+	f.logger.Debug("updating hosts from URL: ", f.options.URL)
+	httpClient := download.NewHTTPClient(f.ctx, f.dialer)
+	defer httpClient.CloseIdleConnections()
+	updated, err := f.downloader.Fetch(ctx, httpClient, f.loadBytes)
+	if err != nil {
+		return err
+	}
+	if updated {
+		f.logger.Info("updated hosts from URL ", f.options.URL)
+	} else {
+		f.logger.Info("update hosts from URL ", f.options.URL, ": not modified")
+	}
+	return nil
+}
+
+func (f *RemoteFile) Close() error {
+	f.cancel()
+	if f.updateTicker != nil {
+		f.updateTicker.Stop()
+	}
+	return nil
+}
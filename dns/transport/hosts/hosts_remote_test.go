@@ -0,0 +1,126 @@
+package hosts
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testOutbound struct {
+	adapter.Outbound
+}
+
+func (o *testOutbound) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, N.NetworkName(network), destination.String())
+}
+
+type testOutboundManager struct {
+	adapter.OutboundManager
+}
+
+func (m *testOutboundManager) Default() adapter.Outbound {
+	return &testOutbound{}
+}
+
+type testCacheFile struct {
+	adapter.CacheFile
+	access sync.Mutex
+	hosts  map[string]*adapter.SavedBinary
+}
+
+func (c *testCacheFile) LoadHosts(key string) *adapter.SavedBinary {
+	c.access.Lock()
+	defer c.access.Unlock()
+	saved := c.hosts[key]
+	if saved == nil {
+		return nil
+	}
+	savedCopy := *saved
+	return &savedCopy
+}
+
+func (c *testCacheFile) SaveHosts(key string, hosts *adapter.SavedBinary) error {
+	c.access.Lock()
+	defer c.access.Unlock()
+	c.hosts[key] = hosts
+	return nil
+}
+
+func TestRemoteFile(t *testing.T) {
+	t.Parallel()
+	var (
+		access  sync.Mutex
+		content = "1.2.3.4 example.com\n"
+		eTag    = `"v1"`
+		fetches []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		access.Lock()
+		defer access.Unlock()
+		fetches = append(fetches, r.Header.Get("If-None-Match"))
+		if r.Header.Get("If-None-Match") == eTag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Etag", eTag)
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+	cacheFile := &testCacheFile{hosts: make(map[string]*adapter.SavedBinary)}
+	ctx := service.ContextWith[adapter.OutboundManager](context.Background(), &testOutboundManager{})
+	ctx = service.ContextWith[adapter.CacheFile](ctx, cacheFile)
+	newFile := func() *RemoteFile {
+		file, err := NewRemoteFile(ctx, log.NewNOPFactory().Logger(), "remote", option.HostsSourceOptions{
+			URL:    server.URL,
+			Format: C.HostsFormatHosts,
+		})
+		require.NoError(t, err)
+		require.NoError(t, file.Start())
+		t.Cleanup(func() {
+			file.Close()
+		})
+		return file
+	}
+
+	file := newFile()
+	require.Empty(t, file.Lookup("example.com"))
+	require.NoError(t, file.fetch(context.Background()))
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("1.2.3.4")}, file.Lookup("example.com"))
+	saved := cacheFile.LoadHosts("remote/" + server.URL)
+	require.NotNil(t, saved)
+	require.Equal(t, `"v1"`, saved.LastEtag)
+	firstUpdated := saved.LastUpdated
+
+	require.NoError(t, file.fetch(context.Background()))
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("1.2.3.4")}, file.Lookup("example.com"))
+	require.True(t, cacheFile.LoadHosts("remote/"+server.URL).LastUpdated.After(firstUpdated))
+
+	access.Lock()
+	content, eTag = "5.6.7.8 example.com\n", `"v2"`
+	access.Unlock()
+	require.NoError(t, file.fetch(context.Background()))
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("5.6.7.8")}, file.Lookup("example.com"))
+
+	restored := newFile()
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("5.6.7.8")}, restored.Lookup("example.com"))
+	require.Equal(t, `"v2"`, restored.downloader.LastEtag)
+
+	access.Lock()
+	require.Equal(t, []string{"", `"v1"`, `"v1"`}, fetches)
+	access.Unlock()
+}
@@ -1,10 +1,13 @@
 package hosts_test
 
 import (
+	"context"
 	"net/netip"
 	"testing"
 
+	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/dns/transport/hosts"
+	"github.com/sagernet/sing-box/log"
 
 	"github.com/stretchr/testify/require"
 )
@@ -14,3 +17,24 @@ func TestHosts(t *testing.T) {
 	require.Equal(t, []netip.Addr{netip.AddrFrom4([4]byte{127, 0, 0, 1}), netip.IPv6Loopback()}, hosts.NewFile("testdata/hosts").Lookup("localhost"))
 	require.NotEmpty(t, hosts.NewFile(hosts.DefaultPath).Lookup("localhost"))
 }
+
+func TestHostsFormat(t *testing.T) {
+	t.Parallel()
+	blocked := []netip.Addr{netip.IPv4Unspecified(), netip.IPv6Unspecified()}
+	domainFile, err := hosts.NewFormatFile(context.Background(), log.NewNOPFactory().Logger(), "testdata/domain", C.HostsFormatDomain)
+	require.NoError(t, err)
+	require.Equal(t, blocked, domainFile.Lookup("ads.example.com"))
+	require.Equal(t, blocked, domainFile.Lookup("tracker.example.org"))
+	require.Empty(t, domainFile.Lookup("sub.ads.example.com"))
+
+	adGuardFile, err := hosts.NewFormatFile(context.Background(), log.NewNOPFactory().Logger(), "testdata/adguard", C.HostsFormatAdGuard)
+	require.NoError(t, err)
+	require.Equal(t, blocked, adGuardFile.Lookup("ads.example.com"))
+	require.Equal(t, blocked, adGuardFile.Lookup("sub.ads.example.com"))
+	require.Equal(t, blocked, adGuardFile.Lookup("tracker.example.org"))
+	require.Empty(t, adGuardFile.Lookup("safe.ads.example.com"))
+	require.Empty(t, adGuardFile.Lookup("example.com"))
+
+	_, err = hosts.NewFormatFile(context.Background(), log.NewNOPFactory().Logger(), "testdata/hosts", "unknown")
+	require.Error(t, err)
+}
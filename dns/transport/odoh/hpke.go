@@ -0,0 +1,115 @@
+package odoh
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// Minimal HPKE (RFC 9180) base mode sender for the only cipher suite
+// ODoH deployments use: DHKEM(X25519, HKDF-SHA256), HKDF-SHA256, AES-128-GCM.
+
+const (
+	kemX25519HKDFSHA256 = 0x0020
+	kdfHKDFSHA256       = 0x0001
+	aeadAES128GCM       = 0x0001
+
+	kemSecretLength = 32
+	aeadKeyLength   = 16
+	aeadNonceLength = 12
+	kdfHashLength   = sha256.Size
+)
+
+var (
+	kemSuiteID  = binary.BigEndian.AppendUint16([]byte("KEM"), kemX25519HKDFSHA256)
+	hpkeSuiteID = binary.BigEndian.AppendUint16(binary.BigEndian.AppendUint16(binary.BigEndian.AppendUint16([]byte("HPKE"), kemX25519HKDFSHA256), kdfHKDFSHA256), aeadAES128GCM)
+)
+
+type hpkeContext struct {
+	aead           cipher.AEAD
+	baseNonce      []byte
+	exporterSecret []byte
+}
+
+func labeledExtract(suiteID []byte, salt []byte, label string, ikm []byte) []byte {
+	labeledIKM := append([]byte("HPKE-v1"), suiteID...)
+	labeledIKM = append(labeledIKM, label...)
+	labeledIKM = append(labeledIKM, ikm...)
+	return hkdf.Extract(sha256.New, labeledIKM, salt)
+}
+
+func labeledExpand(suiteID []byte, prk []byte, label string, info []byte, length int) []byte {
+	labeledInfo := binary.BigEndian.AppendUint16(nil, uint16(length))
+	labeledInfo = append(labeledInfo, "HPKE-v1"...)
+	labeledInfo = append(labeledInfo, suiteID...)
+	labeledInfo = append(labeledInfo, label...)
+	labeledInfo = append(labeledInfo, info...)
+	output := make([]byte, length)
+	_, err := io.ReadFull(hkdf.Expand(sha256.New, prk, labeledInfo), output)
+	if err != nil {
+		panic(err)
+	}
+	return output
+}
+
+func extractAndExpand(dh []byte, kemContext []byte) []byte {
+	eaePRK := labeledExtract(kemSuiteID, nil, "eae_prk", dh)
+	return labeledExpand(kemSuiteID, eaePRK, "shared_secret", kemContext, kemSecretLength)
+}
+
+// setupBaseSender encapsulates a fresh shared secret to the recipient public key
+// and returns the encapsulated key with the sender context.
+func setupBaseSender(publicKey *ecdh.PublicKey, info []byte) ([]byte, *hpkeContext, error) {
+	ephemeralKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	dh, err := ephemeralKey.ECDH(publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	enc := ephemeralKey.PublicKey().Bytes()
+	kemContext := append(append([]byte{}, enc...), publicKey.Bytes()...)
+	context, err := keySchedule(extractAndExpand(dh, kemContext), info)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, context, nil
+}
+
+func keySchedule(sharedSecret []byte, info []byte) (*hpkeContext, error) {
+	pskIDHash := labeledExtract(hpkeSuiteID, nil, "psk_id_hash", nil)
+	infoHash := labeledExtract(hpkeSuiteID, nil, "info_hash", info)
+	keyScheduleContext := append([]byte{0x00}, pskIDHash...)
+	keyScheduleContext = append(keyScheduleContext, infoHash...)
+	secret := labeledExtract(hpkeSuiteID, sharedSecret, "secret", nil)
+	block, err := aes.NewCipher(labeledExpand(hpkeSuiteID, secret, "key", keyScheduleContext, aeadKeyLength))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &hpkeContext{
+		aead:           aead,
+		baseNonce:      labeledExpand(hpkeSuiteID, secret, "base_nonce", keyScheduleContext, aeadNonceLength),
+		exporterSecret: labeledExpand(hpkeSuiteID, secret, "exp", keyScheduleContext, kdfHashLength),
+	}, nil
+}
+
+// seal encrypts the first and only message of the context,
+// whose nonce is the base nonce.
+func (c *hpkeContext) seal(aad []byte, plaintext []byte) []byte {
+	return c.aead.Seal(nil, c.baseNonce, plaintext, aad)
+}
+
+func (c *hpkeContext) export(exporterContext []byte, length int) []byte {
+	return labeledExpand(hpkeSuiteID, c.exporterSecret, "sec", exporterContext, length)
+}
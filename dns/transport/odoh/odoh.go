@@ -0,0 +1,459 @@
+package odoh
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/dns"
+	"github.com/sagernet/sing-box/dns/transport"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	sHTTP "github.com/sagernet/sing/protocol/http"
+
+	mDNS "github.com/miekg/dns"
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/net/http2"
+)
+
+const (
+	MimeType = "application/oblivious-dns-message"
+
+	configVersion         = 0x0001
+	configPath            = "/.well-known/odohconfigs"
+	configRefreshInterval = time.Hour
+	messageTypeQuery      = 0x01
+	messageTypeResponse   = 0x02
+	queryPaddingBlockSize = 128
+	responseNonceLength   = max(aeadKeyLength, aeadNonceLength)
+)
+
+var errConfigRejected = E.New("target config rejected")
+
+var _ adapter.DNSTransport = (*Transport)(nil)
+
+func RegisterTransport(registry *dns.TransportRegistry) {
+	dns.RegisterTransport[option.ODoHDNSServerOptions](registry, C.DNSTypeODoH, NewTransport)
+}
+
+type Transport struct {
+	dns.TransportAdapter
+	logger          logger.ContextLogger
+	dialer          N.Dialer
+	targetDialer    N.Dialer
+	relay           *url.URL
+	target          *url.URL
+	headers         http.Header
+	transportAccess sync.Mutex
+	transport       *transport.HTTPSTransportWrapper
+	targetTransport *transport.HTTPSTransportWrapper
+	configAccess    sync.Mutex
+	config          *targetConfig
+}
+
+type targetConfig struct {
+	publicKey *ecdh.PublicKey
+	keyID     []byte
+	fetchedAt time.Time
+}
+
+func NewTransport(ctx context.Context, logger log.ContextLogger, tag string, options option.ODoHDNSServerOptions) (adapter.DNSTransport, error) {
+	if options.Target == "" {
+		return nil, E.New("missing target")
+	}
+	targetURL, err := url.Parse(options.Target)
+	if err != nil {
+		return nil, E.Cause(err, "parse target")
+	}
+	if targetURL.Scheme != "https" || targetURL.Hostname() == "" {
+		return nil, E.New("invalid target: ", options.Target)
+	}
+	if targetURL.Path == "" {
+		targetURL.Path = "/dns-query"
+	}
+	transportDialer, err := dns.NewRemoteDialer(ctx, options.RemoteDNSServerOptions)
+	if err != nil {
+		return nil, err
+	}
+	targetPort := uint16(443)
+	if targetURL.Port() != "" {
+		port, err := strconv.ParseUint(targetURL.Port(), 10, 16)
+		if err != nil {
+			return nil, E.Cause(err, "parse target port")
+		}
+		targetPort = uint16(port)
+	}
+	targetDialerOptions := options.RemoteDNSServerOptions
+	targetDialerOptions.Server = targetURL.Hostname()
+	targetDialerOptions.ServerPort = targetPort
+	targetDialer, err := dns.NewRemoteDialer(ctx, targetDialerOptions)
+	if err != nil {
+		return nil, E.Cause(err, "create target dialer")
+	}
+	tlsOptions := common.PtrValueOrDefault(options.TLS)
+	tlsOptions.Enabled = true
+	tlsConfig, err := tls.NewClient(ctx, logger, options.Server, tlsOptions)
+	if err != nil {
+		return nil, err
+	}
+	if len(tlsConfig.NextProtos()) == 0 {
+		tlsConfig.SetNextProtos([]string{http2.NextProtoTLS, "http/1.1"})
+	}
+	targetTLSConfig, err := tls.NewClient(ctx, logger, targetURL.Hostname(), option.OutboundTLSOptions{
+		Enabled: true,
+		ALPN:    []string{http2.NextProtoTLS, "http/1.1"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	headers := options.Headers.Build()
+	host := headers.Get("Host")
+	if host != "" {
+		headers.Del("Host")
+	} else if tlsConfig.ServerName() != "" {
+		host = tlsConfig.ServerName()
+	} else {
+		host = options.Server
+	}
+	relayURL := url.URL{
+		Scheme: "https",
+		Host:   host,
+	}
+	if options.ServerPort != 0 && options.ServerPort != 443 {
+		relayURL.Host = net.JoinHostPort(relayURL.Host, strconv.Itoa(int(options.ServerPort)))
+	}
+	path := options.Path
+	if path == "" {
+		path = "/proxy"
+	}
+	err = sHTTP.URLSetPath(&relayURL, path)
+	if err != nil {
+		return nil, err
+	}
+	relayQuery := relayURL.Query()
+	relayQuery.Set("targethost", targetURL.Host)
+	relayQuery.Set("targetpath", targetURL.Path)
+	relayURL.RawQuery = relayQuery.Encode()
+	serverAddr := options.DNSServerAddressOptions.Build()
+	if serverAddr.Port == 0 {
+		serverAddr.Port = 443
+	}
+	if !serverAddr.IsValid() {
+		return nil, E.New("invalid server address: ", serverAddr)
+	}
+	return &Transport{
+		TransportAdapter: dns.NewTransportAdapterWithRemoteOptions(C.DNSTypeODoH, tag, options.RemoteDNSServerOptions),
+		logger:           logger,
+		dialer:           transportDialer,
+		targetDialer:     targetDialer,
+		relay:            &relayURL,
+		target:           targetURL,
+		headers:          headers,
+		transport:        transport.NewHTTPSTransportWrapper(tls.NewDialer(transportDialer, tlsConfig), serverAddr),
+		targetTransport:  transport.NewHTTPSTransportWrapper(tls.NewDialer(targetDialer, targetTLSConfig), M.ParseSocksaddrHostPort(targetURL.Hostname(), targetPort)),
+	}, nil
+}
+
+func (t *Transport) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart {
+		return nil
+	}
+	err := dialer.InitializeDetour(t.dialer)
+	if err != nil {
+		return err
+	}
+	return dialer.InitializeDetour(t.targetDialer)
+}
+
+func (t *Transport) Close() error {
+	t.Reset()
+	return nil
+}
+
+func (t *Transport) Reset() {
+	t.transportAccess.Lock()
+	t.transport.CloseIdleConnections()
+	t.transport = t.transport.Clone()
+	t.targetTransport.CloseIdleConnections()
+	t.targetTransport = t.targetTransport.Clone()
+	t.transportAccess.Unlock()
+	t.configAccess.Lock()
+	t.config = nil
+	t.configAccess.Unlock()
+}
+
+func (t *Transport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	config, err := t.loadConfig(ctx)
+	if err != nil {
+		return nil, E.Cause(err, "fetch target config")
+	}
+	response, err := t.exchange(ctx, config, message)
+	if err != nil {
+		if !errors.Is(err, errConfigRejected) {
+			return nil, err
+		}
+		// the target may have rotated its key
+		t.configAccess.Lock()
+		if t.config == config {
+			t.config = nil
+		}
+		t.configAccess.Unlock()
+		return nil, err
+	}
+	return response, nil
+}
+
+func (t *Transport) loadConfig(ctx context.Context) (*targetConfig, error) {
+	t.configAccess.Lock()
+	defer t.configAccess.Unlock()
+	if t.config != nil && time.Since(t.config.fetchedAt) < configRefreshInterval {
+		return t.config, nil
+	}
+	config, err := t.fetchConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t.config = config
+	return config, nil
+}
+
+func (t *Transport) fetchConfig(ctx context.Context) (*targetConfig, error) {
+	configURL := url.URL{
+		Scheme: "https",
+		Host:   t.target.Host,
+		Path:   configPath,
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, configURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	t.transportAccess.Lock()
+	currentTransport := t.targetTransport
+	t.transportAccess.Unlock()
+	response, err := currentTransport.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, E.New("unexpected status: ", response.Status)
+	}
+	content, err := io.ReadAll(io.LimitReader(response.Body, 65535+2))
+	if err != nil {
+		return nil, err
+	}
+	return parseConfigs(content)
+}
+
+// parseConfigs returns the first supported config in an ObliviousDoHConfigs structure.
+func parseConfigs(content []byte) (*targetConfig, error) {
+	reader := cryptobyte.String(content)
+	var configs cryptobyte.String
+	if !reader.ReadUint16LengthPrefixed(&configs) || !reader.Empty() {
+		return nil, E.New("invalid configs")
+	}
+	for !configs.Empty() {
+		var (
+			version  uint16
+			contents cryptobyte.String
+		)
+		if !configs.ReadUint16(&version) || !configs.ReadUint16LengthPrefixed(&contents) {
+			return nil, E.New("invalid configs")
+		}
+		if version != configVersion {
+			continue
+		}
+		rawContents := []byte(contents)
+		var (
+			kemID, kdfID, aeadID uint16
+			publicKey            cryptobyte.String
+		)
+		if !contents.ReadUint16(&kemID) || !contents.ReadUint16(&kdfID) || !contents.ReadUint16(&aeadID) ||
+			!contents.ReadUint16LengthPrefixed(&publicKey) {
+			return nil, E.New("invalid config contents")
+		}
+		if kemID != kemX25519HKDFSHA256 || kdfID != kdfHKDFSHA256 || aeadID != aeadAES128GCM {
+			continue
+		}
+		key, err := ecdh.X25519().NewPublicKey(publicKey)
+		if err != nil {
+			return nil, E.Cause(err, "invalid public key")
+		}
+		keyID := make([]byte, kdfHashLength)
+		_, err = io.ReadFull(hkdf.Expand(sha256.New, hkdf.Extract(sha256.New, rawContents, nil), []byte("odoh key id")), keyID)
+		if err != nil {
+			return nil, err
+		}
+		return &targetConfig{
+			publicKey: key,
+			keyID:     keyID,
+			fetchedAt: time.Now(),
+		}, nil
+	}
+	return nil, E.New("no supported config found")
+}
+
+func (t *Transport) exchange(ctx context.Context, config *targetConfig, message *mDNS.Msg) (*mDNS.Msg, error) {
+	exMessage := *message
+	exMessage.Id = 0
+	exMessage.Compress = true
+	rawMessage, err := exMessage.Pack()
+	if err != nil {
+		return nil, err
+	}
+	queryPlain := encodePlaintext(rawMessage, (queryPaddingBlockSize-len(rawMessage)%queryPaddingBlockSize)%queryPaddingBlockSize)
+	enc, hpkeContext, err := setupBaseSender(config.publicKey, []byte("odoh query"))
+	if err != nil {
+		return nil, err
+	}
+	queryAAD := encodeAAD(messageTypeQuery, config.keyID)
+	encryptedQuery := append(enc, hpkeContext.seal(queryAAD, queryPlain)...)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, t.relay.String(), bytes.NewReader(encodeMessage(messageTypeQuery, config.keyID, encryptedQuery)))
+	if err != nil {
+		return nil, err
+	}
+	request.Header = t.headers.Clone()
+	request.Header.Set("Content-Type", MimeType)
+	request.Header.Set("Accept", MimeType)
+	t.transportAccess.Lock()
+	currentTransport := t.transport
+	t.transportAccess.Unlock()
+	response, err := currentTransport.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusBadRequest || response.StatusCode == http.StatusUnauthorized {
+		return nil, E.Cause(errConfigRejected, "unexpected status: ", response.Status)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, E.New("unexpected status: ", response.Status)
+	}
+	content, err := io.ReadAll(io.LimitReader(response.Body, 2*65535+5))
+	if err != nil {
+		return nil, err
+	}
+	responseNonce, encryptedResponse, err := decodeMessage(content, messageTypeResponse)
+	if err != nil {
+		return nil, err
+	}
+	if len(responseNonce) != responseNonceLength {
+		return nil, E.New("invalid response nonce")
+	}
+	secret := hpkeContext.export([]byte("odoh response"), aeadKeyLength)
+	salt := binary.BigEndian.AppendUint16(append([]byte{}, queryPlain...), uint16(len(responseNonce)))
+	salt = append(salt, responseNonce...)
+	prk := hkdf.Extract(sha256.New, secret, salt)
+	key := make([]byte, aeadKeyLength)
+	_, err = io.ReadFull(hkdf.Expand(sha256.New, prk, []byte("odoh key")), key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aeadNonceLength)
+	_, err = io.ReadFull(hkdf.Expand(sha256.New, prk, []byte("odoh nonce")), nonce)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	responsePlain, err := aead.Open(nil, nonce, encryptedResponse, encodeAAD(messageTypeResponse, responseNonce))
+	if err != nil {
+		return nil, E.Cause(errConfigRejected, "decrypt response: ", err)
+	}
+	rawMessage, err = decodePlaintext(responsePlain)
+	if err != nil {
+		return nil, err
+	}
+	var responseMessage mDNS.Msg
+	err = responseMessage.Unpack(rawMessage)
+	if err != nil {
+		return nil, err
+	}
+	return &responseMessage, nil
+}
+
+func encodePlaintext(message []byte, paddingLength int) []byte {
+	var builder cryptobyte.Builder
+	builder.AddUint16LengthPrefixed(func(builder *cryptobyte.Builder) {
+		builder.AddBytes(message)
+	})
+	builder.AddUint16LengthPrefixed(func(builder *cryptobyte.Builder) {
+		builder.AddBytes(make([]byte, paddingLength))
+	})
+	return builder.BytesOrPanic()
+}
+
+func decodePlaintext(content []byte) ([]byte, error) {
+	reader := cryptobyte.String(content)
+	var message, padding cryptobyte.String
+	if !reader.ReadUint16LengthPrefixed(&message) || !reader.ReadUint16LengthPrefixed(&padding) || !reader.Empty() {
+		return nil, E.New("invalid plaintext")
+	}
+	return message, nil
+}
+
+func encodeAAD(messageType uint8, keyID []byte) []byte {
+	var builder cryptobyte.Builder
+	builder.AddUint8(messageType)
+	builder.AddUint16LengthPrefixed(func(builder *cryptobyte.Builder) {
+		builder.AddBytes(keyID)
+	})
+	return builder.BytesOrPanic()
+}
+
+func encodeMessage(messageType uint8, keyID []byte, encrypted []byte) []byte {
+	var builder cryptobyte.Builder
+	builder.AddUint8(messageType)
+	builder.AddUint16LengthPrefixed(func(builder *cryptobyte.Builder) {
+		builder.AddBytes(keyID)
+	})
+	builder.AddUint16LengthPrefixed(func(builder *cryptobyte.Builder) {
+		builder.AddBytes(encrypted)
+	})
+	return builder.BytesOrPanic()
+}
+
+func decodeMessage(content []byte, expectedType uint8) ([]byte, []byte, error) {
+	reader := cryptobyte.String(content)
+	var (
+		messageType uint8
+		keyID       cryptobyte.String
+		encrypted   cryptobyte.String
+	)
+	if !reader.ReadUint8(&messageType) || !reader.ReadUint16LengthPrefixed(&keyID) ||
+		!reader.ReadUint16LengthPrefixed(&encrypted) || !reader.Empty() {
+		return nil, nil, E.New("invalid message")
+	}
+	if messageType != expectedType {
+		return nil, nil, E.New("unexpected message type: ", messageType)
+	}
+	return keyID, encrypted, nil
+}
@@ -0,0 +1,208 @@
+package odoh
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/dns"
+	"github.com/sagernet/sing-box/dns/transport"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/hkdf"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	content, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return content
+}
+
+// RFC 9180 A.1.1, DHKEM(X25519, HKDF-SHA256), HKDF-SHA256, AES-128-GCM, base mode
+func TestHPKEVector(t *testing.T) {
+	t.Parallel()
+	privateKey, err := ecdh.X25519().NewPrivateKey(mustDecodeHex(t, "4612c550263fc8ad58375df3f557aac531d26850903e55a9f23f21d8534e8ac8"))
+	require.NoError(t, err)
+	enc := mustDecodeHex(t, "37fda3567bdbd628e88668c3c8d7e97d1d1253b6d4ea6d44c150f741f1bf4431")
+	context, err := setupBaseReceiver(privateKey, enc, mustDecodeHex(t, "4f6465206f6e2061204772656369616e2055726e"))
+	require.NoError(t, err)
+	require.Equal(t, "56d890e5accaaf011cff4b7d", hex.EncodeToString(context.baseNonce))
+	require.Equal(t, "45ff1c2e220db587171952c0592d5f5ebe103f1561a2614e38f2ffd47e99e3f8", hex.EncodeToString(context.exporterSecret))
+	ciphertext := context.seal(mustDecodeHex(t, "436f756e742d30"), mustDecodeHex(t, "4265617574792069732074727574682c20747275746820626561757479"))
+	require.Equal(t, "f938558b5d72f1a23810b4be2ab4f84331acc02fc97babc53a52ae8218a355a96d8770ac83d07bea87e13c512a", hex.EncodeToString(ciphertext))
+	require.Equal(t, "3853fe2b4035195a573ffc53856e77058e15d9ea064de3e59f4961d0095250ee", hex.EncodeToString(context.export(nil, 32)))
+}
+
+func setupBaseReceiver(privateKey *ecdh.PrivateKey, enc []byte, info []byte) (*hpkeContext, error) {
+	publicKey, err := ecdh.X25519().NewPublicKey(enc)
+	if err != nil {
+		return nil, err
+	}
+	dh, err := privateKey.ECDH(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	kemContext := append(append([]byte{}, enc...), privateKey.PublicKey().Bytes()...)
+	return keySchedule(extractAndExpand(dh, kemContext), info)
+}
+
+type testTarget struct {
+	privateKey *ecdh.PrivateKey
+	configs    []byte
+	keyID      []byte
+}
+
+func newTestTarget(t *testing.T) *testTarget {
+	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	var contents cryptobyte.Builder
+	contents.AddUint16(kemX25519HKDFSHA256)
+	contents.AddUint16(kdfHKDFSHA256)
+	contents.AddUint16(aeadAES128GCM)
+	contents.AddUint16LengthPrefixed(func(builder *cryptobyte.Builder) {
+		builder.AddBytes(privateKey.PublicKey().Bytes())
+	})
+	var configs cryptobyte.Builder
+	configs.AddUint16LengthPrefixed(func(builder *cryptobyte.Builder) {
+		// unsupported version, must be skipped
+		builder.AddUint16(0xff03)
+		builder.AddUint16LengthPrefixed(func(builder *cryptobyte.Builder) {
+			builder.AddBytes([]byte{1, 2, 3})
+		})
+		builder.AddUint16(configVersion)
+		builder.AddUint16LengthPrefixed(func(builder *cryptobyte.Builder) {
+			builder.AddBytes(contents.BytesOrPanic())
+		})
+	})
+	config, err := parseConfigs(configs.BytesOrPanic())
+	require.NoError(t, err)
+	return &testTarget{
+		privateKey: privateKey,
+		configs:    configs.BytesOrPanic(),
+		keyID:      config.keyID,
+	}
+}
+
+func (s *testTarget) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	switch request.URL.Path {
+	case configPath:
+		writer.Write(s.configs)
+	case "/proxy":
+		if request.URL.Query().Get("targetpath") != "/dns-query" || request.Header.Get("Content-Type") != MimeType {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		content, _ := io.ReadAll(request.Body)
+		response, err := s.handle(content)
+		if err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		writer.Header().Set("Content-Type", MimeType)
+		writer.Write(response)
+	default:
+		writer.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *testTarget) handle(content []byte) ([]byte, error) {
+	keyID, encryptedQuery, err := decodeMessage(content, messageTypeQuery)
+	if err != nil {
+		return nil, err
+	}
+	if string(keyID) != string(s.keyID) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	context, err := setupBaseReceiver(s.privateKey, encryptedQuery[:32], []byte("odoh query"))
+	if err != nil {
+		return nil, err
+	}
+	queryPlain, err := context.aead.Open(nil, context.baseNonce, encryptedQuery[32:], encodeAAD(messageTypeQuery, keyID))
+	if err != nil {
+		return nil, err
+	}
+	if len(queryPlain)%queryPaddingBlockSize != 4 {
+		return nil, io.ErrShortBuffer
+	}
+	rawQuery, err := decodePlaintext(queryPlain)
+	if err != nil {
+		return nil, err
+	}
+	var query mDNS.Msg
+	err = query.Unpack(rawQuery)
+	if err != nil {
+		return nil, err
+	}
+	rawResponse, err := dns.FixedResponse(query.Id, query.Question[0], []netip.Addr{netip.MustParseAddr("1.2.3.4")}, 60).Pack()
+	if err != nil {
+		return nil, err
+	}
+	responseNonce := make([]byte, responseNonceLength)
+	rand.Read(responseNonce)
+	salt := binary.BigEndian.AppendUint16(append([]byte{}, queryPlain...), uint16(len(responseNonce)))
+	salt = append(salt, responseNonce...)
+	prk := hkdf.Extract(sha256.New, context.export([]byte("odoh response"), aeadKeyLength), salt)
+	key := make([]byte, aeadKeyLength)
+	io.ReadFull(hkdf.Expand(sha256.New, prk, []byte("odoh key")), key)
+	nonce := make([]byte, aeadNonceLength)
+	io.ReadFull(hkdf.Expand(sha256.New, prk, []byte("odoh nonce")), nonce)
+	block, _ := aes.NewCipher(key)
+	aead, _ := cipher.NewGCM(block)
+	encryptedResponse := aead.Seal(nil, nonce, encodePlaintext(rawResponse, 0), encodeAAD(messageTypeResponse, responseNonce))
+	return encodeMessage(messageTypeResponse, responseNonce, encryptedResponse), nil
+}
+
+func TestTransport(t *testing.T) {
+	t.Parallel()
+	target := newTestTarget(t)
+	server := httptest.NewTLSServer(target)
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.ParseUint(serverURL.Port(), 10, 16)
+	require.NoError(t, err)
+	ctx := context.Background()
+	logger := log.NewNOPFactory().NewLogger("dns")
+	var options option.ODoHDNSServerOptions
+	options.Server = "127.0.0.1"
+	options.ServerPort = uint16(port)
+	options.TLS = &option.OutboundTLSOptions{Insecure: true}
+	options.Target = "https://" + serverURL.Host
+	dnsTransport, err := NewTransport(ctx, logger, "odoh", options)
+	require.NoError(t, err)
+	odohTransport := dnsTransport.(*Transport)
+	targetTLSConfig, err := tls.NewClient(ctx, logger, "127.0.0.1", option.OutboundTLSOptions{Enabled: true, Insecure: true})
+	require.NoError(t, err)
+	odohTransport.targetTransport = transport.NewHTTPSTransportWrapper(tls.NewDialer(odohTransport.targetDialer, targetTLSConfig), M.ParseSocksaddrHostPort("127.0.0.1", uint16(port)))
+	require.NoError(t, odohTransport.Start(adapter.StartStateStart))
+	for range 2 {
+		exchangeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		request := new(mDNS.Msg)
+		request.SetQuestion("example.com.", mDNS.TypeA)
+		response, err := odohTransport.Exchange(exchangeCtx, request)
+		cancel()
+		require.NoError(t, err)
+		require.Equal(t, []netip.Addr{netip.MustParseAddr("1.2.3.4")}, dns.MessageToAddresses(response))
+	}
+	require.NoError(t, odohTransport.Close())
+}
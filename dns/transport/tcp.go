@@ -121,17 +121,16 @@ func ReadMessage(reader io.Reader) (*mDNS.Msg, error) {
 }
 
 func WriteMessage(writer io.Writer, messageId uint16, message *mDNS.Msg) error {
-	requestLen := message.Len()
-	buffer := buf.NewSize(3 + requestLen)
-	defer buffer.Release()
-	common.Must(binary.Write(buffer, binary.BigEndian, uint16(requestLen)))
 	exMessage := *message
 	exMessage.Id = messageId
 	exMessage.Compress = true
-	rawMessage, err := exMessage.PackBuffer(buffer.FreeBytes())
+	rawMessage, err := exMessage.Pack()
 	if err != nil {
 		return err
 	}
-	buffer.Truncate(2 + len(rawMessage))
+	buffer := buf.NewSize(2 + len(rawMessage))
+	defer buffer.Release()
+	common.Must(binary.Write(buffer, binary.BigEndian, uint16(len(rawMessage))))
+	common.Must1(buffer.Write(rawMessage))
 	return common.Error(writer.Write(buffer.Bytes()))
 }
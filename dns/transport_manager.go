@@ -201,6 +201,10 @@ func (m *TransportManager) Remove(tag string) error {
 	if !found {
 		return os.ErrInvalid
 	}
+	dependBy := m.dependByTag[tag]
+	if len(dependBy) > 0 {
+		return E.New("server[", tag, "] is depended by ", strings.Join(dependBy, ", "))
+	}
 	delete(m.transportByTag, tag)
 	index := common.Index(m.transports, func(it adapter.DNSTransport) bool {
 		return it == transport
@@ -222,20 +226,7 @@ func (m *TransportManager) Remove(tag string) error {
 			m.defaultTransport = nil
 		}
 	}
-	dependBy := m.dependByTag[tag]
-	if len(dependBy) > 0 {
-		return E.New("server[", tag, "] is depended by ", strings.Join(dependBy, ", "))
-	}
-	dependencies := transport.Dependencies()
-	for _, dependency := range dependencies {
-		if len(m.dependByTag[dependency]) == 1 {
-			delete(m.dependByTag, dependency)
-		} else {
-			m.dependByTag[dependency] = common.Filter(m.dependByTag[dependency], func(it string) bool {
-				return it != tag
-			})
-		}
-	}
+	m.removeDependencies(transport)
 	if started {
 		transport.Close()
 	}
@@ -274,6 +265,7 @@ func (m *TransportManager) Create(ctx context.Context, logger log.ContextLogger,
 			panic("invalid inbound index")
 		}
 		m.transports = append(m.transports[:existsIndex], m.transports[existsIndex+1:]...)
+		m.removeDependencies(existsTransport)
 	}
 	m.transports = append(m.transports, transport)
 	m.transportByTag[tag] = transport
@@ -298,3 +290,16 @@ func (m *TransportManager) Create(ctx context.Context, logger log.ContextLogger,
 	}
 	return nil
 }
+
+func (m *TransportManager) removeDependencies(transport adapter.DNSTransport) {
+	tag := transport.Tag()
+	for _, dependency := range transport.Dependencies() {
+		if len(m.dependByTag[dependency]) == 1 {
+			delete(m.dependByTag, dependency)
+		} else {
+			m.dependByTag[dependency] = common.Filter(m.dependByTag[dependency], func(it string) bool {
+				return it != tag
+			})
+		}
+	}
+}
@@ -23,13 +23,18 @@ var (
 	bucketExpand   = []byte("group_expand")
 	bucketMode     = []byte("clash_mode")
 	bucketRuleSet  = []byte("rule_set")
+	bucketHosts    = []byte("hosts")
 
 	bucketNameList = []string{
 		string(bucketSelected),
 		string(bucketExpand),
 		string(bucketMode),
 		string(bucketRuleSet),
+		string(bucketHosts),
 		string(bucketRDRC),
+		string(bucketConnectionHistory),
+		string(bucketDNSCache),
+		string(bucketUserQuota),
 	}
 
 	cacheIDDefault = []byte("default")
@@ -44,6 +49,7 @@ type CacheFile struct {
 	storeFakeIP       bool
 	storeRDRC         bool
 	rdrcTimeout       time.Duration
+	storeDNS          bool
 	DB                *bbolt.DB
 	resetAccess       sync.Mutex
 	saveMetadataTimer *time.Timer
@@ -53,6 +59,18 @@ type CacheFile struct {
 	saveAddress6      map[string]netip.Addr
 	saveRDRCAccess    sync.RWMutex
 	saveRDRC          map[saveRDRCCacheKey]bool
+
+	storeConnectionHistory    bool
+	connectionHistoryTimeout  time.Duration
+	connectionHistoryMaxCount int
+	connectionHistoryMaxSize  int
+	connectionHistoryCount    int
+	connectionHistorySize     int
+	saveHistoryAccess         sync.Mutex
+	saveHistory               []*adapter.ConnectionRecord
+	saveHistoryTimer          *time.Timer
+	saveHistoryClosed         bool
+	flushHistoryAccess        sync.Mutex
 }
 
 type saveRDRCCacheKey struct {
@@ -80,6 +98,28 @@ func New(ctx context.Context, options option.CacheFileOptions) *CacheFile {
 			rdrcTimeout = 7 * 24 * time.Hour
 		}
 	}
+	var (
+		connectionHistoryTimeout  time.Duration
+		connectionHistoryMaxCount int
+		connectionHistoryMaxSize  int
+	)
+	if options.StoreConnectionHistory {
+		if options.ConnectionHistoryTimeout > 0 {
+			connectionHistoryTimeout = time.Duration(options.ConnectionHistoryTimeout)
+		} else {
+			connectionHistoryTimeout = 7 * 24 * time.Hour
+		}
+		if options.ConnectionHistoryMaxCount > 0 {
+			connectionHistoryMaxCount = options.ConnectionHistoryMaxCount
+		} else {
+			connectionHistoryMaxCount = 100000
+		}
+		if options.ConnectionHistoryMaxSize.Value() > 0 {
+			connectionHistoryMaxSize = int(options.ConnectionHistoryMaxSize.Value())
+		} else {
+			connectionHistoryMaxSize = 64 * 1024 * 1024
+		}
+	}
 	return &CacheFile{
 		ctx:          ctx,
 		path:         filemanager.BasePath(ctx, path),
@@ -87,10 +127,17 @@ func New(ctx context.Context, options option.CacheFileOptions) *CacheFile {
 		storeFakeIP:  options.StoreFakeIP,
 		storeRDRC:    options.StoreRDRC,
 		rdrcTimeout:  rdrcTimeout,
+		storeDNS:     options.StoreDNS,
 		saveDomain:   make(map[netip.Addr]string),
 		saveAddress4: make(map[string]netip.Addr),
 		saveAddress6: make(map[string]netip.Addr),
 		saveRDRC:     make(map[saveRDRCCacheKey]bool),
+
+		storeConnectionHistory:    options.StoreConnectionHistory,
+		connectionHistoryTimeout:  connectionHistoryTimeout,
+		connectionHistoryMaxCount: connectionHistoryMaxCount,
+		connectionHistoryMaxSize:  connectionHistoryMaxSize,
+		connectionHistoryCount:    -1,
 	}
 }
 
@@ -167,6 +214,15 @@ func (c *CacheFile) Close() error {
 	if c.DB == nil {
 		return nil
 	}
+	c.saveHistoryAccess.Lock()
+	c.saveHistoryClosed = true
+	if c.saveHistoryTimer != nil {
+		c.saveHistoryTimer.Stop()
+	}
+	c.saveHistoryAccess.Unlock()
+	c.flushHistoryAccess.Lock()
+	defer c.flushHistoryAccess.Unlock()
+	_ = c.writeConnectionHistory()
 	return c.DB.Close()
 }
 
@@ -271,6 +327,23 @@ func (c *CacheFile) createBucket(t *bbolt.Tx, key []byte) (*bbolt.Bucket, error)
 	return bucket.CreateBucketIfNotExists(key)
 }
 
+func (c *CacheFile) deleteBucket(t *bbolt.Tx, key []byte) error {
+	var err error
+	if c.cacheID == nil {
+		err = t.DeleteBucket(key)
+	} else {
+		bucket := t.Bucket(c.cacheID)
+		if bucket == nil {
+			return nil
+		}
+		err = bucket.DeleteBucket(key)
+	}
+	if errors.Is(err, bbolt.ErrBucketNotFound) {
+		return nil
+	}
+	return err
+}
+
 func (c *CacheFile) LoadSelected(group string) string {
 	var selected string
 	c.view(func(t *bbolt.Tx) error {
@@ -359,3 +432,36 @@ func (c *CacheFile) SaveRuleSet(tag string, set *adapter.SavedBinary) error {
 		return bucket.Put([]byte(tag), setBinary)
 	})
 }
+
+func (c *CacheFile) LoadHosts(key string) *adapter.SavedBinary {
+	var savedHosts adapter.SavedBinary
+	err := c.view(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketHosts)
+		if bucket == nil {
+			return os.ErrNotExist
+		}
+		hostsBinary := bucket.Get([]byte(key))
+		if len(hostsBinary) == 0 {
+			return os.ErrInvalid
+		}
+		return savedHosts.UnmarshalBinary(hostsBinary)
+	})
+	if err != nil {
+		return nil
+	}
+	return &savedHosts
+}
+
+func (c *CacheFile) SaveHosts(key string, hosts *adapter.SavedBinary) error {
+	return c.batch(func(t *bbolt.Tx) error {
+		bucket, err := c.createBucket(t, bucketHosts)
+		if err != nil {
+			return err
+		}
+		hostsBinary, err := hosts.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(key), hostsBinary)
+	})
+}
@@ -0,0 +1,91 @@
+package cachefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/sagernet/bbolt"
+	"github.com/sagernet/sing-box/adapter"
+
+	"github.com/miekg/dns"
+)
+
+var bucketDNSCache = []byte("dns_cache")
+
+func (c *CacheFile) StoreDNS() bool {
+	return c.storeDNS
+}
+
+func (c *CacheFile) LoadDNSCache() ([]adapter.DNSCacheEntry, error) {
+	var entries []adapter.DNSCacheEntry
+	err := c.view(func(tx *bbolt.Tx) error {
+		bucket := c.bucket(tx, bucketDNSCache)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(key, value []byte) error {
+			separatorIndex := bytes.IndexByte(key, 0)
+			if separatorIndex == -1 || len(value) < 8 {
+				return nil
+			}
+			var message dns.Msg
+			err := message.Unpack(value[8:])
+			if err != nil || len(message.Question) == 0 {
+				return nil
+			}
+			entry := adapter.DNSCacheEntry{
+				Transport: string(key[:separatorIndex]),
+				Message:   &message,
+			}
+			if expireAt := int64(binary.BigEndian.Uint64(value)); expireAt > 0 {
+				entry.ExpireAt = time.Unix(0, expireAt)
+			}
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+	return entries, err
+}
+
+func (c *CacheFile) SaveDNSCache(entries []adapter.DNSCacheEntry) error {
+	return c.update(func(tx *bbolt.Tx) error {
+		err := c.deleteBucket(tx, bucketDNSCache)
+		if err != nil {
+			return err
+		}
+		bucket, err := c.createBucket(tx, bucketDNSCache)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			question := entry.Message.Question[0]
+			key := make([]byte, 0, len(entry.Transport)+1+len(question.Name)+4)
+			key = append(key, entry.Transport...)
+			key = append(key, 0)
+			key = append(key, question.Name...)
+			key = binary.BigEndian.AppendUint16(key, question.Qtype)
+			key = binary.BigEndian.AppendUint16(key, question.Qclass)
+			rawMessage, err := entry.Message.Pack()
+			if err != nil {
+				continue
+			}
+			value := make([]byte, 8+len(rawMessage))
+			if !entry.ExpireAt.IsZero() {
+				binary.BigEndian.PutUint64(value, uint64(entry.ExpireAt.UnixNano()))
+			}
+			copy(value[8:], rawMessage)
+			err = bucket.Put(key, value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (c *CacheFile) ClearDNSCache() error {
+	return c.update(func(tx *bbolt.Tx) error {
+		return c.deleteBucket(tx, bucketDNSCache)
+	})
+}
@@ -0,0 +1,183 @@
+package cachefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/sagernet/bbolt"
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+var bucketConnectionHistory = []byte("connection_history")
+
+const connectionHistoryKeyLength = 8 + 16
+
+func (c *CacheFile) StoreConnectionHistory() bool {
+	return c.storeConnectionHistory
+}
+
+func (c *CacheFile) SaveConnectionHistoryAsync(record *adapter.ConnectionRecord) {
+	c.saveHistoryAccess.Lock()
+	defer c.saveHistoryAccess.Unlock()
+	if c.saveHistoryClosed {
+		return
+	}
+	c.saveHistory = append(c.saveHistory, record)
+	if c.saveHistoryTimer == nil {
+		c.saveHistoryTimer = time.AfterFunc(C.ConnectionHistorySaveInterval, func() {
+			_ = c.flushConnectionHistory()
+		})
+	} else if len(c.saveHistory) == 1 {
+		c.saveHistoryTimer.Reset(C.ConnectionHistorySaveInterval)
+	}
+}
+
+func (c *CacheFile) flushConnectionHistory() error {
+	c.flushHistoryAccess.Lock()
+	defer c.flushHistoryAccess.Unlock()
+	c.saveHistoryAccess.Lock()
+	closed := c.saveHistoryClosed
+	c.saveHistoryAccess.Unlock()
+	if closed {
+		return nil
+	}
+	return c.writeConnectionHistory()
+}
+
+// writeConnectionHistory writes pending records, flushHistoryAccess must be held.
+func (c *CacheFile) writeConnectionHistory() error {
+	c.saveHistoryAccess.Lock()
+	records := c.saveHistory
+	c.saveHistory = nil
+	c.saveHistoryAccess.Unlock()
+	if len(records) == 0 {
+		return nil
+	}
+	err := c.update(func(tx *bbolt.Tx) error {
+		bucket, err := c.createBucket(tx, bucketConnectionHistory)
+		if err != nil {
+			return err
+		}
+		if c.connectionHistoryCount < 0 {
+			c.connectionHistoryCount, c.connectionHistorySize = 0, 0
+			err = bucket.ForEach(func(key, value []byte) error {
+				c.connectionHistoryCount++
+				c.connectionHistorySize += len(key) + len(value)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		for _, record := range records {
+			content, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			key := connectionHistoryKey(record)
+			err = bucket.Put(key, content)
+			if err != nil {
+				return err
+			}
+			c.connectionHistoryCount++
+			c.connectionHistorySize += len(key) + len(content)
+		}
+		return c.pruneConnectionHistory(bucket)
+	})
+	if err != nil {
+		// recount on next write since the transaction is rolled back
+		c.connectionHistoryCount = -1
+	}
+	return err
+}
+
+// pruneConnectionHistory deletes the oldest records, which are expired or
+// exceed the count or size limit. Keys are ordered by close time.
+func (c *CacheFile) pruneConnectionHistory(bucket *bbolt.Bucket) error {
+	expiresAt := timeKey(time.Now().Add(-c.connectionHistoryTimeout))
+	count, size := c.connectionHistoryCount, c.connectionHistorySize
+	var deleteKeys [][]byte
+	cursor := bucket.Cursor()
+	for key, value := cursor.First(); key != nil; key, value = cursor.Next() {
+		if count <= c.connectionHistoryMaxCount && size <= c.connectionHistoryMaxSize && bytes.Compare(key[:8], expiresAt) >= 0 {
+			break
+		}
+		deleteKeys = append(deleteKeys, key)
+		count--
+		size -= len(key) + len(value)
+	}
+	for _, key := range deleteKeys {
+		err := bucket.Delete(key)
+		if err != nil {
+			return err
+		}
+	}
+	c.connectionHistoryCount, c.connectionHistorySize = count, size
+	return nil
+}
+
+func (c *CacheFile) LoadConnectionHistory(from time.Time, to time.Time, filter func(record *adapter.ConnectionRecord) bool, limit int) ([]*adapter.ConnectionRecord, error) {
+	err := c.flushConnectionHistory()
+	if err != nil {
+		return nil, err
+	}
+	var records []*adapter.ConnectionRecord
+	err = c.view(func(tx *bbolt.Tx) error {
+		bucket := c.bucket(tx, bucketConnectionHistory)
+		if bucket == nil {
+			return nil
+		}
+		cursor := bucket.Cursor()
+		var key, value []byte
+		if to.IsZero() {
+			key, value = cursor.Last()
+		} else {
+			key, value = cursor.Seek(timeKey(to))
+			if key == nil {
+				key, value = cursor.Last()
+			} else {
+				key, value = cursor.Prev()
+			}
+		}
+		var fromKey []byte
+		if !from.IsZero() {
+			fromKey = timeKey(from)
+		}
+		for ; key != nil; key, value = cursor.Prev() {
+			if fromKey != nil && bytes.Compare(key[:8], fromKey) < 0 {
+				break
+			}
+			record, err := json.UnmarshalExtended[adapter.ConnectionRecord](value)
+			if err != nil {
+				continue
+			}
+			if filter != nil && !filter(&record) {
+				continue
+			}
+			records = append(records, &record)
+			if limit > 0 && len(records) >= limit {
+				break
+			}
+		}
+		return nil
+	})
+	return records, err
+}
+
+func connectionHistoryKey(record *adapter.ConnectionRecord) []byte {
+	key := make([]byte, connectionHistoryKeyLength)
+	copy(key, timeKey(record.ClosedAt))
+	copy(key[8:], uuid.FromStringOrNil(record.ID).Bytes())
+	return key
+}
+
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
@@ -0,0 +1,140 @@
+package cachefile
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json/badoption"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHistoryCache(t *testing.T, options option.CacheFileOptions) *CacheFile {
+	options.Path = filepath.Join(t.TempDir(), "cache.db")
+	options.StoreConnectionHistory = true
+	cacheFile := New(context.Background(), options)
+	require.NoError(t, cacheFile.Start(adapter.StartStateInitialize))
+	t.Cleanup(func() {
+		cacheFile.Close()
+	})
+	return cacheFile
+}
+
+func saveTestHistory(cacheFile *CacheFile, now time.Time, hosts ...string) {
+	for i, host := range hosts {
+		cacheFile.SaveConnectionHistoryAsync(&adapter.ConnectionRecord{
+			ID:       uuid.Must(uuid.NewV4()).String(),
+			Host:     host,
+			ClosedAt: now.Add(time.Duration(i) * time.Minute),
+		})
+	}
+}
+
+func historyHosts(records []*adapter.ConnectionRecord) []string {
+	hosts := make([]string, 0, len(records))
+	for _, record := range records {
+		hosts = append(hosts, record.Host)
+	}
+	return hosts
+}
+
+func TestConnectionHistoryQuery(t *testing.T) {
+	t.Parallel()
+	cacheFile := newTestHistoryCache(t, option.CacheFileOptions{})
+	now := time.Now().Truncate(time.Second)
+	saveTestHistory(cacheFile, now, "a", "b", "c", "d")
+	for _, testCase := range []struct {
+		name     string
+		from     time.Time
+		to       time.Time
+		filter   func(record *adapter.ConnectionRecord) bool
+		limit    int
+		expected []string
+	}{
+		{
+			name:     "all",
+			expected: []string{"d", "c", "b", "a"},
+		},
+		{
+			name:     "from",
+			from:     now.Add(time.Minute),
+			expected: []string{"d", "c", "b"},
+		},
+		{
+			name:     "to",
+			to:       now.Add(2 * time.Minute),
+			expected: []string{"b", "a"},
+		},
+		{
+			name: "filter",
+			filter: func(record *adapter.ConnectionRecord) bool {
+				return record.Host != "c"
+			},
+			limit:    2,
+			expected: []string{"d", "b"},
+		},
+	} {
+		records, err := cacheFile.LoadConnectionHistory(testCase.from, testCase.to, testCase.filter, testCase.limit)
+		require.NoError(t, err, testCase.name)
+		require.Equal(t, testCase.expected, historyHosts(records), testCase.name)
+	}
+}
+
+func TestConnectionHistoryPrune(t *testing.T) {
+	t.Parallel()
+	now := time.Now().Truncate(time.Second)
+	t.Run("count", func(t *testing.T) {
+		t.Parallel()
+		cacheFile := newTestHistoryCache(t, option.CacheFileOptions{ConnectionHistoryMaxCount: 2})
+		saveTestHistory(cacheFile, now, "a", "b", "c")
+		records, err := cacheFile.LoadConnectionHistory(time.Time{}, time.Time{}, nil, 0)
+		require.NoError(t, err)
+		require.Equal(t, []string{"c", "b"}, historyHosts(records))
+	})
+	t.Run("size", func(t *testing.T) {
+		t.Parallel()
+		cacheFile := newTestHistoryCache(t, option.CacheFileOptions{})
+		saveTestHistory(cacheFile, now, "a")
+		_, err := cacheFile.LoadConnectionHistory(time.Time{}, time.Time{}, nil, 0)
+		require.NoError(t, err)
+		// room for two records of the same size
+		cacheFile.connectionHistoryMaxSize = cacheFile.connectionHistorySize*2 + 1
+		saveTestHistory(cacheFile, now.Add(time.Minute), "b", "c")
+		records, err := cacheFile.LoadConnectionHistory(time.Time{}, time.Time{}, nil, 0)
+		require.NoError(t, err)
+		require.Equal(t, []string{"c", "b"}, historyHosts(records))
+		require.Equal(t, 2, cacheFile.connectionHistoryCount)
+	})
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+		cacheFile := newTestHistoryCache(t, option.CacheFileOptions{ConnectionHistoryTimeout: badoption.Duration(time.Hour)})
+		saveTestHistory(cacheFile, now.Add(-2*time.Hour), "a")
+		saveTestHistory(cacheFile, now, "b")
+		records, err := cacheFile.LoadConnectionHistory(time.Time{}, time.Time{}, nil, 0)
+		require.NoError(t, err)
+		require.Equal(t, []string{"b"}, historyHosts(records))
+	})
+}
+
+func TestConnectionHistoryClose(t *testing.T) {
+	t.Parallel()
+	cacheFile := newTestHistoryCache(t, option.CacheFileOptions{})
+	now := time.Now()
+	saveTestHistory(cacheFile, now, "a")
+	require.NoError(t, cacheFile.Close())
+	saveTestHistory(cacheFile, now, "b")
+	require.NoError(t, cacheFile.flushConnectionHistory())
+	require.Empty(t, cacheFile.saveHistory)
+
+	reopened := New(context.Background(), option.CacheFileOptions{Path: cacheFile.path, StoreConnectionHistory: true})
+	require.NoError(t, reopened.Start(adapter.StartStateInitialize))
+	defer reopened.Close()
+	records, err := reopened.LoadConnectionHistory(time.Time{}, time.Time{}, nil, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, historyHosts(records))
+}
@@ -0,0 +1,44 @@
+package cachefile
+
+import (
+	"os"
+
+	"github.com/sagernet/bbolt"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/json"
+)
+
+var bucketUserQuota = []byte("user_quota")
+
+func (c *CacheFile) LoadUserQuota(service string) []*adapter.SavedUserQuota {
+	var users []*adapter.SavedUserQuota
+	err := c.view(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketUserQuota)
+		if bucket == nil {
+			return os.ErrNotExist
+		}
+		content := bucket.Get([]byte(service))
+		if len(content) == 0 {
+			return os.ErrNotExist
+		}
+		return json.Unmarshal(content, &users)
+	})
+	if err != nil {
+		return nil
+	}
+	return users
+}
+
+func (c *CacheFile) SaveUserQuota(service string, users []*adapter.SavedUserQuota) error {
+	content, err := json.Marshal(users)
+	if err != nil {
+		return err
+	}
+	return c.batch(func(t *bbolt.Tx) error {
+		bucket, err := c.createBucket(t, bucketUserQuota)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(service), content)
+	})
+}
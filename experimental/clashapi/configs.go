@@ -1,9 +1,19 @@
 package clashapi
 
 import (
+	"context"
+	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 
+	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/sing/service/filemanager"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -12,7 +22,7 @@ import (
 func configRouter(server *Server, logFactory log.Factory) http.Handler {
 	r := chi.NewRouter()
 	r.Get("/", getConfigs(server, logFactory))
-	r.Put("/", updateConfigs)
+	r.Put("/", updateConfigs(server))
 	r.Patch("/", patchConfigs(server))
 	return r
 }
@@ -66,6 +76,71 @@ func patchConfigs(server *Server) func(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func updateConfigs(w http.ResponseWriter, r *http.Request) {
-	render.NoContent(w, r)
+type updateConfigRequest struct {
+	Path    string `json:"path"`
+	Payload string `json:"payload"`
+}
+
+func updateConfigs(server *Server) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request updateConfigRequest
+		err := render.DecodeJSON(r.Body, &request)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, ErrBadRequest)
+			return
+		}
+		reloader := service.FromContext[adapter.ConfigReloader](server.ctx)
+		if reloader == nil {
+			render.Status(r, http.StatusNotImplemented)
+			render.JSON(w, r, newError("reload is not supported"))
+			return
+		}
+		content := []byte(request.Payload)
+		if request.Payload == "" {
+			if request.Path == "" {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, newError("missing path or payload"))
+				return
+			}
+			content, err = readConfigFile(server.ctx, request.Path)
+			if err != nil {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, newError(err.Error()))
+				return
+			}
+		}
+		options, err := json.UnmarshalExtendedContext[option.Options](server.ctx, content)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			if request.Payload == "" {
+				// errors may quote content of the file
+				render.JSON(w, r, newError("invalid configuration file"))
+			} else {
+				render.JSON(w, r, newError(err.Error()))
+			}
+			return
+		}
+		err = reloader.Reload(options)
+		if err != nil {
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.NoContent(w, r)
+	}
+}
+
+// readConfigFile reads a configuration file in the working directory,
+// paths escaping it, including through symlinks, are rejected.
+func readConfigFile(ctx context.Context, path string) ([]byte, error) {
+	if !filepath.IsLocal(path) {
+		return nil, E.New("path must be relative to the working directory")
+	}
+	file, err := os.OpenInRoot(filemanager.BasePath(ctx, "."), path)
+	if err != nil {
+		return nil, E.New("open configuration file failed")
+	}
+	defer file.Close()
+	return io.ReadAll(file)
 }
@@ -0,0 +1,34 @@
+package clashapi
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sagernet/sing/service/filemanager"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadConfigFile(t *testing.T) {
+	t.Parallel()
+	baseDir := t.TempDir()
+	outsideDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(baseDir, "config.json"), []byte("{}"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(outsideDir, "secret"), []byte("secret"), 0o644))
+	require.NoError(t, os.Symlink(filepath.Join(outsideDir, "secret"), filepath.Join(baseDir, "link.json")))
+	ctx := filemanager.WithDefault(context.Background(), baseDir, "", os.Getuid(), os.Getgid())
+
+	content, err := readConfigFile(ctx, "config.json")
+	require.NoError(t, err)
+	require.Equal(t, "{}", string(content))
+	for _, path := range []string{
+		filepath.Join(outsideDir, "secret"),
+		filepath.Join("..", filepath.Base(outsideDir), "secret"),
+		"link.json",
+	} {
+		_, err = readConfigFile(ctx, path)
+		require.Error(t, err, path)
+	}
+}
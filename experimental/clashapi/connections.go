@@ -21,6 +21,7 @@ import (
 func connectionRouter(ctx context.Context, router adapter.Router, trafficManager *trafficontrol.Manager) http.Handler {
 	r := chi.NewRouter()
 	r.Get("/", getConnections(ctx, trafficManager))
+	r.Get("/history", getConnectionHistory(ctx))
 	r.Delete("/", closeAllConnections(router, trafficManager))
 	r.Delete("/{id}", closeConnection(trafficManager))
 	return r
@@ -28,18 +29,19 @@ func connectionRouter(ctx context.Context, router adapter.Router, trafficManager
 
 func getConnections(ctx context.Context, trafficManager *trafficontrol.Manager) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		query, err := parseConnectionQuery(r.URL.Query())
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
 		if r.Header.Get("Upgrade") != "websocket" {
 			snapshot := trafficManager.Snapshot()
+			snapshot.Connections = query.apply(snapshot.Connections)
 			render.JSON(w, r, snapshot)
 			return
 		}
 
-		conn, _, _, err := ws.UpgradeHTTP(r, w)
-		if err != nil {
-			return
-		}
-		defer conn.Close()
-
 		intervalStr := r.URL.Query().Get("interval")
 		interval := 1000
 		if intervalStr != "" {
@@ -52,12 +54,41 @@ func getConnections(ctx context.Context, trafficManager *trafficontrol.Manager)
 
 			interval = t
 		}
+		var diffMode bool
+		switch r.URL.Query().Get("mode") {
+		case "", "full":
+		case "diff":
+			diffMode = true
+		default:
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError("unknown mode: "+r.URL.Query().Get("mode")))
+			return
+		}
+
+		conn, _, _, err := ws.UpgradeHTTP(r, w)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
 
 		buf := &bytes.Buffer{}
+		var differ *connectionDiffer
+		if diffMode {
+			differ = newConnectionDiffer()
+		}
 		sendSnapshot := func() error {
 			buf.Reset()
 			snapshot := trafficManager.Snapshot()
-			if err := json.NewEncoder(buf).Encode(snapshot); err != nil {
+			var message any
+			if differ != nil {
+				// pages shift as connections come and go, so diffs cover all matched connections
+				snapshot.Connections = query.filter(snapshot.Connections)
+				message = differ.diff(snapshot)
+			} else {
+				snapshot.Connections = query.apply(snapshot.Connections)
+				message = snapshot
+			}
+			if err := json.NewEncoder(buf).Encode(message); err != nil {
 				return err
 			}
 			return wsutil.WriteServerText(conn, buf.Bytes())
@@ -82,6 +113,70 @@ func getConnections(ctx context.Context, trafficManager *trafficontrol.Manager)
 	}
 }
 
+type connectionTraffic struct {
+	Upload   int64 `json:"upload"`
+	Download int64 `json:"download"`
+}
+
+type connectionUpdate struct {
+	ID uuid.UUID `json:"id"`
+	connectionTraffic
+}
+
+type connectionDiff struct {
+	DownloadTotal int64                            `json:"downloadTotal"`
+	UploadTotal   int64                            `json:"uploadTotal"`
+	Memory        uint64                           `json:"memory"`
+	Added         []*trafficontrol.TrackerMetadata `json:"added"`
+	Removed       []uuid.UUID                      `json:"removed"`
+	Updated       []connectionUpdate               `json:"updated"`
+}
+
+// connectionDiffer tracks connections sent to a websocket client, so that
+// only added, removed and updated connections are sent afterwards.
+type connectionDiffer struct {
+	sent map[uuid.UUID]connectionTraffic
+}
+
+func newConnectionDiffer() *connectionDiffer {
+	return &connectionDiffer{
+		sent: make(map[uuid.UUID]connectionTraffic),
+	}
+}
+
+func (d *connectionDiffer) diff(snapshot *trafficontrol.Snapshot) *connectionDiff {
+	message := &connectionDiff{
+		DownloadTotal: snapshot.Download,
+		UploadTotal:   snapshot.Upload,
+		Memory:        snapshot.Memory,
+		Added:         []*trafficontrol.TrackerMetadata{},
+		Removed:       []uuid.UUID{},
+		Updated:       []connectionUpdate{},
+	}
+	current := make(map[uuid.UUID]connectionTraffic, len(snapshot.Connections))
+	for _, connection := range snapshot.Connections {
+		metadata := connection.Metadata()
+		traffic := connectionTraffic{
+			Upload:   metadata.Upload.Load(),
+			Download: metadata.Download.Load(),
+		}
+		current[metadata.ID] = traffic
+		previous, loaded := d.sent[metadata.ID]
+		if !loaded {
+			message.Added = append(message.Added, metadata)
+		} else if previous != traffic {
+			message.Updated = append(message.Updated, connectionUpdate{ID: metadata.ID, connectionTraffic: traffic})
+		}
+	}
+	for id := range d.sent {
+		if _, loaded := current[id]; !loaded {
+			message.Removed = append(message.Removed, id)
+		}
+	}
+	d.sent = current
+	return message
+}
+
 func closeConnection(trafficManager *trafficontrol.Manager) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := uuid.FromStringOrNil(chi.URLParam(r, "id"))
@@ -98,7 +193,20 @@ func closeConnection(trafficManager *trafficontrol.Manager) func(w http.Response
 
 func closeAllConnections(router adapter.Router, trafficManager *trafficontrol.Manager) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		query, err := parseConnectionQuery(r.URL.Query())
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
 		snapshot := trafficManager.Snapshot()
+		if query.selective() {
+			for _, c := range query.apply(snapshot.Connections) {
+				c.Close()
+			}
+			render.NoContent(w, r)
+			return
+		}
 		for _, c := range snapshot.Connections {
 			c.Close()
 		}
@@ -0,0 +1,87 @@
+package clashapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service"
+
+	"github.com/go-chi/render"
+)
+
+const defaultConnectionHistoryLimit = 1000
+
+// getConnectionHistory queries closed connections persisted by the cache file.
+// from and to accept RFC 3339 or unix seconds, other parameters are the same
+// as connectionQuery, except that sorting is always by close time, newest first.
+func getConnectionHistory(ctx context.Context) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacheFile := service.FromContext[adapter.CacheFile](ctx)
+		if cacheFile == nil || !cacheFile.StoreConnectionHistory() {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, newError("connection history is not enabled"))
+			return
+		}
+		query, err := parseConnectionQuery(r.URL.Query())
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		from, err := parseHistoryTime(r.URL.Query().Get("from"))
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		to, err := parseHistoryTime(r.URL.Query().Get("to"))
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		limit := query.limit
+		if limit == 0 {
+			limit = defaultConnectionHistoryLimit
+		}
+		var filter func(record *adapter.ConnectionRecord) bool
+		if query.filtered() {
+			filter = query.matchRecord
+		}
+		records, err := cacheFile.LoadConnectionHistory(from, to, filter, query.offset+limit)
+		if err != nil {
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		if query.offset >= len(records) {
+			records = nil
+		} else {
+			records = records[query.offset:]
+		}
+		if records == nil {
+			records = []*adapter.ConnectionRecord{}
+		}
+		render.JSON(w, r, render.M{
+			"connections": records,
+		})
+	}
+}
+
+func parseHistoryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, E.New("invalid time: ", value)
+	}
+	return t, nil
+}
@@ -0,0 +1,196 @@
+package clashapi
+
+import (
+	"cmp"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/experimental/clashapi/trafficontrol"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// connectionQuery selects connections by query parameters:
+//
+//	host, process, rule: case-insensitive substring
+//	chain: tag of any outbound in the chain
+//	inbound: inbound tag or type
+//	network: tcp or udp
+//	sort: start, upload, download, host, process or rule
+//	order: asc or desc
+//	offset, limit: paging after sorting, ignored by websocket diff mode
+type connectionQuery struct {
+	host    string
+	process string
+	rule    string
+	chain   string
+	inbound string
+	network string
+	sort    string
+	desc    bool
+	offset  int
+	limit   int
+}
+
+func parseConnectionQuery(query url.Values) (*connectionQuery, error) {
+	q := &connectionQuery{
+		host:    strings.ToLower(query.Get("host")),
+		process: strings.ToLower(query.Get("process")),
+		rule:    strings.ToLower(query.Get("rule")),
+		chain:   query.Get("chain"),
+		inbound: query.Get("inbound"),
+		network: strings.ToLower(query.Get("network")),
+		sort:    query.Get("sort"),
+	}
+	switch q.sort {
+	case "", "start", "upload", "download", "host", "process", "rule":
+	default:
+		return nil, E.New("unknown sort field: ", q.sort)
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		q.desc = true
+	default:
+		return nil, E.New("unknown order: ", query.Get("order"))
+	}
+	var err error
+	if offset := query.Get("offset"); offset != "" {
+		q.offset, err = strconv.Atoi(offset)
+		if err != nil || q.offset < 0 {
+			return nil, E.New("invalid offset: ", offset)
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		q.limit, err = strconv.Atoi(limit)
+		if err != nil || q.limit < 0 {
+			return nil, E.New("invalid limit: ", limit)
+		}
+	}
+	return q, nil
+}
+
+func (q *connectionQuery) filtered() bool {
+	return q.host != "" || q.process != "" || q.rule != "" || q.chain != "" || q.inbound != "" || q.network != ""
+}
+
+// selective reports whether the query selects a subset of connections.
+func (q *connectionQuery) selective() bool {
+	return q.filtered() || q.offset > 0 || q.limit > 0
+}
+
+func (q *connectionQuery) match(metadata *trafficontrol.TrackerMetadata) bool {
+	if q.host != "" && !strings.Contains(strings.ToLower(metadata.Host()), q.host) &&
+		!(metadata.Metadata.Destination.Addr.IsValid() && strings.Contains(metadata.Metadata.Destination.Addr.String(), q.host)) {
+		return false
+	}
+	if q.process != "" && !strings.Contains(strings.ToLower(metadata.ProcessPath()), q.process) {
+		return false
+	}
+	if q.rule != "" && !strings.Contains(strings.ToLower(metadata.RuleName()), q.rule) {
+		return false
+	}
+	if q.chain != "" && !slices.Contains(metadata.Chain, q.chain) {
+		return false
+	}
+	if q.inbound != "" && metadata.Metadata.Inbound != q.inbound && metadata.Metadata.InboundType != q.inbound {
+		return false
+	}
+	if q.network != "" && metadata.Metadata.Network != q.network {
+		return false
+	}
+	return true
+}
+
+func (q *connectionQuery) matchRecord(record *adapter.ConnectionRecord) bool {
+	if q.host != "" && !strings.Contains(strings.ToLower(record.Host), q.host) &&
+		!strings.Contains(record.Destination, q.host) {
+		return false
+	}
+	if q.process != "" && !strings.Contains(strings.ToLower(record.Process), q.process) {
+		return false
+	}
+	if q.rule != "" && !strings.Contains(strings.ToLower(record.Rule), q.rule) {
+		return false
+	}
+	if q.chain != "" && !slices.Contains(record.Chain, q.chain) {
+		return false
+	}
+	if q.inbound != "" && record.Inbound != q.inbound &&
+		!strings.HasPrefix(record.Inbound, q.inbound+"/") && !strings.HasSuffix(record.Inbound, "/"+q.inbound) {
+		return false
+	}
+	if q.network != "" && record.Network != q.network {
+		return false
+	}
+	return true
+}
+
+// apply filters, sorts and pages connections.
+func (q *connectionQuery) apply(connections []trafficontrol.Tracker) []trafficontrol.Tracker {
+	return q.page(q.filter(connections))
+}
+
+// filter filters and sorts connections without paging.
+func (q *connectionQuery) filter(connections []trafficontrol.Tracker) []trafficontrol.Tracker {
+	if q.filtered() {
+		connections = slices.DeleteFunc(connections, func(it trafficontrol.Tracker) bool {
+			return !q.match(it.Metadata())
+		})
+	}
+	if q.sort != "" {
+		compare := connectionCompareFunc(q.sort)
+		slices.SortStableFunc(connections, func(a, b trafficontrol.Tracker) int {
+			result := compare(a.Metadata(), b.Metadata())
+			if q.desc {
+				return -result
+			}
+			return result
+		})
+	}
+	return connections
+}
+
+func (q *connectionQuery) page(connections []trafficontrol.Tracker) []trafficontrol.Tracker {
+	if q.offset > 0 {
+		if q.offset >= len(connections) {
+			return []trafficontrol.Tracker{}
+		}
+		connections = connections[q.offset:]
+	}
+	if q.limit > 0 && len(connections) > q.limit {
+		connections = connections[:q.limit]
+	}
+	return connections
+}
+
+func connectionCompareFunc(field string) func(a, b *trafficontrol.TrackerMetadata) int {
+	switch field {
+	case "upload":
+		return func(a, b *trafficontrol.TrackerMetadata) int {
+			return cmp.Compare(a.Upload.Load(), b.Upload.Load())
+		}
+	case "download":
+		return func(a, b *trafficontrol.TrackerMetadata) int {
+			return cmp.Compare(a.Download.Load(), b.Download.Load())
+		}
+	case "host":
+		return func(a, b *trafficontrol.TrackerMetadata) int {
+			return strings.Compare(a.Host(), b.Host())
+		}
+	case "process":
+		return func(a, b *trafficontrol.TrackerMetadata) int {
+			return strings.Compare(a.ProcessPath(), b.ProcessPath())
+		}
+	case "rule":
+		return func(a, b *trafficontrol.TrackerMetadata) int {
+			return strings.Compare(a.RuleName(), b.RuleName())
+		}
+	default:
+		return func(a, b *trafficontrol.TrackerMetadata) int {
+			return a.CreatedAt.Compare(b.CreatedAt)
+		}
+	}
+}
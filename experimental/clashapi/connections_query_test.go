@@ -0,0 +1,214 @@
+package clashapi
+
+import (
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/experimental/clashapi/trafficontrol"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testTracker struct {
+	metadata trafficontrol.TrackerMetadata
+}
+
+func (t *testTracker) Metadata() *trafficontrol.TrackerMetadata {
+	return &t.metadata
+}
+
+func (t *testTracker) Close() error {
+	return nil
+}
+
+func newTestTracker(host string, inbound string, network string, chain []string, upload int64, createdAt time.Time) trafficontrol.Tracker {
+	tracker := &testTracker{metadata: trafficontrol.TrackerMetadata{
+		Metadata: adapter.InboundContext{
+			Inbound:     inbound,
+			InboundType: "mixed",
+			Network:     network,
+			Destination: M.ParseSocksaddrHostPort(host, 443),
+		},
+		CreatedAt: createdAt,
+		Upload:    new(atomic.Int64),
+		Download:  new(atomic.Int64),
+		Chain:     chain,
+	}}
+	tracker.metadata.Upload.Store(upload)
+	return tracker
+}
+
+func testTrackerHosts(connections []trafficontrol.Tracker) []string {
+	hosts := make([]string, 0, len(connections))
+	for _, connection := range connections {
+		hosts = append(hosts, connection.Metadata().Metadata.Destination.String())
+	}
+	return hosts
+}
+
+func TestParseConnectionQuery(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name  string
+		query string
+		err   bool
+	}{
+		{name: "empty", query: ""},
+		{name: "full", query: "host=Example&sort=upload&order=desc&offset=1&limit=2"},
+		{name: "unknown sort", query: "sort=size", err: true},
+		{name: "unknown order", query: "order=up", err: true},
+		{name: "invalid offset", query: "offset=a", err: true},
+		{name: "negative offset", query: "offset=-1", err: true},
+		{name: "invalid limit", query: "limit=-1", err: true},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			values, err := url.ParseQuery(testCase.query)
+			require.NoError(t, err)
+			_, err = parseConnectionQuery(values)
+			if testCase.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConnectionQueryApply(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	newConnections := func() []trafficontrol.Tracker {
+		return []trafficontrol.Tracker{
+			newTestTracker("a.example.com", "in-a", N.NetworkTCP, []string{"proxy", "direct"}, 30, now.Add(2*time.Second)),
+			newTestTracker("b.example.org", "in-b", N.NetworkUDP, []string{"direct"}, 10, now),
+			newTestTracker("c.example.com", "in-a", N.NetworkUDP, []string{"proxy"}, 20, now.Add(time.Second)),
+			newTestTracker("10.0.0.1", "in-b", N.NetworkTCP, []string{"direct"}, 0, now.Add(3*time.Second)),
+		}
+	}
+	for _, testCase := range []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{
+			name:     "all",
+			query:    "",
+			expected: []string{"a.example.com:443", "b.example.org:443", "c.example.com:443", "10.0.0.1:443"},
+		},
+		{
+			name:     "host",
+			query:    "host=EXAMPLE.com",
+			expected: []string{"a.example.com:443", "c.example.com:443"},
+		},
+		{
+			name:     "host address",
+			query:    "host=10.0",
+			expected: []string{"10.0.0.1:443"},
+		},
+		{
+			name:     "chain",
+			query:    "chain=proxy",
+			expected: []string{"a.example.com:443", "c.example.com:443"},
+		},
+		{
+			name:     "inbound tag",
+			query:    "inbound=in-b",
+			expected: []string{"b.example.org:443", "10.0.0.1:443"},
+		},
+		{
+			name:     "inbound type",
+			query:    "inbound=mixed&network=UDP",
+			expected: []string{"b.example.org:443", "c.example.com:443"},
+		},
+		{
+			name:     "sort start",
+			query:    "sort=start",
+			expected: []string{"b.example.org:443", "c.example.com:443", "a.example.com:443", "10.0.0.1:443"},
+		},
+		{
+			name:     "sort upload desc",
+			query:    "sort=upload&order=desc",
+			expected: []string{"a.example.com:443", "c.example.com:443", "b.example.org:443", "10.0.0.1:443"},
+		},
+		{
+			name:     "sort host",
+			query:    "sort=host",
+			expected: []string{"10.0.0.1:443", "a.example.com:443", "b.example.org:443", "c.example.com:443"},
+		},
+		{
+			name:     "page after filter and sort",
+			query:    "chain=direct&sort=upload&offset=1&limit=1",
+			expected: []string{"b.example.org:443"},
+		},
+		{
+			name:     "limit",
+			query:    "limit=2",
+			expected: []string{"a.example.com:443", "b.example.org:443"},
+		},
+		{
+			name:     "offset out of range",
+			query:    "offset=4",
+			expected: []string{},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			values, err := url.ParseQuery(testCase.query)
+			require.NoError(t, err)
+			query, err := parseConnectionQuery(values)
+			require.NoError(t, err)
+			result := query.apply(newConnections())
+			require.NotNil(t, result)
+			require.Equal(t, testCase.expected, testTrackerHosts(result))
+		})
+	}
+}
+
+func TestConnectionQueryFilterIgnoresPaging(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	query, err := parseConnectionQuery(url.Values{"offset": {"1"}, "limit": {"1"}, "network": {"tcp"}})
+	require.NoError(t, err)
+	connections := []trafficontrol.Tracker{
+		newTestTracker("a.example.com", "in", N.NetworkTCP, nil, 0, now),
+		newTestTracker("b.example.com", "in", N.NetworkUDP, nil, 0, now),
+		newTestTracker("c.example.com", "in", N.NetworkTCP, nil, 0, now),
+	}
+	require.Equal(t, []string{"a.example.com:443", "c.example.com:443"}, testTrackerHosts(query.filter(connections)))
+}
+
+func TestConnectionQueryMatchRecord(t *testing.T) {
+	t.Parallel()
+	record := &adapter.ConnectionRecord{
+		Host:        "www.example.com",
+		Destination: "93.184.216.34:443",
+		Inbound:     "mixed/mixed-in",
+		Network:     N.NetworkTCP,
+		Chain:       []string{"proxy"},
+	}
+	for _, testCase := range []struct {
+		query   string
+		matched bool
+	}{
+		{query: "host=EXAMPLE", matched: true},
+		{query: "host=93.184", matched: true},
+		{query: "host=example.org", matched: false},
+		{query: "inbound=mixed-in", matched: true},
+		{query: "inbound=mixed", matched: true},
+		{query: "inbound=socks", matched: false},
+		{query: "chain=proxy&network=tcp", matched: true},
+		{query: "network=udp", matched: false},
+	} {
+		values, err := url.ParseQuery(testCase.query)
+		require.NoError(t, err)
+		query, err := parseConnectionQuery(values)
+		require.NoError(t, err)
+		require.Equal(t, testCase.matched, query.matchRecord(record), testCase.query)
+	}
+}
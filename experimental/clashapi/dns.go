@@ -16,6 +16,9 @@ import (
 func dnsRouter(router adapter.DNSRouter) http.Handler {
 	r := chi.NewRouter()
 	r.Get("/query", queryDNS(router))
+	r.Get("/logs", getDNSLogs(router))
+	r.Delete("/logs", resetDNSLogs(router))
+	r.Get("/stats", getDNSStats(router))
 	return r
 }
 
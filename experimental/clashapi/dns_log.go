@@ -0,0 +1,86 @@
+package clashapi
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/go-chi/render"
+)
+
+const (
+	defaultDNSLogLimit = 100
+	defaultDNSStatsTop = 10
+)
+
+func getDNSLogs(router adapter.DNSRouter) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryLog := router.QueryLog()
+		if queryLog == nil {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, newError("DNS query log is not enabled"))
+			return
+		}
+		limit, err := parseDNSLogCount(r.URL.Query().Get("limit"), defaultDNSLogLimit)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		entries := queryLog.Entries(adapter.DNSQueryLogFilter{
+			Domain: r.URL.Query().Get("domain"),
+			Client: r.URL.Query().Get("client"),
+			Limit:  limit,
+		})
+		if entries == nil {
+			entries = []adapter.DNSQueryLogEntry{}
+		}
+		render.JSON(w, r, render.M{
+			"logs": entries,
+		})
+	}
+}
+
+func resetDNSLogs(router adapter.DNSRouter) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryLog := router.QueryLog()
+		if queryLog == nil {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, newError("DNS query log is not enabled"))
+			return
+		}
+		queryLog.Reset()
+		render.NoContent(w, r)
+	}
+}
+
+func getDNSStats(router adapter.DNSRouter) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryLog := router.QueryLog()
+		if queryLog == nil {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, newError("DNS query log is not enabled"))
+			return
+		}
+		top, err := parseDNSLogCount(r.URL.Query().Get("top"), defaultDNSStatsTop)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.JSON(w, r, queryLog.Stats(top))
+	}
+}
+
+func parseDNSLogCount(value string, defaultValue int) (int, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		return 0, E.New("invalid count: ", value)
+	}
+	return count, nil
+}
@@ -1,7 +1,9 @@
 package clashapi
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/sagernet/sing-box/adapter"
 
@@ -9,16 +11,41 @@ import (
 	"github.com/go-chi/render"
 )
 
-func ruleRouter(router adapter.Router) http.Handler {
+func ruleRouter(ctx context.Context, router adapter.Router, dnsRouter adapter.DNSRouter) http.Handler {
 	r := chi.NewRouter()
 	r.Get("/", getRules(router))
+	r.Get("/dns", getDNSRules(dnsRouter))
+	r.Delete("/statistics", resetRuleStatistics(router, dnsRouter))
+	r.Post("/test", testRules(ctx, router, dnsRouter))
 	return r
 }
 
 type Rule struct {
-	Type    string `json:"type"`
-	Payload string `json:"payload"`
-	Proxy   string `json:"proxy"`
+	Index     int        `json:"index"`
+	Type      string     `json:"type"`
+	Payload   string     `json:"payload"`
+	Proxy     string     `json:"proxy"`
+	Hits      int64      `json:"hits"`
+	LastMatch *time.Time `json:"lastMatch,omitempty"`
+	Upload    int64      `json:"upload"`
+	Download  int64      `json:"download"`
+}
+
+func newRule(index int, rule adapter.Rule) Rule {
+	statistics := rule.Statistics()
+	item := Rule{
+		Index:    index,
+		Type:     rule.Type(),
+		Payload:  rule.String(),
+		Proxy:    rule.Action().String(),
+		Hits:     statistics.Hits.Load(),
+		Upload:   statistics.Upload.Load(),
+		Download: statistics.Download.Load(),
+	}
+	if lastMatch := statistics.LastMatch(); !lastMatch.IsZero() {
+		item.LastMatch = &lastMatch
+	}
+	return item
 }
 
 func getRules(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
@@ -26,15 +53,71 @@ func getRules(router adapter.Router) func(w http.ResponseWriter, r *http.Request
 		rawRules := router.Rules()
 
 		var rules []Rule
-		for _, rule := range rawRules {
-			rules = append(rules, Rule{
-				Type:    rule.Type(),
-				Payload: rule.String(),
-				Proxy:   rule.Action().String(),
-			})
+		for i, rule := range rawRules {
+			rules = append(rules, newRule(i, rule))
 		}
 		render.JSON(w, r, render.M{
 			"rules": rules,
 		})
 	}
 }
+
+func getDNSRules(dnsRouter adapter.DNSRouter) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rawRules := dnsRouter.Rules()
+
+		var rules []Rule
+		for i, rule := range rawRules {
+			rules = append(rules, newRule(i, rule))
+		}
+		render.JSON(w, r, render.M{
+			"rules": rules,
+		})
+	}
+}
+
+func resetRuleStatistics(router adapter.Router, dnsRouter adapter.DNSRouter) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, rule := range router.Rules() {
+			rule.Statistics().Reset()
+		}
+		for _, rule := range dnsRouter.Rules() {
+			rule.Statistics().Reset()
+		}
+		render.NoContent(w, r)
+	}
+}
+
+type RuleTestResponse struct {
+	Route *adapter.RouteTestResult `json:"route"`
+	DNS   *adapter.DNSTestResult   `json:"dns,omitempty"`
+}
+
+func testRules(ctx context.Context, router adapter.Router, dnsRouter adapter.DNSRouter) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request adapter.RuleTestRequest
+		err := render.DecodeJSON(r.Body, &request)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, ErrBadRequest)
+			return
+		}
+		metadata, err := request.Metadata()
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		var response RuleTestResponse
+		if request.Domain != "" {
+			response.DNS = dnsRouter.TestDNS(ctx, metadata)
+		}
+		response.Route, err = router.TestRoute(ctx, metadata)
+		if err != nil {
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.JSON(w, r, response)
+	}
+}
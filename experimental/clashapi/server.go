@@ -122,7 +122,7 @@ func NewServer(ctx context.Context, logFactory log.ObservableFactory, options op
 		r.Get("/version", version)
 		r.Mount("/configs", configRouter(s, logFactory))
 		r.Mount("/proxies", proxyRouter(s, s.router))
-		r.Mount("/rules", ruleRouter(s.router))
+		r.Mount("/rules", ruleRouter(s.ctx, s.router, s.dnsRouter))
 		r.Mount("/connections", connectionRouter(s.ctx, s.router, trafficManager))
 		r.Mount("/providers/proxies", proxyProviderRouter(s))
 		r.Mount("/providers/rules", ruleProviderRouter())
@@ -158,6 +158,9 @@ func (s *Server) Start(stage adapter.StartStage) error {
 			}) {
 				s.mode = mode
 			}
+			if cacheFile.StoreConnectionHistory() {
+				s.trafficManager.SetHistoryStore(cacheFile)
+			}
 		}
 	case adapter.StartStateStarted:
 		if s.externalController {
@@ -6,6 +6,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/compatible"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing/common"
@@ -45,6 +46,7 @@ type Manager struct {
 	memory                  uint64
 
 	eventSubscriber *observable.Subscriber[ConnectionEvent]
+	historyStore    adapter.ConnectionHistoryStore
 }
 
 func NewManager() *Manager {
@@ -55,6 +57,11 @@ func (m *Manager) SetEventHook(subscriber *observable.Subscriber[ConnectionEvent
 	m.eventSubscriber = subscriber
 }
 
+// SetHistoryStore sets the store to persist closed connections to.
+func (m *Manager) SetHistoryStore(store adapter.ConnectionHistoryStore) {
+	m.historyStore = store
+}
+
 func (m *Manager) Join(c Tracker) {
 	metadata := c.Metadata()
 	m.connections.Store(metadata.ID, c)
@@ -80,6 +87,9 @@ func (m *Manager) Leave(c Tracker) {
 		}
 		m.closedConnections.PushBack(metadataCopy)
 		m.closedConnectionsAccess.Unlock()
+		if m.historyStore != nil && metadataCopy.OutboundType != C.TypeDNS {
+			m.historyStore.SaveConnectionHistoryAsync(metadataCopy.Record())
+		}
 		if m.eventSubscriber != nil {
 			m.eventSubscriber.Emit(ConnectionEvent{
 				Type:     ConnectionEventClosed,
@@ -29,18 +29,64 @@ type TrackerMetadata struct {
 }
 
 func (t TrackerMetadata) MarshalJSON() ([]byte, error) {
-	var inbound string
+	return json.Marshal(map[string]any{
+		"id": t.ID,
+		"metadata": map[string]any{
+			"network":         t.Metadata.Network,
+			"type":            t.InboundName(),
+			"sourceIP":        t.Metadata.Source.Addr,
+			"destinationIP":   t.Metadata.Destination.Addr,
+			"sourcePort":      F.ToString(t.Metadata.Source.Port),
+			"destinationPort": F.ToString(t.Metadata.Destination.Port),
+			"host":            t.Host(),
+			"dnsMode":         "normal",
+			"processPath":     t.ProcessPath(),
+		},
+		"upload":      t.Upload.Load(),
+		"download":    t.Download.Load(),
+		"start":       t.CreatedAt,
+		"chains":      t.Chain,
+		"rule":        t.RuleName(),
+		"rulePayload": "",
+	})
+}
+
+func (t TrackerMetadata) Record() *adapter.ConnectionRecord {
+	record := &adapter.ConnectionRecord{
+		ID:          t.ID.String(),
+		Network:     t.Metadata.Network,
+		Inbound:     t.InboundName(),
+		User:        t.Metadata.User,
+		Source:      t.Metadata.Source.String(),
+		Destination: t.Metadata.Destination.String(),
+		Host:        t.Host(),
+		Process:     t.ProcessPath(),
+		Rule:        t.RuleName(),
+		Outbound:    t.Outbound,
+		Chain:       t.Chain,
+		Upload:      t.Upload.Load(),
+		Download:    t.Download.Load(),
+		CreatedAt:   t.CreatedAt,
+		ClosedAt:    t.ClosedAt,
+	}
+	return record
+}
+
+func (t TrackerMetadata) InboundName() string {
 	if t.Metadata.Inbound != "" {
-		inbound = t.Metadata.InboundType + "/" + t.Metadata.Inbound
-	} else {
-		inbound = t.Metadata.InboundType
+		return t.Metadata.InboundType + "/" + t.Metadata.Inbound
 	}
-	var domain string
+	return t.Metadata.InboundType
+}
+
+func (t TrackerMetadata) Host() string {
 	if t.Metadata.Domain != "" {
-		domain = t.Metadata.Domain
-	} else {
-		domain = t.Metadata.Destination.Fqdn
+		return t.Metadata.Domain
 	}
+	return t.Metadata.Destination.Fqdn
+}
+
+func (t TrackerMetadata) ProcessPath() string {
 	var processPath string
 	if t.Metadata.ProcessInfo != nil {
 		if t.Metadata.ProcessInfo.ProcessPath != "" {
@@ -58,32 +104,14 @@ func (t TrackerMetadata) MarshalJSON() ([]byte, error) {
 			processPath = F.ToString(processPath, " (", t.Metadata.ProcessInfo.UserId, ")")
 		}
 	}
-	var rule string
+	return processPath
+}
+
+func (t TrackerMetadata) RuleName() string {
 	if t.Rule != nil {
-		rule = F.ToString(t.Rule, " => ", t.Rule.Action())
-	} else {
-		rule = "final"
+		return F.ToString(t.Rule, " => ", t.Rule.Action())
 	}
-	return json.Marshal(map[string]any{
-		"id": t.ID,
-		"metadata": map[string]any{
-			"network":         t.Metadata.Network,
-			"type":            inbound,
-			"sourceIP":        t.Metadata.Source.Addr,
-			"destinationIP":   t.Metadata.Destination.Addr,
-			"sourcePort":      F.ToString(t.Metadata.Source.Port),
-			"destinationPort": F.ToString(t.Metadata.Destination.Port),
-			"host":            domain,
-			"dnsMode":         "normal",
-			"processPath":     processPath,
-		},
-		"upload":      t.Upload.Load(),
-		"download":    t.Download.Load(),
-		"start":       t.CreatedAt,
-		"chains":      t.Chain,
-		"rule":        rule,
-		"rulePayload": "",
-	})
+	return "final"
 }
 
 type Tracker interface {
@@ -14,7 +14,9 @@ import (
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common/bufio"
 	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
 	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
 )
 
 func init() {
@@ -27,15 +29,17 @@ var (
 )
 
 type StatsService struct {
+	ctx       context.Context
 	createdAt time.Time
 	inbounds  map[string]bool
 	outbounds map[string]bool
 	users     map[string]bool
+	rules     bool
 	access    sync.Mutex
 	counters  map[string]*atomic.Int64
 }
 
-func NewStatsService(options option.V2RayStatsServiceOptions) *StatsService {
+func NewStatsService(ctx context.Context, options option.V2RayStatsServiceOptions) *StatsService {
 	if !options.Enabled {
 		return nil
 	}
@@ -52,10 +56,12 @@ func NewStatsService(options option.V2RayStatsServiceOptions) *StatsService {
 		users[user] = true
 	}
 	return &StatsService{
+		ctx:       ctx,
 		createdAt: time.Now(),
 		inbounds:  inbounds,
 		outbounds: outbounds,
 		users:     users,
+		rules:     options.Rules,
 		counters:  make(map[string]*atomic.Int64),
 	}
 }
@@ -120,9 +126,9 @@ func (s *StatsService) RoutedPacketConnection(ctx context.Context, conn N.Packet
 
 func (s *StatsService) GetStats(ctx context.Context, request *GetStatsRequest) (*GetStatsResponse, error) {
 	s.access.Lock()
-	counter, loaded := s.counters[request.Name]
+	counter := s.loadCounter(request.Name)
 	s.access.Unlock()
-	if !loaded {
+	if counter == nil {
 		return nil, E.New(request.Name, " not found.")
 	}
 	var value int64
@@ -139,7 +145,7 @@ func (s *StatsService) QueryStats(ctx context.Context, request *QueryStatsReques
 	s.access.Lock()
 	defer s.access.Unlock()
 	if len(request.Patterns) == 0 {
-		for name, counter := range s.counters {
+		s.rangeCounters(func(name string, counter *atomic.Int64) {
 			var value int64
 			if request.Reset_ {
 				value = counter.Swap(0)
@@ -147,7 +153,7 @@ func (s *StatsService) QueryStats(ctx context.Context, request *QueryStatsReques
 				value = counter.Load()
 			}
 			response.Stat = append(response.Stat, &Stat{Name: name, Value: value})
-		}
+		})
 	} else if request.Regexp {
 		matchers := make([]*regexp.Regexp, 0, len(request.Patterns))
 		for _, pattern := range request.Patterns {
@@ -157,7 +163,7 @@ func (s *StatsService) QueryStats(ctx context.Context, request *QueryStatsReques
 			}
 			matchers = append(matchers, matcher)
 		}
-		for name, counter := range s.counters {
+		s.rangeCounters(func(name string, counter *atomic.Int64) {
 			for _, matcher := range matchers {
 				if matcher.MatchString(name) {
 					var value int64
@@ -169,9 +175,9 @@ func (s *StatsService) QueryStats(ctx context.Context, request *QueryStatsReques
 					response.Stat = append(response.Stat, &Stat{Name: name, Value: value})
 				}
 			}
-		}
+		})
 	} else {
-		for name, counter := range s.counters {
+		s.rangeCounters(func(name string, counter *atomic.Int64) {
 			for _, matcher := range request.Patterns {
 				if strings.Contains(name, matcher) {
 					var value int64
@@ -183,7 +189,7 @@ func (s *StatsService) QueryStats(ctx context.Context, request *QueryStatsReques
 					response.Stat = append(response.Stat, &Stat{Name: name, Value: value})
 				}
 			}
-		}
+		})
 	}
 	return &response, nil
 }
@@ -210,6 +216,73 @@ func (s *StatsService) GetSysStats(ctx context.Context, request *SysStatsRequest
 func (s *StatsService) mustEmbedUnimplementedStatsServiceServer() {
 }
 
+func (s *StatsService) loadCounter(name string) *atomic.Int64 {
+	counter, loaded := s.counters[name]
+	if loaded {
+		return counter
+	}
+	if !s.rules || !(strings.HasPrefix(name, "rule>>>") || strings.HasPrefix(name, "dns_rule>>>")) {
+		return nil
+	}
+	s.rangeRuleCounters(func(ruleName string, ruleCounter *atomic.Int64) bool {
+		if ruleName == name {
+			counter = ruleCounter
+			return false
+		}
+		return true
+	})
+	return counter
+}
+
+// rangeCounters calls f with connection counters, along with counters of
+// current route and DNS rules if enabled.
+func (s *StatsService) rangeCounters(f func(name string, counter *atomic.Int64)) {
+	for name, counter := range s.counters {
+		f(name, counter)
+	}
+	if !s.rules {
+		return
+	}
+	seen := make(map[string]bool)
+	s.rangeRuleCounters(func(name string, counter *atomic.Int64) bool {
+		if !seen[name] {
+			seen[name] = true
+			f(name, counter)
+		}
+		return true
+	})
+}
+
+// rangeRuleCounters calls f with rule counters until it returns false.
+// Counters are named by rule description and action, so they follow rules
+// across reloads, only the first of identical rules is exposed by rangeCounters.
+func (s *StatsService) rangeRuleCounters(f func(name string, counter *atomic.Int64) bool) {
+	router := service.FromContext[adapter.Router](s.ctx)
+	if router != nil {
+		for _, rule := range router.Rules() {
+			statistics := rule.Statistics()
+			prefix := "rule>>>" + ruleCounterName(rule) + ">>>"
+			if !f(prefix+"hits", &statistics.Hits) ||
+				!f(prefix+"traffic>>>uplink", &statistics.Upload) ||
+				!f(prefix+"traffic>>>downlink", &statistics.Download) {
+				return
+			}
+		}
+	}
+	dnsRouter := service.FromContext[adapter.DNSRouter](s.ctx)
+	if dnsRouter != nil {
+		for _, rule := range dnsRouter.Rules() {
+			if !f("dns_rule>>>"+ruleCounterName(rule)+">>>hits", &rule.Statistics().Hits) {
+				return
+			}
+		}
+	}
+}
+
+func ruleCounterName(rule adapter.Rule) string {
+	return F.ToString(rule, " => ", rule.Action())
+}
+
 //nolint:staticcheck
 func (s *StatsService) loadOrCreateCounter(name string) *atomic.Int64 {
 	counter, loaded := s.counters[name]
@@ -0,0 +1,95 @@
+package v2rayapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	R "github.com/sagernet/sing-box/route/rule"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testRouter struct {
+	adapter.Router
+	rules []adapter.Rule
+}
+
+func (r *testRouter) Rules() []adapter.Rule {
+	return r.rules
+}
+
+type testDNSRouter struct {
+	adapter.DNSRouter
+	rules []adapter.DNSRule
+}
+
+func (r *testDNSRouter) Rules() []adapter.DNSRule {
+	return r.rules
+}
+
+func newTestRules(t *testing.T, content string) []adapter.Rule {
+	ruleOptions, err := json.UnmarshalExtendedContext[[]option.Rule](context.Background(), []byte(content))
+	require.NoError(t, err)
+	var rules []adapter.Rule
+	for _, options := range ruleOptions {
+		rule, err := R.NewRule(context.Background(), log.NewNOPFactory().NewLogger("router"), options, false)
+		require.NoError(t, err)
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func TestRuleCounters(t *testing.T) {
+	t.Parallel()
+	router := &testRouter{rules: newTestRules(t, `[
+		{"domain_suffix": "example.com", "outbound": "proxy"},
+		{"ip_is_private": true, "outbound": "direct"},
+		{"domain_suffix": "example.com", "outbound": "proxy"}
+	]`)}
+	dnsRuleOptions, err := json.UnmarshalExtendedContext[[]option.DNSRule](context.Background(), []byte(`[
+		{"domain_suffix": "example.com", "server": "local"}
+	]`))
+	require.NoError(t, err)
+	dnsRule, err := R.NewDNSRule(context.Background(), log.NewNOPFactory().NewLogger("dns"), dnsRuleOptions[0], false)
+	require.NoError(t, err)
+	dnsRouter := &testDNSRouter{rules: []adapter.DNSRule{dnsRule}}
+	ctx := service.ContextWith[adapter.Router](context.Background(), router)
+	ctx = service.ContextWith[adapter.DNSRouter](ctx, dnsRouter)
+	router.rules[0].Statistics().Hits.Store(3)
+	router.rules[1].Statistics().Upload.Store(5)
+	dnsRule.Statistics().Hits.Store(7)
+
+	s := NewStatsService(ctx, option.V2RayStatsServiceOptions{Enabled: true, Rules: true})
+	hitsName := "rule>>>" + router.rules[0].String() + " => " + router.rules[0].Action().String() + ">>>hits"
+	response, err := s.GetStats(context.Background(), &GetStatsRequest{Name: hitsName})
+	require.NoError(t, err)
+	require.EqualValues(t, 3, response.Stat.Value)
+	response, err = s.GetStats(context.Background(), &GetStatsRequest{Name: "dns_rule>>>" + dnsRule.String() + " => " + dnsRule.Action().String() + ">>>hits"})
+	require.NoError(t, err)
+	require.EqualValues(t, 7, response.Stat.Value)
+
+	// counters follow rules when they are reordered
+	router.rules[0], router.rules[1] = router.rules[1], router.rules[0]
+	response, err = s.GetStats(context.Background(), &GetStatsRequest{Name: hitsName, Reset_: true})
+	require.NoError(t, err)
+	require.EqualValues(t, 3, response.Stat.Value)
+	require.Zero(t, router.rules[1].Statistics().Hits.Load())
+
+	queryResponse, err := s.QueryStats(context.Background(), &QueryStatsRequest{Patterns: []string{"rule>>>"}})
+	require.NoError(t, err)
+	// identical rules are exposed once
+	require.Len(t, queryResponse.Stat, 3*2+1)
+	queryResponse, err = s.QueryStats(context.Background(), &QueryStatsRequest{Patterns: []string{"ip_is_private.*uplink$"}, Regexp: true})
+	require.NoError(t, err)
+	require.Len(t, queryResponse.Stat, 1)
+	require.EqualValues(t, 5, queryResponse.Stat[0].Value)
+
+	s = NewStatsService(ctx, option.V2RayStatsServiceOptions{Enabled: true})
+	_, err = s.GetStats(context.Background(), &GetStatsRequest{Name: hitsName})
+	require.Error(t, err)
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/sagernet/sing-box/dns"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	dnsProtocol "github.com/sagernet/sing-box/protocol/dns"
 	"github.com/sagernet/sing-box/protocol/naive"
 	"github.com/sagernet/sing-box/transport/v2ray"
 	"github.com/sagernet/sing/common/logger"
@@ -44,6 +45,9 @@ func registerQUICInbounds(registry *inbound.Registry) {
 	inbound.Register[option.Hysteria2InboundOptions](registry, C.TypeHysteria2, func(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.Hysteria2InboundOptions) (adapter.Inbound, error) {
 		return nil, C.ErrQUICNotIncluded
 	})
+	dnsProtocol.ConfigureQUICListenerFunc = func(ctx context.Context, logger logger.ContextLogger, listener *listener.Listener, tlsConfig tls.ServerConfig, handler func(ctx context.Context, stream io.ReadWriter, source M.Socksaddr) error) (io.Closer, error) {
+		return nil, C.ErrQUICNotIncluded
+	}
 	naive.ConfigureHTTP3ListenerFunc = func(ctx context.Context, logger logger.Logger, listener *listener.Listener, handler http.Handler, tlsConfig tls.ServerConfig, options option.NaiveInboundOptions) (io.Closer, error) {
 		return nil, C.ErrQUICNotIncluded
 	}
@@ -8,6 +8,8 @@ import (
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/experimental/deprecated"
 	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/auth"
+	"github.com/sagernet/sing/common/byteformats"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/json"
 	"github.com/sagernet/sing/common/json/badjson"
@@ -19,13 +21,23 @@ import (
 )
 
 type RawDNSOptions struct {
-	Servers        []DNSServerOptions `json:"servers,omitempty"`
-	Rules          []DNSRule          `json:"rules,omitempty"`
-	Final          string             `json:"final,omitempty"`
-	ReverseMapping bool               `json:"reverse_mapping,omitempty"`
+	Servers        []DNSServerOptions  `json:"servers,omitempty"`
+	Rules          []DNSRule           `json:"rules,omitempty"`
+	Final          string              `json:"final,omitempty"`
+	ReverseMapping bool                `json:"reverse_mapping,omitempty"`
+	QueryLog       *DNSQueryLogOptions `json:"query_log,omitempty"`
+	RuleStatistics bool                `json:"rule_statistics,omitempty"`
 	DNSClientOptions
 }
 
+type DNSQueryLogOptions struct {
+	Enabled    bool                     `json:"enabled,omitempty"`
+	Capacity   uint32                   `json:"capacity,omitempty"`
+	Path       string                   `json:"path,omitempty"`
+	MaxSize    *byteformats.MemoryBytes `json:"max_size,omitempty"`
+	MaxBackups uint32                   `json:"max_backups,omitempty"`
+}
+
 type LegacyDNSOptions struct {
 	FakeIP *LegacyDNSFakeIPOptions `json:"fakeip,omitempty"`
 }
@@ -109,6 +121,20 @@ type DNSClientOptions struct {
 	IndependentCache bool                  `json:"independent_cache,omitempty"`
 	CacheCapacity    uint32                `json:"cache_capacity,omitempty"`
 	ClientSubnet     *badoption.Prefixable `json:"client_subnet,omitempty"`
+
+	ServeStale         bool               `json:"serve_stale,omitempty"`
+	StaleTTL           badoption.Duration `json:"stale_ttl,omitempty"`
+	StaleAnswerTimeout badoption.Duration `json:"stale_answer_timeout,omitempty"`
+	OptimisticCache    bool               `json:"optimistic_cache,omitempty"`
+	PrefetchHits       uint32             `json:"prefetch_hits,omitempty"`
+
+	DNSSEC *DNSSECOptions `json:"dnssec,omitempty"`
+}
+
+type DNSSECOptions struct {
+	Enabled      bool                                 `json:"enabled,omitempty"`
+	LogOnly      bool                                 `json:"log_only,omitempty"`
+	TrustAnchors badoption.Listable[DNSRecordOptions] `json:"trust_anchors,omitempty"`
 }
 
 type LegacyDNSFakeIPOptions struct {
@@ -363,6 +389,15 @@ type LegacyDNSServerOptions struct {
 type HostsDNSServerOptions struct {
 	Path       badoption.Listable[string]                                `json:"path,omitempty"`
 	Predefined *badjson.TypedMap[string, badoption.Listable[netip.Addr]] `json:"predefined,omitempty"`
+	Sources    []HostsSourceOptions                                      `json:"sources,omitempty"`
+}
+
+type HostsSourceOptions struct {
+	Path           string             `json:"path,omitempty"`
+	URL            string             `json:"url,omitempty"`
+	Format         string             `json:"format,omitempty"`
+	DownloadDetour string             `json:"download_detour,omitempty"`
+	UpdateInterval badoption.Duration `json:"update_interval,omitempty"`
 }
 
 type RawLocalDNSServerOptions struct {
@@ -398,12 +433,38 @@ type RemoteHTTPSDNSServerOptions struct {
 	Headers badoption.HTTPHeader `json:"headers,omitempty"`
 }
 
+type DNSCryptDNSServerOptions struct {
+	RemoteDNSServerOptions
+	Stamp string `json:"stamp"`
+}
+
+type ODoHDNSServerOptions struct {
+	RemoteHTTPSDNSServerOptions
+	Target string `json:"target"`
+}
+
 type FakeIPDNSServerOptions struct {
 	Inet4Range *badoption.Prefix `json:"inet4_range,omitempty"`
 	Inet6Range *badoption.Prefix `json:"inet6_range,omitempty"`
 }
 
+type GroupDNSServerOptions struct {
+	Servers     badoption.Listable[string] `json:"servers"`
+	Mode        string                     `json:"mode,omitempty"`
+	Timeout     badoption.Duration         `json:"timeout,omitempty"`
+	MaxFail     uint32                     `json:"max_fail,omitempty"`
+	FailTimeout badoption.Duration         `json:"fail_timeout,omitempty"`
+}
+
 type DHCPDNSServerOptions struct {
 	LocalDNSServerOptions
 	Interface string `json:"interface,omitempty"`
 }
+
+type DNSInboundOptions struct {
+	ListenOptions
+	Protocol string      `json:"protocol,omitempty"`
+	Path     string      `json:"path,omitempty"`
+	Users    []auth.User `json:"users,omitempty"`
+	InboundTLSOptionsContainer
+}
@@ -1,6 +1,9 @@
 package option
 
-import "github.com/sagernet/sing/common/json/badoption"
+import (
+	"github.com/sagernet/sing/common/byteformats"
+	"github.com/sagernet/sing/common/json/badoption"
+)
 
 type ExperimentalOptions struct {
 	CacheFile *CacheFileOptions `json:"cache_file,omitempty"`
@@ -16,6 +19,12 @@ type CacheFileOptions struct {
 	StoreFakeIP bool               `json:"store_fakeip,omitempty"`
 	StoreRDRC   bool               `json:"store_rdrc,omitempty"`
 	RDRCTimeout badoption.Duration `json:"rdrc_timeout,omitempty"`
+	StoreDNS    bool               `json:"store_dns,omitempty"`
+
+	StoreConnectionHistory    bool                     `json:"store_connection_history,omitempty"`
+	ConnectionHistoryTimeout  badoption.Duration       `json:"connection_history_timeout,omitempty"`
+	ConnectionHistoryMaxCount int                      `json:"connection_history_max_count,omitempty"`
+	ConnectionHistoryMaxSize  *byteformats.MemoryBytes `json:"connection_history_max_size,omitempty"`
 }
 
 type ClashAPIOptions struct {
@@ -51,4 +60,5 @@ type V2RayStatsServiceOptions struct {
 	Inbounds  []string `json:"inbounds,omitempty"`
 	Outbounds []string `json:"outbounds,omitempty"`
 	Users     []string `json:"users,omitempty"`
+	Rules     bool     `json:"rules,omitempty"`
 }
@@ -5,6 +5,7 @@ import "github.com/sagernet/sing/common/json/badoption"
 type RouteOptions struct {
 	GeoIP                      *GeoIPOptions                     `json:"geoip,omitempty"`
 	Geosite                    *GeositeOptions                   `json:"geosite,omitempty"`
+	ASN                        *ASNOptions                       `json:"asn,omitempty"`
 	Rules                      []Rule                            `json:"rules,omitempty"`
 	RuleSet                    []RuleSet                         `json:"rule_set,omitempty"`
 	Final                      string                            `json:"final,omitempty"`
@@ -18,6 +19,7 @@ type RouteOptions struct {
 	DefaultNetworkType         badoption.Listable[InterfaceType] `json:"default_network_type,omitempty"`
 	DefaultFallbackNetworkType badoption.Listable[InterfaceType] `json:"default_fallback_network_type,omitempty"`
 	DefaultFallbackDelay       badoption.Duration                `json:"default_fallback_delay,omitempty"`
+	RuleStatistics             bool                              `json:"rule_statistics,omitempty"`
 }
 
 type GeoIPOptions struct {
@@ -26,6 +28,10 @@ type GeoIPOptions struct {
 	DownloadDetour string `json:"download_detour,omitempty"`
 }
 
+type ASNOptions struct {
+	Path string `json:"path,omitempty"`
+}
+
 type GeositeOptions struct {
 	Path           string `json:"path,omitempty"`
 	DownloadURL    string `json:"download_url,omitempty"`
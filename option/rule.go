@@ -73,6 +73,9 @@ type RawDefaultRule struct {
 	AuthUser                 badoption.Listable[string]                                                  `json:"auth_user,omitempty"`
 	Protocol                 badoption.Listable[string]                                                  `json:"protocol,omitempty"`
 	Client                   badoption.Listable[string]                                                  `json:"client,omitempty"`
+	ClientFingerprint        badoption.Listable[string]                                                  `json:"client_fingerprint,omitempty"`
+	HTTPUserAgent            badoption.Listable[string]                                                  `json:"http_user_agent,omitempty"`
+	HTTPPathRegex            badoption.Listable[string]                                                  `json:"http_path_regex,omitempty"`
 	Domain                   badoption.Listable[string]                                                  `json:"domain,omitempty"`
 	DomainSuffix             badoption.Listable[string]                                                  `json:"domain_suffix,omitempty"`
 	DomainKeyword            badoption.Listable[string]                                                  `json:"domain_keyword,omitempty"`
@@ -84,6 +87,8 @@ type RawDefaultRule struct {
 	SourceIPIsPrivate        bool                                                                        `json:"source_ip_is_private,omitempty"`
 	IPCIDR                   badoption.Listable[string]                                                  `json:"ip_cidr,omitempty"`
 	IPIsPrivate              bool                                                                        `json:"ip_is_private,omitempty"`
+	SourceIPASN              badoption.Listable[uint32]                                                  `json:"source_ip_asn,omitempty"`
+	IPASN                    badoption.Listable[uint32]                                                  `json:"ip_asn,omitempty"`
 	SourcePort               badoption.Listable[uint16]                                                  `json:"source_port,omitempty"`
 	SourcePortRange          badoption.Listable[string]                                                  `json:"source_port_range,omitempty"`
 	Port                     badoption.Listable[uint16]                                                  `json:"port,omitempty"`
@@ -95,6 +100,7 @@ type RawDefaultRule struct {
 	User                     badoption.Listable[string]                                                  `json:"user,omitempty"`
 	UserID                   badoption.Listable[int32]                                                   `json:"user_id,omitempty"`
 	ClashMode                string                                                                      `json:"clash_mode,omitempty"`
+	Time                     *TimeRuleOptions                                                            `json:"time,omitempty"`
 	NetworkType              badoption.Listable[InterfaceType]                                           `json:"network_type,omitempty"`
 	NetworkIsExpensive       bool                                                                        `json:"network_is_expensive,omitempty"`
 	NetworkIsConstrained     bool                                                                        `json:"network_is_constrained,omitempty"`
@@ -161,3 +167,9 @@ func (r *LogicalRule) UnmarshalJSON(data []byte) error {
 func (r *LogicalRule) IsValid() bool {
 	return len(r.Rules) > 0 && common.All(r.Rules, Rule.IsValid)
 }
+
+type TimeRuleOptions struct {
+	Weekday  badoption.Listable[string] `json:"weekday,omitempty"`
+	Range    badoption.Listable[string] `json:"range,omitempty"`
+	Timezone string                     `json:"timezone,omitempty"`
+}
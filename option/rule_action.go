@@ -7,6 +7,7 @@ import (
 	"time"
 
 	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common/byteformats"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/json"
 	"github.com/sagernet/sing/common/json/badjson"
@@ -22,6 +23,8 @@ type _RuleAction struct {
 	RejectOptions       RejectActionOptions       `json:"-"`
 	SniffOptions        RouteActionSniff          `json:"-"`
 	ResolveOptions      RouteActionResolve        `json:"-"`
+	LimitOptions        RouteActionLimit          `json:"-"`
+	HTTPRewriteOptions  RouteActionHTTPRewrite    `json:"-"`
 }
 
 type RuleAction _RuleAction
@@ -49,6 +52,10 @@ func (r RuleAction) MarshalJSON() ([]byte, error) {
 		v = r.SniffOptions
 	case C.RuleActionTypeResolve:
 		v = r.ResolveOptions
+	case C.RuleActionTypeLimit:
+		v = r.LimitOptions
+	case C.RuleActionTypeHTTPRewrite:
+		v = r.HTTPRewriteOptions
 	default:
 		return nil, E.New("unknown rule action: " + r.Action)
 	}
@@ -82,6 +89,10 @@ func (r *RuleAction) UnmarshalJSON(data []byte) error {
 		v = &r.SniffOptions
 	case C.RuleActionTypeResolve:
 		v = &r.ResolveOptions
+	case C.RuleActionTypeLimit:
+		v = &r.LimitOptions
+	case C.RuleActionTypeHTTPRewrite:
+		v = &r.HTTPRewriteOptions
 	default:
 		return E.New("unknown rule action: " + r.Action)
 	}
@@ -187,18 +198,22 @@ func (r *RouteOptionsActionOptions) UnmarshalJSON(data []byte) error {
 }
 
 type DNSRouteActionOptions struct {
-	Server       string                `json:"server,omitempty"`
-	Strategy     DomainStrategy        `json:"strategy,omitempty"`
-	DisableCache bool                  `json:"disable_cache,omitempty"`
-	RewriteTTL   *uint32               `json:"rewrite_ttl,omitempty"`
-	ClientSubnet *badoption.Prefixable `json:"client_subnet,omitempty"`
+	Server        string                `json:"server,omitempty"`
+	Strategy      DomainStrategy        `json:"strategy,omitempty"`
+	DisableCache  bool                  `json:"disable_cache,omitempty"`
+	RewriteTTL    *uint32               `json:"rewrite_ttl,omitempty"`
+	ClientSubnet  *badoption.Prefixable `json:"client_subnet,omitempty"`
+	RequireDNSSEC bool                  `json:"require_dnssec,omitempty"`
+	DNS64Prefix   *badoption.Prefixable `json:"dns64_prefix,omitempty"`
 }
 
 type _DNSRouteOptionsActionOptions struct {
-	Strategy     DomainStrategy        `json:"strategy,omitempty"`
-	DisableCache bool                  `json:"disable_cache,omitempty"`
-	RewriteTTL   *uint32               `json:"rewrite_ttl,omitempty"`
-	ClientSubnet *badoption.Prefixable `json:"client_subnet,omitempty"`
+	Strategy      DomainStrategy        `json:"strategy,omitempty"`
+	DisableCache  bool                  `json:"disable_cache,omitempty"`
+	RewriteTTL    *uint32               `json:"rewrite_ttl,omitempty"`
+	ClientSubnet  *badoption.Prefixable `json:"client_subnet,omitempty"`
+	RequireDNSSEC bool                  `json:"require_dnssec,omitempty"`
+	DNS64Prefix   *badoption.Prefixable `json:"dns64_prefix,omitempty"`
 }
 
 type DNSRouteOptionsActionOptions _DNSRouteOptionsActionOptions
@@ -312,6 +327,30 @@ type RouteActionResolve struct {
 	DisableCache bool                  `json:"disable_cache,omitempty"`
 	RewriteTTL   *uint32               `json:"rewrite_ttl,omitempty"`
 	ClientSubnet *badoption.Prefixable `json:"client_subnet,omitempty"`
+	DNS64Prefix  *badoption.Prefixable `json:"dns64_prefix,omitempty"`
+}
+
+type RouteActionLimit struct {
+	Upload   *byteformats.NetworkBytesCompat `json:"upload,omitempty"`
+	Download *byteformats.NetworkBytesCompat `json:"download,omitempty"`
+	Key      string                          `json:"key,omitempty"`
+}
+
+type RouteActionHTTPRewrite struct {
+	Outbound      string                               `json:"outbound,omitempty"`
+	BlockPath     badoption.Listable[string]           `json:"block_path,omitempty"`
+	RewriteHost   badoption.Listable[HTTPRewriteRegex] `json:"rewrite_host,omitempty"`
+	RewriteURL    badoption.Listable[HTTPRewriteRegex] `json:"rewrite_url,omitempty"`
+	Headers       badoption.HTTPHeader                 `json:"headers,omitempty"`
+	RemoveHeaders badoption.Listable[string]           `json:"remove_headers,omitempty"`
+	StatusCode    int                                  `json:"status_code,omitempty"`
+	Redirect      string                               `json:"redirect,omitempty"`
+	File          string                               `json:"file,omitempty"`
+}
+
+type HTTPRewriteRegex struct {
+	Regex       string `json:"regex"`
+	Replacement string `json:"replacement"`
 }
 
 type DNSRouteActionPredefined struct {
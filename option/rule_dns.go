@@ -84,8 +84,13 @@ type RawDefaultDNSRule struct {
 	IPCIDR                   badoption.Listable[string]                                                  `json:"ip_cidr,omitempty"`
 	IPIsPrivate              bool                                                                        `json:"ip_is_private,omitempty"`
 	IPAcceptAny              bool                                                                        `json:"ip_accept_any,omitempty"`
+	IPASN                    badoption.Listable[uint32]                                                  `json:"ip_asn,omitempty"`
+	ResponseRcode            badoption.Listable[DNSRCode]                                                `json:"response_rcode,omitempty"`
+	ResponseCNAME            badoption.Listable[string]                                                  `json:"response_cname,omitempty"`
+	ResponseCNAMESuffix      badoption.Listable[string]                                                  `json:"response_cname_suffix,omitempty"`
 	SourceIPCIDR             badoption.Listable[string]                                                  `json:"source_ip_cidr,omitempty"`
 	SourceIPIsPrivate        bool                                                                        `json:"source_ip_is_private,omitempty"`
+	SourceIPASN              badoption.Listable[uint32]                                                  `json:"source_ip_asn,omitempty"`
 	SourcePort               badoption.Listable[uint16]                                                  `json:"source_port,omitempty"`
 	SourcePortRange          badoption.Listable[string]                                                  `json:"source_port_range,omitempty"`
 	Port                     badoption.Listable[uint16]                                                  `json:"port,omitempty"`
@@ -98,6 +103,7 @@ type RawDefaultDNSRule struct {
 	UserID                   badoption.Listable[int32]                                                   `json:"user_id,omitempty"`
 	Outbound                 badoption.Listable[string]                                                  `json:"outbound,omitempty"`
 	ClashMode                string                                                                      `json:"clash_mode,omitempty"`
+	Time                     *TimeRuleOptions                                                            `json:"time,omitempty"`
 	NetworkType              badoption.Listable[InterfaceType]                                           `json:"network_type,omitempty"`
 	NetworkIsExpensive       bool                                                                        `json:"network_is_expensive,omitempty"`
 	NetworkIsConstrained     bool                                                                        `json:"network_is_constrained,omitempty"`
@@ -109,6 +115,7 @@ type RawDefaultDNSRule struct {
 	RuleSet                  badoption.Listable[string]                                                  `json:"rule_set,omitempty"`
 	RuleSetIPCIDRMatchSource bool                                                                        `json:"rule_set_ip_cidr_match_source,omitempty"`
 	RuleSetIPCIDRAcceptEmpty bool                                                                        `json:"rule_set_ip_cidr_accept_empty,omitempty"`
+	MatchResponse            bool                                                                        `json:"match_response,omitempty"`
 	Invert                   bool                                                                        `json:"invert,omitempty"`
 
 	// Deprecated: renamed to rule_set_ip_cidr_match_source
@@ -139,9 +146,10 @@ func (r DefaultDNSRule) IsValid() bool {
 }
 
 type RawLogicalDNSRule struct {
-	Mode   string    `json:"mode"`
-	Rules  []DNSRule `json:"rules,omitempty"`
-	Invert bool      `json:"invert,omitempty"`
+	Mode          string    `json:"mode"`
+	Rules         []DNSRule `json:"rules,omitempty"`
+	MatchResponse bool      `json:"match_response,omitempty"`
+	Invert        bool      `json:"invert,omitempty"`
 }
 
 type LogicalDNSRule struct {
@@ -21,6 +21,7 @@ type _RuleSet struct {
 	Type          string        `json:"type,omitempty"`
 	Tag           string        `json:"tag"`
 	Format        string        `json:"format,omitempty"`
+	Category      string        `json:"category,omitempty"`
 	InlineOptions PlainRuleSet  `json:"-"`
 	LocalOptions  LocalRuleSet  `json:"-"`
 	RemoteOptions RemoteRuleSet `json:"-"`
@@ -92,12 +93,22 @@ func (r *RuleSet) UnmarshalJSON(bytes []byte) error {
 		switch r.Format {
 		case "":
 			return E.New("missing format")
-		case C.RuleSetFormatSource, C.RuleSetFormatBinary:
+		case C.RuleSetFormatSource, C.RuleSetFormatBinary,
+			C.RuleSetFormatClashDomain, C.RuleSetFormatClashIPCIDR, C.RuleSetFormatClashClassical,
+			C.RuleSetFormatSurge, C.RuleSetFormatQuantumult, C.RuleSetFormatDnsmasq:
+			if r.Category != "" {
+				return E.New("category is only supported by geosite-dat and geoip-dat formats")
+			}
+		case C.RuleSetFormatGeoSiteDat, C.RuleSetFormatGeoIPDat:
+			if r.Category == "" {
+				return E.New("missing category")
+			}
 		default:
 			return E.New("unknown rule-set format: " + r.Format)
 		}
 	} else {
 		r.Format = ""
+		r.Category = ""
 	}
 	return nil
 }
@@ -190,6 +201,8 @@ type DefaultHeadlessRule struct {
 	DomainRegex             badoption.Listable[string]                                                  `json:"domain_regex,omitempty"`
 	SourceIPCIDR            badoption.Listable[string]                                                  `json:"source_ip_cidr,omitempty"`
 	IPCIDR                  badoption.Listable[string]                                                  `json:"ip_cidr,omitempty"`
+	SourceIPASN             badoption.Listable[uint32]                                                  `json:"source_ip_asn,omitempty"`
+	IPASN                   badoption.Listable[uint32]                                                  `json:"ip_asn,omitempty"`
 	SourcePort              badoption.Listable[uint16]                                                  `json:"source_port,omitempty"`
 	SourcePortRange         badoption.Listable[string]                                                  `json:"source_port_range,omitempty"`
 	Port                    badoption.Listable[uint16]                                                  `json:"port,omitempty"`
@@ -205,6 +218,7 @@ type DefaultHeadlessRule struct {
 	WIFIBSSID               badoption.Listable[string]                                                  `json:"wifi_bssid,omitempty"`
 	NetworkInterfaceAddress *badjson.TypedMap[InterfaceType, badoption.Listable[*badoption.Prefixable]] `json:"network_interface_address,omitempty"`
 	DefaultInterfaceAddress badoption.Listable[*badoption.Prefixable]                                   `json:"default_interface_address,omitempty"`
+	Time                    *TimeRuleOptions                                                            `json:"time,omitempty"`
 
 	Invert bool `json:"invert,omitempty"`
 
@@ -243,7 +257,7 @@ type PlainRuleSetCompat _PlainRuleSetCompat
 func (r PlainRuleSetCompat) MarshalJSON() ([]byte, error) {
 	var v any
 	switch r.Version {
-	case C.RuleSetVersion1, C.RuleSetVersion2, C.RuleSetVersion3, C.RuleSetVersion4:
+	case C.RuleSetVersion1, C.RuleSetVersion2, C.RuleSetVersion3, C.RuleSetVersion4, C.RuleSetVersion5:
 		v = r.Options
 	default:
 		return nil, E.New("unknown rule-set version: ", r.Version)
@@ -258,7 +272,7 @@ func (r *PlainRuleSetCompat) UnmarshalJSON(bytes []byte) error {
 	}
 	var v any
 	switch r.Version {
-	case C.RuleSetVersion1, C.RuleSetVersion2, C.RuleSetVersion3, C.RuleSetVersion4:
+	case C.RuleSetVersion1, C.RuleSetVersion2, C.RuleSetVersion3, C.RuleSetVersion4, C.RuleSetVersion5:
 		v = &r.Options
 	case 0:
 		return E.New("missing rule-set version")
@@ -275,7 +289,7 @@ func (r *PlainRuleSetCompat) UnmarshalJSON(bytes []byte) error {
 
 func (r PlainRuleSetCompat) Upgrade() (PlainRuleSet, error) {
 	switch r.Version {
-	case C.RuleSetVersion1, C.RuleSetVersion2, C.RuleSetVersion3, C.RuleSetVersion4:
+	case C.RuleSetVersion1, C.RuleSetVersion2, C.RuleSetVersion3, C.RuleSetVersion4, C.RuleSetVersion5:
 	default:
 		return PlainRuleSet{}, E.New("unknown rule-set version: " + F.ToString(r.Version))
 	}
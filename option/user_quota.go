@@ -0,0 +1,24 @@
+package option
+
+import (
+	"time"
+
+	"github.com/sagernet/sing/common/byteformats"
+	"github.com/sagernet/sing/common/json/badoption"
+)
+
+type UserQuotaServiceOptions struct {
+	ListenOptions
+	Inbounds badoption.Listable[string] `json:"inbounds,omitempty"`
+	Users    []UserQuotaUser            `json:"users,omitempty"`
+	InboundTLSOptionsContainer
+}
+
+type UserQuotaUser struct {
+	Name           string             `json:"name"`
+	Quota          *byteformats.Bytes `json:"quota,omitempty"`
+	ResetPeriod    string             `json:"reset_period,omitempty"`
+	Expire         *time.Time         `json:"expire,omitempty"`
+	MaxConnections int                `json:"max_connections,omitempty"`
+	MaxIPs         int                `json:"max_ips,omitempty"`
+}
@@ -0,0 +1,392 @@
+package dns
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/inbound"
+	"github.com/sagernet/sing-box/common/listener"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/dns"
+	"github.com/sagernet/sing-box/dns/transport"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	R "github.com/sagernet/sing-box/route/rule"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/auth"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	aTLS "github.com/sagernet/sing/common/tls"
+	sHttp "github.com/sagernet/sing/protocol/http"
+	"github.com/sagernet/sing/service"
+
+	mDNS "github.com/miekg/dns"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+const (
+	defaultDoHPath      = "/dns-query"
+	dnsMessageMediaType = "application/dns-message"
+	// MaxConnectionQueries limits queries in flight on a TCP, TLS or QUIC connection,
+	// further queries are not read until one is answered.
+	MaxConnectionQueries = 128
+)
+
+// ConfigureQUICListenerFunc serves DNS over QUIC, handler is called for each
+// stream, which carries exactly one query and its response.
+var ConfigureQUICListenerFunc func(ctx context.Context, logger logger.ContextLogger, listener *listener.Listener, tlsConfig tls.ServerConfig, handler func(ctx context.Context, stream io.ReadWriter, source M.Socksaddr) error) (io.Closer, error)
+
+func RegisterInbound(registry *inbound.Registry) {
+	inbound.Register[option.DNSInboundOptions](registry, C.TypeDNS, NewInbound)
+}
+
+var (
+	_ adapter.ConnectionHandlerEx = (*Inbound)(nil)
+	_ adapter.PacketHandlerEx     = (*Inbound)(nil)
+)
+
+type Inbound struct {
+	inbound.Adapter
+	ctx           context.Context
+	logger        logger.ContextLogger
+	router        adapter.DNSRouter
+	listener      *listener.Listener
+	protocol      string
+	path          string
+	authenticator *auth.Authenticator
+	tlsConfig     tls.ServerConfig
+	httpServer    *http.Server
+	quicServer    io.Closer
+}
+
+func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.DNSInboundOptions) (adapter.Inbound, error) {
+	inbound := &Inbound{
+		Adapter:  inbound.NewAdapter(C.TypeDNS, tag),
+		ctx:      ctx,
+		logger:   logger,
+		router:   service.FromContext[adapter.DNSRouter](ctx),
+		protocol: options.Protocol,
+		path:     options.Path,
+	}
+	tlsEnabled := options.TLS != nil && options.TLS.Enabled
+	var network []string
+	switch options.Protocol {
+	case "":
+		network = []string{N.NetworkTCP, N.NetworkUDP}
+	case C.DNSTypeUDP:
+		network = []string{N.NetworkUDP}
+	case C.DNSTypeTCP:
+		network = []string{N.NetworkTCP}
+	case C.DNSTypeTLS:
+		if !tlsEnabled {
+			return nil, E.New("TLS is required for DNS over TLS server")
+		}
+		network = []string{N.NetworkTCP}
+	case C.DNSTypeHTTPS:
+		if inbound.path == "" {
+			inbound.path = defaultDoHPath
+		}
+	case C.DNSTypeQUIC:
+		if !tlsEnabled {
+			return nil, E.New("TLS is required for DNS over QUIC server")
+		}
+	default:
+		return nil, E.New("unknown DNS server protocol: ", options.Protocol)
+	}
+	if options.Protocol != C.DNSTypeHTTPS {
+		if options.Path != "" {
+			return nil, E.New("`path` is only supported by DNS over HTTPS server")
+		}
+		if len(options.Users) > 0 {
+			return nil, E.New("`users` is only supported by DNS over HTTPS server")
+		}
+	}
+	if tlsEnabled {
+		switch options.Protocol {
+		case "", C.DNSTypeUDP, C.DNSTypeTCP:
+			return nil, E.New("TLS is not supported by plain DNS server")
+		}
+		tlsConfig, err := tls.NewServer(ctx, logger, common.PtrValueOrDefault(options.TLS))
+		if err != nil {
+			return nil, err
+		}
+		inbound.tlsConfig = tlsConfig
+	}
+	if len(options.Users) > 0 {
+		inbound.authenticator = auth.NewAuthenticator(options.Users)
+	}
+	inbound.listener = listener.New(listener.Options{
+		Context:                  ctx,
+		Logger:                   logger,
+		Network:                  network,
+		Listen:                   options.ListenOptions,
+		ConnectionHandler:        inbound,
+		PacketHandler:            inbound,
+		ThreadUnsafePacketWriter: true,
+	})
+	return inbound, nil
+}
+
+func (i *Inbound) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart {
+		return nil
+	}
+	if i.tlsConfig != nil {
+		err := i.tlsConfig.Start()
+		if err != nil {
+			return E.Cause(err, "create TLS config")
+		}
+	}
+	switch i.protocol {
+	case C.DNSTypeHTTPS:
+		return i.startHTTPServer()
+	case C.DNSTypeQUIC:
+		if len(i.tlsConfig.NextProtos()) == 0 {
+			i.tlsConfig.SetNextProtos([]string{"doq"})
+		}
+		quicServer, err := ConfigureQUICListenerFunc(i.ctx, i.logger, i.listener, i.tlsConfig, i.serveQUICStream)
+		if err != nil {
+			return err
+		}
+		i.quicServer = quicServer
+		return nil
+	default:
+		return i.listener.Start()
+	}
+}
+
+func (i *Inbound) Close() error {
+	return common.Close(
+		i.listener,
+		common.PtrOrNil(i.httpServer),
+		i.quicServer,
+		i.tlsConfig,
+	)
+}
+
+func (i *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
+	if i.tlsConfig != nil {
+		tlsConn, err := tls.ServerHandshake(ctx, conn, i.tlsConfig)
+		if err != nil {
+			N.CloseOnHandshakeFailure(conn, onClose, err)
+			i.logger.ErrorContext(ctx, E.Cause(err, "process connection from ", metadata.Source, ": TLS handshake"))
+			return
+		}
+		conn = tlsConn
+	}
+	metadata.Inbound = i.Tag()
+	metadata.InboundType = i.Type()
+	metadata.Destination = M.Socksaddr{}
+	err := i.serveStream(ctx, conn, metadata)
+	N.CloseOnHandshakeFailure(conn, onClose, err)
+	if err != nil && !E.IsClosedOrCanceled(err) {
+		i.logger.DebugContext(ctx, E.Cause(err, "process connection from ", metadata.Source))
+	}
+}
+
+// serveStream serves pipelined queries on a TCP or TLS connection until the
+// client closes it or it stays idle for C.DNSTimeout.
+func (i *Inbound) serveStream(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	var writeAccess sync.Mutex
+	inflight := make(chan struct{}, MaxConnectionQueries)
+	for {
+		conn.SetReadDeadline(time.Now().Add(C.DNSTimeout))
+		message, err := transport.ReadMessage(conn)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		select {
+		case inflight <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		go func() {
+			defer func() {
+				<-inflight
+			}()
+			metadataInQuery := metadata
+			response := i.exchange(adapter.WithContext(ctx, &metadataInQuery), message)
+			writeAccess.Lock()
+			defer writeAccess.Unlock()
+			err := transport.WriteMessage(conn, message.Id, response)
+			if err != nil {
+				conn.Close()
+			}
+		}()
+	}
+}
+
+func (i *Inbound) serveQUICStream(ctx context.Context, stream io.ReadWriter, source M.Socksaddr) error {
+	message, err := transport.ReadMessage(stream)
+	if err != nil {
+		return E.Cause(err, "read request")
+	}
+	var metadata adapter.InboundContext
+	metadata.Inbound = i.Tag()
+	metadata.InboundType = i.Type()
+	metadata.Source = source
+	response := i.exchange(adapter.WithContext(ctx, &metadata), message)
+	return transport.WriteMessage(stream, message.Id, response)
+}
+
+func (i *Inbound) NewPacketEx(buffer *buf.Buffer, source M.Socksaddr) {
+	go i.exchangePacket(buffer, source)
+}
+
+func (i *Inbound) exchangePacket(buffer *buf.Buffer, source M.Socksaddr) {
+	ctx := log.ContextWithNewID(i.ctx)
+	var message mDNS.Msg
+	err := message.Unpack(buffer.Bytes())
+	buffer.Release()
+	if err != nil {
+		i.logger.DebugContext(ctx, E.Cause(err, "unpack request from ", source))
+		return
+	}
+	var metadata adapter.InboundContext
+	metadata.Inbound = i.Tag()
+	metadata.InboundType = i.Type()
+	metadata.Source = source
+	response := i.exchange(adapter.WithContext(ctx, &metadata), &message)
+	responseBuffer, err := dns.TruncateDNSMessage(&message, response, 0)
+	if err != nil {
+		i.logger.ErrorContext(ctx, E.Cause(err, "pack response to ", source))
+		return
+	}
+	err = i.listener.PacketWriter().WritePacket(responseBuffer, source)
+	if err != nil {
+		i.logger.DebugContext(ctx, E.Cause(err, "write response to ", source))
+	}
+}
+
+// exchange always returns a response, failures are reported to the client
+// as rcode.
+func (i *Inbound) exchange(ctx context.Context, message *mDNS.Msg) *mDNS.Msg {
+	response, err := i.router.Exchange(ctx, message, adapter.DNSQueryOptions{})
+	if err == nil {
+		return response
+	}
+	var rcodeError dns.RcodeError
+	if errors.As(err, &rcodeError) {
+		return dns.FixedResponseStatus(message, int(rcodeError))
+	}
+	if R.IsRejected(err) {
+		return dns.FixedResponseStatus(message, mDNS.RcodeRefused)
+	}
+	i.logger.ErrorContext(ctx, err)
+	return dns.FixedResponseStatus(message, mDNS.RcodeServerFailure)
+}
+
+func (i *Inbound) startHTTPServer() error {
+	tcpListener, err := i.listener.ListenTCP()
+	if err != nil {
+		return err
+	}
+	i.httpServer = &http.Server{
+		Handler: h2c.NewHandler(i, &http2.Server{}),
+		BaseContext: func(listener net.Listener) context.Context {
+			return i.ctx
+		},
+	}
+	listener := net.Listener(tcpListener)
+	if i.tlsConfig != nil {
+		if len(i.tlsConfig.NextProtos()) == 0 {
+			i.tlsConfig.SetNextProtos([]string{http2.NextProtoTLS, "http/1.1"})
+		} else if !common.Contains(i.tlsConfig.NextProtos(), http2.NextProtoTLS) {
+			i.tlsConfig.SetNextProtos(append([]string{http2.NextProtoTLS}, i.tlsConfig.NextProtos()...))
+		}
+		listener = aTLS.NewListener(tcpListener, i.tlsConfig)
+	}
+	go func() {
+		sErr := i.httpServer.Serve(listener)
+		if sErr != nil && !errors.Is(sErr, http.ErrServerClosed) {
+			i.logger.Error("http server serve error: ", sErr)
+		}
+	}()
+	return nil
+}
+
+func (i *Inbound) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	ctx := log.ContextWithNewID(request.Context())
+	if request.URL.Path != i.path {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var metadata adapter.InboundContext
+	metadata.Inbound = i.Tag()
+	metadata.InboundType = i.Type()
+	// X-Forwarded-For is not trusted, since anyone can set it
+	metadata.Source = M.ParseSocksaddr(request.RemoteAddr).Unwrap()
+	if i.authenticator != nil {
+		userName, password, authOk := sHttp.ParseBasicAuth(request.Header.Get("Authorization"))
+		if authOk {
+			authOk = i.authenticator.Verify(userName, password)
+		}
+		if !authOk {
+			writer.Header().Set("WWW-Authenticate", "Basic realm=\"DNS\"")
+			writer.WriteHeader(http.StatusUnauthorized)
+			i.logger.ErrorContext(ctx, "process request from ", metadata.Source, ": authorization failed")
+			return
+		}
+		metadata.User = userName
+	}
+	var rawMessage []byte
+	switch request.Method {
+	case http.MethodGet:
+		var err error
+		rawMessage, err = base64.RawURLEncoding.DecodeString(request.URL.Query().Get("dns"))
+		if err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	case http.MethodPost:
+		if request.Header.Get("Content-Type") != dnsMessageMediaType {
+			writer.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		var err error
+		rawMessage, err = io.ReadAll(io.LimitReader(request.Body, mDNS.MaxMsgSize))
+		if err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	default:
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var message mDNS.Msg
+	err := message.Unpack(rawMessage)
+	if err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if metadata.User != "" {
+		i.logger.DebugContext(ctx, "[", metadata.User, "] inbound request from ", metadata.Source)
+	} else {
+		i.logger.DebugContext(ctx, "inbound request from ", metadata.Source)
+	}
+	response := i.exchange(adapter.WithContext(ctx, &metadata), &message)
+	rawResponse, err := response.Pack()
+	if err != nil {
+		i.logger.ErrorContext(ctx, E.Cause(err, "pack response"))
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", dnsMessageMediaType)
+	writer.WriteHeader(http.StatusOK)
+	writer.Write(rawResponse)
+}
@@ -0,0 +1,68 @@
+package quic
+
+import (
+	"context"
+	"io"
+
+	"github.com/sagernet/quic-go"
+	"github.com/sagernet/sing-box/common/listener"
+	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/protocol/dns"
+	"github.com/sagernet/sing-quic"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+func init() {
+	dns.ConfigureQUICListenerFunc = func(ctx context.Context, logger logger.ContextLogger, listener *listener.Listener, tlsConfig tls.ServerConfig, handler func(ctx context.Context, stream io.ReadWriter, source M.Socksaddr) error) (io.Closer, error) {
+		udpConn, err := listener.ListenUDP()
+		if err != nil {
+			return nil, err
+		}
+		quicListener, err := qtls.Listen(udpConn, tlsConfig, &quic.Config{
+			MaxIncomingStreams: dns.MaxConnectionQueries,
+		})
+		if err != nil {
+			udpConn.Close()
+			return nil, err
+		}
+		go func() {
+			for {
+				conn, aErr := quicListener.Accept(ctx)
+				if aErr != nil {
+					udpConn.Close()
+					if !E.IsClosedOrCanceled(aErr) {
+						logger.Error("quic listener closed: ", aErr)
+					}
+					return
+				}
+				go serveConn(log.ContextWithNewID(ctx), logger, conn, handler)
+			}
+		}()
+		return quicListener, nil
+	}
+}
+
+func serveConn(ctx context.Context, logger logger.ContextLogger, conn *quic.Conn, handler func(ctx context.Context, stream io.ReadWriter, source M.Socksaddr) error) {
+	source := M.SocksaddrFromNet(conn.RemoteAddr()).Unwrap()
+	logger.InfoContext(ctx, "inbound connection from ", source)
+	for {
+		stream, err := conn.AcceptStream(ctx)
+		if err != nil {
+			if !E.IsClosedOrCanceled(err) {
+				logger.DebugContext(ctx, E.Cause(err, "accept stream from ", source))
+			}
+			return
+		}
+		go func() {
+			hErr := handler(ctx, stream, source)
+			if hErr != nil {
+				stream.CancelRead(0)
+				logger.DebugContext(ctx, E.Cause(hErr, "process stream from ", source))
+			}
+			stream.Close()
+		}()
+	}
+}
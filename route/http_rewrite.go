@@ -0,0 +1,132 @@
+package route
+
+import (
+	std_bufio "bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	R "github.com/sagernet/sing-box/route/rule"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+)
+
+func (r *Router) httpRewriteStream(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, action *R.RuleActionHTTPRewrite, outbound adapter.Outbound) error {
+	ctx = adapter.WithContext(ctx, &metadata)
+	defer conn.Close()
+	reader := std_bufio.NewReader(conn)
+	var (
+		serverConn   net.Conn
+		serverReader *std_bufio.Reader
+	)
+	defer func() {
+		if serverConn != nil {
+			serverConn.Close()
+		}
+	}()
+	for {
+		request, err := http.ReadRequest(reader)
+		if err != nil {
+			if errors.Is(err, io.EOF) || E.IsClosedOrCanceled(err) {
+				return nil
+			}
+			return E.Cause(err, "read HTTP request")
+		}
+		response, err := action.Rewrite(request)
+		if err != nil {
+			return err
+		}
+		if response != nil {
+			r.logger.DebugContext(ctx, "http-rewrite: ", request.Method, " ", request.Host, request.URL.RequestURI(), " => ", response.StatusCode)
+			_, err = io.Copy(io.Discard, request.Body)
+			if err != nil {
+				return E.Cause(err, "read HTTP request body")
+			}
+			err = response.Write(conn)
+			if err != nil {
+				return E.Cause(err, "write HTTP response")
+			}
+			if response.Close {
+				return nil
+			}
+			continue
+		}
+		if serverConn == nil {
+			serverConn, err = outbound.DialContext(ctx, N.NetworkTCP, metadata.Destination)
+			if err != nil {
+				return E.Cause(err, "open connection to ", metadata.Destination, " using outbound/", outbound.Type(), "[", outbound.Tag(), "]")
+			}
+			serverReader = std_bufio.NewReader(serverConn)
+		}
+		// Interim responses can not be relayed while the request body is being written,
+		// so answer the expectation here.
+		if strings.EqualFold(request.Header.Get("Expect"), "100-continue") {
+			request.Header.Del("Expect")
+			_, err = io.WriteString(conn, "HTTP/1.1 100 Continue\r\n\r\n")
+			if err != nil {
+				return E.Cause(err, "write HTTP response")
+			}
+		}
+		err = request.Write(serverConn)
+		if err != nil {
+			return E.Cause(err, "write HTTP request")
+		}
+		for {
+			response, err = http.ReadResponse(serverReader, request)
+			if err != nil {
+				return E.Cause(err, "read HTTP response")
+			}
+			if response.StatusCode == http.StatusSwitchingProtocols || response.StatusCode >= 200 {
+				break
+			}
+			err = response.Write(conn)
+			if err != nil {
+				return E.Cause(err, "write HTTP response")
+			}
+		}
+		if response.StatusCode == http.StatusSwitchingProtocols {
+			err = response.Write(conn)
+			if err != nil {
+				return E.Cause(err, "write HTTP response")
+			}
+			response.Body.Close()
+			conn, err = newCachedConn(conn, reader)
+			if err != nil {
+				return err
+			}
+			serverConn, err = newCachedConn(serverConn, serverReader)
+			if err != nil {
+				return err
+			}
+			return bufio.CopyConn(ctx, conn, serverConn)
+		}
+		err = response.Write(conn)
+		response.Body.Close()
+		if err != nil {
+			return E.Cause(err, "write HTTP response")
+		}
+		if request.Close || response.Close {
+			return nil
+		}
+	}
+}
+
+func newCachedConn(conn net.Conn, reader *std_bufio.Reader) (net.Conn, error) {
+	buffered := reader.Buffered()
+	if buffered == 0 {
+		return conn, nil
+	}
+	cache := buf.NewSize(buffered)
+	_, err := cache.ReadFullFrom(reader, buffered)
+	if err != nil {
+		cache.Release()
+		return nil, err
+	}
+	return bufio.NewCachedConn(conn, cache), nil
+}
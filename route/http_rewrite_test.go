@@ -0,0 +1,119 @@
+package route
+
+import (
+	std_bufio "bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	R "github.com/sagernet/sing-box/route/rule"
+	"github.com/sagernet/sing/common/json/badoption"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testHTTPOutbound struct {
+	dial func() net.Conn
+}
+
+func (o *testHTTPOutbound) Type() string {
+	return "test"
+}
+
+func (o *testHTTPOutbound) Tag() string {
+	return "test"
+}
+
+func (o *testHTTPOutbound) Network() []string {
+	return []string{N.NetworkTCP}
+}
+
+func (o *testHTTPOutbound) Dependencies() []string {
+	return nil
+}
+
+func (o *testHTTPOutbound) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	return o.dial(), nil
+}
+
+func (o *testHTTPOutbound) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return nil, io.ErrClosedPipe
+}
+
+func startTestHTTPRewrite(t *testing.T, options option.RouteActionHTTPRewrite, outbound adapter.Outbound) (net.Conn, <-chan error) {
+	action, err := R.NewRuleAction(context.Background(), log.NewNOPFactory().Logger(), option.RuleAction{
+		Action:             C.RuleActionTypeHTTPRewrite,
+		HTTPRewriteOptions: options,
+	})
+	require.NoError(t, err)
+	router := &Router{logger: log.NewNOPFactory().Logger()}
+	clientConn, conn := net.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- router.httpRewriteStream(context.Background(), conn, adapter.InboundContext{
+			Destination: M.ParseSocksaddr("example.com:80"),
+		}, action.(*R.RuleActionHTTPRewrite), outbound)
+	}()
+	t.Cleanup(func() {
+		clientConn.Close()
+	})
+	return clientConn, done
+}
+
+func TestHTTPRewriteStreamLocalResponseClose(t *testing.T) {
+	t.Parallel()
+	clientConn, done := startTestHTTPRewrite(t, option.RouteActionHTTPRewrite{
+		BlockPath: []string{"^/admin"},
+	}, nil)
+	go io.WriteString(clientConn, "GET /admin HTTP/1.1\r\nHost: example.com\r\nConnection: close\r\n\r\n")
+	reader := std_bufio.NewReader(clientConn)
+	response, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, response.StatusCode)
+	_, err = io.ReadAll(response.Body)
+	require.NoError(t, err)
+	_, err = reader.ReadByte()
+	require.ErrorIs(t, err, io.EOF)
+	require.NoError(t, <-done)
+}
+
+func TestHTTPRewriteStreamForwardClose(t *testing.T) {
+	t.Parallel()
+	requestHeader := make(chan http.Header, 1)
+	outbound := &testHTTPOutbound{dial: func() net.Conn {
+		serverConn, conn := net.Pipe()
+		go func() {
+			defer serverConn.Close()
+			request, err := http.ReadRequest(std_bufio.NewReader(serverConn))
+			if err != nil {
+				return
+			}
+			requestHeader <- request.Header
+			io.WriteString(serverConn, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\nConnection: close\r\n\r\nok")
+		}()
+		return conn
+	}}
+	clientConn, done := startTestHTTPRewrite(t, option.RouteActionHTTPRewrite{
+		Headers: badoption.HTTPHeader{"X-Test": {"1"}},
+	}, outbound)
+	go io.WriteString(clientConn, "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	reader := std_bufio.NewReader(clientConn)
+	response, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	content, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Equal(t, "ok", string(content))
+	require.Equal(t, "1", (<-requestHeader).Get("X-Test"))
+	_, err = reader.ReadByte()
+	require.ErrorIs(t, err, io.EOF)
+	require.NoError(t, <-done)
+}
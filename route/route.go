@@ -6,9 +6,12 @@ import (
 	"net"
 	"net/netip"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/ratelimit"
 	"github.com/sagernet/sing-box/common/sniff"
 	C "github.com/sagernet/sing-box/constant"
 	R "github.com/sagernet/sing-box/route/rule"
@@ -88,6 +91,11 @@ func (r *Router) routeConnection(ctx context.Context, conn net.Conn, metadata ad
 	case uot.LegacyMagicAddress:
 		return E.New("global UoT (legacy) not supported since sing-box v1.7.0.")
 	}
+	release, err := r.authorizeUser(ctx, metadata)
+	if err != nil {
+		return err
+	}
+	defer release()
 	if deadline.NeedAdditionalReadDeadline(conn) {
 		conn = deadline.NewConn(conn)
 	}
@@ -95,7 +103,10 @@ func (r *Router) routeConnection(ctx context.Context, conn net.Conn, metadata ad
 	if err != nil {
 		return err
 	}
-	var selectedOutbound adapter.Outbound
+	var (
+		selectedOutbound adapter.Outbound
+		httpRewrite      *R.RuleActionHTTPRewrite
+	)
 	if selectedRule != nil {
 		switch action := selectedRule.Action().(type) {
 		case *R.RuleActionRoute:
@@ -109,6 +120,21 @@ func (r *Router) routeConnection(ctx context.Context, conn net.Conn, metadata ad
 				buf.ReleaseMulti(buffers)
 				return E.New("TCP is not supported by outbound: ", selectedOutbound.Tag())
 			}
+		case *R.RuleActionHTTPRewrite:
+			httpRewrite = action
+			if action.Outbound == "" {
+				break
+			}
+			var loaded bool
+			selectedOutbound, loaded = r.outbound.Outbound(action.Outbound)
+			if !loaded {
+				buf.ReleaseMulti(buffers)
+				return E.New("outbound not found: ", action.Outbound)
+			}
+			if !common.Contains(selectedOutbound.Network(), N.NetworkTCP) {
+				buf.ReleaseMulti(buffers)
+				return E.New("TCP is not supported by outbound: ", selectedOutbound.Tag())
+			}
 		case *R.RuleActionBypass:
 			if action.Outbound == "" {
 				break
@@ -137,7 +163,7 @@ func (r *Router) routeConnection(ctx context.Context, conn net.Conn, metadata ad
 			return nil
 		}
 	}
-	if selectedRule == nil {
+	if selectedOutbound == nil {
 		defaultOutbound := r.outbound.Default()
 		if !common.Contains(defaultOutbound.Network(), N.NetworkTCP) {
 			buf.ReleaseMulti(buffers)
@@ -145,13 +171,26 @@ func (r *Router) routeConnection(ctx context.Context, conn net.Conn, metadata ad
 		}
 		selectedOutbound = defaultOutbound
 	}
+	r.mapNAT64(ctx, &metadata, selectedOutbound)
 
 	for _, buffer := range buffers {
 		conn = bufio.NewCachedConn(conn, buffer)
 	}
-	for _, tracker := range r.trackers {
+	if r.ruleStatistics && selectedRule != nil {
+		statistics := selectedRule.Statistics()
+		conn = bufio.NewInt64CounterConn(conn, []*atomic.Int64{&statistics.Upload}, []*atomic.Int64{&statistics.Download})
+	}
+	if len(metadata.RateLimits) > 0 {
+		conn = ratelimit.NewConn(ctx, conn, metadata.RateLimits)
+	}
+	for _, tracker := range r.connectionTrackers() {
 		conn = tracker.RoutedConnection(ctx, conn, metadata, selectedRule, selectedOutbound)
 	}
+	release()
+	if httpRewrite != nil {
+		N.CloseOnHandshakeFailure(conn, onClose, r.httpRewriteStream(ctx, conn, metadata, httpRewrite, selectedOutbound))
+		return nil
+	}
 	if outboundHandler, isHandler := selectedOutbound.(adapter.ConnectionHandlerEx); isHandler {
 		outboundHandler.NewConnectionEx(ctx, conn, metadata, onClose)
 	} else {
@@ -220,6 +259,11 @@ func (r *Router) routePacketConnection(ctx context.Context, conn N.PacketConn, m
 		conn = deadline.NewPacketConn(bufio.NewNetPacketConn(conn))
 	}*/
 
+	release, err := r.authorizeUser(ctx, metadata)
+	if err != nil {
+		return err
+	}
+	defer release()
 	selectedRule, _, _, packetBuffers, err := r.matchRule(ctx, &metadata, false, false, nil, conn)
 	if err != nil {
 		return err
@@ -271,15 +315,26 @@ func (r *Router) routePacketConnection(ctx context.Context, conn N.PacketConn, m
 		}
 		selectedOutbound = defaultOutbound
 	}
+	nat64Origin, nat64Mapped := r.mapNAT64(ctx, &metadata, selectedOutbound)
 	for _, buffer := range packetBuffers {
 		conn = bufio.NewCachedPacketConn(conn, buffer.Buffer, buffer.Destination)
 		N.PutPacketBuffer(buffer)
 	}
-	for _, tracker := range r.trackers {
+	if r.ruleStatistics && selectedRule != nil {
+		statistics := selectedRule.Statistics()
+		conn = bufio.NewInt64CounterPacketConn(conn, []*atomic.Int64{&statistics.Upload}, nil, []*atomic.Int64{&statistics.Download}, nil)
+	}
+	if len(metadata.RateLimits) > 0 {
+		conn = ratelimit.NewPacketConn(ctx, conn, metadata.RateLimits)
+	}
+	for _, tracker := range r.connectionTrackers() {
 		conn = tracker.RoutedPacketConnection(ctx, conn, metadata, selectedRule, selectedOutbound)
 	}
+	release()
 	if metadata.FakeIP {
 		conn = bufio.NewNATPacketConn(bufio.NewNetPacketConn(conn), metadata.OriginDestination, metadata.Destination)
+	} else if nat64Mapped {
+		conn = bufio.NewNATPacketConn(bufio.NewNetPacketConn(conn), nat64Origin, metadata.Destination)
 	}
 	if outboundHandler, isHandler := selectedOutbound.(adapter.PacketConnectionHandlerEx); isHandler {
 		outboundHandler.NewPacketConnectionEx(ctx, conn, metadata, onClose)
@@ -408,41 +463,21 @@ func (r *Router) matchRule(
 	buffers []*buf.Buffer, packetBuffers []*N.PacketBuffer, fatalErr error,
 ) {
 	r.searchProcessInfo(ctx, metadata)
-	if metadata.Destination.Addr.IsValid() && r.dnsTransport.FakeIP() != nil && r.dnsTransport.FakeIP().Store().Contains(metadata.Destination.Addr) {
-		domain, loaded := r.dnsTransport.FakeIP().Store().Lookup(metadata.Destination.Addr)
-		if !loaded {
-			fatalErr = E.New("missing fakeip record, try enable `experimental.cache_file`")
-			return
-		}
-		if domain != "" {
-			metadata.OriginDestination = metadata.Destination
-			metadata.Destination = M.Socksaddr{
-				Fqdn: domain,
-				Port: metadata.Destination.Port,
-			}
-			metadata.FakeIP = true
-			r.logger.DebugContext(ctx, "found fakeip domain: ", domain)
-		}
-	} else if metadata.Domain == "" {
-		domain, loaded := r.dns.LookupReverseMapping(metadata.Destination.Addr)
-		if loaded {
-			metadata.Domain = domain
-			r.logger.DebugContext(ctx, "found reserve mapped domain: ", metadata.Domain)
-		}
-	}
-	if metadata.Destination.IsIPv4() {
-		metadata.IPVersion = 4
-	} else if metadata.Destination.IsIPv6() {
-		metadata.IPVersion = 6
+	fatalErr = r.lookupDestination(ctx, metadata)
+	if fatalErr != nil {
+		return
 	}
 
 match:
-	for currentRuleIndex, currentRule := range r.rules {
+	for currentRuleIndex, currentRule := range r.Rules() {
 		metadata.ResetRuleCache()
 		if !currentRule.Match(metadata) {
 			continue
 		}
 		if !preMatch {
+			if r.ruleStatistics {
+				currentRule.Statistics().Hit()
+			}
 			ruleDescription := currentRule.String()
 			if ruleDescription != "" {
 				r.logger.DebugContext(ctx, "match[", currentRuleIndex, "] ", currentRule, " => ", currentRule.Action())
@@ -468,53 +503,7 @@ match:
 			routeOptions = action
 		}
 		if routeOptions != nil {
-			// TODO: add nat
-			if (routeOptions.OverrideAddress.IsValid() || routeOptions.OverridePort > 0) && !metadata.RouteOriginalDestination.IsValid() {
-				metadata.RouteOriginalDestination = metadata.Destination
-			}
-			if routeOptions.OverrideAddress.IsValid() {
-				metadata.Destination = M.Socksaddr{
-					Addr: routeOptions.OverrideAddress.Addr,
-					Port: metadata.Destination.Port,
-					Fqdn: routeOptions.OverrideAddress.Fqdn,
-				}
-				metadata.DestinationAddresses = nil
-			}
-			if routeOptions.OverridePort > 0 {
-				metadata.Destination = M.Socksaddr{
-					Addr: metadata.Destination.Addr,
-					Port: routeOptions.OverridePort,
-					Fqdn: metadata.Destination.Fqdn,
-				}
-			}
-			if routeOptions.NetworkStrategy != nil {
-				metadata.NetworkStrategy = routeOptions.NetworkStrategy
-			}
-			if len(routeOptions.NetworkType) > 0 {
-				metadata.NetworkType = routeOptions.NetworkType
-			}
-			if len(routeOptions.FallbackNetworkType) > 0 {
-				metadata.FallbackNetworkType = routeOptions.FallbackNetworkType
-			}
-			if routeOptions.FallbackDelay != 0 {
-				metadata.FallbackDelay = routeOptions.FallbackDelay
-			}
-			if routeOptions.UDPDisableDomainUnmapping {
-				metadata.UDPDisableDomainUnmapping = true
-			}
-			if routeOptions.UDPConnect {
-				metadata.UDPConnect = true
-			}
-			if routeOptions.UDPTimeout > 0 {
-				metadata.UDPTimeout = routeOptions.UDPTimeout
-			}
-			if routeOptions.TLSFragment {
-				metadata.TLSFragment = true
-				metadata.TLSFragmentFallbackDelay = routeOptions.TLSFragmentFallbackDelay
-			}
-			if routeOptions.TLSRecordFragment {
-				metadata.TLSRecordFragment = true
-			}
+			applyRouteOptions(metadata, routeOptions)
 		}
 		switch action := currentRule.Action().(type) {
 		case *R.RuleActionSniff:
@@ -539,6 +528,10 @@ match:
 			if fatalErr != nil {
 				return
 			}
+		case *R.RuleActionLimit:
+			if !preMatch {
+				metadata.RateLimits = append(metadata.RateLimits, action.Limit(metadata))
+			}
 		}
 		actionType := currentRule.Action().Type()
 		if actionType == C.RuleActionTypeRoute ||
@@ -548,6 +541,14 @@ match:
 			selectedRuleIndex = currentRuleIndex
 			break match
 		}
+		if actionType == C.RuleActionTypeHTTPRewrite {
+			if metadata.Network != N.NetworkTCP {
+				continue match
+			}
+			selectedRule = currentRule
+			selectedRuleIndex = currentRuleIndex
+			break match
+		}
 		if actionType == C.RuleActionTypeBypass {
 			bypassAction := currentRule.Action().(*R.RuleActionBypass)
 			if !supportBypass && bypassAction.Outbound == "" {
@@ -561,6 +562,137 @@ match:
 	return
 }
 
+// authorizeUser returns a function releasing resources reserved by authorizers,
+// which is safe to call multiple times.
+func (r *Router) authorizeUser(ctx context.Context, metadata adapter.InboundContext) (func(), error) {
+	if metadata.User == "" {
+		return func() {}, nil
+	}
+	r.access.RLock()
+	authorizers := r.authorizers
+	r.access.RUnlock()
+	var releases []func()
+	release := sync.OnceFunc(func() {
+		for _, it := range releases {
+			it()
+		}
+	})
+	for _, authorizer := range authorizers {
+		authorizerRelease, err := authorizer.AuthorizeUser(ctx, metadata)
+		if err != nil {
+			release()
+			return nil, E.Cause(err, "authorize user ", metadata.User)
+		}
+		if authorizerRelease != nil {
+			releases = append(releases, authorizerRelease)
+		}
+	}
+	return release, nil
+}
+
+func (r *Router) lookupDestination(ctx context.Context, metadata *adapter.InboundContext) error {
+	if metadata.Destination.Addr.IsValid() && r.dnsTransport.FakeIP() != nil && r.dnsTransport.FakeIP().Store().Contains(metadata.Destination.Addr) {
+		domain, loaded := r.dnsTransport.FakeIP().Store().Lookup(metadata.Destination.Addr)
+		if !loaded {
+			return E.New("missing fakeip record, try enable `experimental.cache_file`")
+		}
+		if domain != "" {
+			metadata.OriginDestination = metadata.Destination
+			metadata.Destination = M.Socksaddr{
+				Fqdn: domain,
+				Port: metadata.Destination.Port,
+			}
+			metadata.FakeIP = true
+			r.logger.DebugContext(ctx, "found fakeip domain: ", domain)
+		}
+	} else if metadata.Domain == "" {
+		domain, loaded := r.dns.LookupReverseMapping(metadata.Destination.Addr)
+		if loaded {
+			metadata.Domain = domain
+			r.logger.DebugContext(ctx, "found reserve mapped domain: ", metadata.Domain)
+		}
+	}
+	if metadata.Destination.IsIPv4() {
+		metadata.IPVersion = 4
+	} else if metadata.Destination.IsIPv6() {
+		metadata.IPVersion = 6
+	}
+	return nil
+}
+
+// mapNAT64 replaces destinations synthesized by DNS64 with the embedded IPv4
+// address, unless the outbound, or the outbound finally selected by a group,
+// is direct, which can only reach them through the NAT64 gateway, or does
+// not connect to the destination.
+func (r *Router) mapNAT64(ctx context.Context, metadata *adapter.InboundContext, outbound adapter.Outbound) (M.Socksaddr, bool) {
+	if !metadata.Destination.IsIPv6() || outbound == nil {
+		return M.Socksaddr{}, false
+	}
+	switch adapter.FinalOutbound(outbound).Type() {
+	case C.TypeDirect, C.TypeBlock, C.TypeDNS:
+		return M.Socksaddr{}, false
+	}
+	address, loaded := r.dns.LookupNAT64(metadata.Destination.Addr)
+	if !loaded {
+		return M.Socksaddr{}, false
+	}
+	origin := metadata.Destination
+	metadata.Destination = M.SocksaddrFrom(address, origin.Port)
+	metadata.IPVersion = 4
+	r.logger.DebugContext(ctx, "mapped NAT64 destination ", origin, " to ", metadata.Destination)
+	return origin, true
+}
+
+func applyRouteOptions(metadata *adapter.InboundContext, routeOptions *R.RuleActionRouteOptions) {
+	// TODO: add nat
+	if (routeOptions.OverrideAddress.IsValid() || routeOptions.OverridePort > 0) && !metadata.RouteOriginalDestination.IsValid() {
+		metadata.RouteOriginalDestination = metadata.Destination
+	}
+	if routeOptions.OverrideAddress.IsValid() {
+		metadata.Destination = M.Socksaddr{
+			Addr: routeOptions.OverrideAddress.Addr,
+			Port: metadata.Destination.Port,
+			Fqdn: routeOptions.OverrideAddress.Fqdn,
+		}
+		metadata.DestinationAddresses = nil
+	}
+	if routeOptions.OverridePort > 0 {
+		metadata.Destination = M.Socksaddr{
+			Addr: metadata.Destination.Addr,
+			Port: routeOptions.OverridePort,
+			Fqdn: metadata.Destination.Fqdn,
+		}
+	}
+	if routeOptions.NetworkStrategy != nil {
+		metadata.NetworkStrategy = routeOptions.NetworkStrategy
+	}
+	if len(routeOptions.NetworkType) > 0 {
+		metadata.NetworkType = routeOptions.NetworkType
+	}
+	if len(routeOptions.FallbackNetworkType) > 0 {
+		metadata.FallbackNetworkType = routeOptions.FallbackNetworkType
+	}
+	if routeOptions.FallbackDelay != 0 {
+		metadata.FallbackDelay = routeOptions.FallbackDelay
+	}
+	if routeOptions.UDPDisableDomainUnmapping {
+		metadata.UDPDisableDomainUnmapping = true
+	}
+	if routeOptions.UDPConnect {
+		metadata.UDPConnect = true
+	}
+	if routeOptions.UDPTimeout > 0 {
+		metadata.UDPTimeout = routeOptions.UDPTimeout
+	}
+	if routeOptions.TLSFragment {
+		metadata.TLSFragment = true
+		metadata.TLSFragmentFallbackDelay = routeOptions.TLSFragmentFallbackDelay
+	}
+	if routeOptions.TLSRecordFragment {
+		metadata.TLSRecordFragment = true
+	}
+}
+
 func (r *Router) actionSniff(
 	ctx context.Context, metadata *adapter.InboundContext, action *R.RuleActionSniff,
 	inputConn net.Conn, inputPacketConn N.PacketConn, inputBuffers []*buf.Buffer, inputPacketBuffers []*N.PacketBuffer,
@@ -572,6 +704,9 @@ func (r *Router) actionSniff(
 		r.logger.DebugContext(ctx, "duplicate sniff skipped")
 		return
 	}
+	if r.needClientHello.Load() {
+		ctx = sniff.ContextWithClientHello(ctx)
+	}
 	if inputConn != nil {
 		if len(action.StreamSniffers) == 0 && len(action.PacketSniffers) > 0 {
 			return
@@ -590,6 +725,11 @@ func (r *Router) actionSniff(
 				sniff.BitTorrent,
 				sniff.SSH,
 				sniff.RDP,
+				sniff.StreamOpenVPN,
+				sniff.MQTT,
+				sniff.SMB,
+				sniff.XMPP,
+				sniff.RTSP,
 			}
 		}
 		sniffBuffer := buf.NewPacket()
@@ -647,6 +787,8 @@ func (r *Router) actionSniff(
 				sniff.UDPTracker,
 				sniff.DTLSRecord,
 				sniff.NTP,
+				sniff.WireGuard,
+				sniff.OpenVPN,
 			}
 		}
 		var err error
@@ -774,6 +916,7 @@ func (r *Router) actionResolve(ctx context.Context, metadata *adapter.InboundCon
 			DisableCache: action.DisableCache,
 			RewriteTTL:   action.RewriteTTL,
 			ClientSubnet: action.ClientSubnet,
+			DNS64Prefix:  action.DNS64Prefix,
 		})
 		if err != nil {
 			return err
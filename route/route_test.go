@@ -0,0 +1,137 @@
+package route
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	R "github.com/sagernet/sing-box/route/rule"
+	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleStatistics(t *testing.T) {
+	t.Parallel()
+	for _, ruleStatistics := range []bool{false, true} {
+		ruleOptions, err := json.UnmarshalExtendedContext[[]option.Rule](context.Background(), []byte(`[
+			{"domain_suffix": "example.com", "action": "route-options", "override_port": 8443},
+			{"port": 8443, "outbound": "proxy"}
+		]`))
+		require.NoError(t, err)
+		logger := log.NewNOPFactory().NewLogger("router")
+		router := &Router{
+			logger:         logger,
+			dns:            &testDNSRouter{},
+			dnsTransport:   &testDNSTransportManager{},
+			ruleStatistics: ruleStatistics,
+		}
+		for _, options := range ruleOptions {
+			rule, err := R.NewRule(context.Background(), logger, options, false)
+			require.NoError(t, err)
+			router.rules = append(router.rules, rule)
+		}
+		for _, domain := range []string{"www.example.com", "example.org"} {
+			request := adapter.RuleTestRequest{Domain: domain, Port: 443}
+			metadata, err := request.Metadata()
+			require.NoError(t, err)
+			_, _, _, _, err = router.matchRule(context.Background(), &metadata, false, false, nil, nil)
+			require.NoError(t, err)
+		}
+		var hits []int64
+		for _, rule := range router.rules {
+			statistics := rule.Statistics()
+			hits = append(hits, statistics.Hits.Load())
+			require.Equal(t, ruleStatistics, !statistics.LastMatch().IsZero())
+		}
+		if ruleStatistics {
+			require.Equal(t, []int64{1, 1}, hits)
+		} else {
+			require.Equal(t, []int64{0, 0}, hits)
+		}
+	}
+}
+
+type testTypedOutbound struct {
+	testOutbound
+	outboundType string
+}
+
+func (o *testTypedOutbound) Type() string {
+	return o.outboundType
+}
+
+type testOutboundGroup struct {
+	testOutbound
+	now adapter.Outbound
+}
+
+func (g *testOutboundGroup) Now() string {
+	return g.now.Tag()
+}
+
+func (g *testOutboundGroup) All() []string {
+	return []string{g.now.Tag()}
+}
+
+func (g *testOutboundGroup) Outbounds() []adapter.Outbound {
+	return []adapter.Outbound{g.now}
+}
+
+func (g *testOutboundGroup) Outbound(tag string) (adapter.Outbound, bool) {
+	if tag != g.now.Tag() {
+		return nil, false
+	}
+	return g.now, true
+}
+
+type testNAT64DNSRouter struct {
+	adapter.DNSRouter
+}
+
+func (r *testNAT64DNSRouter) LookupNAT64(ip netip.Addr) (netip.Addr, bool) {
+	if !netip.MustParsePrefix("64:ff9b::/96").Contains(ip) {
+		return netip.Addr{}, false
+	}
+	ip16 := ip.As16()
+	return netip.AddrFrom4([4]byte(ip16[12:])), true
+}
+
+func TestMapNAT64(t *testing.T) {
+	t.Parallel()
+	router := &Router{
+		logger: log.NewNOPFactory().NewLogger("router"),
+		dns:    &testNAT64DNSRouter{},
+	}
+	direct := &testTypedOutbound{testOutbound{tag: "direct"}, C.TypeDirect}
+	proxy := &testTypedOutbound{testOutbound{tag: "proxy"}, "test"}
+	for _, testCase := range []struct {
+		name     string
+		outbound adapter.Outbound
+		mapped   bool
+	}{
+		{"proxy", proxy, true},
+		{"direct", direct, false},
+		{"block", &testTypedOutbound{testOutbound{tag: "block"}, C.TypeBlock}, false},
+		{"group selecting proxy", &testOutboundGroup{testOutbound: testOutbound{tag: "select"}, now: proxy}, true},
+		{"nested group selecting direct", &testOutboundGroup{
+			testOutbound: testOutbound{tag: "select"},
+			now:          &testOutboundGroup{testOutbound: testOutbound{tag: "urltest"}, now: direct},
+		}, false},
+	} {
+		metadata := adapter.InboundContext{Destination: M.ParseSocksaddr("[64:ff9b::101:101]:443")}
+		origin, mapped := router.mapNAT64(context.Background(), &metadata, testCase.outbound)
+		require.Equal(t, testCase.mapped, mapped, testCase.name)
+		if mapped {
+			require.Equal(t, "[64:ff9b::101:101]:443", origin.String())
+			require.Equal(t, "1.1.1.1:443", metadata.Destination.String())
+		} else {
+			require.Equal(t, "[64:ff9b::101:101]:443", metadata.Destination.String())
+		}
+	}
+}
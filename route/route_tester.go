@@ -0,0 +1,95 @@
+package route
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	R "github.com/sagernet/sing-box/route/rule"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// TestRoute matches metadata against route rules the same way connections
+// are matched, without sniffing, resolving or dialing, and records every rule evaluated.
+func (r *Router) TestRoute(ctx context.Context, metadata adapter.InboundContext) (*adapter.RouteTestResult, error) {
+	ctx = adapter.WithContext(ctx, &metadata)
+	err := r.lookupDestination(ctx, &metadata)
+	if err != nil {
+		return nil, err
+	}
+	result := &adapter.RouteTestResult{
+		RuleIndex: -1,
+	}
+	var selectedRule adapter.Rule
+match:
+	for currentRuleIndex, currentRule := range r.Rules() {
+		metadata.ResetRuleCache()
+		trace := adapter.RuleTrace{
+			Index:   currentRuleIndex,
+			Rule:    currentRule.String(),
+			Action:  currentRule.Action().String(),
+			Matched: currentRule.Match(&metadata),
+		}
+		if !trace.Matched {
+			result.Trace = append(result.Trace, trace)
+			continue
+		}
+		var routeOptions *R.RuleActionRouteOptions
+		switch action := currentRule.Action().(type) {
+		case *R.RuleActionRoute:
+			routeOptions = &action.RuleActionRouteOptions
+		case *R.RuleActionRouteOptions:
+			routeOptions = action
+		}
+		if routeOptions != nil {
+			applyRouteOptions(&metadata, routeOptions)
+		}
+		switch action := currentRule.Action().(type) {
+		case *R.RuleActionSniff:
+			if metadata.Protocol == "" {
+				trace.Note = "sniff skipped, set protocol to simulate the result"
+			}
+		case *R.RuleActionResolve:
+			if metadata.Destination.IsDomain() {
+				trace.Note = "resolve skipped, set ip to simulate the result"
+			}
+		case *R.RuleActionBypass:
+			if action.Outbound == "" {
+				trace.Note = "bypass without outbound is only available for pre-match"
+				result.Trace = append(result.Trace, trace)
+				continue match
+			}
+		}
+		result.Trace = append(result.Trace, trace)
+		switch currentRule.Action().Type() {
+		case C.RuleActionTypeRoute, C.RuleActionTypeReject, C.RuleActionTypeHijackDNS, C.RuleActionTypeBypass:
+			selectedRule = currentRule
+			result.RuleIndex = currentRuleIndex
+			break match
+		}
+	}
+	result.Destination = metadata.Destination.String()
+	var outboundTag string
+	if selectedRule == nil {
+		result.Action = C.RuleActionTypeRoute
+		outboundTag = r.outbound.Default().Tag()
+	} else {
+		result.Rule = selectedRule.String()
+		result.Action = selectedRule.Action().String()
+		switch action := selectedRule.Action().(type) {
+		case *R.RuleActionRoute:
+			outboundTag = action.Outbound
+		case *R.RuleActionBypass:
+			outboundTag = action.Outbound
+		}
+	}
+	if outboundTag != "" {
+		result.Outbound = outboundTag
+		outbound, loaded := r.outbound.Outbound(outboundTag)
+		if !loaded {
+			return nil, E.New("outbound not found: ", outboundTag)
+		}
+		result.Chain = adapter.OutboundChain(outbound)
+	}
+	return result, nil
+}
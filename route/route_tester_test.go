@@ -0,0 +1,196 @@
+package route
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	R "github.com/sagernet/sing-box/route/rule"
+	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testOutbound struct {
+	tag string
+}
+
+func (o *testOutbound) Type() string {
+	return "test"
+}
+
+func (o *testOutbound) Tag() string {
+	return o.tag
+}
+
+func (o *testOutbound) Network() []string {
+	return []string{N.NetworkTCP, N.NetworkUDP}
+}
+
+func (o *testOutbound) Dependencies() []string {
+	return nil
+}
+
+func (o *testOutbound) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	return nil, io.ErrClosedPipe
+}
+
+func (o *testOutbound) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return nil, io.ErrClosedPipe
+}
+
+// testOutboundManager implements only methods used by TestRoute.
+type testOutboundManager struct {
+	adapter.OutboundManager
+	outbounds map[string]adapter.Outbound
+}
+
+func (m *testOutboundManager) Outbound(tag string) (adapter.Outbound, bool) {
+	outbound, loaded := m.outbounds[tag]
+	return outbound, loaded
+}
+
+func (m *testOutboundManager) Default() adapter.Outbound {
+	return m.outbounds["default"]
+}
+
+type testDNSTransportManager struct {
+	adapter.DNSTransportManager
+}
+
+func (m *testDNSTransportManager) FakeIP() adapter.FakeIPTransport {
+	return nil
+}
+
+type testDNSRouter struct {
+	adapter.DNSRouter
+}
+
+func (r *testDNSRouter) LookupReverseMapping(ip netip.Addr) (string, bool) {
+	return "", false
+}
+
+func TestTestRoute(t *testing.T) {
+	t.Parallel()
+	ruleOptions, err := json.UnmarshalExtendedContext[[]option.Rule](context.Background(), []byte(`[
+		{"protocol": "bittorrent", "action": "reject"},
+		{"domain_suffix": "example.com", "action": "resolve", "server": "local"},
+		{"ip_cidr": "10.0.0.0/8", "outbound": "proxy"},
+		{"domain_suffix": "example.org", "action": "route-options", "override_port": 8443},
+		{"port": 8443, "outbound": "proxy"}
+	]`))
+	require.NoError(t, err)
+	logger := log.NewNOPFactory().NewLogger("router")
+	router := &Router{
+		logger: logger,
+		outbound: &testOutboundManager{outbounds: map[string]adapter.Outbound{
+			"default": &testOutbound{tag: "default"},
+			"proxy":   &testOutbound{tag: "proxy"},
+		}},
+		dns:          &testDNSRouter{},
+		dnsTransport: &testDNSTransportManager{},
+	}
+	for i, options := range ruleOptions {
+		rule, err := R.NewRule(context.Background(), logger, options, false)
+		require.NoError(t, err, "rule[", i, "]")
+		router.rules = append(router.rules, rule)
+	}
+	for _, testCase := range []struct {
+		name        string
+		request     adapter.RuleTestRequest
+		ruleIndex   int
+		outbound    string
+		destination string
+		trace       []bool
+		notes       map[int]string
+	}{
+		{
+			name:        "reject",
+			request:     adapter.RuleTestRequest{Domain: "example.net", Port: 443, Protocol: "bittorrent"},
+			ruleIndex:   0,
+			destination: "example.net:443",
+			trace:       []bool{true},
+		},
+		{
+			name:        "resolve skipped",
+			request:     adapter.RuleTestRequest{Domain: "www.example.com", Port: 443},
+			ruleIndex:   -1,
+			outbound:    "default",
+			destination: "www.example.com:443",
+			trace:       []bool{false, true, false, false, false},
+			notes:       map[int]string{1: "resolve skipped, set ip to simulate the result"},
+		},
+		{
+			name:        "resolve simulated",
+			request:     adapter.RuleTestRequest{Domain: "www.example.com", IP: "10.0.0.1", Port: 443},
+			ruleIndex:   2,
+			outbound:    "proxy",
+			destination: "10.0.0.1:443",
+			trace:       []bool{false, true, true},
+		},
+		{
+			name:        "route options",
+			request:     adapter.RuleTestRequest{Domain: "example.org", Port: 443},
+			ruleIndex:   4,
+			outbound:    "proxy",
+			destination: "example.org:8443",
+			trace:       []bool{false, false, false, true, true},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			metadata, err := testCase.request.Metadata()
+			require.NoError(t, err)
+			result, err := router.TestRoute(context.Background(), metadata)
+			require.NoError(t, err)
+			require.Equal(t, testCase.ruleIndex, result.RuleIndex)
+			require.Equal(t, testCase.outbound, result.Outbound)
+			require.Equal(t, testCase.destination, result.Destination)
+			trace := make([]bool, 0, len(result.Trace))
+			for _, item := range result.Trace {
+				trace = append(trace, item.Matched)
+				require.Equal(t, testCase.notes[item.Index], item.Note, "trace[", item.Index, "]")
+			}
+			require.Equal(t, testCase.trace, trace)
+		})
+	}
+}
+
+func TestTestRouteProcess(t *testing.T) {
+	t.Parallel()
+	ruleOptions, err := json.UnmarshalExtendedContext[[]option.Rule](context.Background(), []byte(`[
+		{"process_path": "curl", "outbound": "proxy"},
+		{"process_name": "curl", "outbound": "proxy"}
+	]`))
+	require.NoError(t, err)
+	logger := log.NewNOPFactory().NewLogger("router")
+	router := &Router{
+		logger: logger,
+		outbound: &testOutboundManager{outbounds: map[string]adapter.Outbound{
+			"default": &testOutbound{tag: "default"},
+			"proxy":   &testOutbound{tag: "proxy"},
+		}},
+		dns:          &testDNSRouter{},
+		dnsTransport: &testDNSTransportManager{},
+	}
+	for i, options := range ruleOptions {
+		rule, err := R.NewRule(context.Background(), logger, options, false)
+		require.NoError(t, err, "rule[", i, "]")
+		router.rules = append(router.rules, rule)
+	}
+	metadata, err := adapter.RuleTestRequest{Domain: "example.com", Port: 443, ProcessName: "curl"}.Metadata()
+	require.NoError(t, err)
+	result, err := router.TestRoute(context.Background(), metadata)
+	require.NoError(t, err)
+	require.Equal(t, 1, result.RuleIndex)
+
+	_, err = adapter.RuleTestRequest{Domain: "example.com", Port: 443, ProcessName: "curl", ProcessPath: "/usr/bin/wget"}.Metadata()
+	require.Error(t, err)
+}
@@ -2,11 +2,16 @@ package route
 
 import (
 	"context"
+	"net/netip"
 	"os"
 	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/geoip"
 	"github.com/sagernet/sing-box/common/process"
 	"github.com/sagernet/sing-box/common/taskmonitor"
 	C "github.com/sagernet/sing-box/constant"
@@ -19,6 +24,7 @@ import (
 	"github.com/sagernet/sing/contrab/freelru"
 	"github.com/sagernet/sing/contrab/maphash"
 	"github.com/sagernet/sing/service"
+	"github.com/sagernet/sing/service/filemanager"
 	"github.com/sagernet/sing/service/pause"
 )
 
@@ -34,20 +40,30 @@ type Router struct {
 	dnsTransport      adapter.DNSTransportManager
 	connection        adapter.ConnectionManager
 	network           adapter.NetworkManager
+	access            sync.RWMutex
 	rules             []adapter.Rule
 	needFindProcess   bool
+	needClientHello   atomic.Bool
+	ruleStatistics    bool
 	ruleSets          []adapter.RuleSet
 	ruleSetMap        map[string]adapter.RuleSet
 	processSearcher   process.Searcher
 	processCache      freelru.Cache[processCacheKey, processCacheEntry]
 	pauseManager      pause.Manager
 	trackers          []adapter.ConnectionTracker
+	authorizers       []adapter.UserAuthorizer
 	platformInterface adapter.PlatformInterface
+	asnPath           string
+	asnReader         *geoip.ASNReader
 	started           bool
 }
 
 func NewRouter(ctx context.Context, logFactory log.Factory, options option.RouteOptions, dnsOptions option.DNSOptions) *Router {
-	return &Router{
+	var asnPath string
+	if options.ASN != nil && options.ASN.Path != "" {
+		asnPath = filemanager.BasePath(ctx, options.ASN.Path)
+	}
+	router := &Router{
 		ctx:               ctx,
 		logger:            logFactory.NewLogger("router"),
 		inbound:           service.FromContext[adapter.InboundManager](ctx),
@@ -60,9 +76,13 @@ func NewRouter(ctx context.Context, logFactory log.Factory, options option.Route
 		rules:             make([]adapter.Rule, 0, len(options.Rules)),
 		ruleSetMap:        make(map[string]adapter.RuleSet),
 		needFindProcess:   hasRule(options.Rules, isProcessRule) || hasDNSRule(dnsOptions.Rules, isProcessDNSRule) || options.FindProcess,
+		ruleStatistics:    options.RuleStatistics,
 		pauseManager:      service.FromContext[pause.Manager](ctx),
 		platformInterface: service.FromContext[adapter.PlatformInterface](ctx),
+		asnPath:           asnPath,
 	}
+	router.needClientHello.Store(hasRule(options.Rules, isClientFingerprintRule))
+	return router
 }
 
 func (r *Router) Initialize(rules []option.Rule, ruleSets []option.RuleSet) error {
@@ -91,6 +111,15 @@ func (r *Router) Start(stage adapter.StartStage) error {
 	monitor := taskmonitor.New(r.logger, C.StartTimeout)
 	switch stage {
 	case adapter.StartStateStart:
+		if r.asnPath != "" {
+			monitor.Start("initialize ASN database")
+			asnReader, err := geoip.OpenASN(r.asnPath)
+			monitor.Finish()
+			if err != nil {
+				return E.Cause(err, "open ASN database")
+			}
+			r.asnReader = asnReader
+		}
 		var cacheContext *adapter.HTTPStartContext
 		if len(r.ruleSets) > 0 {
 			monitor.Start("initialize rule-set")
@@ -184,14 +213,17 @@ func (r *Router) Start(stage adapter.StartStage) error {
 func (r *Router) Close() error {
 	monitor := taskmonitor.New(r.logger, C.StopTimeout)
 	var err error
-	for i, rule := range r.rules {
+	r.access.RLock()
+	rules, ruleSets := r.rules, r.ruleSets
+	r.access.RUnlock()
+	for i, rule := range rules {
 		monitor.Start("close rule[", i, "]")
 		err = E.Append(err, rule.Close(), func(err error) error {
 			return E.Cause(err, "close rule[", i, "]")
 		})
 		monitor.Finish()
 	}
-	for i, ruleSet := range r.ruleSets {
+	for i, ruleSet := range ruleSets {
 		monitor.Start("close rule-set[", i, "]")
 		err = E.Append(err, ruleSet.Close(), func(err error) error {
 			return E.Cause(err, "close rule-set[", i, "]")
@@ -205,26 +237,73 @@ func (r *Router) Close() error {
 		})
 		monitor.Finish()
 	}
+	if r.asnReader != nil {
+		err = E.Append(err, r.asnReader.Close(), func(err error) error {
+			return E.Cause(err, "close ASN database")
+		})
+	}
 	return err
 }
 
 func (r *Router) RuleSet(tag string) (adapter.RuleSet, bool) {
+	r.access.RLock()
+	defer r.access.RUnlock()
 	ruleSet, loaded := r.ruleSetMap[tag]
 	return ruleSet, loaded
 }
 
 func (r *Router) Rules() []adapter.Rule {
+	r.access.RLock()
+	defer r.access.RUnlock()
 	return r.rules
 }
 
 func (r *Router) AppendTracker(tracker adapter.ConnectionTracker) {
-	r.trackers = append(r.trackers, tracker)
+	r.access.Lock()
+	defer r.access.Unlock()
+	r.trackers = append(slices.Clip(r.trackers), tracker)
+}
+
+func (r *Router) RemoveTracker(tracker adapter.ConnectionTracker) {
+	r.access.Lock()
+	defer r.access.Unlock()
+	r.trackers = slices.DeleteFunc(slices.Clone(r.trackers), func(it adapter.ConnectionTracker) bool {
+		return it == tracker
+	})
+}
+
+func (r *Router) AppendAuthorizer(authorizer adapter.UserAuthorizer) {
+	r.access.Lock()
+	defer r.access.Unlock()
+	r.authorizers = append(slices.Clip(r.authorizers), authorizer)
+}
+
+func (r *Router) RemoveAuthorizer(authorizer adapter.UserAuthorizer) {
+	r.access.Lock()
+	defer r.access.Unlock()
+	r.authorizers = slices.DeleteFunc(slices.Clone(r.authorizers), func(it adapter.UserAuthorizer) bool {
+		return it == authorizer
+	})
+}
+
+func (r *Router) connectionTrackers() []adapter.ConnectionTracker {
+	r.access.RLock()
+	defer r.access.RUnlock()
+	return r.trackers
 }
 
 func (r *Router) NeedFindProcess() bool {
 	return r.needFindProcess
 }
 
+func (r *Router) LookupASN(addr netip.Addr) (uint32, bool) {
+	if r.asnReader == nil {
+		return 0, false
+	}
+	asn, _, loaded := r.asnReader.Lookup(addr)
+	return asn, loaded
+}
+
 func (r *Router) ResetNetwork() {
 	r.network.ResetNetwork()
 	r.dns.ResetNetwork()
@@ -0,0 +1,177 @@
+package route
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/taskmonitor"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	R "github.com/sagernet/sing-box/route/rule"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/task"
+	"github.com/sagernet/sing/service"
+)
+
+// RuleReload holds rules and rule-sets prepared for a started router.
+// Nothing is visible to connections until Commit is called.
+type RuleReload struct {
+	router          *Router
+	ctx             context.Context
+	rules           []adapter.Rule
+	ruleSets        []adapter.RuleSet
+	ruleSetMap      map[string]adapter.RuleSet
+	needClientHello bool
+}
+
+// reloadRouter resolves rule-sets from a pending reload, so that rules
+// created for it never reference rule-sets that are about to be closed.
+type reloadRouter struct {
+	*Router
+	ruleSetMap map[string]adapter.RuleSet
+}
+
+func (r *reloadRouter) RuleSet(tag string) (adapter.RuleSet, bool) {
+	ruleSet, loaded := r.ruleSetMap[tag]
+	return ruleSet, loaded
+}
+
+// PrepareReload creates and starts new rules and rule-sets.
+// All rule-sets are recreated, since unreferenced rule-sets drop their
+// content after start and can not be reused by new rules.
+func (r *Router) PrepareReload(options option.RouteOptions, dnsOptions option.DNSOptions) (*RuleReload, error) {
+	if !r.started {
+		return nil, E.New("router is not started")
+	}
+	needFindProcess := hasRule(options.Rules, isProcessRule) || hasDNSRule(dnsOptions.Rules, isProcessDNSRule) || options.FindProcess
+	if needFindProcess && r.processSearcher == nil {
+		return nil, E.New("process rules require a restart to take effect")
+	}
+	reload := &RuleReload{
+		router:          r,
+		ruleSetMap:      make(map[string]adapter.RuleSet),
+		needClientHello: hasRule(options.Rules, isClientFingerprintRule),
+	}
+	reload.ctx = service.ContextWith[adapter.Router](r.ctx, &reloadRouter{
+		Router:     r,
+		ruleSetMap: reload.ruleSetMap,
+	})
+	err := reload.initialize(options.Rules, options.RuleSet)
+	if err != nil {
+		reload.Abort()
+		return nil, err
+	}
+	return reload, nil
+}
+
+func (p *RuleReload) initialize(rules []option.Rule, ruleSets []option.RuleSet) error {
+	r := p.router
+	for i, options := range ruleSets {
+		if _, exists := p.ruleSetMap[options.Tag]; exists {
+			return E.New("duplicate rule-set tag: ", options.Tag)
+		}
+		ruleSet, err := R.NewRuleSet(r.ctx, r.logger, options)
+		if err != nil {
+			return E.Cause(err, "parse rule-set[", i, "]")
+		}
+		p.ruleSets = append(p.ruleSets, ruleSet)
+		p.ruleSetMap[options.Tag] = ruleSet
+	}
+	for i, options := range rules {
+		rule, err := R.NewRule(p.ctx, r.logger, options, false)
+		if err != nil {
+			return E.Cause(err, "parse rule[", i, "]")
+		}
+		p.rules = append(p.rules, rule)
+	}
+	monitor := taskmonitor.New(r.logger, C.StartTimeout)
+	if len(p.ruleSets) > 0 {
+		monitor.Start("initialize rule-set")
+		cacheContext := adapter.NewHTTPStartContext(r.ctx)
+		var ruleSetStartGroup task.Group
+		for i, ruleSet := range p.ruleSets {
+			ruleSetInPlace := ruleSet
+			ruleSetStartGroup.Append0(func(ctx context.Context) error {
+				err := ruleSetInPlace.StartContext(ctx, cacheContext)
+				if err != nil {
+					return E.Cause(err, "initialize rule-set[", i, "]")
+				}
+				return nil
+			})
+		}
+		ruleSetStartGroup.Concurrency(5)
+		ruleSetStartGroup.FastFail()
+		err := ruleSetStartGroup.Run(r.ctx)
+		monitor.Finish()
+		cacheContext.Close()
+		if err != nil {
+			return err
+		}
+	}
+	for _, ruleSet := range p.ruleSets {
+		if ruleSet.Metadata().ContainsProcessRule && r.processSearcher == nil {
+			return E.New("process rules in rule-set[", ruleSet.Name(), "] require a restart to take effect")
+		}
+	}
+	for i, rule := range p.rules {
+		monitor.Start("initialize rule[", i, "]")
+		err := rule.Start()
+		monitor.Finish()
+		if err != nil {
+			return E.Cause(err, "initialize rule[", i, "]")
+		}
+	}
+	for _, ruleSet := range p.ruleSets {
+		monitor.Start("post start rule_set[", ruleSet.Name(), "]")
+		err := ruleSet.PostStart()
+		monitor.Finish()
+		if err != nil {
+			return E.Cause(err, "post start rule_set[", ruleSet.Name(), "]")
+		}
+	}
+	return nil
+}
+
+// Context returns a context whose router resolves rule-sets of the pending reload.
+// Rules of other routers (e.g. DNS rules) must be created with it before Commit.
+func (p *RuleReload) Context() context.Context {
+	return p.ctx
+}
+
+// Commit swaps the prepared rules in and closes the replaced ones.
+func (p *RuleReload) Commit() {
+	r := p.router
+	r.access.Lock()
+	oldRules := r.rules
+	oldRuleSets := r.ruleSets
+	r.rules = p.rules
+	r.ruleSets = p.ruleSets
+	r.ruleSetMap = p.ruleSetMap
+	r.needClientHello.Store(p.needClientHello)
+	r.access.Unlock()
+	r.network.Initialize(p.ruleSets)
+	for _, ruleSet := range p.ruleSets {
+		ruleSet.Cleanup()
+	}
+	r.closeRules(oldRules, oldRuleSets)
+}
+
+// Abort closes the prepared rules without applying them.
+func (p *RuleReload) Abort() {
+	p.router.closeRules(p.rules, p.ruleSets)
+}
+
+func (r *Router) closeRules(rules []adapter.Rule, ruleSets []adapter.RuleSet) {
+	for i, rule := range rules {
+		err := rule.Close()
+		if err != nil {
+			r.logger.Error(E.Cause(err, "close rule[", i, "]"))
+		}
+	}
+	for i, ruleSet := range ruleSets {
+		err := ruleSet.Close()
+		if err != nil {
+			r.logger.Error(E.Cause(err, "close rule-set[", i, "]"))
+		}
+	}
+}
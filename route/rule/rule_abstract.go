@@ -21,6 +21,7 @@ type abstractDefaultRule struct {
 	ruleSetItem             RuleItem
 	invert                  bool
 	action                  adapter.RuleAction
+	statistics              adapter.RuleStatistics
 }
 
 func (r *abstractDefaultRule) Type() string {
@@ -176,6 +177,10 @@ func (r *abstractDefaultRule) Action() adapter.RuleAction {
 	return r.action
 }
 
+func (r *abstractDefaultRule) Statistics() *adapter.RuleStatistics {
+	return &r.statistics
+}
+
 func (r *abstractDefaultRule) String() string {
 	if !r.invert {
 		return strings.Join(F.MapToString(r.allItems), " ")
@@ -185,10 +190,11 @@ func (r *abstractDefaultRule) String() string {
 }
 
 type abstractLogicalRule struct {
-	rules  []adapter.HeadlessRule
-	mode   string
-	invert bool
-	action adapter.RuleAction
+	rules      []adapter.HeadlessRule
+	mode       string
+	invert     bool
+	action     adapter.RuleAction
+	statistics adapter.RuleStatistics
 }
 
 func (r *abstractLogicalRule) Type() string {
@@ -277,6 +283,10 @@ func (r *abstractLogicalRule) Action() adapter.RuleAction {
 	return r.action
 }
 
+func (r *abstractLogicalRule) Statistics() *adapter.RuleStatistics {
+	return &r.statistics
+}
+
 func (r *abstractLogicalRule) String() string {
 	var op string
 	switch r.mode {
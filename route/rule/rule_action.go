@@ -10,11 +10,13 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/ratelimit"
 	"github.com/sagernet/sing-box/common/sniff"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-tun"
 	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/byteformats"
 	E "github.com/sagernet/sing/common/exceptions"
 	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/sing/common/logger"
@@ -106,13 +108,23 @@ func NewRuleAction(ctx context.Context, logger logger.ContextLogger, action opti
 		}
 		return sniffAction, sniffAction.build()
 	case C.RuleActionTypeResolve:
+		dns64Prefix := action.ResolveOptions.DNS64Prefix.Build(netip.Prefix{})
+		err := checkDNS64Prefix(dns64Prefix)
+		if err != nil {
+			return nil, err
+		}
 		return &RuleActionResolve{
 			Server:       action.ResolveOptions.Server,
 			Strategy:     C.DomainStrategy(action.ResolveOptions.Strategy),
 			DisableCache: action.ResolveOptions.DisableCache,
 			RewriteTTL:   action.ResolveOptions.RewriteTTL,
 			ClientSubnet: action.ResolveOptions.ClientSubnet.Build(netip.Prefix{}),
+			DNS64Prefix:  dns64Prefix,
 		}, nil
+	case C.RuleActionTypeLimit:
+		return newRuleActionLimit(action.LimitOptions)
+	case C.RuleActionTypeHTTPRewrite:
+		return newRuleActionHTTPRewrite(ctx, action.HTTPRewriteOptions)
 	default:
 		panic(F.ToString("unknown rule action: ", action.Action))
 	}
@@ -126,18 +138,22 @@ func NewDNSRuleAction(logger logger.ContextLogger, action option.DNSRuleAction)
 		return &RuleActionDNSRoute{
 			Server: action.RouteOptions.Server,
 			RuleActionDNSRouteOptions: RuleActionDNSRouteOptions{
-				Strategy:     C.DomainStrategy(action.RouteOptions.Strategy),
-				DisableCache: action.RouteOptions.DisableCache,
-				RewriteTTL:   action.RouteOptions.RewriteTTL,
-				ClientSubnet: netip.Prefix(common.PtrValueOrDefault(action.RouteOptions.ClientSubnet)),
+				Strategy:      C.DomainStrategy(action.RouteOptions.Strategy),
+				DisableCache:  action.RouteOptions.DisableCache,
+				RewriteTTL:    action.RouteOptions.RewriteTTL,
+				ClientSubnet:  netip.Prefix(common.PtrValueOrDefault(action.RouteOptions.ClientSubnet)),
+				RequireDNSSEC: action.RouteOptions.RequireDNSSEC,
+				DNS64Prefix:   action.RouteOptions.DNS64Prefix.Build(netip.Prefix{}),
 			},
 		}
 	case C.RuleActionTypeRouteOptions:
 		return &RuleActionDNSRouteOptions{
-			Strategy:     C.DomainStrategy(action.RouteOptionsOptions.Strategy),
-			DisableCache: action.RouteOptionsOptions.DisableCache,
-			RewriteTTL:   action.RouteOptionsOptions.RewriteTTL,
-			ClientSubnet: netip.Prefix(common.PtrValueOrDefault(action.RouteOptionsOptions.ClientSubnet)),
+			Strategy:      C.DomainStrategy(action.RouteOptionsOptions.Strategy),
+			DisableCache:  action.RouteOptionsOptions.DisableCache,
+			RewriteTTL:    action.RouteOptionsOptions.RewriteTTL,
+			ClientSubnet:  netip.Prefix(common.PtrValueOrDefault(action.RouteOptionsOptions.ClientSubnet)),
+			RequireDNSSEC: action.RouteOptionsOptions.RequireDNSSEC,
+			DNS64Prefix:   action.RouteOptionsOptions.DNS64Prefix.Build(netip.Prefix{}),
 		}
 	case C.RuleActionTypeReject:
 		return &RuleActionReject{
@@ -277,14 +293,22 @@ func (r *RuleActionDNSRoute) String() string {
 	if r.ClientSubnet.IsValid() {
 		descriptions = append(descriptions, F.ToString("client-subnet=", r.ClientSubnet))
 	}
+	if r.RequireDNSSEC {
+		descriptions = append(descriptions, "require-dnssec")
+	}
+	if r.DNS64Prefix.IsValid() {
+		descriptions = append(descriptions, F.ToString("dns64-prefix=", r.DNS64Prefix))
+	}
 	return F.ToString("route(", strings.Join(descriptions, ","), ")")
 }
 
 type RuleActionDNSRouteOptions struct {
-	Strategy     C.DomainStrategy
-	DisableCache bool
-	RewriteTTL   *uint32
-	ClientSubnet netip.Prefix
+	Strategy      C.DomainStrategy
+	DisableCache  bool
+	RewriteTTL    *uint32
+	ClientSubnet  netip.Prefix
+	RequireDNSSEC bool
+	DNS64Prefix   netip.Prefix
 }
 
 func (r *RuleActionDNSRouteOptions) Type() string {
@@ -302,6 +326,12 @@ func (r *RuleActionDNSRouteOptions) String() string {
 	if r.ClientSubnet.IsValid() {
 		descriptions = append(descriptions, F.ToString("client-subnet=", r.ClientSubnet))
 	}
+	if r.RequireDNSSEC {
+		descriptions = append(descriptions, "require-dnssec")
+	}
+	if r.DNS64Prefix.IsValid() {
+		descriptions = append(descriptions, F.ToString("dns64-prefix=", r.DNS64Prefix))
+	}
 	return F.ToString("route-options(", strings.Join(descriptions, ","), ")")
 }
 
@@ -451,6 +481,19 @@ func (r *RuleActionSniff) build() error {
 			r.StreamSniffers = append(r.StreamSniffers, sniff.RDP)
 		case C.ProtocolNTP:
 			r.PacketSniffers = append(r.PacketSniffers, sniff.NTP)
+		case C.ProtocolWireGuard:
+			r.PacketSniffers = append(r.PacketSniffers, sniff.WireGuard)
+		case C.ProtocolOpenVPN:
+			r.StreamSniffers = append(r.StreamSniffers, sniff.StreamOpenVPN)
+			r.PacketSniffers = append(r.PacketSniffers, sniff.OpenVPN)
+		case C.ProtocolMQTT:
+			r.StreamSniffers = append(r.StreamSniffers, sniff.MQTT)
+		case C.ProtocolSMB:
+			r.StreamSniffers = append(r.StreamSniffers, sniff.SMB)
+		case C.ProtocolXMPP:
+			r.StreamSniffers = append(r.StreamSniffers, sniff.XMPP)
+		case C.ProtocolRTSP:
+			r.StreamSniffers = append(r.StreamSniffers, sniff.RTSP)
 		default:
 			return E.New("unknown sniffer: ", name)
 		}
@@ -476,6 +519,7 @@ type RuleActionResolve struct {
 	DisableCache bool
 	RewriteTTL   *uint32
 	ClientSubnet netip.Prefix
+	DNS64Prefix  netip.Prefix
 }
 
 func (r *RuleActionResolve) Type() string {
@@ -499,6 +543,9 @@ func (r *RuleActionResolve) String() string {
 	if r.ClientSubnet.IsValid() {
 		options = append(options, F.ToString("client_subnet=", r.ClientSubnet))
 	}
+	if r.DNS64Prefix.IsValid() {
+		options = append(options, F.ToString("dns64_prefix=", r.DNS64Prefix))
+	}
 	if len(options) == 0 {
 		return "resolve"
 	} else {
@@ -506,6 +553,64 @@ func (r *RuleActionResolve) String() string {
 	}
 }
 
+type RuleActionLimit struct {
+	Upload   uint64
+	Download uint64
+	Key      string
+	group    *ratelimit.Group
+}
+
+func newRuleActionLimit(options option.RouteActionLimit) (*RuleActionLimit, error) {
+	action := &RuleActionLimit{
+		Upload:   options.Upload.Value(),
+		Download: options.Download.Value(),
+		Key:      options.Key,
+	}
+	if action.Upload == 0 && action.Download == 0 {
+		return nil, E.New("missing upload or download limit")
+	}
+	switch action.Key {
+	case "":
+		action.Key = C.RuleActionLimitKeyConnection
+	case C.RuleActionLimitKeyConnection, C.RuleActionLimitKeyUser, C.RuleActionLimitKeySourceIP, C.RuleActionLimitKeyRule:
+	default:
+		return nil, E.New("unknown limit key: ", action.Key)
+	}
+	action.group = ratelimit.NewGroup(action.Upload, action.Download, action.Key == C.RuleActionLimitKeyConnection)
+	return action, nil
+}
+
+func (r *RuleActionLimit) Type() string {
+	return C.RuleActionTypeLimit
+}
+
+func (r *RuleActionLimit) String() string {
+	var options []string
+	if r.Upload > 0 {
+		options = append(options, F.ToString("upload=", byteformats.FormatBytes(r.Upload), "/s"))
+	}
+	if r.Download > 0 {
+		options = append(options, F.ToString("download=", byteformats.FormatBytes(r.Download), "/s"))
+	}
+	options = append(options, F.ToString("key=", r.Key))
+	return F.ToString("limit(", strings.Join(options, ","), ")")
+}
+
+// Limit selects the limiter shared by connections with the same key.
+func (r *RuleActionLimit) Limit(metadata *adapter.InboundContext) ratelimit.Limit {
+	var key string
+	switch r.Key {
+	case C.RuleActionLimitKeyUser:
+		key = metadata.User
+	case C.RuleActionLimitKeySourceIP:
+		key = metadata.Source.Addr.Unmap().String()
+	}
+	return ratelimit.Limit{
+		Group: r.group,
+		Key:   key,
+	}
+}
+
 type RuleActionPredefined struct {
 	Rcode  int
 	Answer []dns.RR
@@ -554,3 +659,19 @@ func rewriteRecords(records []dns.RR, question dns.Question) []dns.RR {
 		return it
 	})
 }
+
+// checkDNS64Prefix checks for the NAT64 prefix lengths defined in RFC 6052.
+func checkDNS64Prefix(prefix netip.Prefix) error {
+	if !prefix.IsValid() {
+		return nil
+	}
+	if !prefix.Addr().Is6() || prefix.Addr().Is4In6() {
+		return E.New("invalid DNS64 prefix: ", prefix, ": not an IPv6 prefix")
+	}
+	switch prefix.Bits() {
+	case 32, 40, 48, 56, 64, 96:
+		return nil
+	default:
+		return E.New("invalid DNS64 prefix: ", prefix, ": length must be one of 32, 40, 48, 56, 64 or 96")
+	}
+}
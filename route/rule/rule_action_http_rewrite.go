@@ -0,0 +1,215 @@
+package rule
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/service/filemanager"
+)
+
+type RuleActionHTTPRewrite struct {
+	Outbound      string
+	BlockPath     []*regexp.Regexp
+	RewriteHost   []HTTPRewriteRegex
+	RewriteURL    []HTTPRewriteRegex
+	Headers       http.Header
+	RemoveHeaders []string
+	StatusCode    int
+	Redirect      string
+	File          string
+}
+
+type HTTPRewriteRegex struct {
+	Regex       *regexp.Regexp
+	Replacement string
+}
+
+func newRuleActionHTTPRewrite(ctx context.Context, options option.RouteActionHTTPRewrite) (*RuleActionHTTPRewrite, error) {
+	action := &RuleActionHTTPRewrite{
+		Outbound:      options.Outbound,
+		Headers:       options.Headers.Build(),
+		RemoveHeaders: options.RemoveHeaders,
+		StatusCode:    options.StatusCode,
+		Redirect:      options.Redirect,
+	}
+	for i, pathRegex := range options.BlockPath {
+		regex, err := regexp.Compile(pathRegex)
+		if err != nil {
+			return nil, E.Cause(err, "parse block_path[", i, "]")
+		}
+		action.BlockPath = append(action.BlockPath, regex)
+	}
+	var err error
+	action.RewriteHost, err = newHTTPRewriteRegexList(options.RewriteHost, "rewrite_host")
+	if err != nil {
+		return nil, err
+	}
+	action.RewriteURL, err = newHTTPRewriteRegexList(options.RewriteURL, "rewrite_url")
+	if err != nil {
+		return nil, err
+	}
+	if options.File != "" {
+		action.File = filemanager.BasePath(ctx, options.File)
+	}
+	if action.StatusCode != 0 && (action.StatusCode < 200 || action.StatusCode > 599) {
+		return nil, E.New("invalid status code: ", action.StatusCode)
+	}
+	isRedirectStatus := action.StatusCode >= 300 && action.StatusCode < 400
+	if action.Redirect != "" {
+		if action.File != "" {
+			return nil, E.New("`redirect` and `file` are mutually exclusive")
+		}
+		if action.StatusCode != 0 && !isRedirectStatus {
+			return nil, E.New("invalid status code for redirect: ", action.StatusCode)
+		}
+	} else if isRedirectStatus && len(action.RewriteURL) == 0 {
+		return nil, E.New("missing `redirect` or `rewrite_url` for redirect status code")
+	}
+	if action.File != "" && isRedirectStatus {
+		return nil, E.New("invalid status code for file: ", action.StatusCode)
+	}
+	return action, nil
+}
+
+func newHTTPRewriteRegexList(options []option.HTTPRewriteRegex, name string) ([]HTTPRewriteRegex, error) {
+	rewriteList := make([]HTTPRewriteRegex, 0, len(options))
+	for i, rewriteOptions := range options {
+		regex, err := regexp.Compile(rewriteOptions.Regex)
+		if err != nil {
+			return nil, E.Cause(err, "parse ", name, "[", i, "]")
+		}
+		rewriteList = append(rewriteList, HTTPRewriteRegex{
+			Regex:       regex,
+			Replacement: rewriteOptions.Replacement,
+		})
+	}
+	return rewriteList, nil
+}
+
+func (r *RuleActionHTTPRewrite) Type() string {
+	return C.RuleActionTypeHTTPRewrite
+}
+
+func (r *RuleActionHTTPRewrite) String() string {
+	var descriptions []string
+	if r.Outbound != "" {
+		descriptions = append(descriptions, r.Outbound)
+	}
+	if r.Redirect != "" {
+		descriptions = append(descriptions, F.ToString("redirect=", r.Redirect))
+	} else if r.File != "" {
+		descriptions = append(descriptions, F.ToString("file=", r.File))
+	}
+	if r.StatusCode != 0 {
+		descriptions = append(descriptions, F.ToString("status=", r.StatusCode))
+	}
+	if len(descriptions) == 0 {
+		return "http-rewrite"
+	}
+	return F.ToString("http-rewrite(", strings.Join(descriptions, ","), ")")
+}
+
+// Rewrite modifies the request in place, and returns the response to answer
+// locally, or nil if the request should be forwarded.
+func (r *RuleActionHTTPRewrite) Rewrite(request *http.Request) (*http.Response, error) {
+	for _, regex := range r.BlockPath {
+		if regex.MatchString(request.URL.Path) {
+			return newHTTPRewriteResponse(request, http.StatusForbidden, nil, nil), nil
+		}
+	}
+	if len(r.RewriteHost) > 0 {
+		request.Host = rewriteHTTPString(r.RewriteHost, request.Host)
+	}
+	if len(r.RewriteURL) > 0 {
+		requestURL := "http://" + request.Host + request.URL.RequestURI()
+		newURL := rewriteHTTPString(r.RewriteURL, requestURL)
+		if newURL != requestURL {
+			parsedURL, err := url.Parse(newURL)
+			if err != nil {
+				return nil, E.Cause(err, "parse rewritten URL")
+			}
+			if parsedURL.Host != "" {
+				request.Host = parsedURL.Host
+			}
+			request.URL = parsedURL
+		}
+	}
+	for key, values := range r.Headers {
+		request.Header[key] = values
+	}
+	for _, key := range r.RemoveHeaders {
+		request.Header.Del(key)
+	}
+	if r.Redirect != "" || r.StatusCode >= 300 && r.StatusCode < 400 {
+		statusCode := r.StatusCode
+		if statusCode == 0 {
+			statusCode = http.StatusFound
+		}
+		location := r.Redirect
+		if location == "" {
+			location = request.URL.String()
+			if request.URL.Host == "" {
+				location = "http://" + request.Host + request.URL.RequestURI()
+			}
+		}
+		return newHTTPRewriteResponse(request, statusCode, http.Header{"Location": []string{location}}, nil), nil
+	}
+	if r.File != "" {
+		content, err := os.ReadFile(r.File)
+		if err != nil {
+			return nil, E.Cause(err, "read response file")
+		}
+		statusCode := r.StatusCode
+		if statusCode == 0 {
+			statusCode = http.StatusOK
+		}
+		header := make(http.Header)
+		contentType := mime.TypeByExtension(filepath.Ext(r.File))
+		if contentType == "" {
+			contentType = http.DetectContentType(content)
+		}
+		header.Set("Content-Type", contentType)
+		return newHTTPRewriteResponse(request, statusCode, header, content), nil
+	}
+	if r.StatusCode != 0 {
+		return newHTTPRewriteResponse(request, r.StatusCode, nil, nil), nil
+	}
+	return nil, nil
+}
+
+func rewriteHTTPString(rewriteList []HTTPRewriteRegex, value string) string {
+	for _, rewrite := range rewriteList {
+		value = rewrite.Regex.ReplaceAllString(value, rewrite.Replacement)
+	}
+	return value
+}
+
+func newHTTPRewriteResponse(request *http.Request, statusCode int, header http.Header, content []byte) *http.Response {
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Set("Content-Length", strconv.Itoa(len(content)))
+	return &http.Response{
+		StatusCode:    statusCode,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(content)),
+		ContentLength: int64(len(content)),
+		Close:         request.Close,
+		Request:       request,
+	}
+}
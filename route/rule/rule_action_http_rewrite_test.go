@@ -0,0 +1,111 @@
+package rule
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json/badoption"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readTestHTTPRequest(t *testing.T, content string) *http.Request {
+	request, err := http.ReadRequest(bufio.NewReader(strings.NewReader(content)))
+	require.NoError(t, err)
+	return request
+}
+
+func TestHTTPRewriteForward(t *testing.T) {
+	t.Parallel()
+	action, err := newRuleActionHTTPRewrite(context.Background(), option.RouteActionHTTPRewrite{
+		RewriteHost: []option.HTTPRewriteRegex{{Regex: `^app\.local$`, Replacement: "app.internal"}},
+		RewriteURL:  []option.HTTPRewriteRegex{{Regex: `^http://([^/]+)/v1/`, Replacement: "http://$1/v2/"}},
+		Headers: badoption.HTTPHeader{
+			"x-forwarded-by": {"sing-box"},
+		},
+		RemoveHeaders: []string{"Cookie"},
+	})
+	require.NoError(t, err)
+	request := readTestHTTPRequest(t, "GET /v1/users?id=1 HTTP/1.1\r\nHost: app.local\r\nCookie: a=b\r\n\r\n")
+	response, err := action.Rewrite(request)
+	require.NoError(t, err)
+	require.Nil(t, response)
+	require.Equal(t, "app.internal", request.Host)
+	require.Equal(t, "/v2/users?id=1", request.URL.RequestURI())
+	require.Equal(t, "sing-box", request.Header.Get("X-Forwarded-By"))
+	require.Empty(t, request.Header.Get("Cookie"))
+}
+
+func TestHTTPRewriteBlockPath(t *testing.T) {
+	t.Parallel()
+	action, err := newRuleActionHTTPRewrite(context.Background(), option.RouteActionHTTPRewrite{
+		BlockPath: []string{`^/admin(/|$)`},
+	})
+	require.NoError(t, err)
+	response, err := action.Rewrite(readTestHTTPRequest(t, "GET /admin/login HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	require.Equal(t, http.StatusForbidden, response.StatusCode)
+	response, err = action.Rewrite(readTestHTTPRequest(t, "GET /administrator HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	require.NoError(t, err)
+	require.Nil(t, response)
+}
+
+func TestHTTPRewriteRedirect(t *testing.T) {
+	t.Parallel()
+	action, err := newRuleActionHTTPRewrite(context.Background(), option.RouteActionHTTPRewrite{
+		Redirect: "http://portal.lan/login",
+	})
+	require.NoError(t, err)
+	response, err := action.Rewrite(readTestHTTPRequest(t, "GET /generate_204 HTTP/1.1\r\nHost: connectivitycheck.gstatic.com\r\n\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, response.StatusCode)
+	require.Equal(t, "http://portal.lan/login", response.Header.Get("Location"))
+
+	action, err = newRuleActionHTTPRewrite(context.Background(), option.RouteActionHTTPRewrite{
+		RewriteURL: []option.HTTPRewriteRegex{{Regex: `^http://`, Replacement: "https://"}},
+		StatusCode: http.StatusMovedPermanently,
+	})
+	require.NoError(t, err)
+	response, err = action.Rewrite(readTestHTTPRequest(t, "GET /path?q=1 HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusMovedPermanently, response.StatusCode)
+	require.Equal(t, "https://example.com/path?q=1", response.Header.Get("Location"))
+}
+
+func TestHTTPRewriteFile(t *testing.T) {
+	t.Parallel()
+	filePath := filepath.Join(t.TempDir(), "portal.html")
+	require.NoError(t, os.WriteFile(filePath, []byte("<h1>hello</h1>"), 0o644))
+	action, err := newRuleActionHTTPRewrite(context.Background(), option.RouteActionHTTPRewrite{
+		File: filePath,
+	})
+	require.NoError(t, err)
+	response, err := action.Rewrite(readTestHTTPRequest(t, "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, "text/html; charset=utf-8", response.Header.Get("Content-Type"))
+	content, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Equal(t, "<h1>hello</h1>", string(content))
+}
+
+func TestHTTPRewriteInvalid(t *testing.T) {
+	t.Parallel()
+	_, err := newRuleActionHTTPRewrite(context.Background(), option.RouteActionHTTPRewrite{
+		StatusCode: http.StatusFound,
+	})
+	require.Error(t, err)
+	_, err = newRuleActionHTTPRewrite(context.Background(), option.RouteActionHTTPRewrite{
+		Redirect: "http://portal.lan/",
+		File:     "portal.html",
+	})
+	require.Error(t, err)
+}
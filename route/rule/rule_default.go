@@ -104,6 +104,24 @@ func NewDefaultRule(ctx context.Context, logger log.ContextLogger, options optio
 		rule.items = append(rule.items, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.ClientFingerprint) > 0 {
+		item := NewClientFingerprintItem(options.ClientFingerprint)
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if len(options.HTTPUserAgent) > 0 {
+		item := NewHTTPUserAgentItem(options.HTTPUserAgent)
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if len(options.HTTPPathRegex) > 0 {
+		item, err := NewHTTPPathRegexItem(options.HTTPPathRegex)
+		if err != nil {
+			return nil, E.Cause(err, "http_path_regex")
+		}
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if len(options.Domain) > 0 || len(options.DomainSuffix) > 0 {
 		item, err := NewDomainItem(options.Domain, options.DomainSuffix)
 		if err != nil {
@@ -160,6 +178,16 @@ func NewDefaultRule(ctx context.Context, logger log.ContextLogger, options optio
 		rule.destinationIPCIDRItems = append(rule.destinationIPCIDRItems, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.SourceIPASN) > 0 {
+		item := NewIPASNItem(router, true, options.SourceIPASN)
+		rule.sourceAddressItems = append(rule.sourceAddressItems, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if len(options.IPASN) > 0 {
+		item := NewIPASNItem(router, false, options.IPASN)
+		rule.destinationIPCIDRItems = append(rule.destinationIPCIDRItems, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if len(options.SourcePort) > 0 {
 		item := NewPortItem(true, options.SourcePort)
 		rule.sourcePortItems = append(rule.sourcePortItems, item)
@@ -224,6 +252,14 @@ func NewDefaultRule(ctx context.Context, logger log.ContextLogger, options optio
 		rule.items = append(rule.items, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if options.Time != nil {
+		item, err := NewTimeItem(ctx, *options.Time)
+		if err != nil {
+			return nil, E.Cause(err, "time")
+		}
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if len(options.NetworkType) > 0 {
 		item := NewNetworkTypeItem(networkManager, common.Map(options.NetworkType, option.InterfaceType.Build))
 		rule.items = append(rule.items, item)
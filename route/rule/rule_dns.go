@@ -2,6 +2,7 @@ package rule
 
 import (
 	"context"
+	"net/netip"
 
 	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
@@ -24,6 +25,10 @@ func NewDNSRule(ctx context.Context, logger log.ContextLogger, options option.DN
 				return nil, E.New("missing server field")
 			}
 		}
+		err := checkDNSRuleAction(options.DefaultOptions.DNSRuleAction)
+		if err != nil {
+			return nil, err
+		}
 		return NewDefaultDNSRule(ctx, logger, options.DefaultOptions)
 	case C.RuleTypeLogical:
 		if !options.LogicalOptions.IsValid() {
@@ -35,16 +40,31 @@ func NewDNSRule(ctx context.Context, logger log.ContextLogger, options option.DN
 				return nil, E.New("missing server field")
 			}
 		}
+		err := checkDNSRuleAction(options.LogicalOptions.DNSRuleAction)
+		if err != nil {
+			return nil, err
+		}
 		return NewLogicalDNSRule(ctx, logger, options.LogicalOptions)
 	default:
 		return nil, E.New("unknown rule type: ", options.Type)
 	}
 }
 
+func checkDNSRuleAction(action option.DNSRuleAction) error {
+	switch action.Action {
+	case "", C.RuleActionTypeRoute:
+		return checkDNS64Prefix(action.RouteOptions.DNS64Prefix.Build(netip.Prefix{}))
+	case C.RuleActionTypeRouteOptions:
+		return checkDNS64Prefix(action.RouteOptionsOptions.DNS64Prefix.Build(netip.Prefix{}))
+	}
+	return nil
+}
+
 var _ adapter.DNSRule = (*DefaultDNSRule)(nil)
 
 type DefaultDNSRule struct {
 	abstractDefaultRule
+	matchResponse bool
 }
 
 func (r *DefaultDNSRule) matchStates(metadata *adapter.InboundContext) ruleMatchStateSet {
@@ -52,11 +72,16 @@ func (r *DefaultDNSRule) matchStates(metadata *adapter.InboundContext) ruleMatch
 }
 
 func NewDefaultDNSRule(ctx context.Context, logger log.ContextLogger, options option.DefaultDNSRule) (*DefaultDNSRule, error) {
+	// response items never match when matching queries
+	if !options.MatchResponse && (len(options.ResponseRcode) > 0 || len(options.ResponseCNAME) > 0 || len(options.ResponseCNAMESuffix) > 0) {
+		return nil, E.New("response_* items require match_response")
+	}
 	rule := &DefaultDNSRule{
 		abstractDefaultRule: abstractDefaultRule{
 			invert: options.Invert,
 			action: NewDNSRuleAction(logger, options.DNSRuleAction),
 		},
+		matchResponse: options.MatchResponse,
 	}
 	if len(options.Inbound) > 0 {
 		item := NewInboundRule(options.Inbound)
@@ -156,6 +181,29 @@ func NewDefaultDNSRule(ctx context.Context, logger log.ContextLogger, options op
 		rule.destinationIPCIDRItems = append(rule.destinationIPCIDRItems, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.SourceIPASN) > 0 {
+		item := NewIPASNItem(router, true, options.SourceIPASN)
+		rule.sourceAddressItems = append(rule.sourceAddressItems, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if len(options.IPASN) > 0 {
+		item := NewIPASNItem(router, false, options.IPASN)
+		rule.destinationIPCIDRItems = append(rule.destinationIPCIDRItems, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if len(options.ResponseRcode) > 0 {
+		item := NewResponseRcodeItem(options.ResponseRcode)
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if len(options.ResponseCNAME) > 0 || len(options.ResponseCNAMESuffix) > 0 {
+		item, err := NewResponseCNAMEItem(options.ResponseCNAME, options.ResponseCNAMESuffix)
+		if err != nil {
+			return nil, err
+		}
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if len(options.SourcePort) > 0 {
 		item := NewPortItem(true, options.SourcePort)
 		rule.sourcePortItems = append(rule.sourcePortItems, item)
@@ -225,6 +273,14 @@ func NewDefaultDNSRule(ctx context.Context, logger log.ContextLogger, options op
 		rule.items = append(rule.items, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if options.Time != nil {
+		item, err := NewTimeItem(ctx, *options.Time)
+		if err != nil {
+			return nil, E.Cause(err, "time")
+		}
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if len(options.NetworkType) > 0 {
 		item := NewNetworkTypeItem(networkManager, common.Map(options.NetworkType, option.InterfaceType.Build))
 		rule.items = append(rule.items, item)
@@ -310,10 +366,15 @@ func (r *DefaultDNSRule) MatchAddressLimit(metadata *adapter.InboundContext) boo
 	return !r.matchStates(metadata).isEmpty()
 }
 
+func (r *DefaultDNSRule) MatchResponse() bool {
+	return r.matchResponse
+}
+
 var _ adapter.DNSRule = (*LogicalDNSRule)(nil)
 
 type LogicalDNSRule struct {
 	abstractLogicalRule
+	matchResponse bool
 }
 
 func (r *LogicalDNSRule) matchStates(metadata *adapter.InboundContext) ruleMatchStateSet {
@@ -327,6 +388,7 @@ func NewLogicalDNSRule(ctx context.Context, logger log.ContextLogger, options op
 			invert: options.Invert,
 			action: NewDNSRuleAction(logger, options.DNSRuleAction),
 		},
+		matchResponse: options.MatchResponse,
 	}
 	switch options.Mode {
 	case C.LogicalTypeAnd:
@@ -337,6 +399,11 @@ func NewLogicalDNSRule(ctx context.Context, logger log.ContextLogger, options op
 		return nil, E.New("unknown logical mode: ", options.Mode)
 	}
 	for i, subRule := range options.Rules {
+		// sub rules are matched against responses if the logical rule is
+		if options.MatchResponse {
+			subRule.DefaultOptions.MatchResponse = true
+			subRule.LogicalOptions.MatchResponse = true
+		}
 		rule, err := NewDNSRule(ctx, logger, subRule, false)
 		if err != nil {
 			return nil, E.Cause(err, "sub rule[", i, "]")
@@ -377,3 +444,7 @@ func (r *LogicalDNSRule) Match(metadata *adapter.InboundContext) bool {
 func (r *LogicalDNSRule) MatchAddressLimit(metadata *adapter.InboundContext) bool {
 	return !r.matchStates(metadata).isEmpty()
 }
+
+func (r *LogicalDNSRule) MatchResponse() bool {
+	return r.matchResponse
+}
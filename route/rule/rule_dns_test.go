@@ -0,0 +1,176 @@
+package rule
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/service"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestResponse(rcode int, records ...string) *dns.Msg {
+	response := new(dns.Msg)
+	response.SetQuestion("example.com.", dns.TypeA)
+	response.Response = true
+	response.Rcode = rcode
+	for _, record := range records {
+		response.Answer = append(response.Answer, common.Must1(dns.NewRR(record)))
+	}
+	return response
+}
+
+func matchTestResponse(rule adapter.DNSRule, response *dns.Msg) bool {
+	metadata := &adapter.InboundContext{
+		Domain:      "example.com",
+		DNSResponse: response,
+	}
+	for _, record := range response.Answer {
+		if a, isA := record.(*dns.A); isA {
+			address, _ := netip.AddrFromSlice(a.A.To4())
+			metadata.DestinationAddresses = append(metadata.DestinationAddresses, address)
+		}
+	}
+	return rule.MatchAddressLimit(metadata)
+}
+
+func TestDNSRuleMatchResponse(t *testing.T) {
+	t.Parallel()
+	rule, err := NewDNSRule(context.Background(), log.NewNOPFactory().Logger(), option.DNSRule{
+		Type: C.RuleTypeDefault,
+		DefaultOptions: option.DefaultDNSRule{
+			RawDefaultDNSRule: option.RawDefaultDNSRule{
+				IPCIDR:        []string{"10.0.0.0/8"},
+				MatchResponse: true,
+			},
+			DNSRuleAction: option.DNSRuleAction{
+				Action:       C.RuleActionTypeRoute,
+				RouteOptions: option.DNSRouteActionOptions{Server: "remote"},
+			},
+		},
+	}, true)
+	require.NoError(t, err)
+	require.True(t, rule.MatchResponse())
+	require.True(t, matchTestResponse(rule, newTestResponse(dns.RcodeSuccess, "example.com. 60 IN A 10.0.0.1")))
+	require.False(t, matchTestResponse(rule, newTestResponse(dns.RcodeSuccess, "example.com. 60 IN A 1.1.1.1")))
+	require.False(t, matchTestResponse(rule, newTestResponse(dns.RcodeNameError)))
+}
+
+func TestDNSRuleResponseItems(t *testing.T) {
+	t.Parallel()
+	rule, err := NewDNSRule(context.Background(), log.NewNOPFactory().Logger(), option.DNSRule{
+		Type: C.RuleTypeLogical,
+		LogicalOptions: option.LogicalDNSRule{
+			RawLogicalDNSRule: option.RawLogicalDNSRule{
+				Mode:          C.LogicalTypeOr,
+				MatchResponse: true,
+				Rules: []option.DNSRule{
+					{
+						Type: C.RuleTypeDefault,
+						DefaultOptions: option.DefaultDNSRule{
+							RawDefaultDNSRule: option.RawDefaultDNSRule{
+								ResponseRcode: []option.DNSRCode{dns.RcodeServerFailure, dns.RcodeRefused},
+							},
+						},
+					},
+					{
+						Type: C.RuleTypeDefault,
+						DefaultOptions: option.DefaultDNSRule{
+							RawDefaultDNSRule: option.RawDefaultDNSRule{
+								ResponseCNAMESuffix: []string{"cdn.example.net"},
+							},
+						},
+					},
+				},
+			},
+			DNSRuleAction: option.DNSRuleAction{
+				Action: C.RuleActionTypeReject,
+			},
+		},
+	}, true)
+	require.NoError(t, err)
+	require.True(t, rule.MatchResponse())
+	require.True(t, matchTestResponse(rule, newTestResponse(dns.RcodeRefused)))
+	require.False(t, matchTestResponse(rule, newTestResponse(dns.RcodeNameError)))
+	require.True(t, matchTestResponse(rule, newTestResponse(dns.RcodeSuccess,
+		"example.com. 60 IN CNAME edge.cdn.example.net.",
+		"edge.cdn.example.net. 60 IN A 1.1.1.1",
+	)))
+	require.False(t, matchTestResponse(rule, newTestResponse(dns.RcodeSuccess,
+		"example.com. 60 IN CNAME edge.example.org.",
+		"edge.example.org. 60 IN A 1.1.1.1",
+	)))
+}
+
+func TestDNSRuleResponseItemsRequireMatchResponse(t *testing.T) {
+	t.Parallel()
+	responseRule := option.DNSRule{
+		Type: C.RuleTypeDefault,
+		DefaultOptions: option.DefaultDNSRule{
+			RawDefaultDNSRule: option.RawDefaultDNSRule{
+				ResponseCNAMESuffix: []string{"cdn.example.net"},
+			},
+			DNSRuleAction: option.DNSRuleAction{
+				Action:       C.RuleActionTypeRoute,
+				RouteOptions: option.DNSRouteActionOptions{Server: "remote"},
+			},
+		},
+	}
+	_, err := NewDNSRule(context.Background(), log.NewNOPFactory().Logger(), responseRule, true)
+	require.ErrorContains(t, err, "match_response")
+	_, err = NewDNSRule(context.Background(), log.NewNOPFactory().Logger(), option.DNSRule{
+		Type: C.RuleTypeLogical,
+		LogicalOptions: option.LogicalDNSRule{
+			RawLogicalDNSRule: option.RawLogicalDNSRule{
+				Mode:  C.LogicalTypeAnd,
+				Rules: []option.DNSRule{responseRule},
+			},
+			DNSRuleAction: responseRule.DefaultOptions.DNSRuleAction,
+		},
+	}, true)
+	require.ErrorContains(t, err, "match_response")
+}
+
+type testASNRouter struct {
+	adapter.Router
+	asnMap map[netip.Addr]uint32
+}
+
+func (r *testASNRouter) LookupASN(addr netip.Addr) (uint32, bool) {
+	asn, loaded := r.asnMap[addr]
+	return asn, loaded
+}
+
+func TestDNSRuleIPASN(t *testing.T) {
+	t.Parallel()
+	ctx := service.ContextWith[adapter.Router](context.Background(), &testASNRouter{
+		asnMap: map[netip.Addr]uint32{
+			netip.MustParseAddr("1.1.1.1"): 13335,
+			netip.MustParseAddr("8.8.8.8"): 15169,
+		},
+	})
+	rule, err := NewDNSRule(ctx, log.NewNOPFactory().Logger(), option.DNSRule{
+		Type: C.RuleTypeDefault,
+		DefaultOptions: option.DefaultDNSRule{
+			RawDefaultDNSRule: option.RawDefaultDNSRule{
+				IPASN: []uint32{13335},
+			},
+			DNSRuleAction: option.DNSRuleAction{
+				Action:       C.RuleActionTypeRoute,
+				RouteOptions: option.DNSRouteActionOptions{Server: "remote"},
+			},
+		},
+	}, true)
+	require.NoError(t, err)
+	require.True(t, rule.WithAddressLimit())
+	require.True(t, matchTestResponse(rule, newTestResponse(dns.RcodeSuccess, "example.com. 60 IN A 1.1.1.1")))
+	require.False(t, matchTestResponse(rule, newTestResponse(dns.RcodeSuccess, "example.com. 60 IN A 8.8.8.8")))
+	require.False(t, matchTestResponse(rule, newTestResponse(dns.RcodeSuccess, "example.com. 60 IN A 9.9.9.9")))
+}
@@ -40,6 +40,7 @@ func (r *DefaultHeadlessRule) matchStates(metadata *adapter.InboundContext) rule
 
 func NewDefaultHeadlessRule(ctx context.Context, options option.DefaultHeadlessRule) (*DefaultHeadlessRule, error) {
 	networkManager := service.FromContext[adapter.NetworkManager](ctx)
+	router := service.FromContext[adapter.Router](ctx)
 	rule := &DefaultHeadlessRule{
 		abstractDefaultRule{
 			invert: options.Invert,
@@ -104,6 +105,16 @@ func NewDefaultHeadlessRule(ctx context.Context, options option.DefaultHeadlessR
 		rule.destinationIPCIDRItems = append(rule.destinationIPCIDRItems, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.SourceIPASN) > 0 {
+		item := NewIPASNItem(router, true, options.SourceIPASN)
+		rule.sourceAddressItems = append(rule.sourceAddressItems, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if len(options.IPASN) > 0 {
+		item := NewIPASNItem(router, false, options.IPASN)
+		rule.destinationIPCIDRItems = append(rule.destinationIPCIDRItems, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if len(options.SourcePort) > 0 {
 		item := NewPortItem(true, options.SourcePort)
 		rule.sourcePortItems = append(rule.sourcePortItems, item)
@@ -190,6 +201,14 @@ func NewDefaultHeadlessRule(ctx context.Context, options option.DefaultHeadlessR
 			rule.allItems = append(rule.allItems, item)
 		}
 	}
+	if options.Time != nil {
+		item, err := NewTimeItem(ctx, *options.Time)
+		if err != nil {
+			return nil, E.Cause(err, "time")
+		}
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if len(options.AdGuardDomain) > 0 {
 		item := NewAdGuardDomainItem(options.AdGuardDomain)
 		rule.destinationAddressItems = append(rule.destinationAddressItems, item)
@@ -0,0 +1,42 @@
+package rule
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/sniff"
+	F "github.com/sagernet/sing/common/format"
+)
+
+var _ RuleItem = (*ClientFingerprintItem)(nil)
+
+type ClientFingerprintItem struct {
+	fingerprints   []string
+	fingerprintMap map[string]bool
+}
+
+func NewClientFingerprintItem(fingerprints []string) *ClientFingerprintItem {
+	fingerprintMap := make(map[string]bool)
+	for _, fingerprint := range fingerprints {
+		fingerprintMap[strings.ToLower(fingerprint)] = true
+	}
+	return &ClientFingerprintItem{
+		fingerprints:   fingerprints,
+		fingerprintMap: fingerprintMap,
+	}
+}
+
+func (r *ClientFingerprintItem) Match(metadata *adapter.InboundContext) bool {
+	ja3, ja4 := sniff.ClientFingerprint(metadata)
+	if ja3 != "" && r.fingerprintMap[ja3] {
+		return true
+	}
+	return ja4 != "" && r.fingerprintMap[strings.ToLower(ja4)]
+}
+
+func (r *ClientFingerprintItem) String() string {
+	if len(r.fingerprints) == 1 {
+		return F.ToString("client_fingerprint=", r.fingerprints[0])
+	}
+	return F.ToString("client_fingerprint=[", strings.Join(r.fingerprints, " "), "]")
+}
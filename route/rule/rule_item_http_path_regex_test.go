@@ -0,0 +1,23 @@
+package rule
+
+import (
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPPathRegexItem(t *testing.T) {
+	t.Parallel()
+	item, err := NewHTTPPathRegexItem([]string{"^/api/", "^/static/"})
+	require.NoError(t, err)
+	require.Equal(t, "http_path_regex=[^/api/ ^/static/]", item.String())
+	require.True(t, item.Match(&adapter.InboundContext{HTTPPath: "/api/v1"}))
+	require.False(t, item.Match(&adapter.InboundContext{HTTPPath: "/index.html"}))
+	require.False(t, item.Match(&adapter.InboundContext{}))
+
+	item, err = NewHTTPPathRegexItem([]string{"^/a", "^/b", "^/c", "^/d"})
+	require.NoError(t, err)
+	require.Equal(t, "http_path_regex=[^/a ^/b ^/c...]", item.String())
+}
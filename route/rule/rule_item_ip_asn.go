@@ -0,0 +1,70 @@
+package rule
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	F "github.com/sagernet/sing/common/format"
+)
+
+var _ RuleItem = (*IPASNItem)(nil)
+
+type IPASNItem struct {
+	router   adapter.Router
+	asnList  []uint32
+	asnMap   map[uint32]bool
+	isSource bool
+}
+
+func NewIPASNItem(router adapter.Router, isSource bool, asnList []uint32) *IPASNItem {
+	asnMap := make(map[uint32]bool)
+	for _, asn := range asnList {
+		asnMap[asn] = true
+	}
+	return &IPASNItem{
+		router:   router,
+		asnList:  asnList,
+		asnMap:   asnMap,
+		isSource: isSource,
+	}
+}
+
+func (r *IPASNItem) Match(metadata *adapter.InboundContext) bool {
+	if r.isSource || metadata.IPCIDRMatchSource {
+		return r.match(metadata.Source.Addr)
+	}
+	if metadata.Destination.IsIP() {
+		return r.match(metadata.Destination.Addr)
+	}
+	if len(metadata.DestinationAddresses) > 0 {
+		return slices.ContainsFunc(metadata.DestinationAddresses, r.match)
+	}
+	return metadata.IPCIDRAcceptEmpty
+}
+
+func (r *IPASNItem) match(addr netip.Addr) bool {
+	if r.router == nil || !addr.IsValid() {
+		return false
+	}
+	asn, loaded := r.router.LookupASN(addr.Unmap())
+	return loaded && r.asnMap[asn]
+}
+
+func (r *IPASNItem) String() string {
+	var description string
+	if r.isSource {
+		description = "source_ip_asn="
+	} else {
+		description = "ip_asn="
+	}
+	if aLen := len(r.asnList); aLen == 1 {
+		description += F.ToString(r.asnList[0])
+	} else if aLen > 3 {
+		description += "[" + strings.Join(F.MapToString(r.asnList[:3]), " ") + "...]"
+	} else {
+		description += "[" + strings.Join(F.MapToString(r.asnList), " ") + "]"
+	}
+	return description
+}
@@ -0,0 +1,74 @@
+package rule
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/domain"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/miekg/dns"
+)
+
+var _ RuleItem = (*ResponseCNAMEItem)(nil)
+
+type ResponseCNAMEItem struct {
+	matcher     *domain.Matcher
+	description string
+}
+
+func NewResponseCNAMEItem(domains []string, domainSuffixes []string) (*ResponseCNAMEItem, error) {
+	if slices.Contains(domains, "") {
+		return nil, E.New("response_cname: empty item is not allowed")
+	}
+	if slices.Contains(domainSuffixes, "") {
+		return nil, E.New("response_cname_suffix: empty item is not allowed")
+	}
+	var description string
+	if dLen := len(domains); dLen > 0 {
+		if dLen == 1 {
+			description = "response_cname=" + domains[0]
+		} else if dLen > 3 {
+			description = "response_cname=[" + strings.Join(domains[:3], " ") + "...]"
+		} else {
+			description = "response_cname=[" + strings.Join(domains, " ") + "]"
+		}
+	}
+	if dsLen := len(domainSuffixes); dsLen > 0 {
+		if len(description) > 0 {
+			description += " "
+		}
+		if dsLen == 1 {
+			description += "response_cname_suffix=" + domainSuffixes[0]
+		} else if dsLen > 3 {
+			description += "response_cname_suffix=[" + strings.Join(domainSuffixes[:3], " ") + "...]"
+		} else {
+			description += "response_cname_suffix=[" + strings.Join(domainSuffixes, " ") + "]"
+		}
+	}
+	return &ResponseCNAMEItem{
+		domain.NewMatcher(domains, domainSuffixes, false),
+		description,
+	}, nil
+}
+
+func (r *ResponseCNAMEItem) Match(metadata *adapter.InboundContext) bool {
+	if metadata.DNSResponse == nil {
+		return false
+	}
+	for _, record := range metadata.DNSResponse.Answer {
+		cname, isCNAME := record.(*dns.CNAME)
+		if !isCNAME {
+			continue
+		}
+		if r.matcher.Match(strings.ToLower(strings.TrimSuffix(cname.Target, "."))) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ResponseCNAMEItem) String() string {
+	return r.description
+}
@@ -0,0 +1,48 @@
+package rule
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+
+	"github.com/miekg/dns"
+)
+
+var _ RuleItem = (*ResponseRcodeItem)(nil)
+
+type ResponseRcodeItem struct {
+	rcodeList []int
+	rcodeMap  map[int]bool
+}
+
+func NewResponseRcodeItem(rcodeList []option.DNSRCode) *ResponseRcodeItem {
+	rule := &ResponseRcodeItem{
+		rcodeList: common.Map(rcodeList, func(it option.DNSRCode) int {
+			return int(it)
+		}),
+		rcodeMap: make(map[int]bool),
+	}
+	for _, rcode := range rule.rcodeList {
+		rule.rcodeMap[rcode] = true
+	}
+	return rule
+}
+
+func (r *ResponseRcodeItem) Match(metadata *adapter.InboundContext) bool {
+	if metadata.DNSResponse == nil {
+		return false
+	}
+	return r.rcodeMap[metadata.DNSResponse.Rcode]
+}
+
+func (r *ResponseRcodeItem) String() string {
+	rcodeToString := func(rcode int) string {
+		return dns.RcodeToString[rcode]
+	}
+	if len(r.rcodeList) == 1 {
+		return "response_rcode=" + rcodeToString(r.rcodeList[0])
+	}
+	return "response_rcode=[" + strings.Join(common.Map(r.rcodeList, rcodeToString), " ") + "]"
+}
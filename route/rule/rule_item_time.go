@@ -0,0 +1,156 @@
+package rule
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/ntp"
+)
+
+var _ RuleItem = (*TimeItem)(nil)
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+type timeRange struct {
+	start int
+	end   int
+}
+
+type TimeItem struct {
+	ctx         context.Context
+	location    *time.Location
+	weekdays    uint8
+	ranges      []timeRange
+	description string
+}
+
+func NewTimeItem(ctx context.Context, options option.TimeRuleOptions) (*TimeItem, error) {
+	if len(options.Weekday) == 0 && len(options.Range) == 0 {
+		return nil, E.New("missing weekday or range")
+	}
+	item := &TimeItem{
+		ctx:      ctx,
+		location: time.Local,
+	}
+	if options.Timezone != "" {
+		location, err := time.LoadLocation(options.Timezone)
+		if err != nil {
+			return nil, E.Cause(err, "load timezone")
+		}
+		item.location = location
+	}
+	for _, weekdayString := range options.Weekday {
+		weekday, loaded := weekdayNames[strings.ToLower(weekdayString)]
+		if !loaded {
+			return nil, E.New("invalid weekday: ", weekdayString)
+		}
+		item.weekdays |= 1 << weekday
+	}
+	for _, rangeString := range options.Range {
+		startString, endString, found := strings.Cut(rangeString, "-")
+		if !found {
+			return nil, E.New("invalid time range: ", rangeString)
+		}
+		start, err := parseTimeOfDay(strings.TrimSpace(startString))
+		if err != nil {
+			return nil, E.Cause(err, "invalid time range: ", rangeString)
+		}
+		end, err := parseTimeOfDay(strings.TrimSpace(endString))
+		if err != nil {
+			return nil, E.Cause(err, "invalid time range: ", rangeString)
+		}
+		if start == end || start == 24*60 {
+			return nil, E.New("invalid time range: ", rangeString)
+		}
+		item.ranges = append(item.ranges, timeRange{start, end})
+	}
+	var descriptions []string
+	if len(options.Weekday) > 0 {
+		descriptions = append(descriptions, "weekday=["+strings.Join(options.Weekday, " ")+"]")
+	}
+	if len(options.Range) > 0 {
+		descriptions = append(descriptions, "range=["+strings.Join(options.Range, " ")+"]")
+	}
+	if options.Timezone != "" {
+		descriptions = append(descriptions, "timezone="+options.Timezone)
+	}
+	item.description = "time=" + strings.Join(descriptions, ",")
+	return item, nil
+}
+
+// parseTimeOfDay parses HH:MM into minutes since midnight, where 24:00 is
+// accepted as the end of a day.
+func parseTimeOfDay(value string) (int, error) {
+	hourString, minuteString, found := strings.Cut(value, ":")
+	if !found {
+		return 0, E.New("invalid time of day: ", value)
+	}
+	hour, err := strconv.Atoi(hourString)
+	if err != nil {
+		return 0, err
+	}
+	minute, err := strconv.Atoi(minuteString)
+	if err != nil {
+		return 0, err
+	}
+	if hour < 0 || minute < 0 || minute > 59 || hour > 24 || hour == 24 && minute != 0 {
+		return 0, E.New("invalid time of day: ", value)
+	}
+	return hour*60 + minute, nil
+}
+
+func (r *TimeItem) Match(metadata *adapter.InboundContext) bool {
+	var now time.Time
+	if timeFunc := ntp.TimeFuncFromContext(r.ctx); timeFunc != nil {
+		now = timeFunc()
+	} else {
+		now = time.Now()
+	}
+	return r.matchTime(now.In(r.location))
+}
+
+func (r *TimeItem) matchTime(now time.Time) bool {
+	weekday := now.Weekday()
+	if len(r.ranges) == 0 {
+		return r.matchWeekday(weekday)
+	}
+	minutes := now.Hour()*60 + now.Minute()
+	for _, currentRange := range r.ranges {
+		if currentRange.start < currentRange.end {
+			if minutes >= currentRange.start && minutes < currentRange.end && r.matchWeekday(weekday) {
+				return true
+			}
+		} else if minutes >= currentRange.start {
+			if r.matchWeekday(weekday) {
+				return true
+			}
+		} else if minutes < currentRange.end {
+			// ranges crossing midnight belong to the weekday they start on
+			if r.matchWeekday((weekday + 6) % 7) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *TimeItem) matchWeekday(weekday time.Weekday) bool {
+	return r.weekdays == 0 || r.weekdays&(1<<weekday) != 0
+}
+
+func (r *TimeItem) String() string {
+	return r.description
+}
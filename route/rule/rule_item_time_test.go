@@ -0,0 +1,40 @@
+package rule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeItem(t *testing.T) {
+	t.Parallel()
+	item, err := NewTimeItem(context.Background(), option.TimeRuleOptions{
+		Weekday:  []string{"Mon", "fri"},
+		Range:    []string{"09:00-18:00", "22:00-07:00"},
+		Timezone: "UTC",
+	})
+	require.NoError(t, err)
+	at := func(value string) bool {
+		return item.matchTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(common.Must1(time.ParseDuration(value))))
+	}
+	// 2024-01-01 is a Monday
+	require.True(t, at("9h"))
+	require.False(t, at("18h"))
+	require.True(t, at("23h"))
+	require.True(t, at("30h"), "Tuesday 06:00 belongs to Monday night")
+	require.False(t, at("33h"), "Tuesday 09:00")
+	require.False(t, at("96h30m"), "Friday 00:30 belongs to Thursday night")
+	require.True(t, at("106h"), "Friday 10:00")
+
+	_, err = NewTimeItem(context.Background(), option.TimeRuleOptions{Range: []string{"25:00-26:00"}})
+	require.Error(t, err)
+	_, err = NewTimeItem(context.Background(), option.TimeRuleOptions{Weekday: []string{"someday"}})
+	require.Error(t, err)
+	_, err = NewTimeItem(context.Background(), option.TimeRuleOptions{})
+	require.Error(t, err)
+}
@@ -67,5 +67,5 @@ func isWIFIHeadlessRule(rule option.DefaultHeadlessRule) bool {
 }
 
 func isIPCIDRHeadlessRule(rule option.DefaultHeadlessRule) bool {
-	return len(rule.IPCIDR) > 0 || rule.IPSet != nil
+	return len(rule.IPCIDR) > 0 || rule.IPSet != nil || len(rule.IPASN) > 0
 }
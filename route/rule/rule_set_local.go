@@ -10,6 +10,7 @@ import (
 
 	"github.com/sagernet/fswatch"
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/convertor"
 	"github.com/sagernet/sing-box/common/srs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
@@ -34,6 +35,7 @@ type LocalRuleSet struct {
 	rules      []adapter.HeadlessRule
 	metadata   adapter.RuleSetMetadata
 	fileFormat string
+	category   string
 	watcher    *fswatch.Watcher
 	callbacks  list.List[adapter.RuleSetUpdateCallback]
 	refs       atomic.Int32
@@ -45,6 +47,7 @@ func NewLocalRuleSet(ctx context.Context, logger logger.Logger, options option.R
 		logger:     logger,
 		tag:        options.Tag,
 		fileFormat: options.Format,
+		category:   options.Category,
 	}
 	if options.Type == C.RuleSetTypeInline {
 		if len(options.InlineOptions.Rules) == 0 {
@@ -119,7 +122,19 @@ func (s *LocalRuleSet) reloadFile(path string) error {
 			return err
 		}
 	default:
-		return E.New("unknown rule-set format: ", s.fileFormat)
+		if !convertor.IsSupported(s.fileFormat) {
+			return E.New("unknown rule-set format: ", s.fileFormat)
+		}
+		setFile, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		ruleSet.Version = C.RuleSetVersionCurrent
+		ruleSet.Options, err = convertor.ToOptions(s.fileFormat, s.category, setFile, s.logger)
+		setFile.Close()
+		if err != nil {
+			return err
+		}
 	}
 	plainRuleSet, err := ruleSet.Upgrade()
 	if err != nil {
@@ -3,9 +3,6 @@ package rule
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
-	"io"
-	"net"
 	"net/http"
 	"runtime"
 	"strings"
@@ -14,6 +11,8 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/convertor"
+	"github.com/sagernet/sing-box/common/download"
 	"github.com/sagernet/sing-box/common/srs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
@@ -22,9 +21,7 @@ import (
 	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/sing/common/json"
 	"github.com/sagernet/sing/common/logger"
-	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
-	"github.com/sagernet/sing/common/ntp"
 	"github.com/sagernet/sing/common/x/list"
 	"github.com/sagernet/sing/service"
 	"github.com/sagernet/sing/service/pause"
@@ -45,10 +42,8 @@ type RemoteRuleSet struct {
 	access         sync.RWMutex
 	rules          []adapter.HeadlessRule
 	metadata       adapter.RuleSetMetadata
-	lastUpdated    time.Time
-	lastEtag       string
+	downloader     download.Downloader
 	updateTicker   *time.Ticker
-	cacheFile      adapter.CacheFile
 	pauseManager   pause.Manager
 	callbacks      list.List[adapter.RuleSetUpdateCallback]
 	refs           atomic.Int32
@@ -69,7 +64,11 @@ func NewRemoteRuleSet(ctx context.Context, logger logger.ContextLogger, options
 		logger:         logger,
 		options:        options,
 		updateInterval: updateInterval,
-		pauseManager:   service.FromContext[pause.Manager](ctx),
+		downloader: download.Downloader{
+			Logger: logger,
+			URL:    options.RemoteOptions.URL,
+		},
+		pauseManager: service.FromContext[pause.Manager](ctx),
 	}
 }
 
@@ -82,7 +81,6 @@ func (s *RemoteRuleSet) String() string {
 }
 
 func (s *RemoteRuleSet) StartContext(ctx context.Context, startContext *adapter.HTTPStartContext) error {
-	s.cacheFile = service.FromContext[adapter.CacheFile](s.ctx)
 	var dialer N.Dialer
 	if s.options.RemoteOptions.DownloadDetour != "" {
 		outbound, loaded := s.outbound.Outbound(s.options.RemoteOptions.DownloadDetour)
@@ -94,17 +92,19 @@ func (s *RemoteRuleSet) StartContext(ctx context.Context, startContext *adapter.
 		dialer = s.outbound.Default()
 	}
 	s.dialer = dialer
-	if s.cacheFile != nil {
-		if savedSet := s.cacheFile.LoadRuleSet(s.options.Tag); savedSet != nil {
-			err := s.loadBytes(savedSet.Content)
-			if err != nil {
-				return E.Cause(err, "restore cached rule-set")
-			}
-			s.lastUpdated = savedSet.LastUpdated
-			s.lastEtag = savedSet.LastEtag
+	if cacheFile := service.FromContext[adapter.CacheFile](s.ctx); cacheFile != nil {
+		s.downloader.LoadCache = func() *adapter.SavedBinary {
+			return cacheFile.LoadRuleSet(s.options.Tag)
 		}
+		s.downloader.SaveCache = func(saved *adapter.SavedBinary) error {
+			return cacheFile.SaveRuleSet(s.options.Tag, saved)
+		}
+	}
+	_, err := s.downloader.Restore(s.loadBytes)
+	if err != nil {
+		return E.Cause(err, "restore cached rule-set")
 	}
-	if s.lastUpdated.IsZero() {
+	if s.downloader.LastUpdated.IsZero() {
 		err := s.fetch(ctx, startContext)
 		if err != nil {
 			return E.Cause(err, "initial rule-set: ", s.options.Tag)
@@ -176,7 +176,14 @@ func (s *RemoteRuleSet) loadBytes(content []byte) error {
 			return err
 		}
 	default:
-		return E.New("unknown rule-set format: ", s.options.Format)
+		if !convertor.IsSupported(s.options.Format) {
+			return E.New("unknown rule-set format: ", s.options.Format)
+		}
+		ruleSet.Version = C.RuleSetVersionCurrent
+		ruleSet.Options, err = convertor.ToOptions(s.options.Format, s.options.Category, bytes.NewReader(content), s.logger)
+		if err != nil {
+			return err
+		}
 	}
 	plainRuleSet, err := ruleSet.Upgrade()
 	if err != nil {
@@ -203,7 +210,7 @@ func (s *RemoteRuleSet) loadBytes(content []byte) error {
 }
 
 func (s *RemoteRuleSet) loopUpdate() {
-	if time.Since(s.lastUpdated) > s.updateInterval {
+	if time.Since(s.downloader.LastUpdated) > s.updateInterval {
 		err := s.fetch(s.ctx, nil)
 		if err != nil {
 			s.logger.Error("fetch rule-set ", s.options.Tag, ": ", err)
@@ -237,78 +244,18 @@ func (s *RemoteRuleSet) fetch(ctx context.Context, startContext *adapter.HTTPSta
 	if startContext != nil {
 		httpClient = startContext.HTTPClient(s.options.RemoteOptions.DownloadDetour, s.dialer)
 	} else {
-		httpClient = &http.Client{
-			Transport: &http.Transport{
-				ForceAttemptHTTP2:   true,
-				TLSHandshakeTimeout: C.TCPTimeout,
-				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-					return s.dialer.DialContext(ctx, network, M.ParseSocksaddr(addr))
-				},
-				TLSClientConfig: &tls.Config{
-					Time:    ntp.TimeFuncFromContext(s.ctx),
-					RootCAs: adapter.RootPoolFromContext(s.ctx),
-				},
-			},
-		}
-	}
-	request, err := http.NewRequest("GET", s.options.RemoteOptions.URL, nil)
-	if err != nil {
-		return err
-	}
-	if s.lastEtag != "" {
-		request.Header.Set("If-None-Match", s.lastEtag)
+		httpClient = download.NewHTTPClient(s.ctx, s.dialer)
+		defer httpClient.CloseIdleConnections()
 	}
-	response, err := httpClient.Do(request.WithContext(ctx))
+	updated, err := s.downloader.Fetch(ctx, httpClient, s.loadBytes)
 	if err != nil {
 		return err
 	}
-	switch response.StatusCode {
-	case http.StatusOK:
-	case http.StatusNotModified:
-		s.lastUpdated = time.Now()
-		if s.cacheFile != nil {
-			savedRuleSet := s.cacheFile.LoadRuleSet(s.options.Tag)
-			if savedRuleSet != nil {
-				savedRuleSet.LastUpdated = s.lastUpdated
-				err = s.cacheFile.SaveRuleSet(s.options.Tag, savedRuleSet)
-				if err != nil {
-					s.logger.Error("save rule-set updated time: ", err)
-					return nil
-				}
-			}
-		}
+	if updated {
+		s.logger.Info("updated rule-set ", s.options.Tag)
+	} else {
 		s.logger.Info("update rule-set ", s.options.Tag, ": not modified")
-		return nil
-	default:
-		return E.New("unexpected status: ", response.Status)
-	}
-	content, err := io.ReadAll(response.Body)
-	if err != nil {
-		response.Body.Close()
-		return err
-	}
-	err = s.loadBytes(content)
-	if err != nil {
-		response.Body.Close()
-		return err
-	}
-	response.Body.Close()
-	eTagHeader := response.Header.Get("Etag")
-	if eTagHeader != "" {
-		s.lastEtag = eTagHeader
-	}
-	s.lastUpdated = time.Now()
-	if s.cacheFile != nil {
-		err = s.cacheFile.SaveRuleSet(s.options.Tag, &adapter.SavedBinary{
-			LastUpdated: s.lastUpdated,
-			Content:     content,
-			LastEtag:    s.lastEtag,
-		})
-		if err != nil {
-			s.logger.Error("save rule-set cache: ", err)
-		}
 	}
-	s.logger.Info("updated rule-set ", s.options.Tag)
 	return nil
 }
 
@@ -0,0 +1,137 @@
+package userquota
+
+import (
+	"net/http"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	sHTTP "github.com/sagernet/sing/protocol/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type APIServer struct {
+	logger  logger.Logger
+	service *Service
+}
+
+func NewAPIServer(logger logger.Logger, service *Service) *APIServer {
+	return &APIServer{
+		logger:  logger,
+		service: service,
+	}
+}
+
+func (s *APIServer) Route(r chi.Router) {
+	r.Use(func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			s.logger.Debug(request.Method, " ", request.RequestURI, " ", sHTTP.SourceAddress(request))
+			handler.ServeHTTP(writer, request)
+		})
+	})
+	r.Get("/", s.getServerInfo)
+	r.Get("/users", s.listUser)
+	r.Post("/users", s.addUser)
+	r.Get("/users/{name}", s.getUser)
+	r.Put("/users/{name}", s.updateUser)
+	r.Delete("/users/{name}", s.deleteUser)
+	r.Post("/users/{name}/reset", s.resetUser)
+}
+
+func (s *APIServer) getServerInfo(writer http.ResponseWriter, request *http.Request) {
+	render.JSON(writer, request, render.M{
+		"server": "sing-box " + C.Version,
+	})
+}
+
+func (s *APIServer) listUser(writer http.ResponseWriter, request *http.Request) {
+	users := s.service.Users()
+	objects := make([]*UserObject, 0, len(users))
+	for _, user := range users {
+		objects = append(objects, user.Object())
+	}
+	render.JSON(writer, request, render.M{
+		"users": objects,
+	})
+}
+
+func (s *APIServer) addUser(writer http.ResponseWriter, request *http.Request) {
+	var options option.UserQuotaUser
+	err := render.DecodeJSON(request.Body, &options)
+	if err != nil {
+		render.Status(request, http.StatusBadRequest)
+		render.PlainText(writer, request, err.Error())
+		return
+	}
+	err = s.service.AddUser(options)
+	if err != nil {
+		render.Status(request, http.StatusBadRequest)
+		render.PlainText(writer, request, err.Error())
+		return
+	}
+	s.saveCache()
+	writer.WriteHeader(http.StatusCreated)
+}
+
+func (s *APIServer) getUser(writer http.ResponseWriter, request *http.Request) {
+	user, loaded := s.service.User(chi.URLParam(request, "name"))
+	if !loaded {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	render.JSON(writer, request, user.Object())
+}
+
+func (s *APIServer) updateUser(writer http.ResponseWriter, request *http.Request) {
+	name := chi.URLParam(request, "name")
+	if _, loaded := s.service.User(name); !loaded {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var options option.UserQuotaUser
+	err := render.DecodeJSON(request.Body, &options)
+	if err != nil {
+		render.Status(request, http.StatusBadRequest)
+		render.PlainText(writer, request, err.Error())
+		return
+	}
+	options.Name = name
+	err = s.service.UpdateUser(options)
+	if err != nil {
+		render.Status(request, http.StatusBadRequest)
+		render.PlainText(writer, request, err.Error())
+		return
+	}
+	s.saveCache()
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (s *APIServer) deleteUser(writer http.ResponseWriter, request *http.Request) {
+	if !s.service.RemoveUser(chi.URLParam(request, "name")) {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	s.saveCache()
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (s *APIServer) resetUser(writer http.ResponseWriter, request *http.Request) {
+	user, loaded := s.service.User(chi.URLParam(request, "name"))
+	if !loaded {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	user.Reset()
+	s.saveCache()
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (s *APIServer) saveCache() {
+	err := s.service.saveCache()
+	if err != nil {
+		s.logger.Error(E.Cause(err, "save cache"))
+	}
+}
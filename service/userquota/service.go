@@ -0,0 +1,343 @@
+package userquota
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	boxService "github.com/sagernet/sing-box/adapter/service"
+	"github.com/sagernet/sing-box/common/listener"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+	aTLS "github.com/sagernet/sing/common/tls"
+	"github.com/sagernet/sing/service"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/net/http2"
+)
+
+const checkInterval = 30 * time.Second
+
+func RegisterService(registry *boxService.Registry) {
+	boxService.Register[option.UserQuotaServiceOptions](registry, C.TypeUserQuota, NewService)
+}
+
+var (
+	_ adapter.ConnectionTracker = (*Service)(nil)
+	_ adapter.UserAuthorizer    = (*Service)(nil)
+)
+
+type Service struct {
+	boxService.Adapter
+	ctx        context.Context
+	cancel     context.CancelFunc
+	logger     log.ContextLogger
+	router     adapter.Router
+	listener   *listener.Listener
+	tlsConfig  tls.ServerConfig
+	httpServer *http.Server
+	cacheFile  adapter.CacheFile
+	inbounds   map[string]bool
+	access     sync.RWMutex
+	users      map[string]*User
+}
+
+func NewService(ctx context.Context, logger log.ContextLogger, tag string, options option.UserQuotaServiceOptions) (adapter.Service, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Service{
+		Adapter: boxService.NewAdapter(C.TypeUserQuota, tag),
+		ctx:     ctx,
+		cancel:  cancel,
+		logger:  logger,
+		users:   make(map[string]*User),
+	}
+	inboundManager := service.FromContext[adapter.InboundManager](ctx)
+	if len(options.Inbounds) > 0 {
+		s.inbounds = make(map[string]bool)
+		for _, inboundTag := range options.Inbounds {
+			if _, loaded := inboundManager.Get(inboundTag); !loaded {
+				return nil, E.New("inbound ", inboundTag, " not found")
+			}
+			s.inbounds[inboundTag] = true
+		}
+	}
+	for i, userOptions := range options.Users {
+		user, err := NewUser(userOptions, false)
+		if err != nil {
+			return nil, E.Cause(err, "parse user[", i, "]")
+		}
+		if _, loaded := s.users[user.Name()]; loaded {
+			return nil, E.New("parse user[", i, "]: duplicate user ", user.Name())
+		}
+		s.users[user.Name()] = user
+	}
+	if options.ListenPort != 0 {
+		chiRouter := chi.NewRouter()
+		chiRouter.Route("/", NewAPIServer(logger, s).Route)
+		s.listener = listener.New(listener.Options{
+			Context: ctx,
+			Logger:  logger,
+			Network: []string{N.NetworkTCP},
+			Listen:  options.ListenOptions,
+		})
+		s.httpServer = &http.Server{
+			Handler: chiRouter,
+		}
+		if options.TLS != nil {
+			tlsConfig, err := tls.NewServer(ctx, logger, common.PtrValueOrDefault(options.TLS))
+			if err != nil {
+				return nil, err
+			}
+			s.tlsConfig = tlsConfig
+		}
+	}
+	s.router = service.FromContext[adapter.Router](ctx)
+	return s, nil
+}
+
+func (s *Service) Start(stage adapter.StartStage) error {
+	switch stage {
+	case adapter.StartStateInitialize:
+		s.router.AppendTracker(s)
+		s.router.AppendAuthorizer(s)
+		return nil
+	case adapter.StartStateStart:
+	default:
+		return nil
+	}
+	s.cacheFile = service.FromContext[adapter.CacheFile](s.ctx)
+	if s.cacheFile == nil {
+		s.logger.Warn("cache file is not enabled, usage will be lost on restart")
+	} else {
+		s.loadCache()
+	}
+	go s.loopCheck()
+	if s.httpServer == nil {
+		return nil
+	}
+	if s.tlsConfig != nil {
+		err := s.tlsConfig.Start()
+		if err != nil {
+			return E.Cause(err, "create TLS config")
+		}
+	}
+	tcpListener, err := s.listener.ListenTCP()
+	if err != nil {
+		return err
+	}
+	if s.tlsConfig != nil {
+		if !common.Contains(s.tlsConfig.NextProtos(), http2.NextProtoTLS) {
+			s.tlsConfig.SetNextProtos(append([]string{"h2"}, s.tlsConfig.NextProtos()...))
+		}
+		tcpListener = aTLS.NewListener(tcpListener, s.tlsConfig)
+	}
+	go func() {
+		err = s.httpServer.Serve(tcpListener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("serve error: ", err)
+		}
+	}()
+	return nil
+}
+
+func (s *Service) loadCache() {
+	savedUsers := s.cacheFile.LoadUserQuota(s.Tag())
+	s.access.Lock()
+	defer s.access.Unlock()
+	for _, savedUser := range savedUsers {
+		user, loaded := s.users[savedUser.Name]
+		if !loaded {
+			if savedUser.Options == nil {
+				continue
+			}
+			var err error
+			user, err = NewUser(*savedUser.Options, true)
+			if err != nil {
+				s.logger.Warn(E.Cause(err, "load user ", savedUser.Name))
+				continue
+			}
+			s.users[user.Name()] = user
+		}
+		user.uplink.Store(savedUser.Uplink)
+		user.downlink.Store(savedUser.Downlink)
+		user.lastReset = savedUser.LastReset
+	}
+}
+
+func (s *Service) saveCache() error {
+	if s.cacheFile == nil {
+		return nil
+	}
+	s.access.RLock()
+	savedUsers := make([]*adapter.SavedUserQuota, 0, len(s.users))
+	for _, user := range s.users {
+		user.access.Lock()
+		savedUser := &adapter.SavedUserQuota{
+			Name:      user.Name(),
+			Uplink:    user.uplink.Load(),
+			Downlink:  user.downlink.Load(),
+			LastReset: user.lastReset,
+		}
+		if user.dynamic {
+			savedUser.Options = common.Ptr(user.options)
+		}
+		user.access.Unlock()
+		savedUsers = append(savedUsers, savedUser)
+	}
+	s.access.RUnlock()
+	return s.cacheFile.SaveUserQuota(s.Tag(), savedUsers)
+}
+
+func (s *Service) loopCheck() {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		now := time.Now()
+		for _, user := range s.Users() {
+			if user.enforce(now) {
+				s.logger.Info("user ", user.Name(), " is expired or over quota, connections closed")
+			}
+		}
+		err := s.saveCache()
+		if err != nil {
+			s.logger.Error(E.Cause(err, "save cache"))
+		}
+	}
+}
+
+func (s *Service) Close() error {
+	s.router.RemoveTracker(s)
+	s.router.RemoveAuthorizer(s)
+	if s.cancel != nil {
+		s.cancel()
+	}
+	err := s.saveCache()
+	if err != nil {
+		s.logger.Error(E.Cause(err, "save cache"))
+	}
+	return common.Close(
+		common.PtrOrNil(s.httpServer),
+		common.PtrOrNil(s.listener),
+		s.tlsConfig,
+	)
+}
+
+func (s *Service) Users() []*User {
+	s.access.RLock()
+	defer s.access.RUnlock()
+	users := make([]*User, 0, len(s.users))
+	for _, user := range s.users {
+		users = append(users, user)
+	}
+	return users
+}
+
+func (s *Service) User(name string) (*User, bool) {
+	s.access.RLock()
+	defer s.access.RUnlock()
+	user, loaded := s.users[name]
+	return user, loaded
+}
+
+func (s *Service) AddUser(options option.UserQuotaUser) error {
+	user, err := NewUser(options, true)
+	if err != nil {
+		return err
+	}
+	s.access.Lock()
+	defer s.access.Unlock()
+	if _, loaded := s.users[user.Name()]; loaded {
+		return E.New("user ", user.Name(), " already exists")
+	}
+	s.users[user.Name()] = user
+	return nil
+}
+
+func (s *Service) UpdateUser(options option.UserQuotaUser) error {
+	user, err := NewUser(options, true)
+	if err != nil {
+		return err
+	}
+	s.access.Lock()
+	defer s.access.Unlock()
+	oldUser, loaded := s.users[user.Name()]
+	if !loaded {
+		return E.New("user ", user.Name(), " not found")
+	}
+	// changes would be lost on restart as configured users are not saved
+	if !oldUser.dynamic {
+		return E.New("user ", user.Name(), " is configured statically")
+	}
+	oldUser.access.Lock()
+	defer oldUser.access.Unlock()
+	oldUser.options = user.options
+	oldUser.quota = user.quota
+	return nil
+}
+
+// RemoveUser removes the user and closes its connections, which would
+// otherwise be no longer counted or limited.
+func (s *Service) RemoveUser(name string) bool {
+	s.access.Lock()
+	user, loaded := s.users[name]
+	delete(s.users, name)
+	s.access.Unlock()
+	if loaded {
+		user.closeConnections()
+	}
+	return loaded
+}
+
+func (s *Service) userFor(metadata adapter.InboundContext) *User {
+	if metadata.User == "" || s.inbounds != nil && !s.inbounds[metadata.Inbound] {
+		return nil
+	}
+	user, _ := s.User(metadata.User)
+	return user
+}
+
+func (s *Service) AuthorizeUser(ctx context.Context, metadata adapter.InboundContext) (func(), error) {
+	user := s.userFor(metadata)
+	if user == nil {
+		return nil, nil
+	}
+	return user.Check(time.Now(), metadata.Source.Addr.Unmap())
+}
+
+func (s *Service) RoutedConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, matchedRule adapter.Rule, matchOutbound adapter.Outbound) net.Conn {
+	user := s.userFor(metadata)
+	if user == nil {
+		return conn
+	}
+	conn = bufio.NewInt64CounterConn(conn, []*atomic.Int64{&user.uplink}, []*atomic.Int64{&user.downlink})
+	tracked := &trackedConn{Conn: conn}
+	tracked.release = user.track(tracked, metadata.Source.Addr.Unmap())
+	return tracked
+}
+
+func (s *Service) RoutedPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, matchedRule adapter.Rule, matchOutbound adapter.Outbound) N.PacketConn {
+	user := s.userFor(metadata)
+	if user == nil {
+		return conn
+	}
+	conn = bufio.NewInt64CounterPacketConn(conn, []*atomic.Int64{&user.uplink}, nil, []*atomic.Int64{&user.downlink}, nil)
+	tracked := &trackedPacketConn{PacketConn: conn}
+	tracked.release = user.track(tracked, metadata.Source.Addr.Unmap())
+	return tracked
+}
@@ -0,0 +1,45 @@
+package userquota
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testCloser struct {
+	closed bool
+}
+
+func (c *testCloser) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestServiceRemoveUser(t *testing.T) {
+	t.Parallel()
+	s := &Service{users: make(map[string]*User)}
+	require.NoError(t, s.AddUser(option.UserQuotaUser{Name: "alice"}))
+	user, loaded := s.User("alice")
+	require.True(t, loaded)
+	conn := &testCloser{}
+	user.track(conn, netip.MustParseAddr("192.0.2.1"))
+	require.True(t, s.RemoveUser("alice"))
+	require.True(t, conn.closed)
+	require.False(t, s.RemoveUser("alice"))
+}
+
+func TestServiceUpdateUser(t *testing.T) {
+	t.Parallel()
+	staticUser, err := NewUser(option.UserQuotaUser{Name: "alice"}, false)
+	require.NoError(t, err)
+	s := &Service{users: map[string]*User{"alice": staticUser}}
+	require.Error(t, s.UpdateUser(option.UserQuotaUser{Name: "alice", MaxConnections: 1}))
+	require.Zero(t, staticUser.options.MaxConnections)
+	require.NoError(t, s.AddUser(option.UserQuotaUser{Name: "bob"}))
+	require.NoError(t, s.UpdateUser(option.UserQuotaUser{Name: "bob", MaxConnections: 1}))
+	user, _ := s.User("bob")
+	require.Equal(t, 1, user.options.MaxConnections)
+}
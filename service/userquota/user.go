@@ -0,0 +1,227 @@
+package userquota
+
+import (
+	"io"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/x/list"
+)
+
+const (
+	resetPeriodDaily   = "daily"
+	resetPeriodWeekly  = "weekly"
+	resetPeriodMonthly = "monthly"
+)
+
+type User struct {
+	options     option.UserQuotaUser
+	quota       int64
+	dynamic     bool
+	uplink      atomic.Int64
+	downlink    atomic.Int64
+	access      sync.Mutex
+	lastReset   time.Time
+	connections list.List[io.Closer]
+	reserved    int
+	sourceIPs   map[netip.Addr]int
+}
+
+func NewUser(options option.UserQuotaUser, dynamic bool) (*User, error) {
+	if options.Name == "" {
+		return nil, E.New("missing user name")
+	}
+	switch options.ResetPeriod {
+	case "", resetPeriodDaily, resetPeriodWeekly, resetPeriodMonthly:
+	default:
+		return nil, E.New("unknown reset period: ", options.ResetPeriod)
+	}
+	if options.MaxConnections < 0 {
+		return nil, E.New("invalid max_connections: ", options.MaxConnections)
+	}
+	if options.MaxIPs < 0 {
+		return nil, E.New("invalid max_ips: ", options.MaxIPs)
+	}
+	return &User{
+		options:   options,
+		quota:     int64(options.Quota.Value()),
+		dynamic:   dynamic,
+		lastReset: time.Now(),
+		sourceIPs: make(map[netip.Addr]int),
+	}, nil
+}
+
+func (u *User) Name() string {
+	return u.options.Name
+}
+
+// nextReset returns the start of the next reset period after the last reset,
+// or the zero time if the usage is never reset.
+func (u *User) nextReset() time.Time {
+	year, month, day := u.lastReset.Date()
+	location := u.lastReset.Location()
+	switch u.options.ResetPeriod {
+	case resetPeriodDaily:
+		return time.Date(year, month, day+1, 0, 0, 0, 0, location)
+	case resetPeriodWeekly:
+		daysUntilMonday := (8 - int(u.lastReset.Weekday())) % 7
+		if daysUntilMonday == 0 {
+			daysUntilMonday = 7
+		}
+		return time.Date(year, month, day+daysUntilMonday, 0, 0, 0, 0, location)
+	case resetPeriodMonthly:
+		return time.Date(year, month+1, 1, 0, 0, 0, 0, location)
+	default:
+		return time.Time{}
+	}
+}
+
+func (u *User) update(now time.Time) {
+	nextReset := u.nextReset()
+	if nextReset.IsZero() || now.Before(nextReset) {
+		return
+	}
+	u.uplink.Store(0)
+	u.downlink.Store(0)
+	u.lastReset = now
+}
+
+func (u *User) Reset() {
+	u.access.Lock()
+	defer u.access.Unlock()
+	u.uplink.Store(0)
+	u.downlink.Store(0)
+	u.lastReset = time.Now()
+}
+
+func (u *User) expired(now time.Time) bool {
+	return u.options.Expire != nil && !now.Before(*u.options.Expire)
+}
+
+func (u *User) exceeded() bool {
+	return u.quota > 0 && u.uplink.Load()+u.downlink.Load() >= u.quota
+}
+
+// Check reserves a connection slot and a source IP if the user is allowed to connect,
+// the reservation is held until the returned release function is called,
+// by which time the connection should have been tracked.
+func (u *User) Check(now time.Time, source netip.Addr) (func(), error) {
+	u.access.Lock()
+	defer u.access.Unlock()
+	u.update(now)
+	if u.expired(now) {
+		return nil, E.New("expired")
+	}
+	if u.exceeded() {
+		return nil, E.New("traffic quota exceeded")
+	}
+	if u.options.MaxConnections > 0 && u.connections.Len()+u.reserved >= u.options.MaxConnections {
+		return nil, E.New("too many connections")
+	}
+	if u.options.MaxIPs > 0 && source.IsValid() {
+		if _, loaded := u.sourceIPs[source]; !loaded && len(u.sourceIPs) >= u.options.MaxIPs {
+			return nil, E.New("too many source IPs")
+		}
+	}
+	u.reserved++
+	u.addSource(source)
+	return sync.OnceFunc(func() {
+		u.access.Lock()
+		defer u.access.Unlock()
+		u.reserved--
+		u.removeSource(source)
+	}), nil
+}
+
+func (u *User) track(conn io.Closer, source netip.Addr) func() {
+	u.access.Lock()
+	element := u.connections.PushBack(conn)
+	u.addSource(source)
+	u.access.Unlock()
+	return func() {
+		u.access.Lock()
+		defer u.access.Unlock()
+		u.connections.Remove(element)
+		u.removeSource(source)
+	}
+}
+
+func (u *User) addSource(source netip.Addr) {
+	if source.IsValid() {
+		u.sourceIPs[source]++
+	}
+}
+
+func (u *User) removeSource(source netip.Addr) {
+	if !source.IsValid() {
+		return
+	}
+	u.sourceIPs[source]--
+	if u.sourceIPs[source] == 0 {
+		delete(u.sourceIPs, source)
+	}
+}
+
+// enforce closes all connections if the user is expired or over quota.
+func (u *User) enforce(now time.Time) bool {
+	u.access.Lock()
+	u.update(now)
+	closed := u.expired(now) || u.exceeded()
+	u.access.Unlock()
+	if !closed {
+		return false
+	}
+	return u.closeConnections()
+}
+
+// closeConnections closes all tracked connections and reports whether
+// there are any.
+func (u *User) closeConnections() bool {
+	u.access.Lock()
+	var closers []io.Closer
+	for element := u.connections.Front(); element != nil; element = element.Next() {
+		closers = append(closers, element.Value)
+	}
+	u.access.Unlock()
+	for _, closer := range closers {
+		common.Close(closer)
+	}
+	return len(closers) > 0
+}
+
+type UserObject struct {
+	option.UserQuotaUser
+	Uplink      int64      `json:"uplink"`
+	Downlink    int64      `json:"downlink"`
+	LastReset   time.Time  `json:"last_reset"`
+	NextReset   *time.Time `json:"next_reset,omitempty"`
+	Connections int        `json:"connections"`
+	SourceIPs   []string   `json:"source_ips,omitempty"`
+	Dynamic     bool       `json:"dynamic"`
+}
+
+func (u *User) Object() *UserObject {
+	u.access.Lock()
+	defer u.access.Unlock()
+	u.update(time.Now())
+	object := &UserObject{
+		UserQuotaUser: u.options,
+		Uplink:        u.uplink.Load(),
+		Downlink:      u.downlink.Load(),
+		LastReset:     u.lastReset,
+		Connections:   u.connections.Len(),
+		Dynamic:       u.dynamic,
+	}
+	if nextReset := u.nextReset(); !nextReset.IsZero() {
+		object.NextReset = &nextReset
+	}
+	for source := range u.sourceIPs {
+		object.SourceIPs = append(object.SourceIPs, source.String())
+	}
+	return object
+}
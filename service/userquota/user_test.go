@@ -0,0 +1,112 @@
+package userquota
+
+import (
+	"io"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/byteformats"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserQuota(t *testing.T) {
+	t.Parallel()
+	var quota byteformats.Bytes
+	require.NoError(t, json.Unmarshal([]byte(`"1kb"`), &quota))
+	user, err := NewUser(option.UserQuotaUser{
+		Name:        "alice",
+		Quota:       &quota,
+		ResetPeriod: resetPeriodDaily,
+	}, false)
+	require.NoError(t, err)
+	now := time.Now()
+	require.NoError(t, checkUser(user, now, netip.Addr{}))
+	user.uplink.Add(600)
+	user.downlink.Add(400)
+	require.Error(t, checkUser(user, now, netip.Addr{}))
+	require.NoError(t, checkUser(user, now.Add(24*time.Hour), netip.Addr{}))
+	require.Zero(t, user.uplink.Load())
+}
+
+func TestUserExpire(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	user, err := NewUser(option.UserQuotaUser{
+		Name:   "alice",
+		Expire: common.Ptr(now.Add(time.Hour)),
+	}, false)
+	require.NoError(t, err)
+	require.NoError(t, checkUser(user, now, netip.Addr{}))
+	require.Error(t, checkUser(user, now.Add(time.Hour), netip.Addr{}))
+}
+
+func TestUserConnectionLimits(t *testing.T) {
+	t.Parallel()
+	user, err := NewUser(option.UserQuotaUser{
+		Name:           "alice",
+		MaxConnections: 2,
+		MaxIPs:         1,
+	}, false)
+	require.NoError(t, err)
+	now := time.Now()
+	first := netip.MustParseAddr("192.0.2.1")
+	second := netip.MustParseAddr("192.0.2.2")
+	release := user.track(io.NopCloser(nil), first)
+	require.Error(t, checkUser(user, now, second))
+	require.NoError(t, checkUser(user, now, first))
+	user.track(io.NopCloser(nil), first)
+	require.Error(t, checkUser(user, now, first))
+	release()
+	require.NoError(t, checkUser(user, now, first))
+}
+
+func TestUserReservation(t *testing.T) {
+	t.Parallel()
+	user, err := NewUser(option.UserQuotaUser{
+		Name:           "alice",
+		MaxConnections: 1,
+		MaxIPs:         1,
+	}, false)
+	require.NoError(t, err)
+	now := time.Now()
+	first := netip.MustParseAddr("192.0.2.1")
+	second := netip.MustParseAddr("192.0.2.2")
+	release, err := user.Check(now, first)
+	require.NoError(t, err)
+	require.Error(t, checkUser(user, now, first))
+	require.Error(t, checkUser(user, now, second))
+	release()
+	release()
+	require.Zero(t, user.reserved)
+	require.Empty(t, user.sourceIPs)
+	release, err = user.Check(now, second)
+	require.NoError(t, err)
+	user.track(io.NopCloser(nil), second)
+	release()
+	require.Equal(t, 1, user.connections.Len())
+	require.Error(t, checkUser(user, now, second))
+}
+
+func TestUserNextReset(t *testing.T) {
+	t.Parallel()
+	user, err := NewUser(option.UserQuotaUser{Name: "alice", ResetPeriod: resetPeriodWeekly}, false)
+	require.NoError(t, err)
+	user.lastReset = time.Date(2024, time.January, 3, 12, 0, 0, 0, time.UTC)
+	require.Equal(t, time.Date(2024, time.January, 8, 0, 0, 0, 0, time.UTC), user.nextReset())
+	user.options.ResetPeriod = resetPeriodMonthly
+	require.Equal(t, time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC), user.nextReset())
+}
+
+func checkUser(user *User, now time.Time, source netip.Addr) error {
+	release, err := user.Check(now, source)
+	if err != nil {
+		return err
+	}
+	release()
+	return nil
+}